@@ -6,8 +6,12 @@ import (
 	db "go-musthave-diploma-tpl/internal/gophermart/config/db"
 	chiRouter "go-musthave-diploma-tpl/internal/gophermart/handler"
 	"go-musthave-diploma-tpl/internal/gophermart/listener"
+	"go-musthave-diploma-tpl/internal/gophermart/middleware"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
 	"go-musthave-diploma-tpl/internal/gophermart/repository/postgres"
 	"go-musthave-diploma-tpl/internal/gophermart/service"
+	"go-musthave-diploma-tpl/pkg/oidc"
+	"go-musthave-diploma-tpl/pkg/password"
 	logger "go-musthave-diploma-tpl/pkg/runtime/logger"
 	"net/http"
 	"os"
@@ -24,6 +28,19 @@ func main() {
 	cfg := config.Load()
 	// логгер
 	customLogger := logger.NewHTTPLogger().Logger.Sugar()
+	// ключи шифрования куки
+	cookieKeys, err := cfg.CookieKeys()
+	if err != nil {
+		customLogger.Fatalf("Ключи куки не загружены: %v", err)
+	}
+	if err := cfg.ValidateCookieKeys(cookieKeys); err != nil {
+		customLogger.Fatalf("Недопустимые ключи куки: %v", err)
+	}
+	keyring, err := middleware.NewKeyring(cookieKeys)
+	if err != nil {
+		customLogger.Fatalf("Ключи куки не загружены: %v", err)
+	}
+	middleware.SetKeyring(keyring)
 	// бд и миграции
 	if err := db.Init(cfg.DatabaseURI); err != nil {
 		customLogger.Fatalf("PostgreSQL недоступна: %v", zap.Error(err))
@@ -38,7 +55,120 @@ func main() {
 		addr = "http://" + addr
 	}
 
-	svc := service.NewGofemartService(repo, addr)
+	// политика паролей
+	var denyList []string
+	if cfg.PasswordDenyListFile != "" {
+		denyList, err = password.LoadDenyList(cfg.PasswordDenyListFile)
+		if err != nil {
+			customLogger.Fatalf("Список запрещённых паролей не загружен: %v", err)
+		}
+	}
+
+	// вход через провайдера OpenID Connect
+	var oidcProvider *oidc.Provider
+	if cfg.OIDCIssuer != "" {
+		oidcCtx, oidcCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		oidcProvider, err = oidc.NewProvider(oidcCtx, oidc.Config{
+			IssuerURL:    cfg.OIDCIssuer,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Scopes:       strings.Fields(cfg.OIDCScopes),
+		}, nil)
+		oidcCancel()
+		if err != nil {
+			customLogger.Fatalf("Провайдер OpenID Connect недоступен: %v", err)
+		}
+	}
+
+	// сгорание баллов
+	if !service.ValidPointsExpiryPolicy(cfg.PointsExpiry) {
+		customLogger.Fatalf("Неизвестная политика сгорания баллов: %s", cfg.PointsExpiry)
+	}
+
+	// уровни программы лояльности
+	tiers, err := service.ParseTiers(cfg.Tiers)
+	if err != nil {
+		customLogger.Fatalf("Недопустимые уровни программы лояльности: %v", err)
+	}
+	tierPolicy := models.TierPolicy{Tiers: tiers, Window: cfg.TierWindow, Review: cfg.TierReview}
+
+	svc := service.NewGofemartService(repo, addr,
+		service.WithPasswordHasher(password.NewHasher(cfg.PasswordHashCost)),
+		service.WithPasswordPolicy(password.NewPolicy(cfg.PasswordMinLength, denyList)),
+		service.WithTokenSettings(service.TokenSettings{
+			Secret:     []byte(cfg.JWTSecret),
+			AccessTTL:  cfg.AccessTokenTTL,
+			RefreshTTL: cfg.RefreshTokenTTL,
+		}),
+		service.WithSessionTTL(cfg.SessionTTL),
+		service.WithTwoFactorSettings(service.TwoFactorSettings{
+			Issuer:            cfg.TOTPIssuer,
+			WithdrawThreshold: cfg.TOTPWithdrawThreshold,
+		}),
+		service.WithLoginThrottle(service.LoginThrottle{
+			MaxFailures:      cfg.LoginMaxFailures,
+			MaxFailuresPerIP: cfg.LoginMaxFailuresPerIP,
+			Delay:            cfg.LoginDelay,
+			Lockout:          cfg.LoginLockout,
+			Window:           cfg.LoginFailureWindow,
+		}),
+		service.WithOIDCProvider(oidcProvider),
+		service.WithIdempotencyKeyTTL(cfg.IdempotencyKeyTTL),
+		service.WithHoldTTL(cfg.HoldTTL),
+		service.WithPointsExpiry(models.PointsExpiry{
+			Policy:   cfg.PointsExpiry,
+			Lifetime: cfg.PointsLifetime,
+		}, cfg.PointsExpiringSoon),
+		service.WithTierPolicy(tierPolicy),
+		service.WithTransferLimits(models.TransferLimits{
+			DailyAmount: cfg.TransferDailyLimit,
+			DailyCount:  cfg.TransferDailyCount,
+		}),
+		service.WithReferralLimits(models.ReferralLimits{
+			DailyCount:    cfg.ReferralDailyCount,
+			AddressWindow: cfg.ReferralAddressWindow,
+		}),
+	)
+	// режим администратора: назначить роль, снять блокировку входа и выйти
+	if cfg.UnlockLogin != "" || cfg.UnlockIP != "" || cfg.GrantRole != "" {
+		if cfg.GrantRole != "" {
+			login, role, _ := strings.Cut(cfg.GrantRole, ":")
+			if err := svc.SetRoleByLogin(login, role); err != nil {
+				customLogger.Fatalf("Не удалось назначить роль: %v", err)
+			}
+			customLogger.Infof("Пользователю %s назначена роль %s", login, role)
+		}
+		if cfg.UnlockLogin != "" {
+			if err := svc.UnlockLogin(cfg.UnlockLogin); err != nil {
+				customLogger.Fatalf("Не удалось разблокировать логин: %v", err)
+			}
+			customLogger.Infof("Вход для логина %s разблокирован", cfg.UnlockLogin)
+		}
+		if cfg.UnlockIP != "" {
+			if err := svc.UnlockIP(cfg.UnlockIP); err != nil {
+				customLogger.Fatalf("Не удалось разблокировать адрес: %v", err)
+			}
+			customLogger.Infof("Вход с адреса %s разблокирован", cfg.UnlockIP)
+		}
+		return
+	}
+	// сверка журнала: расхождения печатаются, код выхода ненулевой
+	if cfg.CheckLedger {
+		mismatches, err := svc.CheckLedger()
+		if err != nil {
+			customLogger.Fatalf("Не удалось сверить журнал: %v", err)
+		}
+		for _, m := range mismatches {
+			customLogger.Infof("Расхождение: пользователь %d, %s: ожидалось %s, фактически %s",
+				m.UserID, m.Check, m.Expected, m.Actual)
+		}
+		if len(mismatches) > 0 {
+			os.Exit(1)
+		}
+		customLogger.Infof("Журнал баллов сходится с остатками")
+		return
+	}
 	//инициализируем хандлеры
 	h := chiRouter.NewHandler(svc)
 	//инициализируем роуты
@@ -49,8 +179,12 @@ func main() {
 	defer cancel()
 
 	// ПЕРЕДАЕМ АДРЕС СЕРВИСА НАЧИСЛЕНИЙ В LISTENER
-	orderListener := listener.NewOrderListener(cfg.DatabaseURI, cfg.AccrualSystemAddress, customLogger)
+	orderListener := listener.NewOrderListener(cfg.DatabaseURI, cfg.AccrualSystemAddress, customLogger,
+		listener.WithTiers(tierPolicy),
+		listener.WithReferralBonus(cfg.ReferralBonus))
 	orderListener.Start(ctx)
+	// периодические задачи: очистка ключей идемпотентности, снятие истёкших резервов
+	svc.StartBackgroundJobs(ctx)
 
 	//создаём серве
 	server := &http.Server{
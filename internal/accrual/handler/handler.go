@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"go-musthave-diploma-tpl/internal/accrual/models"
 	"go-musthave-diploma-tpl/internal/accrual/storage"
+	"go-musthave-diploma-tpl/pkg/money"
 	"net/http"
 	"strconv"
 	"strings"
@@ -15,9 +16,9 @@ import (
 
 //go:generate mockgen -source=handler.go -destination=mocks/mock.go
 type Service interface {
-	CreateProductReward(ctx context.Context, match string, reward float64, rewardType string) error
+	CreateProductReward(ctx context.Context, match string, reward money.Amount, rewardType string) error
 	RegisterNewOrder(ctx context.Context, order models.Order) (bool, error)
-	GetAccrualInfo(order int64) (string, float64, bool, error)
+	GetAccrualInfo(order int64) (string, money.Amount, bool, error)
 }
 
 type Handler struct {
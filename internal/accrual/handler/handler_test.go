@@ -7,6 +7,7 @@ import (
 	"errors"
 	mock_handler "go-musthave-diploma-tpl/internal/accrual/handler/mocks"
 	"go-musthave-diploma-tpl/internal/accrual/models"
+	"go-musthave-diploma-tpl/pkg/money"
 	"net/http/httptest"
 	"testing"
 
@@ -18,7 +19,7 @@ import (
 func TestHandler_CreateProductReward(t *testing.T) {
 	type args struct {
 		match      string
-		reward     float64
+		reward     money.Amount
 		rewardType string
 	}
 	type mockBehavior func(r *mock_handler.MockService, args args)
@@ -36,7 +37,7 @@ func TestHandler_CreateProductReward(t *testing.T) {
 			inputBody: `{"match":"12345","reward":10.5,"reward_type":"%"}`,
 			inputArgs: args{
 				match:      "12345",
-				reward:     10.5,
+				reward:     money.FromCents(1050),
 				rewardType: "%",
 			},
 			mockBehavior: func(r *mock_handler.MockService, args args) {
@@ -45,6 +46,20 @@ func TestHandler_CreateProductReward(t *testing.T) {
 			expectedStatusCode:   200,
 			expectedResponseBody: "",
 		},
+		{
+			name:      "Reward with three decimals is rounded",
+			inputBody: `{"match":"12345","reward":7.555,"reward_type":"pt"}`,
+			inputArgs: args{
+				match:      "12345",
+				reward:     money.FromCents(756),
+				rewardType: "pt",
+			},
+			mockBehavior: func(r *mock_handler.MockService, args args) {
+				r.EXPECT().CreateProductReward(context.Background(), args.match, args.reward, args.rewardType).Return(nil)
+			},
+			expectedStatusCode:   200,
+			expectedResponseBody: "",
+		},
 		{
 			name:                 "Wrong input",
 			inputBody:            `{"match":"12345"`,
@@ -58,7 +73,7 @@ func TestHandler_CreateProductReward(t *testing.T) {
 			inputBody: `{"match":"12345","reward":10.5,"reward_type":"%"}`,
 			inputArgs: args{
 				match:      "12345",
-				reward:     10.5,
+				reward:     money.FromCents(1050),
 				rewardType: "%",
 			},
 			mockBehavior: func(r *mock_handler.MockService, args args) {
@@ -118,7 +133,7 @@ func TestHandler_RegisterNewOrder(t *testing.T) {
 					Goods: []models.Goods{
 						{
 							Description: "product1",
-							Price:       100.5,
+							Price:       money.FromCents(10050),
 						},
 					},
 				},
@@ -129,6 +144,26 @@ func TestHandler_RegisterNewOrder(t *testing.T) {
 			expectedStatusCode:   200,
 			expectedResponseBody: "",
 		},
+		{
+			name:      "Price with three decimals is rounded",
+			inputBody: `{"order":"12345","goods":[{"description":"product1","price":47399.999}]}`,
+			inputArgs: args{
+				order: models.Order{
+					Order: "12345",
+					Goods: []models.Goods{
+						{
+							Description: "product1",
+							Price:       money.FromCents(4740000),
+						},
+					},
+				},
+			},
+			mockBehavior: func(r *mock_handler.MockService, args args) {
+				r.EXPECT().RegisterNewOrder(context.Background(), args.order).Return(false, nil)
+			},
+			expectedStatusCode:   202,
+			expectedResponseBody: "",
+		},
 		{
 			name:      "Order already exists",
 			inputBody: `{"order":"12345","goods":[{"description":"product1","price":100.5}]}`,
@@ -138,7 +173,7 @@ func TestHandler_RegisterNewOrder(t *testing.T) {
 					Goods: []models.Goods{
 						{
 							Description: "product1",
-							Price:       100.5,
+							Price:       money.FromCents(10050),
 						},
 					},
 				},
@@ -166,7 +201,7 @@ func TestHandler_RegisterNewOrder(t *testing.T) {
 					Goods: []models.Goods{
 						{
 							Description: "product1",
-							Price:       100.5,
+							Price:       money.FromCents(10050),
 						},
 					},
 				},
@@ -209,14 +244,14 @@ func TestHandler_GetAccrualInfo(t *testing.T) {
 	type args struct {
 		order int64
 	}
-	type mockBehavior func(r *mock_handler.MockService, args args, status string, accrual float64, exist bool)
+	type mockBehavior func(r *mock_handler.MockService, args args, status string, accrual money.Amount, exist bool)
 
 	tests := []struct {
 		name                 string
 		orderNumber          string
 		inputArgs            args
 		status               string
-		accrual              float64
+		accrual              money.Amount
 		exist                bool
 		mockBehavior         mockBehavior
 		expectedStatusCode   int
@@ -229,9 +264,9 @@ func TestHandler_GetAccrualInfo(t *testing.T) {
 				order: 12345,
 			},
 			status:  models.Processed,
-			accrual: 100.5,
+			accrual: money.FromCents(10050),
 			exist:   true,
-			mockBehavior: func(r *mock_handler.MockService, args args, status string, accrual float64, exist bool) {
+			mockBehavior: func(r *mock_handler.MockService, args args, status string, accrual money.Amount, exist bool) {
 				r.EXPECT().GetAccrualInfo(args.order).Return(status, accrual, exist, nil)
 			},
 			expectedStatusCode:   200,
@@ -244,9 +279,9 @@ func TestHandler_GetAccrualInfo(t *testing.T) {
 				order: 12345,
 			},
 			status:  "",
-			accrual: 0,
+			accrual: money.Zero,
 			exist:   false,
-			mockBehavior: func(r *mock_handler.MockService, args args, status string, accrual float64, exist bool) {
+			mockBehavior: func(r *mock_handler.MockService, args args, status string, accrual money.Amount, exist bool) {
 				r.EXPECT().GetAccrualInfo(args.order).Return(status, accrual, exist, nil)
 			},
 			expectedStatusCode:   204,
@@ -256,7 +291,7 @@ func TestHandler_GetAccrualInfo(t *testing.T) {
 			name:                 "Wrong input",
 			orderNumber:          "abc",
 			inputArgs:            args{},
-			mockBehavior:         func(r *mock_handler.MockService, args args, status string, accrual float64, exist bool) {},
+			mockBehavior:         func(r *mock_handler.MockService, args args, status string, accrual money.Amount, exist bool) {},
 			expectedStatusCode:   400,
 			expectedResponseBody: "",
 		},
@@ -266,8 +301,8 @@ func TestHandler_GetAccrualInfo(t *testing.T) {
 			inputArgs: args{
 				order: 12345,
 			},
-			mockBehavior: func(r *mock_handler.MockService, args args, status string, accrual float64, exist bool) {
-				r.EXPECT().GetAccrualInfo(args.order).Return("", 0.0, false, errors.New("service error"))
+			mockBehavior: func(r *mock_handler.MockService, args args, status string, accrual money.Amount, exist bool) {
+				r.EXPECT().GetAccrualInfo(args.order).Return("", money.Zero, false, errors.New("service error"))
 			},
 			expectedStatusCode:   500,
 			expectedResponseBody: "",
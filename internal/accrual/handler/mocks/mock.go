@@ -7,6 +7,7 @@ package mock_handler
 import (
 	context "context"
 	models "go-musthave-diploma-tpl/internal/accrual/models"
+	money "go-musthave-diploma-tpl/pkg/money"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -36,7 +37,7 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 }
 
 // CreateProductReward mocks base method.
-func (m *MockService) CreateProductReward(ctx context.Context, match string, reward float64, rewardType string) error {
+func (m *MockService) CreateProductReward(ctx context.Context, match string, reward money.Amount, rewardType string) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateProductReward", ctx, match, reward, rewardType)
 	ret0, _ := ret[0].(error)
@@ -50,11 +51,11 @@ func (mr *MockServiceMockRecorder) CreateProductReward(ctx, match, reward, rewar
 }
 
 // GetAccrualInfo mocks base method.
-func (m *MockService) GetAccrualInfo(order int64) (string, float64, bool, error) {
+func (m *MockService) GetAccrualInfo(order int64) (string, money.Amount, bool, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetAccrualInfo", order)
 	ret0, _ := ret[0].(string)
-	ret1, _ := ret[1].(float64)
+	ret1, _ := ret[1].(money.Amount)
 	ret2, _ := ret[2].(bool)
 	ret3, _ := ret[3].(error)
 	return ret0, ret1, ret2, ret3
@@ -0,0 +1 @@
+ALTER TABLE products ALTER COLUMN reward TYPE FLOAT USING reward::float;
@@ -0,0 +1,2 @@
+-- FLOAT не хранит сотые точно: 7.1% превращался в 7.0999999...
+ALTER TABLE products ALTER COLUMN reward TYPE NUMERIC(10,2) USING round(reward::numeric, 2);
@@ -1,9 +1,35 @@
 package models
 
+import (
+	"encoding/json"
+
+	"go-musthave-diploma-tpl/pkg/money"
+)
+
 type ProductReward struct {
-	Match      string  `json:"match"`
-	Reward     float64 `json:"reward"`
-	RewardType string  `json:"reward_type"`
+	Match string `json:"match"`
+	// Reward - процент от цены товара для reward_type "%" или баллы для "pt"
+	Reward     money.Amount `json:"reward"`
+	RewardType string       `json:"reward_type"`
+}
+
+// UnmarshalJSON округляет вознаграждение до сотых: его присылают внешние системы, лишние знаки - не ошибка
+func (p *ProductReward) UnmarshalJSON(data []byte) error {
+	type productReward ProductReward
+	var raw struct {
+		productReward
+		Reward json.RawMessage `json:"reward"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	reward, err := money.UnmarshalJSONRound(raw.Reward, money.HalfEven)
+	if err != nil {
+		return err
+	}
+	*p = ProductReward(raw.productReward)
+	p.Reward = reward
+	return nil
 }
 
 type Order struct {
@@ -12,8 +38,27 @@ type Order struct {
 }
 
 type Goods struct {
-	Description string  `json:"description"`
-	Price       float64 `json:"price"`
+	Description string       `json:"description"`
+	Price       money.Amount `json:"price"`
+}
+
+// UnmarshalJSON округляет цену до сотых: цены приходят от магазинов, лишние знаки - не ошибка
+func (g *Goods) UnmarshalJSON(data []byte) error {
+	type goods Goods
+	var raw struct {
+		goods
+		Price json.RawMessage `json:"price"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	price, err := money.UnmarshalJSONRound(raw.Price, money.HalfEven)
+	if err != nil {
+		return err
+	}
+	*g = Goods(raw.goods)
+	g.Price = price
+	return nil
 }
 
 const (
@@ -24,12 +69,12 @@ const (
 )
 
 type AccrualInfo struct {
-	Order   int64   `json:"order"`
-	Status  string  `json:"status"`
-	Accrual float64 `json:"accrual,omitempty"`
+	Order   int64        `json:"order"`
+	Status  string       `json:"status"`
+	Accrual money.Amount `json:"accrual,omitempty"`
 }
 
 type ParseMatch struct {
-	Order int64   `json:"order"`
-	Price float64 `json:"price"`
+	Order int64        `json:"order"`
+	Price money.Amount `json:"price"`
 }
@@ -3,14 +3,15 @@ package repository
 import (
 	"context"
 	"go-musthave-diploma-tpl/internal/accrual/models"
+	"go-musthave-diploma-tpl/pkg/money"
 )
 
 type Storage interface {
-	CreateProductReward(ctx context.Context, match string, reward float64, rewardType string) error
+	CreateProductReward(ctx context.Context, match string, reward money.Amount, rewardType string) error
 	RegisterNewOrder(ctx context.Context, order int64, goods []models.Goods, status string) error
 	CheckOrderExists(order int64) (bool, error)
-	GetAccrualInfo(order int64) (string, float64, error)
-	UpdateAccrualInfo(ctx context.Context, order int64, accrual float64, status string) error
+	GetAccrualInfo(order int64) (string, money.Amount, error)
+	UpdateAccrualInfo(ctx context.Context, order int64, accrual money.Amount, status string) error
 	UpdateStatus(ctx context.Context, status string, order int64) error
 	GetProductsInfo() ([]models.ProductReward, error)
 	ParseMatch(match string) ([]models.ParseMatch, error)
@@ -25,7 +26,7 @@ func NewRepository(storage Storage) *Repository {
 	return &Repository{storage: storage}
 }
 
-func (r *Repository) CreateProductReward(ctx context.Context, match string, reward float64, rewardType string) error {
+func (r *Repository) CreateProductReward(ctx context.Context, match string, reward money.Amount, rewardType string) error {
 	return r.storage.CreateProductReward(ctx, match, reward, rewardType)
 }
 
@@ -37,11 +38,11 @@ func (r *Repository) CheckOrderExists(order int64) (bool, error) {
 	return r.storage.CheckOrderExists(order)
 }
 
-func (r *Repository) GetAccrualInfo(order int64) (string, float64, error) {
+func (r *Repository) GetAccrualInfo(order int64) (string, money.Amount, error) {
 	return r.storage.GetAccrualInfo(order)
 }
 
-func (r *Repository) UpdateAccrualInfo(ctx context.Context, order int64, accrual float64, status string) error {
+func (r *Repository) UpdateAccrualInfo(ctx context.Context, order int64, accrual money.Amount, status string) error {
 	return r.storage.UpdateAccrualInfo(ctx, order, accrual, status)
 }
 
@@ -7,6 +7,7 @@ package mock_service
 import (
 	context "context"
 	models "go-musthave-diploma-tpl/internal/accrual/models"
+	money "go-musthave-diploma-tpl/pkg/money"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -51,7 +52,7 @@ func (mr *MockRepositoryMockRecorder) CheckOrderExists(order interface{}) *gomoc
 }
 
 // CreateProductReward mocks base method.
-func (m *MockRepository) CreateProductReward(ctx context.Context, match string, reward float64, rewardType string) error {
+func (m *MockRepository) CreateProductReward(ctx context.Context, match string, reward money.Amount, rewardType string) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateProductReward", ctx, match, reward, rewardType)
 	ret0, _ := ret[0].(error)
@@ -65,11 +66,11 @@ func (mr *MockRepositoryMockRecorder) CreateProductReward(ctx, match, reward, re
 }
 
 // GetAccrualInfo mocks base method.
-func (m *MockRepository) GetAccrualInfo(order int64) (string, float64, error) {
+func (m *MockRepository) GetAccrualInfo(order int64) (string, money.Amount, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetAccrualInfo", order)
 	ret0, _ := ret[0].(string)
-	ret1, _ := ret[1].(float64)
+	ret1, _ := ret[1].(money.Amount)
 	ret2, _ := ret[2].(error)
 	return ret0, ret1, ret2
 }
@@ -140,7 +141,7 @@ func (mr *MockRepositoryMockRecorder) RegisterNewOrder(ctx, order, goods, status
 }
 
 // UpdateAccrualInfo mocks base method.
-func (m *MockRepository) UpdateAccrualInfo(ctx context.Context, order int64, accrual float64, status string) error {
+func (m *MockRepository) UpdateAccrualInfo(ctx context.Context, order int64, accrual money.Amount, status string) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UpdateAccrualInfo", ctx, order, accrual, status)
 	ret0, _ := ret[0].(error)
@@ -5,12 +5,16 @@ import (
 	"fmt"
 	"go-musthave-diploma-tpl/internal/accrual/models"
 	luhn "go-musthave-diploma-tpl/pkg"
+	"go-musthave-diploma-tpl/pkg/money"
 	"strconv"
 	"time"
 
 	"go.uber.org/zap"
 )
 
+// rewardRounding - доли сотых в начислении округляются один раз на заказ, к чётному
+const rewardRounding = money.HalfEven
+
 // orderProductMatch represents a match between an order item and a product rule
 type orderProductMatch struct {
 	Order   models.ParseMatch
@@ -19,11 +23,11 @@ type orderProductMatch struct {
 
 //go:generate mockgen -source=service.go -destination=mocks/mock.go -package=mock_service
 type Repository interface {
-	CreateProductReward(ctx context.Context, match string, reward float64, rewardType string) error
+	CreateProductReward(ctx context.Context, match string, reward money.Amount, rewardType string) error
 	RegisterNewOrder(ctx context.Context, order int64, goods []models.Goods, status string) error
 	CheckOrderExists(order int64) (bool, error)
-	GetAccrualInfo(order int64) (string, float64, error)
-	UpdateAccrualInfo(ctx context.Context, order int64, accrual float64, status string) error
+	GetAccrualInfo(order int64) (string, money.Amount, error)
+	UpdateAccrualInfo(ctx context.Context, order int64, accrual money.Amount, status string) error
 	UpdateStatus(ctx context.Context, status string, order int64) error
 	GetProductsInfo() ([]models.ProductReward, error)
 	ParseMatch(match string) ([]models.ParseMatch, error)
@@ -40,7 +44,7 @@ func NewService(repo Repository) *Service {
 	}
 }
 
-func (s *Service) CreateProductReward(ctx context.Context, match string, reward float64, rewardType string) error {
+func (s *Service) CreateProductReward(ctx context.Context, match string, reward money.Amount, rewardType string) error {
 	return s.repo.CreateProductReward(ctx, match, reward, rewardType)
 }
 
@@ -64,7 +68,7 @@ func (s *Service) RegisterNewOrder(ctx context.Context, order models.Order) (boo
 	return exist, nil
 }
 
-func (s *Service) GetAccrualInfo(order int64) (string, float64, bool, error) {
+func (s *Service) GetAccrualInfo(order int64) (string, money.Amount, bool, error) {
 	exist, err := s.repo.CheckOrderExists(order)
 	if err != nil {
 		return "", 0, exist, err
@@ -143,22 +147,20 @@ func (s *Service) processOrders(ctx context.Context, log *zap.SugaredLogger) err
 			continue
 		}
 
-		totalAccrual := 0.0
+		var sum money.Accumulator
 		for _, match := range matches {
-			var accrual float64
 			if match.Product.RewardType == "%" {
-				accrual = match.Order.Price * match.Product.Reward / 100
+				sum.AddPercent(match.Order.Price, match.Product.Reward)
 			} else {
-
-				accrual = match.Product.Reward
+				sum.Add(match.Product.Reward)
 			}
-			totalAccrual += accrual
 		}
+		totalAccrual := sum.Total(rewardRounding)
 
 		if err := s.repo.UpdateAccrualInfo(ctx, orderID, totalAccrual, models.Processed); err != nil {
 			log.Errorf("Failed to update accrual for order %d: %v", orderID, err)
 		} else {
-			log.Infof("Updated accrual for order %d: %.2f", orderID, totalAccrual)
+			log.Infof("Updated accrual for order %d: %s", orderID, totalAccrual)
 		}
 	}
 
@@ -185,26 +187,23 @@ func (s *Service) processOrders(ctx context.Context, log *zap.SugaredLogger) err
 
 // updateOrderAccrual обновляет начисление бонусов для заказа
 func (s *Service) updateOrderAccrual(ctx context.Context, log *zap.SugaredLogger, orderID int64, orderItems []models.ParseMatch, product models.ProductReward) error {
-	var totalAccrual float64
+	var sum money.Accumulator
 
 	// Общая сумма начислений для всех товаров в заказе
 	for _, item := range orderItems {
-		var accrual float64
 		if product.RewardType == "%" {
-
-			accrual = item.Price * product.Reward / 100
+			sum.AddPercent(item.Price, product.Reward)
 		} else {
-
-			accrual = product.Reward
+			sum.Add(product.Reward)
 		}
-		totalAccrual += accrual
 	}
+	totalAccrual := sum.Total(rewardRounding)
 
 	// Обновление информации о начислениях для заказа
 	if err := s.repo.UpdateAccrualInfo(ctx, orderID, totalAccrual, models.Processed); err != nil {
 		return fmt.Errorf("failed to update accrual info for order %d: %w", orderID, err)
 	}
 
-	log.Infof("Updated accrual for order %d: %.2f", orderID, totalAccrual)
+	log.Infof("Updated accrual for order %d: %s", orderID, totalAccrual)
 	return nil
 }
@@ -8,6 +8,7 @@ import (
 
 	"go-musthave-diploma-tpl/internal/accrual/models"
 	mock_service "go-musthave-diploma-tpl/internal/accrual/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -17,7 +18,7 @@ import (
 func TestService_CreateProductReward(t *testing.T) {
 	type args struct {
 		match      string
-		reward     float64
+		reward     money.Amount
 		rewardType string
 	}
 	type mockBehavior func(r *mock_service.MockRepository, args args)
@@ -32,7 +33,7 @@ func TestService_CreateProductReward(t *testing.T) {
 			name: "Ok",
 			inputArgs: args{
 				match:      "12345",
-				reward:     10.5,
+				reward:     money.FromCents(1050),
 				rewardType: "%",
 			},
 			mockBehavior: func(r *mock_service.MockRepository, args args) {
@@ -44,7 +45,7 @@ func TestService_CreateProductReward(t *testing.T) {
 			name: "Repository error",
 			inputArgs: args{
 				match:      "12345",
-				reward:     10.5,
+				reward:     money.FromCents(1050),
 				rewardType: "%",
 			},
 			mockBehavior: func(r *mock_service.MockRepository, args args) {
@@ -98,7 +99,7 @@ func TestService_RegisterNewOrder(t *testing.T) {
 					Goods: []models.Goods{
 						{
 							Description: "product1",
-							Price:       100.5,
+							Price:       money.FromCents(10050),
 						},
 					},
 				},
@@ -118,7 +119,7 @@ func TestService_RegisterNewOrder(t *testing.T) {
 					Goods: []models.Goods{
 						{
 							Description: "product1",
-							Price:       100.5,
+							Price:       money.FromCents(10050),
 						},
 					},
 				},
@@ -138,7 +139,7 @@ func TestService_RegisterNewOrder(t *testing.T) {
 					Goods: []models.Goods{
 						{
 							Description: "product1",
-							Price:       100.5,
+							Price:       money.FromCents(10050),
 						},
 					},
 				},
@@ -155,7 +156,7 @@ func TestService_RegisterNewOrder(t *testing.T) {
 					Goods: []models.Goods{
 						{
 							Description: "product1",
-							Price:       100.5,
+							Price:       money.FromCents(10050),
 						},
 					},
 				},
@@ -174,7 +175,7 @@ func TestService_RegisterNewOrder(t *testing.T) {
 					Goods: []models.Goods{
 						{
 							Description: "product1",
-							Price:       100.5,
+							Price:       money.FromCents(10050),
 						},
 					},
 				},
@@ -217,13 +218,13 @@ func TestService_GetAccrualInfo(t *testing.T) {
 	type args struct {
 		order int64
 	}
-	type mockBehavior func(r *mock_service.MockRepository, args args, status string, accrual float64, exist bool)
+	type mockBehavior func(r *mock_service.MockRepository, args args, status string, accrual money.Amount, exist bool)
 
 	tests := []struct {
 		name         string
 		inputArgs    args
 		status       string
-		accrual      float64
+		accrual      money.Amount
 		exist        bool
 		mockBehavior mockBehavior
 		expectedErr  bool
@@ -234,9 +235,9 @@ func TestService_GetAccrualInfo(t *testing.T) {
 				order: 12345,
 			},
 			status:  models.Processed,
-			accrual: 100.5,
+			accrual: money.FromCents(10050),
 			exist:   true,
-			mockBehavior: func(r *mock_service.MockRepository, args args, status string, accrual float64, exist bool) {
+			mockBehavior: func(r *mock_service.MockRepository, args args, status string, accrual money.Amount, exist bool) {
 				r.EXPECT().CheckOrderExists(args.order).Return(exist, nil)
 				r.EXPECT().GetAccrualInfo(args.order).Return(status, accrual, nil)
 			},
@@ -248,9 +249,9 @@ func TestService_GetAccrualInfo(t *testing.T) {
 				order: 12345,
 			},
 			status:  "",
-			accrual: 0.0,
+			accrual: money.Zero,
 			exist:   false,
-			mockBehavior: func(r *mock_service.MockRepository, args args, status string, accrual float64, exist bool) {
+			mockBehavior: func(r *mock_service.MockRepository, args args, status string, accrual money.Amount, exist bool) {
 				r.EXPECT().CheckOrderExists(args.order).Return(exist, nil)
 			},
 			expectedErr: false,
@@ -260,7 +261,7 @@ func TestService_GetAccrualInfo(t *testing.T) {
 			inputArgs: args{
 				order: 12345,
 			},
-			mockBehavior: func(r *mock_service.MockRepository, args args, status string, accrual float64, exist bool) {
+			mockBehavior: func(r *mock_service.MockRepository, args args, status string, accrual money.Amount, exist bool) {
 				r.EXPECT().CheckOrderExists(args.order).Return(false, errors.New("database error"))
 			},
 			expectedErr: true,
@@ -271,9 +272,9 @@ func TestService_GetAccrualInfo(t *testing.T) {
 				order: 12345,
 			},
 			exist: true,
-			mockBehavior: func(r *mock_service.MockRepository, args args, status string, accrual float64, exist bool) {
+			mockBehavior: func(r *mock_service.MockRepository, args args, status string, accrual money.Amount, exist bool) {
 				r.EXPECT().CheckOrderExists(args.order).Return(exist, nil)
-				r.EXPECT().GetAccrualInfo(args.order).Return("", 0.0, errors.New("database error"))
+				r.EXPECT().GetAccrualInfo(args.order).Return("", money.Zero, errors.New("database error"))
 			},
 			expectedErr: true,
 		},
@@ -364,12 +365,12 @@ func TestService_updateOrderAccrual(t *testing.T) {
 		orderItems []models.ParseMatch
 		product    models.ProductReward
 	}
-	type mockBehavior func(r *mock_service.MockRepository, args args, totalAccrual float64)
+	type mockBehavior func(r *mock_service.MockRepository, args args, totalAccrual money.Amount)
 
 	tests := []struct {
 		name         string
 		inputArgs    args
-		totalAccrual float64
+		totalAccrual money.Amount
 		mockBehavior mockBehavior
 		expectedErr  bool
 	}{
@@ -380,17 +381,17 @@ func TestService_updateOrderAccrual(t *testing.T) {
 				orderItems: []models.ParseMatch{
 					{
 						Order: 12345,
-						Price: 100,
+						Price: money.FromUnits(100),
 					},
 				},
 				product: models.ProductReward{
 					Match:      "12345",
-					Reward:     10,
+					Reward:     money.FromUnits(10),
 					RewardType: "pt",
 				},
 			},
-			totalAccrual: 10.0,
-			mockBehavior: func(r *mock_service.MockRepository, args args, totalAccrual float64) {
+			totalAccrual: money.FromUnits(10),
+			mockBehavior: func(r *mock_service.MockRepository, args args, totalAccrual money.Amount) {
 				r.EXPECT().UpdateAccrualInfo(gomock.Any(), args.orderID, totalAccrual, models.Processed).Return(nil)
 			},
 			expectedErr: false,
@@ -402,17 +403,39 @@ func TestService_updateOrderAccrual(t *testing.T) {
 				orderItems: []models.ParseMatch{
 					{
 						Order: 12345,
-						Price: 100,
+						Price: money.FromUnits(100),
 					},
 				},
 				product: models.ProductReward{
 					Match:      "12345",
-					Reward:     50,
+					Reward:     money.FromUnits(50),
 					RewardType: "abs",
 				},
 			},
-			totalAccrual: 50.0,
-			mockBehavior: func(r *mock_service.MockRepository, args args, totalAccrual float64) {
+			totalAccrual: money.FromUnits(50),
+			mockBehavior: func(r *mock_service.MockRepository, args args, totalAccrual money.Amount) {
+				r.EXPECT().UpdateAccrualInfo(gomock.Any(), args.orderID, totalAccrual, models.Processed).Return(nil)
+			},
+			expectedErr: false,
+		},
+		{
+			name: "Ok - Fractional percentage rounded once per order",
+			inputArgs: args{
+				orderID: 12345,
+				orderItems: []models.ParseMatch{
+					{Order: 12345, Price: money.FromCents(33)},
+					{Order: 12345, Price: money.FromCents(33)},
+					{Order: 12345, Price: money.FromCents(33)},
+				},
+				product: models.ProductReward{
+					Match:      "12345",
+					Reward:     money.FromUnits(50),
+					RewardType: "%",
+				},
+			},
+			// 3 * 0.165 = 0.495 -> 0.50 (к чётному), по строкам вышло бы 0.48
+			totalAccrual: money.FromCents(50),
+			mockBehavior: func(r *mock_service.MockRepository, args args, totalAccrual money.Amount) {
 				r.EXPECT().UpdateAccrualInfo(gomock.Any(), args.orderID, totalAccrual, models.Processed).Return(nil)
 			},
 			expectedErr: false,
@@ -424,17 +447,17 @@ func TestService_updateOrderAccrual(t *testing.T) {
 				orderItems: []models.ParseMatch{
 					{
 						Order: 12345,
-						Price: 100,
+						Price: money.FromUnits(100),
 					},
 				},
 				product: models.ProductReward{
 					Match:      "12345",
-					Reward:     10,
+					Reward:     money.FromUnits(10),
 					RewardType: "pt",
 				},
 			},
-			totalAccrual: 10.0,
-			mockBehavior: func(r *mock_service.MockRepository, args args, totalAccrual float64) {
+			totalAccrual: money.FromUnits(10),
+			mockBehavior: func(r *mock_service.MockRepository, args args, totalAccrual money.Amount) {
 				r.EXPECT().UpdateAccrualInfo(gomock.Any(), args.orderID, totalAccrual, models.Processed).Return(errors.New("database error"))
 			},
 			expectedErr: true,
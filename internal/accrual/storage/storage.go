@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"go-musthave-diploma-tpl/internal/accrual/models"
+	"go-musthave-diploma-tpl/pkg/money"
 	"strings"
 
 	"github.com/golang-migrate/migrate/v4"
@@ -64,7 +65,7 @@ func (db *PostgresDB) Close() error {
 	return db.DB.Close()
 }
 
-func (db *PostgresDB) CreateProductReward(ctx context.Context, match string, reward float64, rewardType string) error {
+func (db *PostgresDB) CreateProductReward(ctx context.Context, match string, reward money.Amount, rewardType string) error {
 	op := "path: internal/accrual/storage/CreateProductReward"
 	tx, err := db.DB.Begin()
 	if err != nil {
@@ -125,7 +126,7 @@ func (db *PostgresDB) RegisterNewOrder(ctx context.Context, order int64, goods [
 
 		desc := strings.ReplaceAll(good.Description, "'", "''")
 
-		goodsValues[i] = fmt.Sprintf(`"(%s,%f)"`, desc, good.Price)
+		goodsValues[i] = fmt.Sprintf(`"(%s,%s)"`, desc, good.Price)
 	}
 
 	// Create the array literal with proper PostgreSQL syntax
@@ -165,7 +166,7 @@ func (db *PostgresDB) CheckOrderExists(order int64) (bool, error) {
 	return exists, nil
 }
 
-func (db *PostgresDB) GetAccrualInfo(order int64) (string, float64, error) {
+func (db *PostgresDB) GetAccrualInfo(order int64) (string, money.Amount, error) {
 	op := "path: internal/accrual/storage/GetAccrualInfo"
 	tx, err := db.DB.Begin()
 	if err != nil {
@@ -177,7 +178,7 @@ func (db *PostgresDB) GetAccrualInfo(order int64) (string, float64, error) {
 		}
 	}()
 
-	var accrual sql.NullFloat64
+	var accrual money.Amount
 	var status string
 	err = tx.QueryRow(`
 		SELECT accrual, status FROM orders_accrual
@@ -186,14 +187,12 @@ func (db *PostgresDB) GetAccrualInfo(order int64) (string, float64, error) {
 	if err != nil {
 		return "", 0, fmt.Errorf("%s QueryRow err:%w", op, err)
 	}
-	if accrual.Valid {
-		return status, accrual.Float64, nil
-	}
-	return status, 0, nil
+	// NULL до расчёта сканируется в ноль
+	return status, accrual, nil
 
 }
 
-func (db *PostgresDB) UpdateAccrualInfo(ctx context.Context, order int64, accrual float64, status string) error {
+func (db *PostgresDB) UpdateAccrualInfo(ctx context.Context, order int64, accrual money.Amount, status string) error {
 	op := "path: internal/accrual/storage/UpdateAccrualInfo"
 	tx, err := db.DB.Begin()
 	if err != nil {
@@ -259,7 +258,7 @@ func (db *PostgresDB) GetProductsInfo() ([]models.ProductReward, error) {
 	defer rows.Close()
 	for rows.Next() {
 		var match string
-		var reward float64
+		var reward money.Amount
 		var rewardType string
 		err = rows.Scan(&match, &reward, &rewardType)
 		if err != nil {
@@ -287,7 +286,7 @@ func (db *PostgresDB) ParseMatch(match string) ([]models.ParseMatch, error) {
 	var parseMatches []models.ParseMatch
 	for rows.Next() {
 		var order int64
-		var price float64
+		var price money.Amount
 		err = rows.Scan(&order, &price)
 		if err != nil {
 			return []models.ParseMatch{}, fmt.Errorf("%s error scanning row:%w", op, err)
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DefaultCookieKeyID - идентификатор встроенного ключа
+const DefaultCookieKeyID = "default"
+
+var ErrDefaultCookieKey = errors.New("built-in default cookie key is used, set COOKIE_KEYS or COOKIE_KEYS_FILE (or -dev for local runs)")
+
+// CookieKey - ключ шифрования куки с идентификатором,
+// идентификатор пишется в куку, чтобы при ротации знать, каким ключом её открывать
+type CookieKey struct {
+	ID     string
+	Secret []byte
+}
+
+// CookieKeys возвращает связку ключей: первый - активный (им шифруем),
+// остальные - старые, ими только расшифровываем.
+// Источники по приоритету: файл, строка из env/флага, встроенный ключ
+func (cfg *Config) CookieKeys() ([]CookieKey, error) {
+	if cfg.CookieKeysFile != "" {
+		data, err := os.ReadFile(cfg.CookieKeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cookie keys file: %w", err)
+		}
+		return ParseCookieKeys(strings.Split(string(data), "\n"))
+	}
+
+	if cfg.CookieKeysRaw != "" {
+		return ParseCookieKeys(strings.Split(cfg.CookieKeysRaw, ","))
+	}
+
+	return []CookieKey{{ID: DefaultCookieKeyID, Secret: []byte(EncryptionKey)}}, nil
+}
+
+// ValidateCookieKeys - проверка при старте: без флага разработчика
+// сервис не запускается со встроенным ключом
+func (cfg *Config) ValidateCookieKeys(keys []CookieKey) error {
+	if len(keys) == 0 {
+		return errors.New("no cookie keys configured")
+	}
+	if cfg.Dev {
+		return nil
+	}
+	if string(keys[0].Secret) == EncryptionKey {
+		return ErrDefaultCookieKey
+	}
+	return nil
+}
+
+// ParseCookieKeys разбирает записи вида "id:secret".
+// Пустые строки и строки, начинающиеся с #, пропускаются
+func ParseCookieKeys(entries []string) ([]CookieKey, error) {
+	var keys []CookieKey
+	seen := make(map[string]bool)
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		id, secret, ok := strings.Cut(entry, ":")
+		if !ok || id == "" || secret == "" {
+			return nil, fmt.Errorf("invalid cookie key entry %q, expected id:secret", entry)
+		}
+		if strings.Contains(id, ".") {
+			return nil, fmt.Errorf("cookie key id %q must not contain '.'", id)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("duplicate cookie key id %q", id)
+		}
+		switch len(secret) {
+		case 16, 24, 32:
+		default:
+			return nil, fmt.Errorf("cookie key %q must be 16, 24 or 32 bytes long, got %d", id, len(secret))
+		}
+
+		seen[id] = true
+		keys = append(keys, CookieKey{ID: id, Secret: []byte(secret)})
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no cookie keys configured")
+	}
+
+	return keys, nil
+}
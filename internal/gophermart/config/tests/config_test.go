@@ -0,0 +1,39 @@
+package tests
+
+import (
+	"os"
+	"testing"
+
+	"go-musthave-diploma-tpl/internal/gophermart/config"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// флаги регистрируются в общем flag.CommandLine, поэтому Load вызывается в пакете один раз
+func TestLoad_Defaults(t *testing.T) {
+	for _, name := range []string{"TIERS", "REFERRAL_BONUS"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	args := os.Args
+	os.Args = []string{"gophermart"}
+	defer func() { os.Args = args }()
+
+	cfg := config.Load()
+
+	// уровни выключены, пока оператор их не настроит: начисления не умножаются
+	assert.Empty(t, cfg.Tiers)
+	tiers, err := service.ParseTiers(cfg.Tiers)
+	require.NoError(t, err)
+	policy := models.TierPolicy{Tiers: tiers, Window: cfg.TierWindow, Review: cfg.TierReview}
+	assert.False(t, policy.Enabled())
+	accrual := money.FromCents(12345)
+	assert.Equal(t, accrual, policy.ByName(models.TierGold).Apply(accrual))
+
+	// бонус за приглашение тоже включается только настройкой
+	assert.True(t, cfg.ReferralBonus.IsZero())
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-musthave-diploma-tpl/internal/gophermart/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCookieKeys(t *testing.T) {
+	tests := []struct {
+		name      string
+		entries   []string
+		expectErr bool
+		expectIDs []string
+	}{
+		{
+			name:      "Active and old key",
+			entries:   []string{"k2:new-key-new-key-new-key-new-key!", " k1:old-key-old-key-old-key-old-key! "},
+			expectIDs: []string{"k2", "k1"},
+		},
+		{
+			name:      "Comments and empty lines are skipped",
+			entries:   []string{"# ротация 2025", "", "k1:0123456789abcdef"},
+			expectIDs: []string{"k1"},
+		},
+		{
+			name:      "Missing separator",
+			entries:   []string{"0123456789abcdef"},
+			expectErr: true,
+		},
+		{
+			name:      "Wrong key length",
+			entries:   []string{"k1:short"},
+			expectErr: true,
+		},
+		{
+			name:      "Dot in key id",
+			entries:   []string{"k.1:0123456789abcdef"},
+			expectErr: true,
+		},
+		{
+			name:      "Duplicate key id",
+			entries:   []string{"k1:0123456789abcdef", "k1:fedcba9876543210"},
+			expectErr: true,
+		},
+		{
+			name:      "No keys",
+			entries:   []string{"", "# only comment"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := config.ParseCookieKeys(tt.entries)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			var ids []string
+			for _, k := range keys {
+				ids = append(ids, k.ID)
+			}
+			assert.Equal(t, tt.expectIDs, ids)
+		})
+	}
+}
+
+func TestConfig_CookieKeysFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cookie_keys")
+	require.NoError(t, os.WriteFile(path, []byte("k2:new-key-new-key-new-key-new-key!\nk1:old-key-old-key-old-key-old-key!\n"), 0600))
+
+	cfg := &config.Config{CookieKeysFile: path, CookieKeysRaw: "ignored:0123456789abcdef"}
+	keys, err := cfg.CookieKeys()
+
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "k2", keys[0].ID)
+}
+
+func TestConfig_ValidateCookieKeys(t *testing.T) {
+	t.Run("Built-in key is refused", func(t *testing.T) {
+		cfg := &config.Config{}
+		keys, err := cfg.CookieKeys()
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, cfg.ValidateCookieKeys(keys), config.ErrDefaultCookieKey)
+	})
+
+	t.Run("Built-in key is allowed in dev mode", func(t *testing.T) {
+		cfg := &config.Config{Dev: true}
+		keys, err := cfg.CookieKeys()
+		require.NoError(t, err)
+
+		assert.NoError(t, cfg.ValidateCookieKeys(keys))
+	})
+
+	t.Run("Configured key", func(t *testing.T) {
+		cfg := &config.Config{CookieKeysRaw: "k1:0123456789abcdef"}
+		keys, err := cfg.CookieKeys()
+		require.NoError(t, err)
+
+		assert.NoError(t, cfg.ValidateCookieKeys(keys))
+	})
+}
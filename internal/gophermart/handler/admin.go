@@ -0,0 +1,336 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go-musthave-diploma-tpl/internal/gophermart/middleware"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// adminActor - ID пользователя, выполняющего действие, и ID пользователя из пути
+func adminActor(w http.ResponseWriter, r *http.Request, withTarget bool) (actorID, targetID int, ok bool) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"`+ErrUserIsNotAuthenticated.Error()+`"}`, http.StatusUnauthorized)
+		return 0, 0, false
+	}
+	actorID, _ = strconv.Atoi(userID)
+
+	if withTarget {
+		targetID, err = strconv.Atoi(chi.URLParam(r, "userID"))
+		if err != nil || targetID <= 0 {
+			http.Error(w, `{"error":"`+ErrInvalidUserID.Error()+`"}`, http.StatusBadRequest)
+			return 0, 0, false
+		}
+	}
+	return actorID, targetID, true
+}
+
+func writeAdminError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrUserNotFound), errors.Is(err, ErrOrderNotFound), errors.Is(err, ErrLedgerEntryNotFound):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusNotFound)
+	case errors.Is(err, ErrInvalidRole), errors.Is(err, ErrSelfAdminAction),
+		errors.Is(err, ErrInvalidAmount), errors.Is(err, ErrInvalidReversal):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+	case errors.Is(err, ErrLackOfFunds):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusPaymentRequired)
+	case errors.Is(err, ErrOrderAlreadyProcessed):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusConflict)
+	default:
+		castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+		http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+	}
+}
+
+// AdminSearchUsers - поиск пользователей по части логина (?login=)
+func (h *Handler) AdminSearchUsers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actorID, _, ok := adminActor(w, r, false)
+	if !ok {
+		return
+	}
+
+	users, err := h.svc.AdminSearchUsers(actorID, r.URL.Query().Get("login"))
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if users == nil {
+		users = []models.User{}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(users)
+}
+
+func (h *Handler) AdminUserOrders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actorID, targetID, ok := adminActor(w, r, true)
+	if !ok {
+		return
+	}
+
+	orders, err := h.svc.AdminUserOrders(actorID, targetID)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if orders == nil {
+		orders = []models.Order{}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(orders)
+}
+
+func (h *Handler) AdminUserBalance(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actorID, targetID, ok := adminActor(w, r, true)
+	if !ok {
+		return
+	}
+
+	balance, err := h.svc.AdminUserBalance(actorID, targetID)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(balance)
+}
+
+func (h *Handler) AdminUserWithdrawals(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actorID, targetID, ok := adminActor(w, r, true)
+	if !ok {
+		return
+	}
+
+	withdrawals, err := h.svc.AdminUserWithdrawals(actorID, targetID)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if withdrawals == nil {
+		withdrawals = []models.WithdrawBalance{}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(withdrawals)
+}
+
+func (h *Handler) AdminBlockUser(w http.ResponseWriter, r *http.Request) {
+	h.adminSetBlocked(w, r, true)
+}
+
+func (h *Handler) AdminUnblockUser(w http.ResponseWriter, r *http.Request) {
+	h.adminSetBlocked(w, r, false)
+}
+
+func (h *Handler) adminSetBlocked(w http.ResponseWriter, r *http.Request, blocked bool) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actorID, targetID, ok := adminActor(w, r, true)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.AdminSetBlocked(actorID, targetID, blocked); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct{}{})
+}
+
+func (h *Handler) AdminSetRole(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actorID, targetID, ok := adminActor(w, r, true)
+	if !ok {
+		return
+	}
+
+	var req models.SetRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"`+ErrInvalidJSONFormat.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.svc.AdminSetRole(actorID, targetID, req.Role); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct{}{})
+}
+
+// AdminUnlockLogin - снятие блокировки входа после перебора паролей
+func (h *Handler) AdminUnlockLogin(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actorID, targetID, ok := adminActor(w, r, true)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.AdminUnlockLogin(actorID, targetID); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct{}{})
+}
+
+// AdminRequeueOrder - повторная постановка заказа в очередь опроса системы начислений
+func (h *Handler) AdminRequeueOrder(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actorID, _, ok := adminActor(w, r, false)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.AdminRequeueOrder(actorID, chi.URLParam(r, "number")); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(struct{}{})
+}
+
+func (h *Handler) AdminAuditLog(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actorID, _, ok := adminActor(w, r, false)
+	if !ok {
+		return
+	}
+
+	entries, err := h.svc.AdminAuditLog(actorID)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if entries == nil {
+		entries = []models.AuditEntry{}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+}
+
+// AdminUserLedger - последние проводки пользователя
+func (h *Handler) AdminUserLedger(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actorID, targetID, ok := adminActor(w, r, true)
+	if !ok {
+		return
+	}
+
+	entries, err := h.svc.AdminLedgerEntries(actorID, targetID)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if entries == nil {
+		entries = []models.LedgerEntry{}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+}
+
+// AdminAdjustBalance - ручная корректировка баланса проводкой adjustment
+func (h *Handler) AdminAdjustBalance(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actorID, targetID, ok := adminActor(w, r, true)
+	if !ok {
+		return
+	}
+
+	var req models.AdjustBalanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"`+ErrInvalidJSONFormat.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.svc.AdminAdjustBalance(actorID, targetID, req)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// AdminReverseEntry - сторно проводки целиком или частично
+func (h *Handler) AdminReverseEntry(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actorID, _, ok := adminActor(w, r, false)
+	if !ok {
+		return
+	}
+
+	entryID, err := strconv.ParseInt(chi.URLParam(r, "entryID"), 10, 64)
+	if err != nil || entryID <= 0 {
+		http.Error(w, `{"error":"`+ErrInvalidEntryID.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req models.ReverseEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"`+ErrInvalidJSONFormat.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.svc.AdminReverseEntry(actorID, entryID, req)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// AdminCheckLedger - сверка журнала с остатками, заказами и списаниями
+func (h *Handler) AdminCheckLedger(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actorID, _, ok := adminActor(w, r, false)
+	if !ok {
+		return
+	}
+
+	mismatches, err := h.svc.AdminCheckLedger(actorID)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	if mismatches == nil {
+		mismatches = []models.LedgerMismatch{}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(mismatches)
+}
@@ -0,0 +1,140 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go-musthave-diploma-tpl/internal/gophermart/middleware"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// CreateAPIKey - выпуск персонального API-ключа, значение отдаётся один раз
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"`+ErrUserIsNotAuthenticated.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"`+ErrInvalidJSONFormat.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	userIDint, _ := strconv.Atoi(userID)
+	key, err := h.svc.CreateAPIKey(userIDint, req)
+	if err != nil {
+		writeAPIKeyError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(key)
+}
+
+// AdminCreateAPIKey - выпуск ключа пользователю администратором, в том числе для возвратов партнёром
+func (h *Handler) AdminCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actorID, targetID, ok := adminActor(w, r, true)
+	if !ok {
+		return
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"`+ErrInvalidJSONFormat.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.svc.AdminCreateAPIKey(actorID, targetID, req)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusNotFound)
+			return
+		}
+		writeAPIKeyError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(key)
+}
+
+func writeAPIKeyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidScope), errors.Is(err, ErrInvalidAPIKeyExpiry):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+	case errors.Is(err, ErrTooManyAPIKeys):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusConflict)
+	default:
+		castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+		http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+	}
+}
+
+// APIKeys - список действующих ключей пользователя без самих значений
+func (h *Handler) APIKeys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"`+ErrUserIsNotAuthenticated.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+
+	userIDint, _ := strconv.Atoi(userID)
+	keys, err := h.svc.APIKeys(userIDint)
+	if err != nil {
+		castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+		http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if len(keys) == 0 {
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode([]interface{}{})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(keys)
+}
+
+// RevokeAPIKey - отзыв ключа, запросы с ним сразу перестают проходить
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"`+ErrUserIsNotAuthenticated.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+
+	keyID, err := strconv.Atoi(chi.URLParam(r, "keyID"))
+	if err != nil || keyID <= 0 {
+		http.Error(w, `{"error":"`+ErrInvalidAPIKeyID.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	userIDint, _ := strconv.Atoi(userID)
+	if err := h.svc.RevokeAPIKey(userIDint, keyID); err != nil {
+		if errors.Is(err, ErrAPIKeyNotFound) {
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusNotFound)
+			return
+		}
+		castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+		http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct{}{})
+}
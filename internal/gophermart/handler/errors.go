@@ -1,9 +1,13 @@
 package httpserver
 
-import "errors"
+import (
+	"errors"
+
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+)
 
 var (
-	ErrLoginAndPasswordRequired = errors.New("login and password are required")
+	ErrLoginAndPasswordRequired = service.ErrLoginAndPasswordRequired
 	ErrInvalidUserID            = errors.New("invalid user ID")
 	ErrInternalServerError      = errors.New("internal server error")
 	ErrInvalidJSONFormat        = errors.New("invalid JSON format")
@@ -14,7 +18,68 @@ var (
 	ErrInvalidOrderNumber       = errors.New("invalid order number")
 	ErrLackOfFunds              = errors.New("lack of funds")
 	ErrInvalidNumberFormat      = errors.New("invalid number format")
-	ErrInvalidLoginOrPassword   = errors.New("invalid login or password")
+	ErrInvalidLoginOrPassword   = service.ErrInvalidLoginOrPassword
 	ErrInvalidRequestFormat     = errors.New("invalid request format")
-	ErrLoginAlreadyExists       = errors.New("login already exists")
+	ErrLoginAlreadyExists       = service.ErrLoginAlreadyExists
+	ErrInvalidRefreshToken      = service.ErrInvalidRefreshToken
+	ErrTooManyLoginAttempts     = service.ErrTooManyLoginAttempts
+	ErrPasswordPolicy           = service.ErrPasswordPolicy
+	ErrInvalidCurrentPassword   = service.ErrInvalidCurrentPassword
+	ErrPasswordsRequired        = errors.New("current and new password are required")
+	ErrTOTPAlreadyEnabled       = service.ErrTOTPAlreadyEnabled
+	ErrTOTPNotSetUp             = service.ErrTOTPNotSetUp
+	ErrInvalidTOTPCode          = service.ErrInvalidTOTPCode
+	ErrTOTPRequired             = service.ErrTOTPRequired
+	ErrInvalidLoginChallenge    = service.ErrInvalidLoginChallenge
+	ErrTOTPCodeRequired         = errors.New("code is required")
+	ErrUserBlocked              = service.ErrUserBlocked
+	ErrUserNotFound             = service.ErrUserNotFound
+	ErrInvalidRole              = service.ErrInvalidRole
+	ErrSelfAdminAction          = service.ErrSelfAdminAction
+	ErrOrderNotFound            = service.ErrOrderNotFound
+	ErrOrderAlreadyProcessed    = service.ErrOrderAlreadyProcessed
+	ErrForbidden                = errors.New("forbidden")
+	ErrInvalidAPIKey            = service.ErrInvalidAPIKey
+	ErrAPIKeyNotFound           = service.ErrAPIKeyNotFound
+	ErrInvalidScope             = service.ErrInvalidScope
+	ErrInvalidAPIKeyExpiry      = service.ErrInvalidAPIKeyExpiry
+	ErrTooManyAPIKeys           = service.ErrTooManyAPIKeys
+	ErrInvalidAPIKeyID          = errors.New("invalid api key ID")
+	ErrOIDCNotConfigured        = service.ErrOIDCNotConfigured
+	ErrInvalidOIDCState         = service.ErrInvalidOIDCState
+	ErrOIDCLoginFailed          = service.ErrOIDCLoginFailed
+	ErrLedgerEntryNotFound      = service.ErrLedgerEntryNotFound
+	ErrInvalidReversal          = service.ErrInvalidReversal
+	ErrInvalidAmount            = service.ErrInvalidAmount
+	ErrInvalidEntryID           = errors.New("invalid ledger entry ID")
+	ErrInvalidIdempotencyKey    = service.ErrInvalidIdempotencyKey
+	ErrIdempotencyKeyReused     = service.ErrIdempotencyKeyReused
+	ErrIdempotencyConflict      = service.ErrIdempotencyConflict
+	ErrOrderAlreadyPaid         = service.ErrOrderAlreadyPaid
+	ErrAccrualOrderWithdrawal   = service.ErrAccrualOrderWithdrawal
+	ErrOrderOnHold              = service.ErrOrderOnHold
+	ErrHoldNotFound             = service.ErrHoldNotFound
+	ErrHoldNotActive            = service.ErrHoldNotActive
+	ErrInvalidHoldID            = errors.New("invalid hold ID")
+	ErrWithdrawalNotFound       = service.ErrWithdrawalNotFound
+	ErrRefundExceedsWithdrawal  = service.ErrRefundExceedsWithdrawal
+	ErrTiersDisabled            = service.ErrTiersDisabled
+	ErrRecipientNotFound        = service.ErrRecipientNotFound
+	ErrTransferToSelf           = service.ErrTransferToSelf
+	ErrTransferLimitExceeded    = service.ErrTransferLimitExceeded
+	ErrTransferNoteTooLong      = service.ErrTransferNoteTooLong
+	ErrInvalidReferralCode      = service.ErrInvalidReferralCode
+	ErrSelfReferral             = service.ErrSelfReferral
+	ErrReferralLimitExceeded    = service.ErrReferralLimitExceeded
+	ErrInvalidPromoCode         = service.ErrInvalidPromoCode
+	ErrPromoCodeExists          = service.ErrPromoCodeExists
+	ErrPromoCodeNotFound        = service.ErrPromoCodeNotFound
+	ErrPromoCodeInactive        = service.ErrPromoCodeInactive
+	ErrPromoCodeExhausted       = service.ErrPromoCodeExhausted
+	ErrPromoCodeAlreadyRedeemed = service.ErrPromoCodeAlreadyRedeemed
+	ErrInvalidCursor            = service.ErrInvalidCursor
+	ErrInvalidHistoryFilter     = service.ErrInvalidHistoryFilter
+	ErrInvalidStatementPeriod   = service.ErrInvalidStatementPeriod
+	ErrInvalidStatementFormat   = service.ErrInvalidStatementFormat
+	ErrInvalidOrdersFilter      = service.ErrInvalidOrdersFilter
 )
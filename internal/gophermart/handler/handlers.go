@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"math"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -18,6 +20,9 @@ import (
 
 var castomLogger = logger.NewHTTPLogger().Logger.Sugar()
 
+// totpCodeHeader - заголовок со свежим TOTP-кодом для крупных списаний
+const totpCodeHeader = "X-TOTP-Code"
+
 type Handler struct {
 	svc *service.GofemartService
 }
@@ -45,8 +50,19 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.svc.RegisterUser(req.Login, req.Password)
+	user, err := h.svc.RegisterReferredUser(req.Login, req.Password, req.ReferralCode, clientIP(r))
 	if err != nil {
+		if writePasswordPolicyError(w, err) {
+			return
+		}
+		if errors.Is(err, ErrInvalidReferralCode) || errors.Is(err, ErrSelfReferral) {
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, ErrReferralLimitExceeded) {
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusTooManyRequests)
+			return
+		}
 		switch err.Error() {
 		case "login already exists":
 			http.Error(w, `{"error":"login already taken"}`, http.StatusConflict)
@@ -58,8 +74,7 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	middleware.SetEncryptedCookie(w, strconv.Itoa(user.ID))
-	w.WriteHeader(http.StatusOK)
+	h.authenticate(w, r, user.ID)
 }
 
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
@@ -70,21 +85,185 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req models.RegisterRequest
+	var req models.LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, `{"error":"`+ErrInvalidJSONFormat.Error()+`"}`, http.StatusBadRequest)
 		return
 	}
 
+	// второй шаг входа с включённой 2FA
+	if req.Challenge != "" {
+		h.completeLogin(w, r, req)
+		return
+	}
+
 	if req.Login == "" || req.Password == "" {
 		http.Error(w, `{"error":"`+ErrLoginAndPasswordRequired.Error()+`"}`, http.StatusBadRequest)
 		return
 	}
 
+	ip := clientIP(r)
+	if err := h.svc.CheckLoginAllowed(req.Login, ip); err != nil {
+		writeLoginError(w, err)
+		return
+	}
+
 	user, err := h.svc.LoginUser(req.Login, req.Password)
 	if err != nil {
 		if errors.Is(err, ErrInvalidLoginOrPassword) {
-			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+			if err := h.svc.RecordLoginFailure(req.Login, ip); err != nil {
+				castomLogger.Infof("failed to record login failure: %v", err)
+			}
+		}
+		writeLoginError(w, err)
+		return
+	}
+
+	// пароль верный, но включена 2FA - ждём код вторым запросом.
+	// Счётчик неудач сбрасывается только после второго фактора, иначе коды можно перебирать
+	if h.requireSecondFactor(w, user.ID) {
+		return
+	}
+
+	if err := h.svc.LoginSucceeded(req.Login); err != nil {
+		castomLogger.Infof("failed to reset login attempts: %v", err)
+	}
+	h.authenticate(w, r, user.ID)
+}
+
+// requireSecondFactor отвечает 202 с challenge, если у пользователя включена 2FA.
+// true - ответ уже записан и вход не завершён
+func (h *Handler) requireSecondFactor(w http.ResponseWriter, userID int) bool {
+	challenge, err := h.svc.StartLoginChallenge(userID)
+	if err != nil {
+		castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+		http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+		return true
+	}
+	if challenge == nil {
+		return false
+	}
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(challenge)
+	return true
+}
+
+// completeLogin - второй шаг входа: challenge из первого ответа и TOTP-код или код восстановления
+func (h *Handler) completeLogin(w http.ResponseWriter, r *http.Request, req models.LoginRequest) {
+	if req.Code == "" {
+		http.Error(w, `{"error":"`+ErrTOTPCodeRequired.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	login, err := h.svc.LoginChallengeLogin(req.Challenge)
+	if err != nil {
+		writeChallengeError(w, err)
+		return
+	}
+
+	// новые challenge не дают новых попыток: неверные коды копятся в счётчике логина и адреса
+	ip := clientIP(r)
+	if err := h.svc.CheckLoginAllowed(login, ip); err != nil {
+		writeLoginError(w, err)
+		return
+	}
+
+	userID, err := h.svc.CompleteLoginChallenge(req.Challenge, req.Code)
+	if err != nil {
+		if errors.Is(err, ErrInvalidTOTPCode) {
+			if err := h.svc.RecordLoginFailure(login, ip); err != nil {
+				castomLogger.Infof("failed to record login failure: %v", err)
+			}
+		}
+		writeChallengeError(w, err)
+		return
+	}
+
+	if err := h.svc.LoginSucceeded(login); err != nil {
+		castomLogger.Infof("failed to reset login attempts: %v", err)
+	}
+	h.authenticate(w, r, userID)
+}
+
+func writeChallengeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidLoginChallenge), errors.Is(err, ErrInvalidTOTPCode):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+	default:
+		castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+		http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+	}
+}
+
+func writeLoginError(w http.ResponseWriter, err error) {
+	var locked *service.LoginLockedError
+	switch {
+	case errors.As(err, &locked):
+		retryAfter := int(math.Ceil(locked.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		http.Error(w, `{"error":"`+ErrTooManyLoginAttempts.Error()+`","retry_after":`+strconv.Itoa(retryAfter)+`}`, http.StatusTooManyRequests)
+	case errors.Is(err, ErrInvalidLoginOrPassword):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+	case errors.Is(err, ErrUserBlocked):
+		http.Error(w, `{"error":"`+ErrUserBlocked.Error()+`"}`, http.StatusForbidden)
+	default:
+		castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+		http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+	}
+}
+
+// clientIP - адрес клиента для учёта попыток входа. Заголовкам X-Forwarded-For
+// не доверяем: за обратным прокси RemoteAddr должен выставлять chi middleware.RealIP
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// authenticate завершает вход/регистрацию: по умолчанию ставит куку,
+// в режиме токенов (?mode=token) возвращает пару access/refresh
+func (h *Handler) authenticate(w http.ResponseWriter, r *http.Request, userID int) {
+	if r.URL.Query().Get("mode") != "token" {
+		sessionToken, expiresAt, err := h.svc.CreateSession(userID, r.UserAgent(), clientIP(r))
+		if err == nil {
+			err = middleware.SetEncryptedCookie(w, strconv.Itoa(userID), sessionToken, expiresAt)
+		}
+		if err != nil {
+			castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+			http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	pair, err := h.svc.IssueTokens(userID)
+	if err != nil {
+		castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+		http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(pair)
+}
+
+// RefreshToken - обмен refresh-токена на новую пару токенов
+func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req models.RefreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"`+ErrInvalidJSONFormat.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	pair, err := h.svc.RefreshTokens(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, ErrInvalidRefreshToken) {
+			http.Error(w, `{"error":"`+ErrInvalidRefreshToken.Error()+`"}`, http.StatusUnauthorized)
 		} else {
 			castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
 			http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
@@ -92,8 +271,231 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	middleware.SetEncryptedCookie(w, strconv.Itoa(user.ID))
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(pair)
+}
+
+// RevokeToken - отзыв refresh-токена (выход в режиме токенов)
+func (h *Handler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req models.RefreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"`+ErrInvalidJSONFormat.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.svc.RevokeRefreshToken(req.RefreshToken); err != nil {
+		if errors.Is(err, ErrInvalidRefreshToken) {
+			http.Error(w, `{"error":"`+ErrInvalidRefreshToken.Error()+`"}`, http.StatusBadRequest)
+		} else {
+			castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+			http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct{}{})
+}
+
+// Logout - выход из текущей сессии
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"`+ErrUserIsNotAuthenticated.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+
+	// при входе по Bearer-токену сессии нет, выход - через /api/user/token/revoke
+	if sessionID, ok := middleware.GetSessionID(r.Context()); ok {
+		userIDint, _ := strconv.Atoi(userID)
+		if err := h.svc.Logout(userIDint, sessionID); err != nil {
+			castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+			http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	middleware.ClearCookie(w)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct{}{})
+}
+
+// LogoutAll - выход на всех устройствах
+func (h *Handler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"`+ErrUserIsNotAuthenticated.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+
+	userIDint, _ := strconv.Atoi(userID)
+	if err := h.svc.LogoutAll(userIDint); err != nil {
+		castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+		http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	middleware.ClearCookie(w)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct{}{})
+}
+
+// Sessions - список активных сессий пользователя
+func (h *Handler) Sessions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"`+ErrUserIsNotAuthenticated.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+
+	userIDint, _ := strconv.Atoi(userID)
+	currentSessionID, _ := middleware.GetSessionID(r.Context())
+	sessions, err := h.svc.Sessions(userIDint, currentSessionID)
+	if err != nil {
+		http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if len(sessions) == 0 {
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode([]interface{}{})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// ChangePassword - смена пароля, остальные сессии пользователя завершаются
+func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"`+ErrUserIsNotAuthenticated.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"`+ErrInvalidJSONFormat.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		http.Error(w, `{"error":"`+ErrPasswordsRequired.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	userIDint, _ := strconv.Atoi(userID)
+	currentSessionID, _ := middleware.GetSessionID(r.Context())
+	err = h.svc.ChangePassword(userIDint, currentSessionID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		if writePasswordPolicyError(w, err) {
+			return
+		}
+		if errors.Is(err, ErrInvalidCurrentPassword) {
+			http.Error(w, `{"error":"`+ErrInvalidCurrentPassword.Error()+`"}`, http.StatusForbidden)
+			return
+		}
+		castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+		http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(struct{}{})
+}
+
+// SetupTOTP - начало подключения 2FA: секрет и otpauth-ссылка для приложения
+func (h *Handler) SetupTOTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"`+ErrUserIsNotAuthenticated.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+
+	userIDint, _ := strconv.Atoi(userID)
+	setup, err := h.svc.SetupTOTP(userIDint)
+	if err != nil {
+		if errors.Is(err, ErrTOTPAlreadyEnabled) {
+			http.Error(w, `{"error":"`+ErrTOTPAlreadyEnabled.Error()+`"}`, http.StatusConflict)
+		} else {
+			castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+			http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(setup)
+}
+
+// ConfirmTOTP - включение 2FA по первому коду, в ответе одноразовые коды восстановления
+func (h *Handler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"`+ErrUserIsNotAuthenticated.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req models.TOTPCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"`+ErrInvalidJSONFormat.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Code == "" {
+		http.Error(w, `{"error":"`+ErrTOTPCodeRequired.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	userIDint, _ := strconv.Atoi(userID)
+	codes, err := h.svc.ConfirmTOTP(userIDint, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidTOTPCode):
+			http.Error(w, `{"error":"`+ErrInvalidTOTPCode.Error()+`"}`, http.StatusUnprocessableEntity)
+		case errors.Is(err, ErrTOTPNotSetUp):
+			http.Error(w, `{"error":"`+ErrTOTPNotSetUp.Error()+`"}`, http.StatusBadRequest)
+		case errors.Is(err, ErrTOTPAlreadyEnabled):
+			http.Error(w, `{"error":"`+ErrTOTPAlreadyEnabled.Error()+`"}`, http.StatusConflict)
+		default:
+			castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+			http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.TOTPConfirmResponse{RecoveryCodes: codes})
+}
+
+// writePasswordPolicyError отвечает 400 со списком нарушенных правил, если err - нарушение политики паролей
+func writePasswordPolicyError(w http.ResponseWriter, err error) bool {
+	var policyErr *service.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(models.PasswordPolicyResponse{
+		Error:      ErrPasswordPolicy.Error(),
+		Violations: policyErr.Violations,
+	})
+	return true
 }
 
 func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
@@ -157,6 +559,11 @@ func (h *Handler) GetOrders(w http.ResponseWriter, r *http.Request) {
 	}
 
 	userIDint, _ := strconv.Atoi(userID)
+	// без параметров - весь список массивом, как раньше
+	if ordersPageRequested(r.URL.Query()) {
+		h.ordersPage(w, r, userIDint)
+		return
+	}
 	result, err := h.svc.GetOrders(userIDint)
 	if err != nil {
 		http.Error(w, ErrInternalServerError.Error(), http.StatusInternalServerError)
@@ -231,14 +638,65 @@ func (h *Handler) Withdraw(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, `{"error":"invalid user ID"}`, http.StatusInternalServerError)
 		return
 	}
+
+	// повтор с тем же Idempotency-Key получает прежний ответ без второго списания
+	const scope = models.IdempotencyScopeWithdraw
+	key, withKey := idempotencyKey(r)
+	fingerprint := service.RequestFingerprint(withdraw.Order, withdraw.Sum.String())
+	if withKey {
+		if h.beginIdempotent(w, userIDint, scope, key, fingerprint) {
+			return
+		}
+		withdraw.IdempotencyKey = key
+	}
+
+	// крупные списания при включённой 2FA подтверждаются свежим кодом
+	if err := h.svc.CheckWithdrawSecondFactor(userIDint, withdraw.Sum, r.Header.Get(totpCodeHeader)); err != nil {
+		h.releaseIdempotent(userIDint, scope, key)
+		if errors.Is(err, ErrTOTPRequired) || errors.Is(err, ErrInvalidTOTPCode) {
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusForbidden)
+		} else {
+			castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+			http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
 	err = h.svc.Withdraw(userIDint, withdraw)
 	if err != nil {
 		switch err {
 		case ErrInvalidOrderNumber:
-			http.Error(w, `{"error":"`+ErrInvalidOrderNumber.Error()+`"}`, http.StatusUnprocessableEntity)
+			h.finishIdempotent(w, userIDint, scope, key, models.IdempotentResponse{
+				StatusCode: http.StatusUnprocessableEntity,
+				Body:       `{"error":"` + ErrInvalidOrderNumber.Error() + `"}`,
+			})
 		case ErrLackOfFunds:
-			http.Error(w, `{"error":"`+ErrLackOfFunds.Error()+`"}`, http.StatusPaymentRequired)
+			h.finishIdempotent(w, userIDint, scope, key, models.IdempotentResponse{
+				StatusCode: http.StatusPaymentRequired,
+				Body:       `{"error":"` + ErrLackOfFunds.Error() + `"}`,
+			})
+		case ErrOrderAlreadyPaid:
+			h.finishIdempotent(w, userIDint, scope, key, models.IdempotentResponse{
+				StatusCode: http.StatusConflict,
+				Body:       `{"error":"` + ErrOrderAlreadyPaid.Error() + `"}`,
+			})
+		case ErrOrderOnHold:
+			h.finishIdempotent(w, userIDint, scope, key, models.IdempotentResponse{
+				StatusCode: http.StatusConflict,
+				Body:       `{"error":"` + ErrOrderOnHold.Error() + `"}`,
+			})
+		case ErrAccrualOrderWithdrawal:
+			h.finishIdempotent(w, userIDint, scope, key, models.IdempotentResponse{
+				StatusCode: http.StatusUnprocessableEntity,
+				Body:       `{"error":"` + ErrAccrualOrderWithdrawal.Error() + `"}`,
+			})
+		case ErrIdempotencyConflict:
+			// параллельный запрос с тем же ключом успел списать - отдаём его ответ
+			if !h.beginIdempotent(w, userIDint, scope, key, fingerprint) {
+				http.Error(w, `{"error":"`+ErrIdempotencyConflict.Error()+`"}`, http.StatusConflict)
+			}
 		default:
+			h.releaseIdempotent(userIDint, scope, key)
 			castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
 			http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
 		}
@@ -0,0 +1,95 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/middleware"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+)
+
+// BalanceHistory - история движений баллов постранично.
+// Параметры: cursor, limit, from и to (RFC 3339 или дата YYYY-MM-DD, to включительно), type через запятую
+func (h *Handler) BalanceHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"`+ErrUserIsNotAuthenticated.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+
+	req, err := balanceHistoryRequest(r.URL.Query())
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	userIDint, _ := strconv.Atoi(userID)
+	history, err := h.svc.BalanceHistory(userIDint, req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCursor) || errors.Is(err, ErrInvalidHistoryFilter) {
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+			return
+		}
+		castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+		http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(history)
+}
+
+func balanceHistoryRequest(q url.Values) (models.BalanceHistoryRequest, error) {
+	req := models.BalanceHistoryRequest{Cursor: q.Get("cursor")}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return req, ErrInvalidHistoryFilter
+		}
+		req.Limit = limit
+	}
+
+	var err error
+	if req.From, err = parseHistoryTime(q.Get("from"), false); err != nil {
+		return req, err
+	}
+	if req.To, err = parseHistoryTime(q.Get("to"), true); err != nil {
+		return req, err
+	}
+
+	// type=accrual,withdrawal или type=accrual&type=withdrawal
+	for _, v := range q["type"] {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				req.Types = append(req.Types, t)
+			}
+		}
+	}
+	return req, nil
+}
+
+// parseHistoryTime разбирает границу периода. Дата без времени в to включает весь день
+func parseHistoryTime(v string, end bool) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return nil, ErrInvalidHistoryFilter
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
@@ -0,0 +1,148 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go-musthave-diploma-tpl/internal/gophermart/middleware"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+
+	pgk "go-musthave-diploma-tpl/pkg"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// CreateHold резервирует баллы под заказ: списать их можно будет позже через capture
+func (h *Handler) CreateHold(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"`+ErrUserIsNotAuthenticated.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+
+	if r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, `{"error":"content-type must be application/json"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"`+ErrInvalidJSONFormat.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.Amount <= 0 {
+		http.Error(w, `{"error":"amount must be positive"}`, http.StatusBadRequest)
+		return
+	}
+	if !pgk.ContainsOnlyDigits(req.Order) || !pgk.ValidateLuhn(req.Order) {
+		http.Error(w, `{"error":"invalid order number"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	userIDint, _ := strconv.Atoi(userID)
+
+	// резерв списывается без повторной проверки, поэтому код 2FA спрашивается здесь
+	if err := h.svc.CheckWithdrawSecondFactor(userIDint, req.Amount, r.Header.Get(totpCodeHeader)); err != nil {
+		if errors.Is(err, ErrTOTPRequired) || errors.Is(err, ErrInvalidTOTPCode) {
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusForbidden)
+		} else {
+			castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+			http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	hold, err := h.svc.CreateHold(userIDint, req)
+	if err != nil {
+		writeHoldError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hold)
+}
+
+// CaptureHold списывает зарезервированные баллы
+func (h *Handler) CaptureHold(w http.ResponseWriter, r *http.Request) {
+	h.closeHold(w, r, h.svc.CaptureHold)
+}
+
+// VoidHold отменяет резерв
+func (h *Handler) VoidHold(w http.ResponseWriter, r *http.Request) {
+	h.closeHold(w, r, h.svc.VoidHold)
+}
+
+func (h *Handler) closeHold(w http.ResponseWriter, r *http.Request, closeFn func(userID, holdID int) (models.Hold, error)) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"`+ErrUserIsNotAuthenticated.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+
+	holdID, err := strconv.Atoi(chi.URLParam(r, "holdID"))
+	if err != nil || holdID <= 0 {
+		http.Error(w, `{"error":"`+ErrInvalidHoldID.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	userIDint, _ := strconv.Atoi(userID)
+	hold, err := closeFn(userIDint, holdID)
+	if err != nil {
+		writeHoldError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(hold)
+}
+
+func (h *Handler) Holds(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"`+ErrUserIsNotAuthenticated.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+
+	userIDint, _ := strconv.Atoi(userID)
+	holds, err := h.svc.Holds(userIDint)
+	if err != nil {
+		castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+		http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if len(holds) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(holds)
+}
+
+func writeHoldError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidAmount):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+	case errors.Is(err, ErrHoldNotFound):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusNotFound)
+	case errors.Is(err, ErrLackOfFunds):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusPaymentRequired)
+	case errors.Is(err, ErrHoldNotActive), errors.Is(err, ErrOrderAlreadyPaid), errors.Is(err, ErrOrderOnHold):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusConflict)
+	case errors.Is(err, ErrAccrualOrderWithdrawal):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnprocessableEntity)
+	default:
+		castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+		http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+	}
+}
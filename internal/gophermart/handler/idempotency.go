@@ -0,0 +1,78 @@
+package httpserver
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+)
+
+const (
+	// idempotencyKeyHeader - ключ, по которому повтор запроса получает прежний ответ
+	idempotencyKeyHeader = "Idempotency-Key"
+	// idempotentReplayedHeader помечает ответ, взятый из сохранённых
+	idempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// idempotencyKey - ключ из заголовка и признак, что заголовок передан
+func idempotencyKey(r *http.Request) (string, bool) {
+	values := r.Header.Values(idempotencyKeyHeader)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// beginIdempotent занимает ключ. true - ответ уже записан (повтор или ошибка), запрос выполнять не нужно
+func (h *Handler) beginIdempotent(w http.ResponseWriter, userID int, scope, key, fingerprint string) bool {
+	stored, err := h.svc.BeginIdempotent(userID, scope, key, fingerprint)
+	switch {
+	case errors.Is(err, ErrInvalidIdempotencyKey):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+	case errors.Is(err, ErrIdempotencyKeyReused):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnprocessableEntity)
+	case err != nil:
+		castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+		http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+	case stored != nil:
+		replayIdempotent(w, *stored)
+	default:
+		return false
+	}
+	return true
+}
+
+// finishIdempotent сохраняет ответ для повторов и отдаёт его клиенту
+func (h *Handler) finishIdempotent(w http.ResponseWriter, userID int, scope, key string, resp models.IdempotentResponse) {
+	if key != "" {
+		if err := h.svc.FinishIdempotent(userID, scope, key, resp); err != nil {
+			castomLogger.Infof("failed to save idempotent response: %v", err)
+		}
+	}
+	writeIdempotent(w, resp)
+}
+
+// releaseIdempotent освобождает ключ, чтобы клиент мог повторить запрос с ним же
+func (h *Handler) releaseIdempotent(userID int, scope, key string) {
+	if key == "" {
+		return
+	}
+	if err := h.svc.ReleaseIdempotent(userID, scope, key); err != nil {
+		castomLogger.Infof("failed to release idempotency key: %v", err)
+	}
+}
+
+func replayIdempotent(w http.ResponseWriter, resp models.IdempotentResponse) {
+	w.Header().Set(idempotentReplayedHeader, "true")
+	writeIdempotent(w, resp)
+}
+
+func writeIdempotent(w http.ResponseWriter, resp models.IdempotentResponse) {
+	if resp.StatusCode >= http.StatusBadRequest {
+		http.Error(w, resp.Body, resp.StatusCode)
+		return
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.WriteString(w, resp.Body+"\n")
+}
@@ -0,0 +1,94 @@
+package httpserver
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"time"
+)
+
+const (
+	// oidcStateCookie привязывает начатый вход к браузеру, иначе по чужой ссылке
+	// на callback можно было бы войти в аккаунт атакующего
+	oidcStateCookie = "oidc_state"
+	oidcCookiePath  = "/api/user/oidc"
+	oidcCookieTTL   = 10 * time.Minute
+)
+
+// OIDCLogin - редирект на страницу входа провайдера
+func (h *Handler) OIDCLogin(w http.ResponseWriter, r *http.Request) {
+	authURL, state, err := h.svc.StartOIDCLogin()
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		if errors.Is(err, ErrOIDCNotConfigured) {
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusNotFound)
+			return
+		}
+		castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+		http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     oidcCookiePath,
+		MaxAge:   int(oidcCookieTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback - возврат от провайдера: проверяем state, меняем код на ID-токен
+// и выдаём ту же сессию, что и вход по паролю
+func (h *Handler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// state одноразовый, куку убираем в любом случае
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    "",
+		Path:     oidcCookiePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		castomLogger.Infof("oidc provider returned error: %s", providerErr)
+		http.Error(w, `{"error":"`+ErrOIDCLoginFailed.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+
+	state := query.Get("state")
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		http.Error(w, `{"error":"`+ErrInvalidOIDCState.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.svc.FinishOIDCLogin(r.Context(), state, query.Get("code"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrOIDCNotConfigured):
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusNotFound)
+		case errors.Is(err, ErrInvalidOIDCState):
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+		case errors.Is(err, ErrOIDCLoginFailed):
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnauthorized)
+		default:
+			writeLoginError(w, err)
+		}
+		return
+	}
+
+	// вход через провайдера не отменяет второй фактор: с 2FA завершается кодом через /api/user/login
+	if h.requireSecondFactor(w, user.ID) {
+		return
+	}
+	h.authenticate(w, r, user.ID)
+}
@@ -0,0 +1,98 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+)
+
+// параметры, с которыми GET /api/user/orders отдаёт страницу вместо полного массива
+var ordersPageParams = []string{"cursor", "limit", "status", "from", "to", "sort"}
+
+func ordersPageRequested(q url.Values) bool {
+	for _, p := range ordersPageParams {
+		if q.Has(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// ordersPage - страница заказов: cursor, limit, status через запятую, from и to (RFC 3339 или дата
+// YYYY-MM-DD, to включительно), sort=asc|desc. Ссылка на следующую страницу - и в next_cursor, и в Link
+func (h *Handler) ordersPage(w http.ResponseWriter, r *http.Request, userID int) {
+	q := r.URL.Query()
+	req, err := ordersRequest(q)
+	if err != nil {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.svc.OrdersPage(userID, req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCursor) || errors.Is(err, ErrInvalidOrdersFilter) {
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+			return
+		}
+		castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+		http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	links := []string{ordersLink(r.URL, q, "", "first")}
+	if page.NextCursor != "" {
+		links = append(links, ordersLink(r.URL, q, page.NextCursor, "next"))
+	}
+	w.Header().Set("Link", strings.Join(links, ", "))
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+
+// ordersLink - ссылка на страницу с теми же фильтрами и другим курсором
+func ordersLink(u *url.URL, q url.Values, cursor, rel string) string {
+	params := url.Values{}
+	for k, v := range q {
+		params[k] = v
+	}
+	params.Del("cursor")
+	if cursor != "" {
+		params.Set("cursor", cursor)
+	}
+	return "<" + u.Path + "?" + params.Encode() + `>; rel="` + rel + `"`
+}
+
+func ordersRequest(q url.Values) (models.OrdersRequest, error) {
+	req := models.OrdersRequest{Cursor: q.Get("cursor"), Sort: strings.ToLower(q.Get("sort"))}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return req, ErrInvalidOrdersFilter
+		}
+		req.Limit = limit
+	}
+
+	var err error
+	if req.From, err = parseHistoryTime(q.Get("from"), false); err != nil {
+		return req, ErrInvalidOrdersFilter
+	}
+	if req.To, err = parseHistoryTime(q.Get("to"), true); err != nil {
+		return req, ErrInvalidOrdersFilter
+	}
+
+	// status=NEW,PROCESSING или status=NEW&status=PROCESSING
+	for _, v := range q["status"] {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				req.Statuses = append(req.Statuses, strings.ToUpper(s))
+			}
+		}
+	}
+	return req, nil
+}
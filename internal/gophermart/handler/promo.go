@@ -0,0 +1,122 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go-musthave-diploma-tpl/internal/gophermart/middleware"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RedeemPromo гасит промокод: начисляет баллы или включает множитель следующих заказов
+func (h *Handler) RedeemPromo(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"`+ErrUserIsNotAuthenticated.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+
+	if r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, `{"error":"content-type must be application/json"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req models.RedeemPromoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"`+ErrInvalidJSONFormat.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	userIDint, _ := strconv.Atoi(userID)
+	redemption, err := h.svc.RedeemPromoCode(userIDint, req.Code)
+	if err != nil {
+		writePromoError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(redemption)
+}
+
+// AdminCreatePromoCode заводит промокод
+func (h *Handler) AdminCreatePromoCode(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actorID, _, ok := adminActor(w, r, false)
+	if !ok {
+		return
+	}
+
+	var req models.CreatePromoCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"`+ErrInvalidJSONFormat.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	promo, err := h.svc.AdminCreatePromoCode(actorID, req)
+	if err != nil {
+		writePromoError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(promo)
+}
+
+// AdminPromoCodes - все промокоды с числом погашений
+func (h *Handler) AdminPromoCodes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	codes, err := h.svc.AdminPromoCodes()
+	if err != nil {
+		writePromoError(w, err)
+		return
+	}
+	if codes == nil {
+		codes = []models.PromoCode{}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(codes)
+}
+
+// AdminDisablePromoCode закрывает промокод для новых погашений
+func (h *Handler) AdminDisablePromoCode(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actorID, _, ok := adminActor(w, r, false)
+	if !ok {
+		return
+	}
+
+	promo, err := h.svc.AdminDisablePromoCode(actorID, chi.URLParam(r, "code"))
+	if err != nil {
+		writePromoError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(promo)
+}
+
+func writePromoError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidPromoCode):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+	case errors.Is(err, ErrPromoCodeNotFound):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusNotFound)
+	case errors.Is(err, ErrPromoCodeExists), errors.Is(err, ErrPromoCodeExhausted), errors.Is(err, ErrPromoCodeAlreadyRedeemed):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusConflict)
+	case errors.Is(err, ErrPromoCodeInactive):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusUnprocessableEntity)
+	default:
+		castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+		http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,31 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"go-musthave-diploma-tpl/internal/gophermart/middleware"
+)
+
+// Referrals - реферальный код пользователя и приглашённые им со статусом бонуса
+func (h *Handler) Referrals(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"`+ErrUserIsNotAuthenticated.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+
+	userIDint, _ := strconv.Atoi(userID)
+	referrals, err := h.svc.Referrals(userIDint)
+	if err != nil {
+		castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+		http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(referrals)
+}
@@ -0,0 +1,84 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go-musthave-diploma-tpl/internal/gophermart/middleware"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AdminRefundWithdrawal - возврат баллов по списанию поддержкой или администратором
+func (h *Handler) AdminRefundWithdrawal(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	actorID, _, ok := adminActor(w, r, false)
+	if !ok {
+		return
+	}
+
+	var req models.RefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"`+ErrInvalidJSONFormat.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	refund, err := h.svc.AdminRefundWithdrawal(actorID, chi.URLParam(r, "order"), req)
+	if err != nil {
+		writeRefundError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(refund)
+}
+
+// PartnerRefundWithdrawal - возврат по отменённому заказу сервером партнёра с ключом withdrawals:refund
+func (h *Handler) PartnerRefundWithdrawal(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"`+ErrUserIsNotAuthenticated.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+	key, ok := middleware.GetAPIKey(r.Context())
+	if !ok {
+		http.Error(w, `{"error":"`+ErrForbidden.Error()+`"}`, http.StatusForbidden)
+		return
+	}
+
+	var req models.RefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"`+ErrInvalidJSONFormat.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	userIDint, _ := strconv.Atoi(userID)
+	refund, err := h.svc.PartnerRefundWithdrawal(userIDint, key.ID, chi.URLParam(r, "order"), req)
+	if err != nil {
+		writeRefundError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(refund)
+}
+
+func writeRefundError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrWithdrawalNotFound):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusNotFound)
+	case errors.Is(err, ErrInvalidAmount):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+	case errors.Is(err, ErrRefundExceedsWithdrawal):
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusConflict)
+	default:
+		castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+		http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	middleware "go-musthave-diploma-tpl/internal/gophermart/middleware"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
 	service "go-musthave-diploma-tpl/internal/gophermart/service"
 
 	"github.com/go-chi/chi/v5"
@@ -18,27 +19,119 @@ func NewRouter(h *Handler, svc *service.GofemartService) http.Handler {
 	// публичные маршруты
 	r.Post("/api/user/register", h.Register)
 	r.Post("/api/user/login", h.Login)
+	// режим токенов: обновление и отзыв refresh-токена
+	r.Post("/api/user/token/refresh", h.RefreshToken)
+	r.Post("/api/user/token/revoke", h.RevokeToken)
+	// вход через провайдера OpenID Connect
+	r.Get("/api/user/oidc/login", h.OIDCLogin)
+	r.Get("/api/user/oidc/callback", h.OIDCCallback)
 
 	// защищённые маршруты
 	r.Route("/api", func(r chi.Router) {
 		r.Route("/user", func(r chi.Router) {
-			// подключаем проверку cookie
+			// подключаем проверку cookie, Bearer-токена или API-ключа
 			r.Use(middleware.AccessCookieMiddleware(svc))
 
+			// по API-ключу доступны только маршруты с разрешённой ключу областью
 			r.Route("/orders", func(r chi.Router) {
 				// загрузка пользователем номера заказа для расчёта
-				r.Post("/", h.CreateOrder)
+				r.With(middleware.RequireScope(models.ScopeOrdersWrite)).Post("/", h.CreateOrder)
 				// получение списка загруженных пользователем номеров заказов, статусов их обработки и информации о начислениях
-				r.Get("/", h.GetOrders)
+				r.With(middleware.RequireScope(models.ScopeOrdersRead)).Get("/", h.GetOrders)
 			})
 			r.Route("/balance", func(r chi.Router) {
 				// получение текущего баланса счёта баллов лояльности пользователя
-				r.Get("/", h.GetBalance)
+				r.With(middleware.RequireScope(models.ScopeBalanceRead)).Get("/", h.GetBalance)
 				// запрос на списание баллов с накопительного счёта в счёт оплаты нового заказа
-				r.Post("/withdraw", h.Withdraw)
+				r.With(middleware.DenyAPIKey).Post("/withdraw", h.Withdraw)
+				// перевод баллов другому пользователю и история переводов
+				r.With(middleware.DenyAPIKey).Post("/transfer", h.Transfer)
+				r.With(middleware.RequireScope(models.ScopeBalanceRead)).Get("/transfers", h.Transfers)
+				// единая история движений баллов с остатком после каждого
+				r.With(middleware.RequireScope(models.ScopeBalanceRead)).Get("/history", h.BalanceHistory)
+				// двухфазное списание: резерв под заказ, затем списание или отмена
+				r.Route("/holds", func(r chi.Router) {
+					r.Use(middleware.DenyAPIKey)
+					r.Post("/", h.CreateHold)
+					r.Get("/", h.Holds)
+					r.Post("/{holdID}/capture", h.CaptureHold)
+					r.Post("/{holdID}/void", h.VoidHold)
+				})
+			})
+			// уровень программы лояльности
+			r.With(middleware.RequireScope(models.ScopeBalanceRead)).Get("/tier", h.GetTier)
+			// выписка по счёту в CSV или PDF
+			r.With(middleware.RequireScope(models.ScopeBalanceRead)).Get("/statements", h.Statements)
+			// погашение промокода
+			r.With(middleware.DenyAPIKey).Post("/promo", h.RedeemPromo)
+			r.Route("/withdrawals", func(r chi.Router) {
+				// получение информации о выводе средств с накопительного счёта пользователем
+				r.With(middleware.RequireScope(models.ScopeWithdrawalsRead)).Get("/", h.Withdrawals)
+				// возврат баллов по отменённому заказу - только сервером партнёра по API-ключу
+				r.With(middleware.RequireAPIKey(models.ScopeWithdrawalsRefund)).Post("/{order}/refunds", h.PartnerRefundWithdrawal)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.DenyAPIKey)
+
+				// выход из текущей сессии и со всех устройств
+				r.Post("/logout", h.Logout)
+				r.Post("/logout-all", h.LogoutAll)
+				// список активных сессий
+				r.Get("/sessions", h.Sessions)
+				// смена пароля
+				r.Post("/password", h.ChangePassword)
+				// подключение двухфакторной аутентификации
+				r.Route("/2fa", func(r chi.Router) {
+					r.Post("/setup", h.SetupTOTP)
+					r.Post("/confirm", h.ConfirmTOTP)
+				})
+				// персональные API-ключи для серверов партнёров
+				r.Route("/api-keys", func(r chi.Router) {
+					r.Post("/", h.CreateAPIKey)
+					r.Get("/", h.APIKeys)
+					r.Delete("/{keyID}", h.RevokeAPIKey)
+				})
+				// реферальный код и приглашённые пользователи
+				r.Get("/referrals", h.Referrals)
+			})
+		})
+
+		// администрирование: поддержка смотрит данные, администратор ещё и меняет
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(middleware.AccessCookieMiddleware(svc))
+			r.Use(middleware.DenyAPIKey)
+			r.Use(middleware.RequireRole(models.RoleSupport, models.RoleAdmin))
+
+			r.Get("/users", h.AdminSearchUsers)
+			r.Get("/users/{userID}/orders", h.AdminUserOrders)
+			r.Get("/users/{userID}/balance", h.AdminUserBalance)
+			r.Get("/users/{userID}/withdrawals", h.AdminUserWithdrawals)
+			r.Get("/users/{userID}/ledger", h.AdminUserLedger)
+			// повторный опрос системы начислений по зависшему заказу
+			r.Post("/orders/{number}/requeue", h.AdminRequeueOrder)
+			// возврат баллов по списанию, полный или частичный
+			r.Post("/withdrawals/{order}/refunds", h.AdminRefundWithdrawal)
+			r.Get("/promo-codes", h.AdminPromoCodes)
+
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireRole(models.RoleAdmin))
+
+				r.Post("/users/{userID}/block", h.AdminBlockUser)
+				r.Post("/users/{userID}/unblock", h.AdminUnblockUser)
+				r.Post("/users/{userID}/role", h.AdminSetRole)
+				r.Post("/users/{userID}/unlock-login", h.AdminUnlockLogin)
+				// ключ для сервера партнёра, в том числе с областями, недоступными самому пользователю
+				r.Post("/users/{userID}/api-keys", h.AdminCreateAPIKey)
+				r.Get("/audit", h.AdminAuditLog)
+				// корректировки и сторно проводок, сверка журнала
+				r.Post("/users/{userID}/adjustments", h.AdminAdjustBalance)
+				r.Post("/ledger/entries/{entryID}/reverse", h.AdminReverseEntry)
+				r.Get("/ledger/check", h.AdminCheckLedger)
+				// промокоды маркетинговых кампаний
+				r.Post("/promo-codes", h.AdminCreatePromoCode)
+				r.Post("/promo-codes/{code}/disable", h.AdminDisablePromoCode)
 			})
-			// получение информации о выводе средств с накопительного счёта пользователем
-			r.Get("/withdrawals", h.Withdrawals)
 		})
 	})
 	return r
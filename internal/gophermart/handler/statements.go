@@ -0,0 +1,83 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/middleware"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/statement"
+)
+
+// Statements - выписка по счёту файлом. Параметры: from и to обязательны (RFC 3339 или дата YYYY-MM-DD,
+// to включительно), format - csv (по умолчанию) или pdf
+func (h *Handler) Statements(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		http.Error(w, `{"error":"`+ErrUserIsNotAuthenticated.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	from, errFrom := parseHistoryTime(q.Get("from"), false)
+	to, errTo := parseHistoryTime(q.Get("to"), true)
+	if errFrom != nil || errTo != nil || from == nil || to == nil {
+		w.Header().Set("Content-Type", "application/json")
+		http.Error(w, `{"error":"`+ErrInvalidStatementPeriod.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+	format := q.Get("format")
+	if format == "" {
+		format = models.StatementFormatCSV
+	}
+
+	// заголовки уйдут с первым блоком файла, до этого ошибку ещё можно вернуть статусом
+	out := &statementResponse{ResponseWriter: w}
+	sw, err := statement.New(format, out)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", statement.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="statement-%s-%s.%s"`,
+		from.UTC().Format(time.DateOnly), to.Add(-time.Nanosecond).UTC().Format(time.DateOnly), format))
+
+	userIDint, _ := strconv.Atoi(userID)
+	err = h.svc.WriteStatement(r.Context(), userIDint, *from, *to, sw)
+	if err == nil {
+		return
+	}
+	if out.started {
+		// файл уже частично отправлен, остаётся оборвать его
+		castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+		return
+	}
+	w.Header().Del("Content-Disposition")
+	w.Header().Set("Content-Type", "application/json")
+	if errors.Is(err, ErrInvalidStatementPeriod) {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, ErrUserNotFound) {
+		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusNotFound)
+		return
+	}
+	castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+	http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+}
+
+// statementResponse запоминает, начал ли уходить файл клиенту
+type statementResponse struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (s *statementResponse) Write(b []byte) (int, error) {
+	s.started = true
+	return s.ResponseWriter.Write(b)
+}
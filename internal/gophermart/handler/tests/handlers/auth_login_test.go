@@ -7,12 +7,15 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
 	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
 	"go-musthave-diploma-tpl/internal/gophermart/models"
 	"go-musthave-diploma-tpl/internal/gophermart/service"
 	mocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/password"
 
 	"github.com/golang/mock/gomock"
 )
@@ -21,8 +24,14 @@ func TestLoginHandler(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
+	hasher := password.NewHasher(4)
+	correctHash, err := hasher.Hash("correctpassword")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
 	mockRepo := mocks.NewMockGofemartRepo(ctrl)
-	svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+	svc := service.NewGofemartService(mockRepo, "http://localhost:8081", service.WithPasswordHasher(hasher))
 	h := handler.NewHandler(svc)
 
 	tests := []struct {
@@ -42,12 +51,21 @@ func TestLoginHandler(t *testing.T) {
 			},
 			contentType: "application/json",
 			mockSetup: func() {
+				expectNotLocked(mockRepo, "testuser")
 				mockRepo.EXPECT().
-					GetUserByLoginAndPassword("testuser", "correctpassword").
+					GetUserByLogin("testuser").
 					Return(&models.User{
-						ID:    1,
-						Login: "testuser",
+						ID:           1,
+						Login:        "testuser",
+						PasswordHash: correctHash,
 					}, nil)
+				mockRepo.EXPECT().
+					ResetLoginAttempts(models.LoginAttemptScopeLogin, "testuser").
+					Return(nil)
+				mockRepo.EXPECT().GetTOTP(1).Return(nil, nil)
+				mockRepo.EXPECT().
+					CreateSession(1, gomock.Any(), "test-agent", gomock.Any(), gomock.Any()).
+					Return(nil)
 			},
 			expectedStatus: http.StatusOK,
 			expectedBody:   "",
@@ -69,16 +87,20 @@ func TestLoginHandler(t *testing.T) {
 			},
 			contentType: "application/json",
 			mockSetup: func() {
+				expectNotLocked(mockRepo, "testuser")
 				mockRepo.EXPECT().
-					GetUserByLoginAndPassword("testuser", "wrongpassword").
-					Return(nil, sql.ErrNoRows)
+					GetUserByLogin("testuser").
+					Return(&models.User{
+						ID:           1,
+						Login:        "testuser",
+						PasswordHash: correctHash,
+					}, nil)
+				expectFailureRecorded(mockRepo, "testuser")
 			},
-			// СЕРВИС возвращает ошибку, которую хендлер трактует как internal error
-			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   handler.ErrInternalServerError.Error(),
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   handler.ErrInvalidLoginOrPassword.Error(),
 		},
 		{
-			// аналогично
 			name: "Invalid login or password (nonexistent user)",
 			payload: models.RegisterRequest{
 				Login:    "nonexistent",
@@ -86,9 +108,28 @@ func TestLoginHandler(t *testing.T) {
 			},
 			contentType: "application/json",
 			mockSetup: func() {
+				expectNotLocked(mockRepo, "nonexistent")
 				mockRepo.EXPECT().
-					GetUserByLoginAndPassword("nonexistent", "password").
-					Return(nil, sql.ErrNoRows)
+					GetUserByLogin("nonexistent").
+					Return(nil, nil)
+				expectFailureRecorded(mockRepo, "nonexistent")
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   handler.ErrInvalidLoginOrPassword.Error(),
+		},
+		{
+			// СЕРВИС возвращает ошибку БД, которую хендлер трактует как internal error
+			name: "Database error on user lookup",
+			payload: models.RegisterRequest{
+				Login:    "testuser",
+				Password: "password",
+			},
+			contentType: "application/json",
+			mockSetup: func() {
+				expectNotLocked(mockRepo, "testuser")
+				mockRepo.EXPECT().
+					GetUserByLogin("testuser").
+					Return(nil, sql.ErrConnDone)
 			},
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   handler.ErrInternalServerError.Error(),
@@ -142,13 +183,53 @@ func TestLoginHandler(t *testing.T) {
 			},
 			contentType: "application/json",
 			mockSetup: func() {
+				expectNotLocked(mockRepo, "testuser")
 				mockRepo.EXPECT().
-					GetUserByLoginAndPassword("testuser", "password").
+					GetUserByLogin("testuser").
 					Return(nil, fmt.Errorf("database error"))
 			},
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   handler.ErrInternalServerError.Error(),
 		},
+		{
+			name: "Locked out - password is not checked",
+			payload: models.RegisterRequest{
+				Login:    "testuser",
+				Password: "correctpassword",
+			},
+			contentType: "application/json",
+			mockSetup: func() {
+				mockRepo.EXPECT().
+					LoginLockedUntil("testuser", testClientIP).
+					Return(time.Now().Add(90*time.Second), nil)
+			},
+			expectedStatus: http.StatusTooManyRequests,
+			expectedBody:   handler.ErrTooManyLoginAttempts.Error(),
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				retryAfter, err := strconv.Atoi(rr.Header().Get("Retry-After"))
+				if err != nil {
+					t.Fatalf("expected numeric Retry-After, got %q", rr.Header().Get("Retry-After"))
+				}
+				if retryAfter < 89 || retryAfter > 90 {
+					t.Fatalf("expected Retry-After about 90 seconds, got %d", retryAfter)
+				}
+			},
+		},
+		{
+			name: "Lock check database error",
+			payload: models.RegisterRequest{
+				Login:    "testuser",
+				Password: "correctpassword",
+			},
+			contentType: "application/json",
+			mockSetup: func() {
+				mockRepo.EXPECT().
+					LoginLockedUntil("testuser", testClientIP).
+					Return(time.Time{}, fmt.Errorf("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   handler.ErrInternalServerError.Error(),
+		},
 	}
 
 	for _, tt := range tests {
@@ -169,6 +250,7 @@ func TestLoginHandler(t *testing.T) {
 
 			req := httptest.NewRequest(http.MethodPost, "/api/user/login", bytes.NewBuffer(bodyBytes))
 			req.Header.Set("Content-Type", tt.contentType)
+			req.Header.Set("User-Agent", "test-agent")
 
 			rr := httptest.NewRecorder()
 			h.Login(rr, req)
@@ -187,3 +269,21 @@ func TestLoginHandler(t *testing.T) {
 		})
 	}
 }
+
+// адрес клиента, который httptest.NewRequest ставит в RemoteAddr
+const testClientIP = "192.0.2.1"
+
+func expectNotLocked(mockRepo *mocks.MockGofemartRepo, login string) {
+	mockRepo.EXPECT().
+		LoginLockedUntil(login, testClientIP).
+		Return(time.Time{}, nil)
+}
+
+func expectFailureRecorded(mockRepo *mocks.MockGofemartRepo, login string) {
+	mockRepo.EXPECT().
+		RecordLoginFailure(models.LoginAttemptScopeLogin, login, gomock.Any()).
+		Return(1, nil)
+	mockRepo.EXPECT().
+		RecordLoginFailure(models.LoginAttemptScopeIP, testClientIP, gomock.Any()).
+		Return(1, nil)
+}
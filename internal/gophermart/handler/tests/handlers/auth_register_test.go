@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
@@ -43,11 +44,13 @@ func TestHandler_Register(t *testing.T) {
 			},
 			contentType: "application/json",
 			mockSetup: func() {
-				mockRepo.EXPECT().CreateUser("newuser", "password123").
+				mockRepo.EXPECT().CreateUser("newuser", gomock.Any()).
 					Return(&models.User{
 						ID:    1,
 						Login: "newuser",
 					}, nil)
+				mockRepo.EXPECT().CreateSession(1, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil)
 			},
 			expectedStatus: http.StatusOK,
 			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
@@ -62,12 +65,112 @@ func TestHandler_Register(t *testing.T) {
 			},
 			contentType: "application/json",
 			mockSetup: func() {
-				mockRepo.EXPECT().CreateUser("existinguser", "password123").
+				mockRepo.EXPECT().CreateUser("existinguser", gomock.Any()).
 					Return(nil, fmt.Errorf("login already exists"))
 			},
 			expectedStatus: http.StatusConflict,
 			expectedBody:   `{"error":"login already taken"}`,
 		},
+		{
+			name: "Registration with referral code",
+			payload: map[string]string{
+				"login":         "friend",
+				"password":      "password123",
+				"referral_code": " ab12cd34ef ",
+			},
+			contentType: "application/json",
+			mockSetup: func() {
+				// код нормализуется, адрес и ограничения приглашений уходят в хранилище для проверки
+				mockRepo.EXPECT().CreateReferredUser("friend", gomock.Any(), models.ReferralSignup{
+					Code:   "AB12CD34EF",
+					IP:     testClientIP,
+					Limits: service.DefaultReferralLimits(),
+				}).Return(&models.User{ID: 2, Login: "friend"}, nil)
+				mockRepo.EXPECT().CreateSession(2, gomock.Any(), gomock.Any(), testClientIP, gomock.Any()).
+					Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Unknown referral code",
+			payload: map[string]string{
+				"login":         "friend",
+				"password":      "password123",
+				"referral_code": "NOPE",
+			},
+			contentType: "application/json",
+			mockSetup: func() {
+				mockRepo.EXPECT().CreateReferredUser("friend", gomock.Any(), gomock.Any()).
+					Return(nil, handler.ErrInvalidReferralCode)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid referral code"}`,
+		},
+		{
+			name: "Referral code from the referrer's address",
+			payload: map[string]string{
+				"login":         "friend",
+				"password":      "password123",
+				"referral_code": "AB12CD34EF",
+			},
+			contentType: "application/json",
+			mockSetup: func() {
+				mockRepo.EXPECT().CreateReferredUser("friend", gomock.Any(), gomock.Any()).
+					Return(nil, handler.ErrSelfReferral)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"cannot use your own referral code"}`,
+		},
+		{
+			name: "Referrer invited too many users today",
+			payload: map[string]string{
+				"login":         "friend",
+				"password":      "password123",
+				"referral_code": "AB12CD34EF",
+			},
+			contentType: "application/json",
+			mockSetup: func() {
+				mockRepo.EXPECT().CreateReferredUser("friend", gomock.Any(), gomock.Any()).
+					Return(nil, handler.ErrReferralLimitExceeded)
+			},
+			expectedStatus: http.StatusTooManyRequests,
+			expectedBody:   `{"error":"daily referral limit exceeded"}`,
+		},
+		{
+			name: "Short password is accepted by default",
+			payload: map[string]string{
+				"login":    "newuser",
+				"password": "123",
+			},
+			contentType: "application/json",
+			mockSetup: func() {
+				mockRepo.EXPECT().CreateUser("newuser", gomock.Any()).Return(&models.User{ID: 1, Login: "newuser"}, nil)
+				mockRepo.EXPECT().CreateSession(1, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Password equal to login",
+			payload: map[string]string{
+				"login":    "newuser",
+				"password": "NewUser",
+			},
+			contentType:    "application/json",
+			mockSetup:      func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"password does not meet policy","violations":[{"rule":"equals_login","message":"password must not be equal to login"}]}`,
+		},
+		{
+			name: "Password longer than bcrypt accepts",
+			payload: map[string]string{
+				"login":    "newuser",
+				"password": strings.Repeat("p", 73),
+			},
+			contentType:    "application/json",
+			mockSetup:      func() {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"password does not meet policy","violations":[{"rule":"max_length","message":"password must be at most 72 bytes long"}]}`,
+		},
 		{
 			name: "Empty login and password",
 			payload: map[string]string{
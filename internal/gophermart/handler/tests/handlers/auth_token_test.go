@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+	mocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/password"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginHandler_TokenMode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hasher := password.NewHasher(4)
+	hash, err := hasher.Hash("correctpassword")
+	require.NoError(t, err)
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	svc := service.NewGofemartService(mockRepo, "http://localhost:8081", service.WithPasswordHasher(hasher))
+	h := handler.NewHandler(svc)
+
+	expectNotLocked(mockRepo, "testuser")
+	mockRepo.EXPECT().
+		GetUserByLogin("testuser").
+		Return(&models.User{ID: 1, Login: "testuser", PasswordHash: hash}, nil)
+	mockRepo.EXPECT().
+		ResetLoginAttempts(models.LoginAttemptScopeLogin, "testuser").
+		Return(nil)
+	mockRepo.EXPECT().GetTOTP(1).Return(nil, nil)
+	mockRepo.EXPECT().
+		CreateRefreshToken(1, gomock.Any(), gomock.Any()).
+		Return(nil)
+
+	body, _ := json.Marshal(models.RegisterRequest{Login: "testuser", Password: "correctpassword"})
+	req := httptest.NewRequest(http.MethodPost, "/api/user/login?mode=token", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	h.Login(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	// в режиме токенов кука не ставится
+	assert.Empty(t, rr.Header().Get("Set-Cookie"))
+
+	var pair models.TokenPair
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&pair))
+	assert.NotEmpty(t, pair.AccessToken)
+	assert.NotEmpty(t, pair.RefreshToken)
+	assert.Equal(t, "Bearer", pair.TokenType)
+}
+
+func TestRefreshTokenHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		mockSetup      func(mockRepo *mocks.MockGofemartRepo)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Successful refresh",
+			body: `{"refresh_token":"old"}`,
+			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
+				mockRepo.EXPECT().
+					RotateRefreshToken(service.HashToken("old"), gomock.Any(), gomock.Any()).
+					Return(1, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "access_token",
+		},
+		{
+			name: "Revoked token",
+			body: `{"refresh_token":"old"}`,
+			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
+				mockRepo.EXPECT().
+					RotateRefreshToken(service.HashToken("old"), gomock.Any(), gomock.Any()).
+					Return(0, handler.ErrInvalidRefreshToken)
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   handler.ErrInvalidRefreshToken.Error(),
+		},
+		{
+			name:           "Empty token",
+			body:           `{}`,
+			mockSetup:      func(mockRepo *mocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   handler.ErrInvalidRefreshToken.Error(),
+		},
+		{
+			name:           "Invalid JSON",
+			body:           `{"refresh_token":`,
+			mockSetup:      func(mockRepo *mocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   handler.ErrInvalidJSONFormat.Error(),
+		},
+		{
+			name: "Database error",
+			body: `{"refresh_token":"old"}`,
+			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
+				mockRepo.EXPECT().
+					RotateRefreshToken(service.HashToken("old"), gomock.Any(), gomock.Any()).
+					Return(0, errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   handler.ErrInternalServerError.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := mocks.NewMockGofemartRepo(ctrl)
+			svc := service.NewGofemartService(mockRepo, "http://localhost:8081", service.WithTokenSettings(service.TokenSettings{
+				AccessTTL: time.Minute,
+			}))
+			h := handler.NewHandler(svc)
+			tt.mockSetup(mockRepo)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/user/token/refresh", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+
+			h.RefreshToken(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Contains(t, rr.Body.String(), tt.expectedBody)
+		})
+	}
+}
+
+func TestRevokeTokenHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+	h := handler.NewHandler(svc)
+
+	mockRepo.EXPECT().
+		RevokeRefreshToken(service.HashToken("refresh")).
+		Return(nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/user/token/revoke", bytes.NewBufferString(`{"refresh_token":"refresh"}`))
+	rr := httptest.NewRecorder()
+
+	h.RevokeToken(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
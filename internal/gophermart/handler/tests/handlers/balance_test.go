@@ -12,6 +12,7 @@ import (
 	"go-musthave-diploma-tpl/internal/gophermart/models"
 	"go-musthave-diploma-tpl/internal/gophermart/service"
 	mocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -40,12 +41,13 @@ func TestHandler_GetBalance(t *testing.T) {
 			},
 			setupMock: func() {
 				mockRepo.EXPECT().GetBalance(1).Return(models.Balance{
-					Current:   500.5,
-					Withdrawn: 42,
+					Current:   money.FromCents(50050),
+					Held:      money.FromUnits(20),
+					Withdrawn: money.FromUnits(42),
 				}, nil)
 			},
 			expectedStatus: http.StatusOK,
-			expectedBody:   `{"current":500.5,"withdrawn":42}`,
+			expectedBody:   `{"current":500.5,"held":20,"withdrawn":42}`,
 			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
 				assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
 			},
@@ -82,7 +84,7 @@ func TestHandler_GetBalance(t *testing.T) {
 				}, nil)
 			},
 			expectedStatus: http.StatusOK,
-			expectedBody:   `{"current":0,"withdrawn":0}`,
+			expectedBody:   `{"current":0,"held":0,"withdrawn":0}`,
 		},
 	}
 
@@ -0,0 +1,163 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/middleware"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+	mocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithdrawHandler_IdempotencyKey(t *testing.T) {
+	const key = "8e03978e-40d5-43e8-bc93-6894a57f9324"
+	fingerprint := service.RequestFingerprint("2377225624", "751.00")
+	withdraw := models.WithdrawBalance{Order: "2377225624", Sum: money.FromUnits(751), IdempotencyKey: key}
+	reserved := func(mockRepo *mocks.MockGofemartRepo, existing *models.IdempotencyKey) {
+		mockRepo.EXPECT().ReserveIdempotencyKey(gomock.Any()).DoAndReturn(
+			func(k models.IdempotencyKey) (*models.IdempotencyKey, error) {
+				assert.Equal(t, 1, k.UserID)
+				assert.Equal(t, models.IdempotencyScopeWithdraw, k.Scope)
+				assert.Equal(t, key, k.Key)
+				assert.Equal(t, fingerprint, k.Fingerprint)
+				return existing, nil
+			})
+	}
+
+	tests := []struct {
+		name           string
+		key            string
+		mockSetup      func(*mocks.MockGofemartRepo)
+		expectedStatus int
+		expectedBody   string
+		replayed       bool
+	}{
+		{
+			name: "First request withdraws",
+			key:  key,
+			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
+				reserved(mockRepo, nil)
+				mockRepo.EXPECT().Withdraw(1, withdraw).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{}",
+		},
+		{
+			name: "Retry replays stored response",
+			key:  key,
+			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
+				reserved(mockRepo, &models.IdempotencyKey{Fingerprint: fingerprint, Response: &models.WithdrawSucceeded})
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{}",
+			replayed:       true,
+		},
+		{
+			name: "Stored business error is replayed",
+			key:  key,
+			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
+				reserved(mockRepo, &models.IdempotencyKey{Fingerprint: fingerprint, Response: &models.IdempotentResponse{
+					StatusCode: http.StatusPaymentRequired,
+					Body:       `{"error":"lack of funds"}`,
+				}})
+			},
+			expectedStatus: http.StatusPaymentRequired,
+			expectedBody:   "lack of funds",
+			replayed:       true,
+		},
+		{
+			name: "Key reused with different body",
+			key:  key,
+			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
+				reserved(mockRepo, &models.IdempotencyKey{Fingerprint: "other", Response: &models.WithdrawSucceeded})
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   handler.ErrIdempotencyKeyReused.Error(),
+		},
+		{
+			name:           "Invalid key",
+			key:            "",
+			mockSetup:      func(mockRepo *mocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   handler.ErrInvalidIdempotencyKey.Error(),
+		},
+		{
+			name: "Lack of funds is stored for retries",
+			key:  key,
+			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
+				reserved(mockRepo, nil)
+				mockRepo.EXPECT().Withdraw(1, withdraw).Return(handler.ErrLackOfFunds)
+				mockRepo.EXPECT().SaveIdempotentResponse(1, models.IdempotencyScopeWithdraw, key, models.IdempotentResponse{
+					StatusCode: http.StatusPaymentRequired,
+					Body:       `{"error":"lack of funds"}`,
+				}).Return(nil)
+			},
+			expectedStatus: http.StatusPaymentRequired,
+			expectedBody:   "lack of funds",
+		},
+		{
+			name: "Server error releases key",
+			key:  key,
+			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
+				reserved(mockRepo, nil)
+				mockRepo.EXPECT().Withdraw(1, withdraw).Return(errors.New("database error"))
+				mockRepo.EXPECT().ReleaseIdempotencyKey(1, models.IdempotencyScopeWithdraw, key).Return(nil)
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   handler.ErrInternalServerError.Error(),
+		},
+		{
+			name: "Concurrent duplicate gets the winner's response",
+			key:  key,
+			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
+				// оба запроса заняли ключ, первый успел зафиксировать списание
+				reserved(mockRepo, &models.IdempotencyKey{Fingerprint: fingerprint})
+				mockRepo.EXPECT().Withdraw(1, withdraw).Return(handler.ErrIdempotencyConflict)
+				reserved(mockRepo, &models.IdempotencyKey{Fingerprint: fingerprint, Response: &models.WithdrawSucceeded})
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "{}",
+			replayed:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := mocks.NewMockGofemartRepo(ctrl)
+			svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+			h := handler.NewHandler(svc)
+			tt.mockSetup(mockRepo)
+
+			req := httptest.NewRequest("POST", "/api/user/balance/withdraw",
+				bytes.NewBufferString(`{"order":"2377225624","sum":751}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Idempotency-Key", tt.key)
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "1"))
+			rr := httptest.NewRecorder()
+
+			h.Withdraw(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			assert.Contains(t, strings.TrimSpace(rr.Body.String()), tt.expectedBody)
+			if tt.replayed {
+				assert.Equal(t, "true", rr.Header().Get("Idempotent-Replayed"))
+			} else {
+				assert.Empty(t, rr.Header().Get("Idempotent-Replayed"))
+			}
+		})
+	}
+}
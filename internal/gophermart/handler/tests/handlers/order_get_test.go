@@ -15,6 +15,7 @@ import (
 	"go-musthave-diploma-tpl/internal/gophermart/models"
 	"go-musthave-diploma-tpl/internal/gophermart/service"
 	mocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
 
 	"github.com/golang/mock/gomock"
 )
@@ -46,7 +47,7 @@ func TestGetOrdersHandler(t *testing.T) {
 						{
 							Number:     "1234567890",
 							Status:     "PROCESSED",
-							Accrual:    100.5,
+							Accrual:    money.FromCents(10050),
 							UploadedAt: now,
 						},
 						{
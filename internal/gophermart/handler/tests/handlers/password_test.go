@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/middleware"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+	mocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/password"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangePasswordHandler(t *testing.T) {
+	hasher := password.NewHasher(4)
+	currentHash, err := hasher.Hash("old-password")
+	require.NoError(t, err)
+	user := &models.User{ID: 1, Login: "user", PasswordHash: currentHash}
+
+	tests := []struct {
+		name           string
+		payload        string
+		mockSetup      func(mockRepo *mocks.MockGofemartRepo)
+		expectedStatus int
+		checkResponse  func(t *testing.T, rr *httptest.ResponseRecorder)
+	}{
+		{
+			name:    "Password changed, other sessions revoked",
+			payload: `{"current_password":"old-password","new_password":"new-password"}`,
+			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
+				mockRepo.EXPECT().GetUserByID(1).Return(user, nil)
+				mockRepo.EXPECT().ChangePassword(1, gomock.Any(), 5).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:    "Wrong current password",
+			payload: `{"current_password":"wrong","new_password":"new-password"}`,
+			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
+				mockRepo.EXPECT().GetUserByID(1).Return(user, nil)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:    "Policy violations are listed",
+			payload: `{"current_password":"old-password","new_password":"user"}`,
+			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
+				mockRepo.EXPECT().GetUserByID(1).Return(user, nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				var resp models.PasswordPolicyResponse
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+				assert.Equal(t, handler.ErrPasswordPolicy.Error(), resp.Error)
+				require.Len(t, resp.Violations, 1)
+				assert.Equal(t, password.RuleEqualsLogin, resp.Violations[0].Rule)
+			},
+		},
+		{
+			name:    "New password longer than bcrypt accepts",
+			payload: `{"current_password":"old-password","new_password":"` + strings.Repeat("p", 73) + `"}`,
+			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
+				mockRepo.EXPECT().GetUserByID(1).Return(user, nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				var resp models.PasswordPolicyResponse
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+				require.Len(t, resp.Violations, 1)
+				assert.Equal(t, password.RuleMaxLength, resp.Violations[0].Rule)
+			},
+		},
+		{
+			name:           "Missing new password",
+			payload:        `{"current_password":"old-password"}`,
+			mockSetup:      func(mockRepo *mocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid JSON",
+			payload:        `{"current_password":`,
+			mockSetup:      func(mockRepo *mocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := mocks.NewMockGofemartRepo(ctrl)
+			h := handler.NewHandler(service.NewGofemartService(mockRepo, "http://localhost:8081", service.WithPasswordHasher(hasher)))
+			tt.mockSetup(mockRepo)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/user/password", bytes.NewBufferString(tt.payload))
+			ctx := context.WithValue(req.Context(), middleware.UserIDKey, "1")
+			ctx = context.WithValue(ctx, middleware.SessionIDKey, 5)
+			rr := httptest.NewRecorder()
+
+			h.ChangePassword(rr, req.WithContext(ctx))
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.checkResponse != nil {
+				tt.checkResponse(t, rr)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/middleware"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+	mocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// запрос от пользователя, вошедшего по куке с сессией sessionID
+func sessionRequest(method, target string, userID string, sessionID int) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	ctx := context.WithValue(req.Context(), middleware.UserIDKey, userID)
+	if sessionID != 0 {
+		ctx = context.WithValue(ctx, middleware.SessionIDKey, sessionID)
+	}
+	return req.WithContext(ctx)
+}
+
+func TestLogoutHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		sessionID      int
+		mockSetup      func(mockRepo *mocks.MockGofemartRepo)
+		expectedStatus int
+	}{
+		{
+			name:      "Logout current session",
+			sessionID: 5,
+			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
+				mockRepo.EXPECT().RevokeSession(1, 5).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Bearer request without session",
+			sessionID:      0,
+			mockSetup:      func(mockRepo *mocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:      "Database error",
+			sessionID: 5,
+			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
+				mockRepo.EXPECT().RevokeSession(1, 5).Return(errors.New("database error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := mocks.NewMockGofemartRepo(ctrl)
+			h := handler.NewHandler(service.NewGofemartService(mockRepo, "http://localhost:8081"))
+			tt.mockSetup(mockRepo)
+
+			rr := httptest.NewRecorder()
+			h.Logout(rr, sessionRequest(http.MethodPost, "/api/user/logout", "1", tt.sessionID))
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Contains(t, rr.Header().Get("Set-Cookie"), "Max-Age=0")
+			}
+		})
+	}
+}
+
+func TestLogoutAllHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	h := handler.NewHandler(service.NewGofemartService(mockRepo, "http://localhost:8081"))
+
+	mockRepo.EXPECT().RevokeAllSessions(1).Return(nil)
+
+	rr := httptest.NewRecorder()
+	h.LogoutAll(rr, sessionRequest(http.MethodPost, "/api/user/logout-all", "1", 5))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	h.LogoutAll(rr, httptest.NewRequest(http.MethodPost, "/api/user/logout-all", nil))
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestSessionsHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	h := handler.NewHandler(service.NewGofemartService(mockRepo, "http://localhost:8081"))
+
+	now := time.Now()
+	mockRepo.EXPECT().ActiveSessions(1).Return([]models.Session{
+		{ID: 5, UserID: 1, UserAgent: "phone", CreatedAt: now, LastSeenAt: now, ExpiresAt: now.Add(time.Hour)},
+		{ID: 6, UserID: 1, UserAgent: "laptop", CreatedAt: now, LastSeenAt: now, ExpiresAt: now.Add(time.Hour)},
+	}, nil)
+
+	rr := httptest.NewRecorder()
+	h.Sessions(rr, sessionRequest(http.MethodGet, "/api/user/sessions", "1", 6))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var sessions []models.Session
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&sessions))
+	require.Len(t, sessions, 2)
+	assert.False(t, sessions[0].Current)
+	assert.True(t, sessions[1].Current)
+	assert.Equal(t, "laptop", sessions[1].UserAgent)
+}
@@ -0,0 +1,271 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/middleware"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+	mocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
+	"go-musthave-diploma-tpl/pkg/password"
+	"go-musthave-diploma-tpl/pkg/totp"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestLoginHandler_TwoFactor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hasher := password.NewHasher(4)
+	hash, err := hasher.Hash("correctpassword")
+	require.NoError(t, err)
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	h := handler.NewHandler(service.NewGofemartService(mockRepo, "http://localhost:8081", service.WithPasswordHasher(hasher)))
+	enabled := &models.TOTP{UserID: 1, Secret: testTOTPSecret, Enabled: true}
+
+	// шаг 1: верный пароль - вместо куки незавершённый вход
+	expectNotLocked(mockRepo, "testuser")
+	mockRepo.EXPECT().GetUserByLogin("testuser").
+		Return(&models.User{ID: 1, Login: "testuser", PasswordHash: hash}, nil)
+	// счётчик неудач не сбрасывается до второго фактора
+	mockRepo.EXPECT().GetTOTP(1).Return(enabled, nil)
+	mockRepo.EXPECT().CreateLoginChallenge(1, gomock.Any(), gomock.Any()).Return(nil)
+
+	body, _ := json.Marshal(models.LoginRequest{Login: "testuser", Password: "correctpassword"})
+	req := httptest.NewRequest(http.MethodPost, "/api/user/login", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	h.Login(rr, req)
+
+	require.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Empty(t, rr.Header().Get("Set-Cookie"))
+
+	var challenge models.LoginChallenge
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&challenge))
+	assert.Equal(t, models.LoginChallengeStatusTOTP, challenge.Status)
+	require.NotEmpty(t, challenge.Challenge)
+
+	// шаг 2: неверный код учитывается и в challenge, и в счётчике входа
+	challengeHash := service.HashToken(challenge.Challenge)
+	expectChallengeOwner(mockRepo, challengeHash)
+	mockRepo.EXPECT().LoginChallengeUser(challengeHash, gomock.Any()).Return(1, nil)
+	mockRepo.EXPECT().GetTOTP(1).Return(enabled, nil)
+	mockRepo.EXPECT().FailLoginChallenge(challengeHash).Return(nil)
+	expectFailureRecorded(mockRepo, "testuser")
+
+	body, _ = json.Marshal(models.LoginRequest{Challenge: challenge.Challenge, Code: "abcdef"})
+	req = httptest.NewRequest(http.MethodPost, "/api/user/login", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr = httptest.NewRecorder()
+	h.Login(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	// шаг 2: верный код - ставится кука
+	code, err := totp.Code(testTOTPSecret, totp.Counter(time.Now()))
+	require.NoError(t, err)
+	expectChallengeOwner(mockRepo, challengeHash)
+	mockRepo.EXPECT().LoginChallengeUser(challengeHash, gomock.Any()).Return(1, nil)
+	mockRepo.EXPECT().GetTOTP(1).Return(enabled, nil)
+	mockRepo.EXPECT().UseTOTPCounter(1, gomock.Any()).Return(true, nil)
+	mockRepo.EXPECT().CompleteLoginChallenge(challengeHash).Return(true, nil)
+	mockRepo.EXPECT().ResetLoginAttempts(models.LoginAttemptScopeLogin, "testuser").Return(nil)
+	mockRepo.EXPECT().CreateSession(1, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+	body, _ = json.Marshal(models.LoginRequest{Challenge: challenge.Challenge, Code: code})
+	req = httptest.NewRequest(http.MethodPost, "/api/user/login", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr = httptest.NewRecorder()
+	h.Login(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Header().Get("Set-Cookie"), "userID")
+}
+
+// expectChallengeOwner - поиск логина владельца challenge перед проверкой блокировки
+func expectChallengeOwner(mockRepo *mocks.MockGofemartRepo, challengeHash string) {
+	mockRepo.EXPECT().LoginChallengeUser(challengeHash, gomock.Any()).Return(1, nil)
+	mockRepo.EXPECT().GetUserByID(1).Return(&models.User{ID: 1, Login: "testuser"}, nil)
+	expectNotLocked(mockRepo, "testuser")
+}
+
+func TestLoginHandler_TwoFactorThrottle(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hasher := password.NewHasher(4)
+	hash, err := hasher.Hash("correctpassword")
+	require.NoError(t, err)
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	h := handler.NewHandler(service.NewGofemartService(mockRepo, "http://localhost:8081",
+		service.WithPasswordHasher(hasher),
+		service.WithLoginThrottle(service.LoginThrottle{MaxFailures: 3, Lockout: time.Minute, Window: time.Hour})))
+	enabled := &models.TOTP{UserID: 1, Secret: testTOTPSecret, Enabled: true}
+
+	// счётчик неудач по логину, как в login_attempts
+	var (
+		failures    int
+		lockedUntil time.Time
+	)
+	mockRepo.EXPECT().LoginLockedUntil("testuser", testClientIP).
+		DoAndReturn(func(string, string) (time.Time, error) { return lockedUntil, nil }).AnyTimes()
+	mockRepo.EXPECT().RecordLoginFailure(models.LoginAttemptScopeLogin, "testuser", gomock.Any()).
+		DoAndReturn(func(string, string, time.Duration) (int, error) { failures++; return failures, nil }).AnyTimes()
+	mockRepo.EXPECT().LockLogin(models.LoginAttemptScopeLogin, "testuser", gomock.Any()).
+		DoAndReturn(func(_, _ string, until time.Time) error { lockedUntil = until; return nil }).AnyTimes()
+	mockRepo.EXPECT().ResetLoginAttempts(gomock.Any(), gomock.Any()).Times(0)
+	mockRepo.EXPECT().GetUserByLogin("testuser").
+		Return(&models.User{ID: 1, Login: "testuser", PasswordHash: hash}, nil).AnyTimes()
+	mockRepo.EXPECT().GetUserByID(1).Return(&models.User{ID: 1, Login: "testuser"}, nil).AnyTimes()
+	mockRepo.EXPECT().GetTOTP(1).Return(enabled, nil).AnyTimes()
+	mockRepo.EXPECT().CreateLoginChallenge(1, gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	mockRepo.EXPECT().LoginChallengeUser(gomock.Any(), gomock.Any()).Return(1, nil).AnyTimes()
+	mockRepo.EXPECT().FailLoginChallenge(gomock.Any()).Return(nil).AnyTimes()
+
+	login := func(req models.LoginRequest) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(req)
+		r := httptest.NewRequest(http.MethodPost, "/api/user/login", bytes.NewBuffer(body))
+		r.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		h.Login(rr, r)
+		return rr
+	}
+
+	// на каждый challenge - одна неверная попытка, лимит challenge (5) не достигается
+	for i := 0; i < 3; i++ {
+		rr := login(models.LoginRequest{Login: "testuser", Password: "correctpassword"})
+		require.Equal(t, http.StatusAccepted, rr.Code, "attempt %d", i)
+		var challenge models.LoginChallenge
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&challenge))
+
+		rr = login(models.LoginRequest{Challenge: challenge.Challenge, Code: "abcdef"})
+		assert.Equal(t, http.StatusUnauthorized, rr.Code, "attempt %d", i)
+	}
+
+	// вход заблокирован - и по паролю, и вторым шагом
+	rr := login(models.LoginRequest{Login: "testuser", Password: "correctpassword"})
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	rr = login(models.LoginRequest{Challenge: "any", Code: "abcdef"})
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+}
+
+func TestWithdrawHandler_TwoFactor(t *testing.T) {
+	enabled := &models.TOTP{UserID: 1, Secret: testTOTPSecret, Enabled: true}
+	withdraw := models.WithdrawBalance{Order: "2377225624", Sum: money.FromUnits(1500)}
+
+	tests := []struct {
+		name           string
+		code           string
+		mockSetup      func(mockRepo *mocks.MockGofemartRepo)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "Code required above threshold",
+			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
+				mockRepo.EXPECT().GetTOTP(1).Return(enabled, nil)
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   handler.ErrTOTPRequired.Error(),
+		},
+		{
+			name: "Fresh code accepted",
+			code: "current",
+			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
+				mockRepo.EXPECT().GetTOTP(1).Return(enabled, nil)
+				mockRepo.EXPECT().UseTOTPCounter(1, gomock.Any()).Return(true, nil)
+				mockRepo.EXPECT().Withdraw(1, withdraw).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Used code rejected",
+			code: "current",
+			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
+				mockRepo.EXPECT().GetTOTP(1).Return(enabled, nil)
+				mockRepo.EXPECT().UseTOTPCounter(1, gomock.Any()).Return(false, nil)
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   handler.ErrInvalidTOTPCode.Error(),
+		},
+		{
+			name: "2FA not enabled",
+			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
+				mockRepo.EXPECT().GetTOTP(1).Return(nil, nil)
+				mockRepo.EXPECT().Withdraw(1, withdraw).Return(nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := mocks.NewMockGofemartRepo(ctrl)
+			h := handler.NewHandler(service.NewGofemartService(mockRepo, "http://localhost:8081"))
+			tt.mockSetup(mockRepo)
+
+			body, _ := json.Marshal(withdraw)
+			req := httptest.NewRequest(http.MethodPost, "/api/user/balance/withdraw", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.code == "current" {
+				code, err := totp.Code(testTOTPSecret, totp.Counter(time.Now()))
+				require.NoError(t, err)
+				req.Header.Set("X-TOTP-Code", code)
+			}
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "1"))
+			rr := httptest.NewRecorder()
+
+			h.Withdraw(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedBody != "" {
+				assert.Contains(t, rr.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+func TestConfirmTOTPHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	h := handler.NewHandler(service.NewGofemartService(mockRepo, "http://localhost:8081"))
+
+	code, err := totp.Code(testTOTPSecret, totp.Counter(time.Now()))
+	require.NoError(t, err)
+
+	mockRepo.EXPECT().GetTOTP(1).Return(&models.TOTP{UserID: 1, Secret: testTOTPSecret}, nil)
+	mockRepo.EXPECT().EnableTOTP(1, gomock.Any(), gomock.Any()).Return(nil)
+
+	body, _ := json.Marshal(models.TOTPCodeRequest{Code: code})
+	req := httptest.NewRequest(http.MethodPost, "/api/user/2fa/confirm", bytes.NewBuffer(body))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, "1"))
+	rr := httptest.NewRecorder()
+
+	h.ConfirmTOTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var resp models.TOTPConfirmResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Len(t, resp.RecoveryCodes, 10)
+}
@@ -14,6 +14,7 @@ import (
 	"go-musthave-diploma-tpl/internal/gophermart/models"
 	"go-musthave-diploma-tpl/internal/gophermart/service"
 	mocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
 
 	"github.com/golang/mock/gomock"
 )
@@ -32,12 +33,12 @@ func TestWithdrawHandler(t *testing.T) {
 			userID: "1",
 			requestBody: models.WithdrawBalance{
 				Order: "2377225624",
-				Sum:   751,
+				Sum:   money.FromUnits(751),
 			},
 			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
 				mockRepo.EXPECT().Withdraw(1, models.WithdrawBalance{
 					Order: "2377225624",
-					Sum:   751,
+					Sum:   money.FromUnits(751),
 				}).Return(nil)
 			},
 			expectedStatus: http.StatusOK,
@@ -48,12 +49,12 @@ func TestWithdrawHandler(t *testing.T) {
 			userID: "1",
 			requestBody: models.WithdrawBalance{
 				Order: "2377225624",
-				Sum:   751,
+				Sum:   money.FromUnits(751),
 			},
 			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
 				mockRepo.EXPECT().Withdraw(1, models.WithdrawBalance{
 					Order: "2377225624",
-					Sum:   751,
+					Sum:   money.FromUnits(751),
 				}).Return(handler.ErrInvalidOrderNumber)
 			},
 			expectedStatus: http.StatusUnprocessableEntity,
@@ -64,28 +65,60 @@ func TestWithdrawHandler(t *testing.T) {
 			userID: "1",
 			requestBody: models.WithdrawBalance{
 				Order: "2377225624",
-				Sum:   751,
+				Sum:   money.FromUnits(751),
 			},
 			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
 				mockRepo.EXPECT().Withdraw(1, models.WithdrawBalance{
 					Order: "2377225624",
-					Sum:   751,
+					Sum:   money.FromUnits(751),
 				}).Return(handler.ErrLackOfFunds)
 			},
 			expectedStatus: http.StatusPaymentRequired,
 			expectedBody:   "lack of funds",
 		},
+		{
+			name:   "Order already paid",
+			userID: "1",
+			requestBody: models.WithdrawBalance{
+				Order: "2377225624",
+				Sum:   money.FromUnits(751),
+			},
+			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
+				mockRepo.EXPECT().Withdraw(1, models.WithdrawBalance{
+					Order: "2377225624",
+					Sum:   money.FromUnits(751),
+				}).Return(handler.ErrOrderAlreadyPaid)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   handler.ErrOrderAlreadyPaid.Error(),
+		},
+		{
+			name:   "Accrual order cannot be paid with points",
+			userID: "1",
+			requestBody: models.WithdrawBalance{
+				Order: "2377225624",
+				Sum:   money.FromUnits(751),
+			},
+			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
+				mockRepo.EXPECT().Withdraw(1, models.WithdrawBalance{
+					Order: "2377225624",
+					Sum:   money.FromUnits(751),
+				}).Return(handler.ErrAccrualOrderWithdrawal)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   handler.ErrAccrualOrderWithdrawal.Error(),
+		},
 		{
 			name:   "internal server error",
 			userID: "1",
 			requestBody: models.WithdrawBalance{
 				Order: "2377225624",
-				Sum:   751,
+				Sum:   money.FromUnits(751),
 			},
 			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
 				mockRepo.EXPECT().Withdraw(1, models.WithdrawBalance{
 					Order: "2377225624",
-					Sum:   751,
+					Sum:   money.FromUnits(751),
 				}).Return(errors.New("database error"))
 			},
 			expectedStatus: http.StatusInternalServerError,
@@ -94,7 +127,7 @@ func TestWithdrawHandler(t *testing.T) {
 		{
 			name:           handler.ErrUserIsNotAuthenticated.Error(),
 			userID:         "",
-			requestBody:    models.WithdrawBalance{Order: "2377225624", Sum: 751},
+			requestBody:    models.WithdrawBalance{Order: "2377225624", Sum: money.FromUnits(751)},
 			mockSetup:      func(mockRepo *mocks.MockGofemartRepo) {},
 			expectedStatus: http.StatusUnauthorized,
 			expectedBody:   handler.ErrUserIsNotAuthenticated.Error(),
@@ -102,7 +135,7 @@ func TestWithdrawHandler(t *testing.T) {
 		{
 			name:           "Invalid userID",
 			userID:         "invalid",
-			requestBody:    models.WithdrawBalance{Order: "2377225624", Sum: 751},
+			requestBody:    models.WithdrawBalance{Order: "2377225624", Sum: money.FromUnits(751)},
 			mockSetup:      func(mockRepo *mocks.MockGofemartRepo) {},
 			expectedStatus: http.StatusInternalServerError,
 			expectedBody:   handler.ErrInvalidUserID.Error(),
@@ -110,7 +143,7 @@ func TestWithdrawHandler(t *testing.T) {
 		{
 			name:           "Invalid Content-Type",
 			userID:         "1",
-			requestBody:    models.WithdrawBalance{Order: "2377225624", Sum: 751},
+			requestBody:    models.WithdrawBalance{Order: "2377225624", Sum: money.FromUnits(751)},
 			mockSetup:      func(mockRepo *mocks.MockGofemartRepo) {},
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   "content-type must be application/json",
@@ -120,13 +153,13 @@ func TestWithdrawHandler(t *testing.T) {
 			userID: "1",
 			requestBody: models.WithdrawBalance{
 				Order: "",
-				Sum:   751,
+				Sum:   money.FromUnits(751),
 			},
 			mockSetup: func(mockRepo *mocks.MockGofemartRepo) {
 				mockRepo.EXPECT().
 					Withdraw(1, models.WithdrawBalance{
 						Order: "",
-						Sum:   751,
+						Sum:   money.FromUnits(751),
 					}).
 					Return(handler.ErrInvalidOrderNumber)
 			},
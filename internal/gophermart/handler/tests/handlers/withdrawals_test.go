@@ -14,6 +14,7 @@ import (
 	"go-musthave-diploma-tpl/internal/gophermart/models"
 	"go-musthave-diploma-tpl/internal/gophermart/service"
 	mocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -35,12 +36,12 @@ func TestHandler_Withdrawals(t *testing.T) {
 				expectedWithdrawals := []models.WithdrawBalance{
 					{
 						Order:       "2377225624",
-						Sum:         751.50,
+						Sum:         money.FromCents(75150),
 						ProcessedAt: time.Now().Add(-24 * time.Hour),
 					},
 					{
 						Order:       "49927398716",
-						Sum:         500.25,
+						Sum:         money.FromCents(50025),
 						ProcessedAt: time.Now().Add(-12 * time.Hour),
 					},
 				}
@@ -120,9 +121,9 @@ func TestHandler_Withdrawals(t *testing.T) {
 				assert.NoError(t, err)
 				assert.Len(t, withdrawals, 2)
 				assert.Equal(t, "2377225624", withdrawals[0].Order)
-				assert.Equal(t, 751.50, withdrawals[0].Sum)
+				assert.Equal(t, money.FromCents(75150), withdrawals[0].Sum)
 				assert.Equal(t, "49927398716", withdrawals[1].Order)
-				assert.Equal(t, 500.25, withdrawals[1].Sum)
+				assert.Equal(t, money.FromCents(50025), withdrawals[1].Sum)
 			}
 		})
 	}
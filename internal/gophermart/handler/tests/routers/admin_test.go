@@ -0,0 +1,198 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/middleware"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+	serviceMocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// adminRequest - запрос с кукой сессии пользователя 1 с ролью role
+func adminRequest(t *testing.T, mockRepo *serviceMocks.MockGofemartRepo, role, method, path string, body []byte) *http.Request {
+	t.Helper()
+
+	value, err := middleware.Encrypt("1:session")
+	require.NoError(t, err)
+
+	mockRepo.EXPECT().TouchSession(1, service.HashToken("session")).Return(5, nil)
+	mockRepo.EXPECT().GetUserByID(1).Return(&models.User{ID: 1, Login: "staff", Role: role}, nil)
+
+	req := httptest.NewRequest(method, path, bytes.NewBuffer(body))
+	req.AddCookie(&http.Cookie{Name: "userID", Value: value})
+	return req
+}
+
+func TestRouter_AdminRoles(t *testing.T) {
+	tests := []struct {
+		name           string
+		role           string
+		method         string
+		path           string
+		mockSetup      func(mockRepo *serviceMocks.MockGofemartRepo)
+		expectedStatus int
+	}{
+		{
+			name:           "User cannot search users",
+			role:           models.RoleUser,
+			method:         http.MethodGet,
+			path:           "/api/admin/users?login=bob",
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:   "Support searches users",
+			role:   models.RoleSupport,
+			method: http.MethodGet,
+			path:   "/api/admin/users?login=bob",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().RecordAudit(gomock.Any()).
+					DoAndReturn(func(e models.AuditEntry) error {
+						assert.Equal(t, 1, e.ActorID)
+						assert.Equal(t, models.AuditSearchUsers, e.Action)
+						return nil
+					})
+				mockRepo.EXPECT().SearchUsers("bob", gomock.Any()).
+					Return([]models.User{{ID: 2, Login: "bob", Role: models.RoleUser}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:   "Support views balance of any user",
+			role:   models.RoleSupport,
+			method: http.MethodGet,
+			path:   "/api/admin/users/2/balance",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().RecordAudit(gomock.Any()).Return(nil)
+				mockRepo.EXPECT().GetBalance(2).Return(models.Balance{Current: money.FromUnits(10)}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Support cannot block",
+			role:           models.RoleSupport,
+			method:         http.MethodPost,
+			path:           "/api/admin/users/2/block",
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:   "Admin blocks user",
+			role:   models.RoleAdmin,
+			method: http.MethodPost,
+			path:   "/api/admin/users/2/block",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().SetUserBlocked(2, true, gomock.Any()).
+					DoAndReturn(func(_ int, _ bool, e models.AuditEntry) error {
+						assert.Equal(t, models.AuditBlockUser, e.Action)
+						require.NotNil(t, e.TargetUserID)
+						assert.Equal(t, 2, *e.TargetUserID)
+						return nil
+					})
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Admin cannot block himself",
+			role:           models.RoleAdmin,
+			method:         http.MethodPost,
+			path:           "/api/admin/users/1/block",
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "Requeue processed order",
+			role:   models.RoleSupport,
+			method: http.MethodPost,
+			path:   "/api/admin/orders/12345678903/requeue",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().RequeueOrder("12345678903", gomock.Any()).Return(handler.ErrOrderAlreadyProcessed)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:   "Requeue stuck order",
+			role:   models.RoleAdmin,
+			method: http.MethodPost,
+			path:   "/api/admin/orders/12345678903/requeue",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().RequeueOrder("12345678903", gomock.Any()).Return(nil)
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+			svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+			router := handler.NewRouter(handler.NewHandler(svc), svc)
+
+			req := adminRequest(t, mockRepo, tt.role, tt.method, tt.path, nil)
+			tt.mockSetup(mockRepo)
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+		})
+	}
+}
+
+func TestRouter_AdminSetRole(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+	svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+	router := handler.NewRouter(handler.NewHandler(svc), svc)
+
+	body, _ := json.Marshal(models.SetRoleRequest{Role: "superuser"})
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest(t, mockRepo, models.RoleAdmin, http.MethodPost, "/api/admin/users/2/role", body))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	mockRepo.EXPECT().SetUserRole(2, models.RoleSupport, gomock.Any()).Return(nil)
+	body, _ = json.Marshal(models.SetRoleRequest{Role: models.RoleSupport})
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest(t, mockRepo, models.RoleAdmin, http.MethodPost, "/api/admin/users/2/role", body))
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestRouter_BlockedUserRejected(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+	svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+	router := handler.NewRouter(handler.NewHandler(svc), svc)
+
+	value, err := middleware.Encrypt("1:session")
+	require.NoError(t, err)
+	blockedAt := time.Now()
+	mockRepo.EXPECT().TouchSession(1, service.HashToken("session")).Return(5, nil)
+	mockRepo.EXPECT().GetUserByID(1).Return(&models.User{ID: 1, Login: "bob", Role: models.RoleUser, BlockedAt: &blockedAt}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/user/balance", nil)
+	req.AddCookie(&http.Cookie{Name: "userID", Value: value})
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
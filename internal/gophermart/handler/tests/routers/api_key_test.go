@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+	serviceMocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+const testAPIKey = "gm_partner-shop-key"
+
+func TestRouter_APIKey(t *testing.T) {
+	writeOnly := &models.APIKey{ID: 1, UserID: 1, Scopes: []string{models.ScopeOrdersWrite}}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		key            string
+		mockSetup      func(mockRepo *serviceMocks.MockGofemartRepo)
+		expectedStatus int
+	}{
+		{
+			name:   "Upload order with orders:write",
+			method: http.MethodPost,
+			path:   "/api/user/orders",
+			body:   "12345678903",
+			key:    testAPIKey,
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().UseAPIKey(service.HashToken(testAPIKey)).Return(writeOnly, nil)
+				mockRepo.EXPECT().GetUserByID(1).Return(&models.User{ID: 1, Role: models.RoleUser}, nil)
+				mockRepo.EXPECT().CreateOrder(1, "12345678903").Return(nil)
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:   "Balance needs balance:read",
+			method: http.MethodGet,
+			path:   "/api/user/balance",
+			key:    testAPIKey,
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().UseAPIKey(service.HashToken(testAPIKey)).Return(writeOnly, nil)
+				mockRepo.EXPECT().GetUserByID(1).Return(&models.User{ID: 1, Role: models.RoleUser}, nil)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:   "Key management is not available with a key",
+			method: http.MethodGet,
+			path:   "/api/user/api-keys",
+			key:    testAPIKey,
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().UseAPIKey(service.HashToken(testAPIKey)).Return(writeOnly, nil)
+				mockRepo.EXPECT().GetUserByID(1).Return(&models.User{ID: 1, Role: models.RoleUser}, nil)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:   "Revoked or expired key",
+			method: http.MethodPost,
+			path:   "/api/user/orders",
+			body:   "12345678903",
+			key:    testAPIKey,
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().UseAPIKey(service.HashToken(testAPIKey)).Return(nil, nil)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Malformed key",
+			method:         http.MethodPost,
+			path:           "/api/user/orders",
+			body:           "12345678903",
+			key:            "not-a-key",
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+			tt.mockSetup(mockRepo)
+			svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+			router := handler.NewRouter(handler.NewHandler(svc), svc)
+
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("X-API-Key", tt.key)
+			req.Header.Set("Content-Type", "text/plain")
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"go-musthave-diploma-tpl/internal/gophermart/models"
 	"go-musthave-diploma-tpl/internal/gophermart/service"
 	serviceMocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/golang/mock/gomock"
@@ -40,14 +41,14 @@ func TestGetBalance(t *testing.T) {
 			name: "Успешное получение баланса",
 			setupMock: func() {
 				mockRepo.EXPECT().GetBalance(1).Return(models.Balance{
-					Current:   500.5,
-					Withdrawn: 42,
+					Current:   money.FromCents(50050),
+					Withdrawn: money.FromUnits(42),
 				}, nil)
 			},
 			expectedStatus: http.StatusOK,
 			expectedBody: models.Balance{
-				Current:   500.5,
-				Withdrawn: 42,
+				Current:   money.FromCents(50050),
+				Withdrawn: money.FromUnits(42),
 			},
 		},
 	}
@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+	serviceMocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter_BalanceHistory(t *testing.T) {
+	now := time.Now()
+	entry := func(id int64, entryType string, amount, balance int64) models.LedgerEntry {
+		return models.LedgerEntry{
+			ID:            id,
+			UserID:        1,
+			Type:          entryType,
+			Amount:        money.FromUnits(amount),
+			BalanceAfter:  money.FromUnits(balance),
+			ContraAccount: "accruals",
+			CreatedAt:     now,
+		}
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		mockSetup      func(mockRepo *serviceMocks.MockGofemartRepo)
+		expectedStatus int
+		checkBody      func(t *testing.T, body []byte)
+	}{
+		{
+			name: "First page has a next cursor",
+			path: "/api/user/balance/history?limit=2",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().BalanceHistory(models.BalanceHistoryFilter{UserID: 1, Limit: 3}).Return([]models.LedgerEntry{
+					entry(12, models.LedgerEntryWithdrawal, -20, 130),
+					entry(11, models.LedgerEntryAdjustment, 50, 150),
+					entry(10, models.LedgerEntryAccrual, 100, 100),
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body []byte) {
+				var history models.BalanceHistory
+				require.NoError(t, json.Unmarshal(body, &history))
+				require.Len(t, history.Entries, 2)
+				assert.Equal(t, int64(12), history.Entries[0].ID)
+				assert.Equal(t, money.FromUnits(150), history.Entries[1].BalanceAfter)
+				assert.NotEmpty(t, history.NextCursor)
+				// системный счёт клиенту не показывается
+				assert.NotContains(t, string(body), "contra_account")
+			},
+		},
+		{
+			name: "Next page continues after the cursor",
+			path: "/api/user/balance/history?limit=2&cursor=MTE",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().BalanceHistory(models.BalanceHistoryFilter{UserID: 1, BeforeID: 11, Limit: 3}).Return([]models.LedgerEntry{
+					entry(10, models.LedgerEntryAccrual, 100, 100),
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body []byte) {
+				var history models.BalanceHistory
+				require.NoError(t, json.Unmarshal(body, &history))
+				require.Len(t, history.Entries, 1)
+				assert.Empty(t, history.NextCursor)
+			},
+		},
+		{
+			name: "Date range and type filters",
+			path: "/api/user/balance/history?from=2026-03-01&to=2026-03-31&type=accrual,reversal&type=adjustment",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().BalanceHistory(gomock.Any()).DoAndReturn(func(f models.BalanceHistoryFilter) ([]models.LedgerEntry, error) {
+					assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), *f.From)
+					// дата в to - включительно
+					assert.Equal(t, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), *f.To)
+					assert.Equal(t, []string{"accrual", "reversal", "adjustment"}, f.Types)
+					assert.Equal(t, service.DefaultHistoryLimit+1, f.Limit)
+					return nil, nil
+				})
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body []byte) {
+				assert.JSONEq(t, `{"entries":[]}`, string(body))
+			},
+		},
+		{
+			name:           "Unknown type",
+			path:           "/api/user/balance/history?type=bonus",
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Broken cursor",
+			path:           "/api/user/balance/history?cursor=%21%21",
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Limit too large",
+			path:           "/api/user/balance/history?limit=1000",
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid date",
+			path:           "/api/user/balance/history?from=yesterday",
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+			svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+			router := handler.NewRouter(handler.NewHandler(svc), svc)
+
+			req := adminRequest(t, mockRepo, models.RoleUser, http.MethodGet, tt.path, nil)
+			tt.mockSetup(mockRepo)
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+			if tt.checkBody != nil {
+				tt.checkBody(t, rr.Body.Bytes())
+			}
+		})
+	}
+}
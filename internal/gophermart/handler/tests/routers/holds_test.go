@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+	serviceMocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter_Holds(t *testing.T) {
+	activeHold := models.Hold{
+		ID:        3,
+		UserID:    1,
+		Order:     "2377225624",
+		Amount:    money.FromUnits(300),
+		Status:    models.HoldStatusActive,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		mockSetup      func(mockRepo *serviceMocks.MockGofemartRepo)
+		expectedStatus int
+		checkBody      func(t *testing.T, body []byte)
+	}{
+		{
+			name:   "Authorize hold",
+			method: http.MethodPost,
+			path:   "/api/user/balance/holds",
+			body:   `{"order":"2377225624","amount":300}`,
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().CreateHold(gomock.Any()).DoAndReturn(func(h models.Hold) (models.Hold, error) {
+					assert.Equal(t, 1, h.UserID)
+					assert.Equal(t, money.FromUnits(300), h.Amount)
+					// срок по умолчанию
+					assert.WithinDuration(t, time.Now().Add(service.DefaultHoldTTL), h.ExpiresAt, time.Minute)
+					return activeHold, nil
+				})
+			},
+			expectedStatus: http.StatusCreated,
+			checkBody: func(t *testing.T, body []byte) {
+				var hold models.Hold
+				require.NoError(t, json.Unmarshal(body, &hold))
+				assert.Equal(t, 3, hold.ID)
+				assert.Equal(t, models.HoldStatusActive, hold.Status)
+			},
+		},
+		{
+			name:   "Held points cannot be held again",
+			method: http.MethodPost,
+			path:   "/api/user/balance/holds",
+			body:   `{"order":"2377225624","amount":300}`,
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().CreateHold(gomock.Any()).Return(models.Hold{}, handler.ErrLackOfFunds)
+			},
+			expectedStatus: http.StatusPaymentRequired,
+		},
+		{
+			name:   "Order already on hold",
+			method: http.MethodPost,
+			path:   "/api/user/balance/holds",
+			body:   `{"order":"2377225624","amount":300}`,
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().CreateHold(gomock.Any()).Return(models.Hold{}, handler.ErrOrderOnHold)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "Non-positive amount",
+			method:         http.MethodPost,
+			path:           "/api/user/balance/holds",
+			body:           `{"order":"2377225624","amount":0}`,
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid order number",
+			method:         http.MethodPost,
+			path:           "/api/user/balance/holds",
+			body:           `{"order":"12345","amount":10}`,
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "Capture hold",
+			method: http.MethodPost,
+			path:   "/api/user/balance/holds/3/capture",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				captured := activeHold
+				captured.Status = models.HoldStatusCaptured
+				mockRepo.EXPECT().CaptureHold(1, 3).Return(captured, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body []byte) {
+				var hold models.Hold
+				require.NoError(t, json.Unmarshal(body, &hold))
+				assert.Equal(t, models.HoldStatusCaptured, hold.Status)
+			},
+		},
+		{
+			name:   "Capture expired hold",
+			method: http.MethodPost,
+			path:   "/api/user/balance/holds/3/capture",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().CaptureHold(1, 3).Return(models.Hold{}, handler.ErrHoldNotActive)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:   "Void hold of another user",
+			method: http.MethodPost,
+			path:   "/api/user/balance/holds/4/void",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().VoidHold(1, 4).Return(models.Hold{}, handler.ErrHoldNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Invalid hold ID",
+			method:         http.MethodPost,
+			path:           "/api/user/balance/holds/abc/void",
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "No holds",
+			method: http.MethodGet,
+			path:   "/api/user/balance/holds",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().Holds(1).Return(nil, nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+			svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+			router := handler.NewRouter(handler.NewHandler(svc), svc)
+
+			req := adminRequest(t, mockRepo, models.RoleUser, tt.method, tt.path, []byte(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			tt.mockSetup(mockRepo)
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+			if tt.checkBody != nil {
+				tt.checkBody(t, rr.Body.Bytes())
+			}
+		})
+	}
+}
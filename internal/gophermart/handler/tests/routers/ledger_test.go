@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+	serviceMocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_AdminLedger(t *testing.T) {
+	tests := []struct {
+		name           string
+		role           string
+		method         string
+		path           string
+		body           interface{}
+		mockSetup      func(mockRepo *serviceMocks.MockGofemartRepo)
+		expectedStatus int
+	}{
+		{
+			name:   "Support reads user ledger",
+			role:   models.RoleSupport,
+			method: http.MethodGet,
+			path:   "/api/admin/users/2/ledger",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().RecordAudit(gomock.Any()).Return(nil)
+				mockRepo.EXPECT().LedgerEntries(2, gomock.Any()).Return(nil, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Support cannot adjust balance",
+			role:           models.RoleSupport,
+			method:         http.MethodPost,
+			path:           "/api/admin/users/2/adjustments",
+			body:           models.AdjustBalanceRequest{Amount: money.FromUnits(10)},
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:   "Adjustment below zero balance",
+			role:   models.RoleAdmin,
+			method: http.MethodPost,
+			path:   "/api/admin/users/2/adjustments",
+			body:   models.AdjustBalanceRequest{Amount: money.FromUnits(-10)},
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().AdjustBalance(2, money.FromUnits(-10), "", gomock.Any()).
+					Return(models.LedgerEntry{}, handler.ErrLackOfFunds)
+			},
+			expectedStatus: http.StatusPaymentRequired,
+		},
+		{
+			name:           "Invalid entry id",
+			role:           models.RoleAdmin,
+			method:         http.MethodPost,
+			path:           "/api/admin/ledger/entries/abc/reverse",
+			body:           models.ReverseEntryRequest{},
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "Reverse unknown entry",
+			role:   models.RoleAdmin,
+			method: http.MethodPost,
+			path:   "/api/admin/ledger/entries/10/reverse",
+			body:   models.ReverseEntryRequest{},
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().ReverseLedgerEntry(int64(10), money.Zero, "", gomock.Any()).
+					Return(models.LedgerEntry{}, handler.ErrLedgerEntryNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:   "Check ledger",
+			role:   models.RoleAdmin,
+			method: http.MethodGet,
+			path:   "/api/admin/ledger/check",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().RecordAudit(gomock.Any()).Return(nil)
+				mockRepo.EXPECT().CheckLedger().Return(nil, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+			svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+			router := handler.NewRouter(handler.NewHandler(svc), svc)
+			tt.mockSetup(mockRepo)
+
+			var body []byte
+			if tt.body != nil {
+				body, _ = json.Marshal(tt.body)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, adminRequest(t, mockRepo, tt.role, tt.method, tt.path, body))
+
+			assert.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+	serviceMocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/oidc"
+	"go-musthave-diploma-tpl/pkg/oidc/oidctest"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newOIDCRouter(t *testing.T, stub *oidctest.Server, mockRepo *serviceMocks.MockGofemartRepo) http.Handler {
+	t.Helper()
+
+	provider, err := oidc.NewProvider(context.Background(), oidc.Config{
+		IssuerURL:    stub.URL,
+		ClientID:     stub.ClientID,
+		ClientSecret: stub.ClientSecret,
+		RedirectURL:  "http://localhost:8080/api/user/oidc/callback",
+	}, stub.Client())
+	require.NoError(t, err)
+
+	svc := service.NewGofemartService(mockRepo, "http://localhost:8081", service.WithOIDCProvider(provider))
+	return handler.NewRouter(handler.NewHandler(svc), svc)
+}
+
+// startOIDCLogin проходит /oidc/login и страницу провайдера,
+// возвращает куку state и адрес callback с кодом
+func startOIDCLogin(t *testing.T, router http.Handler, mockRepo *serviceMocks.MockGofemartRepo) (*http.Cookie, *url.URL, models.OIDCLoginState) {
+	t.Helper()
+
+	var saved models.OIDCLoginState
+	mockRepo.EXPECT().CreateOIDCState(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ string, state models.OIDCLoginState, expiresAt time.Time) error {
+			saved = state
+			assert.True(t, expiresAt.After(time.Now()))
+			return nil
+		})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/user/oidc/login", nil))
+	require.Equal(t, http.StatusFound, rr.Code)
+
+	var stateCookie *http.Cookie
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == "oidc_state" {
+			stateCookie = c
+		}
+	}
+	require.NotNil(t, stateCookie)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(rr.Header().Get("Location"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return stateCookie, callback, saved
+}
+
+func TestRouter_OIDCLogin_ProvisionsUser(t *testing.T) {
+	stub := oidctest.NewServer("gophermart", "secret")
+	defer stub.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+	router := newOIDCRouter(t, stub, mockRepo)
+
+	stateCookie, callback, saved := startOIDCLogin(t, router, mockRepo)
+	state := callback.Query().Get("state")
+
+	identity := models.UserIdentity{Issuer: stub.URL, Subject: "subject-1", Email: "user@example.com"}
+	mockRepo.EXPECT().ConsumeOIDCState(service.HashToken(state)).Return(&saved, nil)
+	mockRepo.EXPECT().GetUserByIdentity(stub.URL, "subject-1").Return(nil, nil)
+	// локальный логин "user" уже занят - склеивать нельзя, берётся следующий вариант
+	mockRepo.EXPECT().CreateUserWithIdentity("user", identity).Return(nil, handler.ErrLoginAlreadyExists)
+	mockRepo.EXPECT().CreateUserWithIdentity(gomock.Any(), identity).
+		Return(&models.User{ID: 42, Login: "user-1a2b3c4d", Role: models.RoleUser}, nil)
+	mockRepo.EXPECT().GetTOTP(42).Return(nil, nil)
+	mockRepo.EXPECT().CreateSession(42, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+	req := httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil)
+	req.AddCookie(stateCookie)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var sessionCookie bool
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == "userID" && c.Value != "" {
+			sessionCookie = true
+		}
+	}
+	assert.True(t, sessionCookie, "session cookie must be set")
+}
+
+func TestRouter_OIDCLogin_LinkedUser(t *testing.T) {
+	stub := oidctest.NewServer("gophermart", "")
+	defer stub.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+	router := newOIDCRouter(t, stub, mockRepo)
+
+	stateCookie, callback, saved := startOIDCLogin(t, router, mockRepo)
+
+	mockRepo.EXPECT().ConsumeOIDCState(gomock.Any()).Return(&saved, nil)
+	mockRepo.EXPECT().GetUserByIdentity(stub.URL, "subject-1").Return(&models.User{ID: 7, Login: "alice"}, nil)
+	mockRepo.EXPECT().GetTOTP(7).Return(nil, nil)
+	mockRepo.EXPECT().CreateSession(7, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+	req := httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil)
+	req.AddCookie(stateCookie)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+}
+
+func TestRouter_OIDCLogin_TwoFactorUser(t *testing.T) {
+	stub := oidctest.NewServer("gophermart", "")
+	defer stub.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+	router := newOIDCRouter(t, stub, mockRepo)
+
+	stateCookie, callback, saved := startOIDCLogin(t, router, mockRepo)
+
+	mockRepo.EXPECT().ConsumeOIDCState(gomock.Any()).Return(&saved, nil)
+	mockRepo.EXPECT().GetUserByIdentity(stub.URL, "subject-1").Return(&models.User{ID: 7, Login: "alice"}, nil)
+	mockRepo.EXPECT().GetTOTP(7).Return(&models.TOTP{UserID: 7, Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", Enabled: true}, nil)
+	mockRepo.EXPECT().CreateLoginChallenge(7, gomock.Any(), gomock.Any()).Return(nil)
+	// сессия не создаётся до кода
+
+	req := httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil)
+	req.AddCookie(stateCookie)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+	for _, c := range rr.Result().Cookies() {
+		assert.NotEqual(t, "userID", c.Name, "session cookie must not be set before the second factor")
+	}
+	var challenge models.LoginChallenge
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&challenge))
+	assert.Equal(t, models.LoginChallengeStatusTOTP, challenge.Status)
+	assert.NotEmpty(t, challenge.Challenge)
+}
+
+func TestRouter_OIDCCallback_StateMismatch(t *testing.T) {
+	stub := oidctest.NewServer("gophermart", "")
+	defer stub.Close()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+	router := newOIDCRouter(t, stub, mockRepo)
+
+	_, callback, _ := startOIDCLogin(t, router, mockRepo)
+
+	// ссылка на callback, начатая в другом браузере
+	req := httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil)
+	req.AddCookie(&http.Cookie{Name: "oidc_state", Value: "someone-else"})
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestRouter_OIDCLogin_NotConfigured(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+	svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+	router := handler.NewRouter(handler.NewHandler(svc), svc)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/user/oidc/login", nil))
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
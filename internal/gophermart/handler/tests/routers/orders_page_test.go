@@ -0,0 +1,220 @@
+package tests
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+	serviceMocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter_OrdersPage(t *testing.T) {
+	cursor := func(value string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+	// следующая страница по возрастанию после заказа 7, без фильтров
+	plainCursor := cursor("asc|2026-03-10T11:00:00Z|7|||")
+	// та же позиция в выборке обработанных заказов с 1 марта
+	filteredCursor := cursor("asc|2026-03-10T11:00:00Z|7|PROCESSED|2026-03-01T00:00:00Z|")
+	march := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	uploaded := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	order := func(uid int, number, status string, age time.Duration) models.Order {
+		return models.Order{UID: uid, UserID: 1, Number: number, Status: status, UploadedAt: uploaded.Add(-age)}
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		mockSetup      func(mockRepo *serviceMocks.MockGofemartRepo)
+		expectedStatus int
+		checkResponse  func(t *testing.T, rr *httptest.ResponseRecorder)
+	}{
+		{
+			name: "No parameters keep the plain array",
+			path: "/api/user/orders",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().GetOrders(1).Return([]models.Order{order(3, "12345678903", models.OrderStatusNew, 0)}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				var orders []models.Order
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &orders))
+				assert.Len(t, orders, 1)
+				assert.Empty(t, rr.Header().Get("Link"))
+			},
+		},
+		{
+			name: "First page has a next cursor and Link",
+			path: "/api/user/orders?limit=2&status=new,processed",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().OrdersPage(models.OrdersFilter{
+					UserID:   1,
+					Statuses: []string{models.OrderStatusNew, models.OrderStatusProcessed},
+					Limit:    3,
+				}).Return([]models.Order{
+					order(9, "12345678903", models.OrderStatusNew, 0),
+					order(7, "2377225624", models.OrderStatusProcessed, time.Hour),
+					order(4, "9278923470", models.OrderStatusProcessed, 2*time.Hour),
+				}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				var page models.OrdersPage
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &page))
+				require.Len(t, page.Orders, 2)
+				assert.Equal(t, "2377225624", page.Orders[1].Number)
+				require.NotEmpty(t, page.NextCursor)
+
+				link := rr.Header().Get("Link")
+				assert.Contains(t, link, `rel="first"`)
+				assert.Contains(t, link, "cursor="+url.QueryEscape(page.NextCursor))
+				assert.True(t, strings.HasPrefix(link, "</api/user/orders?"))
+
+				// курсор помнит фильтр по статусам
+				next, err := base64.RawURLEncoding.DecodeString(page.NextCursor)
+				require.NoError(t, err)
+				assert.Equal(t, "desc|2026-03-10T11:00:00Z|7|NEW,PROCESSED||", string(next))
+			},
+		},
+		{
+			name: "Next page continues after the cursor in the same order",
+			path: "/api/user/orders?cursor=" + plainCursor,
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				after := uploaded.Add(-time.Hour)
+				mockRepo.EXPECT().OrdersPage(models.OrdersFilter{
+					UserID:          1,
+					Asc:             true,
+					AfterUploadedAt: &after,
+					AfterUID:        7,
+					Limit:           service.DefaultOrdersLimit + 1,
+				}).Return(nil, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				assert.JSONEq(t, `{"orders":[]}`, rr.Body.String())
+				assert.NotContains(t, rr.Header().Get("Link"), `rel="next"`)
+			},
+		},
+		{
+			name: "Cursor restores its filters",
+			path: "/api/user/orders?cursor=" + filteredCursor,
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				after := uploaded.Add(-time.Hour)
+				mockRepo.EXPECT().OrdersPage(models.OrdersFilter{
+					UserID:          1,
+					Statuses:        []string{models.OrderStatusProcessed},
+					From:            &march,
+					Asc:             true,
+					AfterUploadedAt: &after,
+					AfterUID:        7,
+					Limit:           service.DefaultOrdersLimit + 1,
+				}).Return(nil, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Cursor with the same filters repeated",
+			path: "/api/user/orders?cursor=" + filteredCursor + "&status=processed&from=2026-03-01&sort=asc",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().OrdersPage(gomock.Any()).Return(nil, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Cursor and status disagree",
+			path:           "/api/user/orders?cursor=" + filteredCursor + "&status=new",
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Cursor and period disagree",
+			path:           "/api/user/orders?cursor=" + filteredCursor + "&from=2026-03-02",
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Cursor without a period and an added to",
+			path:           "/api/user/orders?cursor=" + plainCursor + "&to=2026-03-31",
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Date range",
+			path: "/api/user/orders?from=2026-03-01&to=2026-03-31&sort=asc",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().OrdersPage(gomock.Any()).DoAndReturn(func(f models.OrdersFilter) ([]models.Order, error) {
+					assert.Equal(t, march, *f.From)
+					assert.Equal(t, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), *f.To)
+					assert.True(t, f.Asc)
+					return nil, nil
+				})
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Cursor and sort disagree",
+			path:           "/api/user/orders?cursor=" + plainCursor + "&sort=desc",
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unknown status",
+			path:           "/api/user/orders?status=LOST",
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unknown sort",
+			path:           "/api/user/orders?sort=number",
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Broken cursor",
+			path:           "/api/user/orders?cursor=bm9wZQ",
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid date",
+			path:           "/api/user/orders?to=tomorrow",
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+			svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+			router := handler.NewRouter(handler.NewHandler(svc), svc)
+
+			req := adminRequest(t, mockRepo, models.RoleUser, http.MethodGet, tt.path, nil)
+			tt.mockSetup(mockRepo)
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+			if tt.checkResponse != nil {
+				tt.checkResponse(t, rr)
+			}
+		})
+	}
+}
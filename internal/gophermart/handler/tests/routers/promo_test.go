@@ -0,0 +1,193 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+	serviceMocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter_Promo(t *testing.T) {
+	tests := []struct {
+		name           string
+		role           string
+		method         string
+		path           string
+		body           string
+		mockSetup      func(mockRepo *serviceMocks.MockGofemartRepo)
+		expectedStatus int
+		checkBody      func(t *testing.T, body []byte)
+	}{
+		{
+			name:   "Redeem points code",
+			role:   models.RoleUser,
+			method: http.MethodPost,
+			path:   "/api/user/promo",
+			body:   `{"code":" spring "}`,
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				entryID := int64(30)
+				mockRepo.EXPECT().RedeemPromoCode(1, "SPRING", gomock.Any()).Return(models.PromoRedemption{
+					ID:            7,
+					Code:          "SPRING",
+					Kind:          models.PromoKindPoints,
+					Points:        money.FromUnits(50),
+					LedgerEntryID: &entryID,
+				}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			checkBody: func(t *testing.T, body []byte) {
+				var redemption models.PromoRedemption
+				require.NoError(t, json.Unmarshal(body, &redemption))
+				assert.Equal(t, money.FromUnits(50), redemption.Points)
+			},
+		},
+		{
+			name:   "Code already redeemed",
+			role:   models.RoleUser,
+			method: http.MethodPost,
+			path:   "/api/user/promo",
+			body:   `{"code":"SPRING"}`,
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().RedeemPromoCode(1, "SPRING", gomock.Any()).Return(models.PromoRedemption{}, handler.ErrPromoCodeAlreadyRedeemed)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:   "Code outside validity window",
+			role:   models.RoleUser,
+			method: http.MethodPost,
+			path:   "/api/user/promo",
+			body:   `{"code":"SPRING"}`,
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().RedeemPromoCode(1, "SPRING", gomock.Any()).Return(models.PromoRedemption{}, handler.ErrPromoCodeInactive)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "Empty code",
+			role:           models.RoleUser,
+			method:         http.MethodPost,
+			path:           "/api/user/promo",
+			body:           `{"code":"  "}`,
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:   "Admin creates multiplier code",
+			role:   models.RoleAdmin,
+			method: http.MethodPost,
+			path:   "/api/admin/promo-codes",
+			body:   `{"code":"double3","kind":"multiplier","multiplier":2,"orders":3,"max_redemptions":500}`,
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().CreatePromoCode(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(p models.PromoCode, entry models.AuditEntry) (models.PromoCode, error) {
+						assert.Equal(t, "DOUBLE3", p.Code)
+						assert.Equal(t, money.FromUnits(2), p.Multiplier)
+						assert.Equal(t, 3, p.Orders)
+						// по умолчанию один раз на пользователя, действует сразу
+						assert.Equal(t, 1, p.PerUserLimit)
+						assert.WithinDuration(t, time.Now(), p.StartsAt, time.Minute)
+						assert.Equal(t, models.AuditCreatePromo, entry.Action)
+						p.ID = 2
+						return p, nil
+					})
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Multiplier code needs orders",
+			role:           models.RoleAdmin,
+			method:         http.MethodPost,
+			path:           "/api/admin/promo-codes",
+			body:           `{"code":"DOUBLE","kind":"multiplier","multiplier":2}`,
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Validity window ends before it starts",
+			role:           models.RoleAdmin,
+			method:         http.MethodPost,
+			path:           "/api/admin/promo-codes",
+			body:           `{"code":"WELCOME","kind":"points","points":50,"starts_at":"2026-05-01T00:00:00Z","ends_at":"2026-04-01T00:00:00Z"}`,
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "Duplicate code",
+			role:   models.RoleAdmin,
+			method: http.MethodPost,
+			path:   "/api/admin/promo-codes",
+			body:   `{"code":"WELCOME","kind":"points","points":50}`,
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().CreatePromoCode(gomock.Any(), gomock.Any()).Return(models.PromoCode{}, handler.ErrPromoCodeExists)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "Support cannot create codes",
+			role:           models.RoleSupport,
+			method:         http.MethodPost,
+			path:           "/api/admin/promo-codes",
+			body:           `{"code":"WELCOME","kind":"points","points":50}`,
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:   "Support lists codes",
+			role:   models.RoleSupport,
+			method: http.MethodGet,
+			path:   "/api/admin/promo-codes",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().PromoCodes().Return(nil, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body []byte) {
+				assert.JSONEq(t, `[]`, string(body))
+			},
+		},
+		{
+			name:   "Admin disables code",
+			role:   models.RoleAdmin,
+			method: http.MethodPost,
+			path:   "/api/admin/promo-codes/welcome/disable",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().DisablePromoCode("WELCOME", gomock.Any()).Return(models.PromoCode{ID: 1, Code: "WELCOME"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+			svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+			router := handler.NewRouter(handler.NewHandler(svc), svc)
+
+			req := adminRequest(t, mockRepo, tt.role, tt.method, tt.path, []byte(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			tt.mockSetup(mockRepo)
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+			if tt.checkBody != nil {
+				tt.checkBody(t, rr.Body.Bytes())
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+	serviceMocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter_Referrals(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+	svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+	router := handler.NewRouter(handler.NewHandler(svc), svc)
+
+	bonus := money.FromUnits(100)
+	now := time.Now()
+	req := adminRequest(t, mockRepo, models.RoleUser, http.MethodGet, "/api/user/referrals", nil)
+	mockRepo.EXPECT().ReferralCode(1).Return("AB12CD34EF", nil)
+	mockRepo.EXPECT().Referrals(1).Return([]models.Referral{
+		{Login: "carol", Status: models.ReferralStatusPending, RegisteredAt: now},
+		{Login: "bo", Status: models.ReferralStatusRewarded, Bonus: &bonus, RegisteredAt: now, RewardedAt: &now},
+	}, nil)
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var resp models.ReferralsResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "AB12CD34EF", resp.Code)
+	require.Len(t, resp.Referrals, 2)
+	assert.Equal(t, "c***l", resp.Referrals[0].Login)
+	assert.Equal(t, "b***", resp.Referrals[1].Login)
+	assert.Equal(t, models.ReferralStatusRewarded, resp.Referrals[1].Status)
+}
+
+func TestRouter_ReferralsEmpty(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+	svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+	router := handler.NewRouter(handler.NewHandler(svc), svc)
+
+	req := adminRequest(t, mockRepo, models.RoleUser, http.MethodGet, "/api/user/referrals", nil)
+	mockRepo.EXPECT().ReferralCode(1).Return("AB12CD34EF", nil)
+	mockRepo.EXPECT().Referrals(1).Return(nil, nil)
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"code":"AB12CD34EF","referrals":[]}`, rr.Body.String())
+}
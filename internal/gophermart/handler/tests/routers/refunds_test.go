@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+	serviceMocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter_AdminRefundWithdrawal(t *testing.T) {
+	tests := []struct {
+		name           string
+		role           string
+		body           string
+		mockSetup      func(mockRepo *serviceMocks.MockGofemartRepo)
+		expectedStatus int
+	}{
+		{
+			name: "Support refunds part of a withdrawal",
+			role: models.RoleSupport,
+			body: `{"amount":200,"reason":" order cancelled "}`,
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().RefundWithdrawal(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(r models.WithdrawalRefund, e *models.AuditEntry) (models.WithdrawalRefund, error) {
+						assert.Equal(t, "2377225624", r.Order)
+						assert.Equal(t, money.FromUnits(200), r.Amount)
+						assert.Equal(t, "order cancelled", r.Reason)
+						assert.Equal(t, models.RefundSourceStaff, r.Source)
+						// сотрудник может вернуть списание любого пользователя
+						assert.Zero(t, r.UserID)
+						require.NotNil(t, e)
+						assert.Equal(t, models.AuditRefund, e.Action)
+						r.ID = 3
+						r.ProcessedAt = time.Now()
+						return r, nil
+					})
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "User cannot refund",
+			role:           models.RoleUser,
+			body:           `{"amount":200}`,
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "Refund exceeds withdrawal",
+			role: models.RoleAdmin,
+			body: `{"amount":600}`,
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().RefundWithdrawal(gomock.Any(), gomock.Any()).
+					Return(models.WithdrawalRefund{}, handler.ErrRefundExceedsWithdrawal)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name: "Unknown withdrawal",
+			role: models.RoleAdmin,
+			body: `{}`,
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().RefundWithdrawal(gomock.Any(), gomock.Any()).
+					Return(models.WithdrawalRefund{}, handler.ErrWithdrawalNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Negative amount",
+			role:           models.RoleAdmin,
+			body:           `{"amount":-1}`,
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+			svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+			router := handler.NewRouter(handler.NewHandler(svc), svc)
+
+			req := adminRequest(t, mockRepo, tt.role, http.MethodPost, "/api/admin/withdrawals/2377225624/refunds", []byte(tt.body))
+			tt.mockSetup(mockRepo)
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+		})
+	}
+}
+
+func TestRouter_PartnerRefundWithdrawal(t *testing.T) {
+	refundKey := &models.APIKey{ID: 2, UserID: 1, Scopes: []string{models.ScopeWithdrawalsRefund}}
+
+	t.Run("Partner refunds with API key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+		mockRepo.EXPECT().UseAPIKey(service.HashToken(testAPIKey)).Return(refundKey, nil)
+		mockRepo.EXPECT().GetUserByID(1).Return(&models.User{ID: 1, Role: models.RoleUser}, nil)
+		mockRepo.EXPECT().RefundWithdrawal(gomock.Any(), nil).
+			DoAndReturn(func(r models.WithdrawalRefund, _ *models.AuditEntry) (models.WithdrawalRefund, error) {
+				// партнёр возвращает только списания владельца ключа
+				assert.Equal(t, 1, r.UserID)
+				assert.Equal(t, models.RefundSourcePartner, r.Source)
+				require.NotNil(t, r.APIKeyID)
+				assert.Equal(t, 2, *r.APIKeyID)
+				r.ID = 4
+				return r, nil
+			})
+		svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+		router := handler.NewRouter(handler.NewHandler(svc), svc)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/user/withdrawals/2377225624/refunds", bytes.NewBufferString(`{"amount":100}`))
+		req.Header.Set("X-API-Key", testAPIKey)
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		var refund models.WithdrawalRefund
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &refund))
+		assert.Equal(t, 4, refund.ID)
+		assert.Equal(t, money.FromUnits(100), refund.Amount)
+	})
+
+	t.Run("Session cannot refund", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+		svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+		router := handler.NewRouter(handler.NewHandler(svc), svc)
+
+		req := adminRequest(t, mockRepo, models.RoleUser, http.MethodPost, "/api/user/withdrawals/2377225624/refunds", []byte(`{"amount":100}`))
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("Refund scope is issued only by admin", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+		svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+		router := handler.NewRouter(handler.NewHandler(svc), svc)
+
+		req := adminRequest(t, mockRepo, models.RoleUser, http.MethodPost, "/api/user/api-keys", []byte(`{"name":"shop","scopes":["withdrawals:refund"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Admin issues refund key", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+		svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+		router := handler.NewRouter(handler.NewHandler(svc), svc)
+
+		req := adminRequest(t, mockRepo, models.RoleAdmin, http.MethodPost, "/api/admin/users/7/api-keys", []byte(`{"name":"shop","scopes":["withdrawals:refund"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		mockRepo.EXPECT().GetUserByID(7).Return(&models.User{ID: 7, Role: models.RoleUser}, nil)
+		mockRepo.EXPECT().APIKeys(7).Return(nil, nil)
+		mockRepo.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).DoAndReturn(func(k *models.APIKey, _ string) error {
+			assert.Equal(t, 7, k.UserID)
+			assert.Equal(t, []string{models.ScopeWithdrawalsRefund}, k.Scopes)
+			k.ID = 5
+			return nil
+		})
+		mockRepo.EXPECT().RecordAudit(gomock.Any()).DoAndReturn(func(e models.AuditEntry) error {
+			assert.Equal(t, models.AuditIssueAPIKey, e.Action)
+			return nil
+		})
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	})
+}
@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+	serviceMocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_Statements(t *testing.T) {
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	lines := func(mockRepo *serviceMocks.MockGofemartRepo) {
+		mockRepo.EXPECT().GetUserByID(1).Return(&models.User{ID: 1, Login: "user"}, nil)
+		mockRepo.EXPECT().StatementOpeningBalance(1, from).Return(money.FromUnits(100), nil)
+		mockRepo.EXPECT().StatementLines(gomock.Any(), 1, from, to, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ int, _, _ time.Time, fn func(models.StatementLine) error) error {
+				for _, line := range []models.StatementLine{
+					{Date: from.Add(time.Hour), Type: models.StatementLineAccrual, Order: "12345678903", Amount: money.FromCents(50050)},
+					{Date: from.Add(2 * time.Hour), Type: models.StatementLineWithdrawal, Order: "2377225624", Amount: money.FromUnits(-200)},
+					{Date: from.Add(3 * time.Hour), Type: models.StatementLineRefund, Order: "2377225624", Amount: money.FromUnits(50)},
+					{Date: from.Add(4 * time.Hour), Type: models.LedgerEntryTransfer, Amount: money.FromUnits(-30)},
+					{Date: from.Add(5 * time.Hour), Type: models.LedgerEntryPromo, Amount: money.FromUnits(10)},
+				} {
+					if err := fn(line); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		mockSetup      func(mockRepo *serviceMocks.MockGofemartRepo)
+		expectedStatus int
+		checkResponse  func(t *testing.T, rr *httptest.ResponseRecorder)
+	}{
+		{
+			name:           "CSV with running balance",
+			path:           "/api/user/statements?from=2026-03-01&to=2026-03-31",
+			mockSetup:      lines,
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+				assert.Equal(t, `attachment; filename="statement-2026-03-01-2026-03-31.csv"`, rr.Header().Get("Content-Disposition"))
+				assert.Equal(t, strings.Join([]string{
+					"date,type,order,amount,balance",
+					"2026-03-01T00:00:00Z,opening,,,100.00",
+					"2026-03-01T01:00:00Z,accrual,12345678903,500.50,600.50",
+					"2026-03-01T02:00:00Z,withdrawal,2377225624,-200.00,400.50",
+					"2026-03-01T03:00:00Z,refund,2377225624,50.00,450.50",
+					"2026-03-01T04:00:00Z,transfer,,-30.00,420.50",
+					"2026-03-01T05:00:00Z,promo,,10.00,430.50",
+					"2026-04-01T00:00:00Z,closing,,,430.50",
+					"",
+				}, "\n"), rr.Body.String())
+			},
+		},
+		{
+			name:           "PDF",
+			path:           "/api/user/statements?from=2026-03-01&to=2026-03-31&format=pdf",
+			mockSetup:      lines,
+			expectedStatus: http.StatusOK,
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				assert.Equal(t, "application/pdf", rr.Header().Get("Content-Type"))
+				body := rr.Body.String()
+				assert.True(t, strings.HasPrefix(body, "%PDF-1.4"))
+				assert.True(t, strings.HasSuffix(body, "%%EOF\n"))
+				assert.Contains(t, body, "2377225624")
+				// прочие движения вынесены отдельным итогом, остаток на конец сходится с балансом
+				assert.Contains(t, body, `Other \(transfers, bonuses, adjustments, expiry\)`)
+				assert.Contains(t, body, "-20.00")
+				assert.Contains(t, body, "430.50")
+			},
+		},
+		{
+			name:           "Period is required",
+			path:           "/api/user/statements?from=2026-03-01",
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "To before from",
+			path:           "/api/user/statements?from=2026-03-10&to=2026-03-01",
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unknown format",
+			path:           "/api/user/statements?from=2026-03-01&to=2026-03-31&format=xlsx",
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "User not found",
+			path: "/api/user/statements?from=2026-03-01&to=2026-03-31",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().GetUserByID(1).Return(nil, nil)
+			},
+			expectedStatus: http.StatusNotFound,
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				assert.Empty(t, rr.Header().Get("Content-Disposition"))
+			},
+		},
+		{
+			name: "Error before the file is sent",
+			path: "/api/user/statements?from=2026-03-01&to=2026-03-31",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().GetUserByID(1).Return(&models.User{ID: 1, Login: "user"}, nil)
+				mockRepo.EXPECT().StatementOpeningBalance(1, from).Return(money.Zero, errors.New("db down"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			checkResponse: func(t *testing.T, rr *httptest.ResponseRecorder) {
+				assert.Empty(t, rr.Header().Get("Content-Disposition"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+			svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+			router := handler.NewRouter(handler.NewHandler(svc), svc)
+
+			req := adminRequest(t, mockRepo, models.RoleUser, http.MethodGet, tt.path, nil)
+			tt.mockSetup(mockRepo)
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+			if tt.checkResponse != nil {
+				tt.checkResponse(t, rr)
+			}
+		})
+	}
+}
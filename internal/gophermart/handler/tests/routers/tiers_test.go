@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+	serviceMocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter_GetTier(t *testing.T) {
+	tiers, err := service.ParseTiers(service.ExampleTiers)
+	require.NoError(t, err)
+
+	t.Run("Current tier and progress", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+		svc := service.NewGofemartService(mockRepo, "http://localhost:8081",
+			service.WithTierPolicy(models.TierPolicy{Tiers: tiers}))
+		router := handler.NewRouter(handler.NewHandler(svc), svc)
+
+		req := adminRequest(t, mockRepo, models.RoleUser, http.MethodGet, "/api/user/tier", nil)
+		mockRepo.EXPECT().UserTier(1).Return(&models.UserTier{
+			UserID:           1,
+			Tier:             models.TierBronze,
+			Earned:           money.FromUnits(250),
+			EvaluatedAt:      time.Now(),
+			NextEvaluationAt: time.Now().Add(time.Hour),
+		}, nil)
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, "bronze", body["tier"])
+		assert.Equal(t, 1.0, body["multiplier"])
+		assert.Contains(t, body, "next_evaluation_at")
+		assert.Equal(t, map[string]any{"tier": "silver", "threshold": 1000.0, "remaining": 750.0, "percent": 25.0}, body["next"])
+	})
+
+	t.Run("Tiers disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+		svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+		router := handler.NewRouter(handler.NewHandler(svc), svc)
+
+		req := adminRequest(t, mockRepo, models.RoleUser, http.MethodGet, "/api/user/tier", nil)
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
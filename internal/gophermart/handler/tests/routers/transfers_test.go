@@ -0,0 +1,240 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+	serviceMocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter_Transfers(t *testing.T) {
+	sent := models.Transfer{
+		ID:           5,
+		Direction:    models.TransferDirectionOut,
+		Counterparty: "bob",
+		Amount:       money.FromUnits(100),
+		Note:         "за обед",
+		CreatedAt:    time.Now(),
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		idempotencyKey string
+		mockSetup      func(mockRepo *serviceMocks.MockGofemartRepo)
+		expectedStatus int
+		checkBody      func(t *testing.T, body []byte)
+	}{
+		{
+			name:   "Transfer points",
+			method: http.MethodPost,
+			path:   "/api/user/balance/transfer",
+			body:   `{"to":" bob ","amount":100,"note":" за обед "}`,
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().Transfer(gomock.Any(), service.DefaultTransferLimits()).DoAndReturn(
+					func(tr models.Transfer, _ models.TransferLimits) (models.Transfer, error) {
+						assert.Equal(t, 1, tr.FromUserID)
+						assert.Equal(t, "bob", tr.Counterparty)
+						assert.Equal(t, "за обед", tr.Note)
+						assert.Empty(t, tr.IdempotencyKey)
+						return sent, nil
+					})
+			},
+			expectedStatus: http.StatusCreated,
+			checkBody: func(t *testing.T, body []byte) {
+				var tr models.Transfer
+				require.NoError(t, json.Unmarshal(body, &tr))
+				assert.Equal(t, int64(5), tr.ID)
+				assert.Equal(t, models.TransferDirectionOut, tr.Direction)
+				assert.Equal(t, money.FromUnits(100), tr.Amount)
+			},
+		},
+		{
+			name:   "Unknown recipient",
+			method: http.MethodPost,
+			path:   "/api/user/balance/transfer",
+			body:   `{"to":"nobody","amount":100}`,
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().Transfer(gomock.Any(), gomock.Any()).Return(models.Transfer{}, handler.ErrRecipientNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:   "Transfer to self",
+			method: http.MethodPost,
+			path:   "/api/user/balance/transfer",
+			body:   `{"to":"staff","amount":100}`,
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().Transfer(gomock.Any(), gomock.Any()).Return(models.Transfer{}, handler.ErrTransferToSelf)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "Not enough points",
+			method: http.MethodPost,
+			path:   "/api/user/balance/transfer",
+			body:   `{"to":"bob","amount":100}`,
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().Transfer(gomock.Any(), gomock.Any()).Return(models.Transfer{}, handler.ErrLackOfFunds)
+			},
+			expectedStatus: http.StatusPaymentRequired,
+		},
+		{
+			name:   "Daily limit exceeded",
+			method: http.MethodPost,
+			path:   "/api/user/balance/transfer",
+			body:   `{"to":"bob","amount":100}`,
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().Transfer(gomock.Any(), gomock.Any()).Return(models.Transfer{}, handler.ErrTransferLimitExceeded)
+			},
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:           "Non-positive amount",
+			method:         http.MethodPost,
+			path:           "/api/user/balance/transfer",
+			body:           `{"to":"bob","amount":-1}`,
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Note too long",
+			method:         http.MethodPost,
+			path:           "/api/user/balance/transfer",
+			body:           `{"to":"bob","amount":1,"note":"` + longNote() + `"}`,
+			mockSetup:      func(mockRepo *serviceMocks.MockGofemartRepo) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Repeated request gets the stored response",
+			method:         http.MethodPost,
+			path:           "/api/user/balance/transfer",
+			body:           `{"to":"bob","amount":100}`,
+			idempotencyKey: "transfer-1",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().ReserveIdempotencyKey(gomock.Any()).DoAndReturn(
+					func(k models.IdempotencyKey) (*models.IdempotencyKey, error) {
+						assert.Equal(t, models.IdempotencyScopeTransfer, k.Scope)
+						k.Response = &models.IdempotentResponse{StatusCode: http.StatusCreated, Body: `{"id":5}`}
+						return &k, nil
+					})
+			},
+			expectedStatus: http.StatusCreated,
+			checkBody: func(t *testing.T, body []byte) {
+				assert.JSONEq(t, `{"id":5}`, string(body))
+			},
+		},
+		{
+			name:           "Transfer with idempotency key",
+			method:         http.MethodPost,
+			path:           "/api/user/balance/transfer",
+			body:           `{"to":"bob","amount":100}`,
+			idempotencyKey: "transfer-2",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().ReserveIdempotencyKey(gomock.Any()).Return(nil, nil)
+				mockRepo.EXPECT().Transfer(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(tr models.Transfer, _ models.TransferLimits) (models.Transfer, error) {
+						// ответ сохраняется в транзакции перевода
+						assert.Equal(t, "transfer-2", tr.IdempotencyKey)
+						return sent, nil
+					})
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:   "Transfer history",
+			method: http.MethodGet,
+			path:   "/api/user/balance/transfers",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				received := sent
+				received.ID = 6
+				received.Direction = models.TransferDirectionIn
+				mockRepo.EXPECT().Transfers(1).Return([]models.Transfer{received, sent}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			checkBody: func(t *testing.T, body []byte) {
+				var transfers []models.Transfer
+				require.NoError(t, json.Unmarshal(body, &transfers))
+				require.Len(t, transfers, 2)
+				assert.Equal(t, models.TransferDirectionIn, transfers[0].Direction)
+			},
+		},
+		{
+			name:   "No transfers",
+			method: http.MethodGet,
+			path:   "/api/user/balance/transfers",
+			mockSetup: func(mockRepo *serviceMocks.MockGofemartRepo) {
+				mockRepo.EXPECT().Transfers(1).Return(nil, nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+			svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+			router := handler.NewRouter(handler.NewHandler(svc), svc)
+
+			req := adminRequest(t, mockRepo, models.RoleUser, tt.method, tt.path, []byte(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.idempotencyKey != "" {
+				req.Header.Set("Idempotency-Key", tt.idempotencyKey)
+			}
+			tt.mockSetup(mockRepo)
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+			if tt.checkBody != nil {
+				tt.checkBody(t, rr.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestRouter_TransferDeniedForAPIKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+	svc := service.NewGofemartService(mockRepo, "http://localhost:8081")
+	router := handler.NewRouter(handler.NewHandler(svc), svc)
+
+	key := &models.APIKey{ID: 2, UserID: 1, Scopes: []string{models.ScopeBalanceRead, models.ScopeOrdersWrite}}
+	mockRepo.EXPECT().UseAPIKey(service.HashToken(testAPIKey)).Return(key, nil)
+	mockRepo.EXPECT().GetUserByID(1).Return(&models.User{ID: 1, Role: models.RoleUser}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/user/balance/transfer", nil)
+	req.Header.Set("X-API-Key", testAPIKey)
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func longNote() string {
+	note := make([]rune, models.TransferNoteMaxLen+1)
+	for i := range note {
+		note[i] = 'ё'
+	}
+	return string(note)
+}
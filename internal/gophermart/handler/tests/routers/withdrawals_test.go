@@ -13,6 +13,7 @@ import (
 	"go-musthave-diploma-tpl/internal/gophermart/models"
 	"go-musthave-diploma-tpl/internal/gophermart/service"
 	mocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -33,12 +34,12 @@ func TestWithdrawalsHandler(t *testing.T) {
 				expectedWithdrawals := []models.WithdrawBalance{
 					{
 						Order:       "2377225624",
-						Sum:         751,
+						Sum:         money.FromUnits(751),
 						ProcessedAt: time.Now().Add(-24 * time.Hour),
 					},
 					{
 						Order:       "49927398716",
-						Sum:         500,
+						Sum:         money.FromUnits(500),
 						ProcessedAt: time.Now().Add(-12 * time.Hour),
 					},
 				}
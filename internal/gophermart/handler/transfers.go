@@ -0,0 +1,125 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go-musthave-diploma-tpl/internal/gophermart/middleware"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+)
+
+// Transfer переводит баллы другому пользователю по логину
+func (h *Handler) Transfer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"`+ErrUserIsNotAuthenticated.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+
+	if r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, `{"error":"content-type must be application/json"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req models.TransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"`+ErrInvalidJSONFormat.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.Amount <= 0 {
+		http.Error(w, `{"error":"amount must be positive"}`, http.StatusBadRequest)
+		return
+	}
+
+	userIDint, _ := strconv.Atoi(userID)
+
+	// повтор с тем же Idempotency-Key получает прежний ответ без второго перевода
+	const scope = models.IdempotencyScopeTransfer
+	key, withKey := idempotencyKey(r)
+	fingerprint := service.RequestFingerprint(req.To, req.Amount.String(), req.Note)
+	if withKey {
+		if h.beginIdempotent(w, userIDint, scope, key, fingerprint) {
+			return
+		}
+	}
+
+	// перевод - такой же расход баллов, как списание
+	if err := h.svc.CheckWithdrawSecondFactor(userIDint, req.Amount, r.Header.Get(totpCodeHeader)); err != nil {
+		h.releaseIdempotent(userIDint, scope, key)
+		if errors.Is(err, ErrTOTPRequired) || errors.Is(err, ErrInvalidTOTPCode) {
+			http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusForbidden)
+		} else {
+			castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+			http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	transfer, err := h.svc.Transfer(userIDint, req, key)
+	if err != nil {
+		var status int
+		switch {
+		case errors.Is(err, ErrInvalidAmount), errors.Is(err, ErrTransferToSelf), errors.Is(err, ErrTransferNoteTooLong):
+			status = http.StatusBadRequest
+		case errors.Is(err, ErrRecipientNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, ErrLackOfFunds):
+			status = http.StatusPaymentRequired
+		case errors.Is(err, ErrTransferLimitExceeded):
+			status = http.StatusTooManyRequests
+		case errors.Is(err, ErrIdempotencyConflict):
+			// параллельный запрос с тем же ключом успел перевести - отдаём его ответ
+			if !h.beginIdempotent(w, userIDint, scope, key, fingerprint) {
+				http.Error(w, `{"error":"`+ErrIdempotencyConflict.Error()+`"}`, http.StatusConflict)
+			}
+			return
+		default:
+			h.releaseIdempotent(userIDint, scope, key)
+			castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+			http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+			return
+		}
+		h.finishIdempotent(w, userIDint, scope, key, models.IdempotentResponse{
+			StatusCode: status,
+			Body:       `{"error":"` + err.Error() + `"}`,
+		})
+		return
+	}
+
+	// ответ по ключу сохранён в транзакции перевода
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// Transfers - отправленные и полученные переводы пользователя
+func (h *Handler) Transfers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, err := middleware.GetUserID(r.Context())
+	if err != nil {
+		http.Error(w, `{"error":"`+ErrUserIsNotAuthenticated.Error()+`"}`, http.StatusUnauthorized)
+		return
+	}
+
+	userIDint, _ := strconv.Atoi(userID)
+	transfers, err := h.svc.Transfers(userIDint)
+	if err != nil {
+		castomLogger.Infof(ErrInternalServerError.Error(), err.Error())
+		http.Error(w, `{"error":"`+ErrInternalServerError.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if len(transfers) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(transfers)
+}
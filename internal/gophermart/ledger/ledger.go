@@ -0,0 +1,373 @@
+// Package ledger - проводки журнала баллов. Остаток счёта меняется только
+// вместе с проводкой и в той же транзакции, что и заказ или списание
+package ledger
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/pkg/money"
+)
+
+var (
+	ErrInsufficientFunds  = errors.New("insufficient funds")
+	ErrInvalidEntry       = errors.New("invalid ledger entry")
+	ErrEntryNotFound      = errors.New("ledger entry not found")
+	ErrNotReversible      = errors.New("reversal entries cannot be reversed")
+	ErrReversalExceedsSum = errors.New("reversal exceeds the remaining amount of the entry")
+)
+
+// Tx - *sql.Tx или *sql.DB
+type Tx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Querier - для сверки достаточно чтения
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// defaultContra - системный счёт по умолчанию для типа проводки
+var defaultContra = map[string]string{
+	models.LedgerEntryAccrual:    models.LedgerAccountAccruals,
+	models.LedgerEntryWithdrawal: models.LedgerAccountRedemptions,
+	models.LedgerEntryAdjustment: models.LedgerAccountAdjustments,
+	models.LedgerEntryExpiration: models.LedgerAccountExpirations,
+	models.LedgerEntryTransfer:   models.LedgerAccountTransfers,
+	models.LedgerEntryReferral:   models.LedgerAccountReferrals,
+	models.LedgerEntryPromo:      models.LedgerAccountPromotions,
+}
+
+// Post проводит запись: меняет остаток счёта пользователя и добавляет проводку в журнал.
+// Расход сверх остатка - ErrInsufficientFunds, счёт при этом блокируется до конца транзакции
+func Post(ctx context.Context, tx Tx, e models.LedgerEntry) (models.LedgerEntry, error) {
+	if e.UserID == 0 || e.Amount == 0 {
+		return models.LedgerEntry{}, ErrInvalidEntry
+	}
+	if e.ContraAccount == "" {
+		contra, ok := defaultContra[e.Type]
+		if !ok {
+			return models.LedgerEntry{}, ErrInvalidEntry
+		}
+		e.ContraAccount = contra
+	}
+
+	// списания и их возвраты двигают ещё и сумму "использовано"
+	var withdrawnDelta money.Amount
+	if e.WithdrawalID != nil {
+		withdrawnDelta = -e.Amount
+	}
+
+	var err error
+	if e.Amount > 0 {
+		err = tx.QueryRowContext(ctx, `
+            INSERT INTO accounts (user_id, balance, withdrawn)
+            VALUES ($1, $2, $3)
+            ON CONFLICT (user_id) DO UPDATE SET
+                balance = accounts.balance + EXCLUDED.balance,
+                withdrawn = accounts.withdrawn + EXCLUDED.withdrawn,
+                updated_at = NOW()
+            RETURNING balance
+        `, e.UserID, e.Amount, withdrawnDelta).Scan(&e.BalanceAfter)
+	} else {
+		// проверка остатка и изменение - одним оператором под блокировкой строки.
+		// Зарезервированные баллы (held) потратить нельзя
+		err = tx.QueryRowContext(ctx, `
+            UPDATE accounts SET
+                balance = balance + $2,
+                withdrawn = withdrawn + $3,
+                updated_at = NOW()
+            WHERE user_id = $1 AND balance - held + $2 >= 0
+            RETURNING balance
+        `, e.UserID, e.Amount, withdrawnDelta).Scan(&e.BalanceAfter)
+		if err == sql.ErrNoRows {
+			return models.LedgerEntry{}, ErrInsufficientFunds
+		}
+	}
+	if err != nil {
+		return models.LedgerEntry{}, fmt.Errorf("failed to update account: %w", err)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO ledger_entries
+            (user_id, entry_type, amount, balance_after, contra_account, order_number, withdrawal_id, reverses_entry_id, note)
+        VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9)
+        RETURNING id, created_at
+    `, e.UserID, e.Type, e.Amount, e.BalanceAfter, e.ContraAccount, e.OrderNumber,
+		e.WithdrawalID, e.ReversesEntryID, e.Note).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return models.LedgerEntry{}, fmt.Errorf("failed to write ledger entry: %w", err)
+	}
+
+	if err := postLots(ctx, tx, e); err != nil {
+		return models.LedgerEntry{}, err
+	}
+
+	return e, nil
+}
+
+// postLots ведёт партии баллов: приход - новая партия, расход гасит открытые партии
+// по порядку начисления (первыми - те, что раньше сгорят) и запоминает, какие именно.
+// Сторно расхода возвращает баллы в погашенные партии, перевод переносит сроки партий отправителя,
+// поэтому ни возврат, ни сторно сгорания не продлевают срок баллов.
+// Строка счёта уже заблокирована проводкой, поэтому параллельный расход партии не делит
+func postLots(ctx context.Context, tx Tx, e models.LedgerEntry) error {
+	if e.Amount < 0 {
+		if _, err := tx.ExecContext(ctx, `
+            WITH lots AS (
+                SELECT id, remaining,
+                       SUM(remaining) OVER (ORDER BY earned_at, id) - remaining AS before
+                FROM point_lots
+                WHERE user_id = $1 AND remaining > 0
+            ),
+            used AS (
+                UPDATE point_lots p
+                SET remaining = p.remaining - LEAST(lots.remaining, $3 - lots.before)
+                FROM lots
+                WHERE p.id = lots.id AND lots.before < $3
+                RETURNING p.id, LEAST(lots.remaining, $3 - lots.before) AS amount
+            )
+            INSERT INTO point_lot_consumptions (entry_id, lot_id, amount)
+            SELECT $2, id, amount FROM used
+        `, e.UserID, e.ID, -e.Amount); err != nil {
+			return fmt.Errorf("failed to update point lots: %w", err)
+		}
+		return nil
+	}
+
+	var (
+		placed money.Amount
+		err    error
+	)
+	switch {
+	case e.ReversesEntryID != nil:
+		placed, err = restoreLots(ctx, tx, e)
+	case e.LotsFromEntryID != nil:
+		placed, err = copyLots(ctx, tx, e)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update point lots: %w", err)
+	}
+
+	// то, что вернуть некуда (расход старше партий), - новая партия от сегодняшнего дня
+	if rest := e.Amount - placed; rest > 0 {
+		if _, err := tx.ExecContext(ctx, `
+            INSERT INTO point_lots (user_id, entry_id, amount, remaining)
+            VALUES ($1, $2, $3, $3)
+        `, e.UserID, e.ID, rest); err != nil {
+			return fmt.Errorf("failed to update point lots: %w", err)
+		}
+	}
+	return nil
+}
+
+// restoreLots возвращает сторно в партии, погашенные исходной проводкой и ещё не возвращённые
+// её прошлыми сторно, начиная с последних погашенных. Возврат пишется строкой с отрицательной суммой
+func restoreLots(ctx context.Context, tx Tx, e models.LedgerEntry) (money.Amount, error) {
+	var restored money.Amount
+	err := tx.QueryRowContext(ctx, `
+        WITH consumed AS (
+            SELECT c.lot_id, SUM(c.amount) AS open
+            FROM point_lot_consumptions c
+            WHERE c.entry_id = $2
+               OR c.entry_id IN (SELECT id FROM ledger_entries WHERE reverses_entry_id = $2 AND id <> $1)
+            GROUP BY c.lot_id
+            HAVING SUM(c.amount) > 0
+        ),
+        lots AS (
+            SELECT c.lot_id, c.open,
+                   SUM(c.open) OVER (ORDER BY l.earned_at DESC, l.id DESC) - c.open AS before
+            FROM consumed c
+            JOIN point_lots l ON l.id = c.lot_id
+        ),
+        restored AS (
+            UPDATE point_lots p
+            SET remaining = p.remaining + LEAST(lots.open, $3 - lots.before)
+            FROM lots
+            WHERE p.id = lots.lot_id AND lots.before < $3
+            RETURNING p.id, LEAST(lots.open, $3 - lots.before) AS amount
+        ),
+        logged AS (
+            INSERT INTO point_lot_consumptions (entry_id, lot_id, amount)
+            SELECT $1, id, -amount FROM restored
+            RETURNING amount
+        )
+        SELECT COALESCE(-SUM(amount), 0) FROM logged
+    `, e.ID, *e.ReversesEntryID, e.Amount).Scan(&restored)
+	return restored, err
+}
+
+// copyLots заводит получателю партии с датами начисления партий, погашенных расходом отправителя
+func copyLots(ctx context.Context, tx Tx, e models.LedgerEntry) (money.Amount, error) {
+	var copied money.Amount
+	err := tx.QueryRowContext(ctx, `
+        WITH source AS (
+            SELECT c.amount, l.earned_at,
+                   SUM(c.amount) OVER (ORDER BY l.earned_at, l.id) - c.amount AS before
+            FROM point_lot_consumptions c
+            JOIN point_lots l ON l.id = c.lot_id
+            WHERE c.entry_id = $3 AND c.amount > 0
+        ),
+        copied AS (
+            INSERT INTO point_lots (user_id, entry_id, amount, remaining, earned_at)
+            SELECT $1, $2, LEAST(amount, $4 - before), LEAST(amount, $4 - before), earned_at
+            FROM source
+            WHERE before < $4
+            RETURNING amount
+        )
+        SELECT COALESCE(SUM(amount), 0) FROM copied
+    `, e.UserID, e.ID, *e.LotsFromEntryID, e.Amount).Scan(&copied)
+	return copied, err
+}
+
+// Reverse сторнирует проводку целиком или частично встречной проводкой.
+// amount = 0 - на весь ещё не сторнированный остаток
+func Reverse(ctx context.Context, tx Tx, entryID int64, amount money.Amount, note string) (models.LedgerEntry, error) {
+	var (
+		orig         models.LedgerEntry
+		orderNumber  sql.NullString
+		withdrawalID sql.NullInt64
+	)
+	// блокируем исходную проводку, чтобы параллельные сторно не превысили её сумму
+	err := tx.QueryRowContext(ctx, `
+        SELECT id, user_id, entry_type, amount, contra_account, order_number, withdrawal_id
+        FROM ledger_entries
+        WHERE id = $1
+        FOR UPDATE
+    `, entryID).Scan(&orig.ID, &orig.UserID, &orig.Type, &orig.Amount, &orig.ContraAccount, &orderNumber, &withdrawalID)
+	if err == sql.ErrNoRows {
+		return models.LedgerEntry{}, ErrEntryNotFound
+	}
+	if err != nil {
+		return models.LedgerEntry{}, fmt.Errorf("failed to get ledger entry: %w", err)
+	}
+	if orig.Type == models.LedgerEntryReversal {
+		return models.LedgerEntry{}, ErrNotReversible
+	}
+
+	var reversed money.Amount
+	if err := tx.QueryRowContext(ctx, `
+        SELECT COALESCE(SUM(amount), 0) FROM ledger_entries WHERE reverses_entry_id = $1
+    `, entryID).Scan(&reversed); err != nil {
+		return models.LedgerEntry{}, fmt.Errorf("failed to sum reversals: %w", err)
+	}
+
+	remaining := orig.Amount.Abs() - reversed.Abs()
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount <= 0 || amount > remaining {
+		return models.LedgerEntry{}, ErrReversalExceedsSum
+	}
+
+	// сторно - в обратную сторону от исходной проводки
+	if orig.Amount > 0 {
+		amount = -amount
+	}
+	rev := models.LedgerEntry{
+		UserID:          orig.UserID,
+		Type:            models.LedgerEntryReversal,
+		Amount:          amount,
+		ContraAccount:   orig.ContraAccount,
+		OrderNumber:     orderNumber.String,
+		ReversesEntryID: &orig.ID,
+		Note:            note,
+	}
+	if withdrawalID.Valid {
+		id := int(withdrawalID.Int64)
+		rev.WithdrawalID = &id
+	}
+	return Post(ctx, tx, rev)
+}
+
+// Check сверяет журнал с остатками счетов, обработанными заказами и списаниями.
+// Пустой результат - расхождений нет
+func Check(ctx context.Context, db Querier) ([]models.LedgerMismatch, error) {
+	rows, err := db.QueryContext(ctx, `
+        WITH l AS (
+            SELECT
+                user_id,
+                SUM(amount) AS balance,
+                COALESCE(SUM(amount) FILTER (WHERE entry_type = 'accrual'), 0) AS accrued,
+                COALESCE(-SUM(amount) FILTER (WHERE entry_type = 'withdrawal'), 0) AS withdrawn_gross,
+                COALESCE(-SUM(amount) FILTER (WHERE withdrawal_id IS NOT NULL), 0) AS withdrawn_net
+            FROM ledger_entries
+            GROUP BY user_id
+        ),
+        o AS (
+            SELECT user_id, SUM(accrual) AS accrued
+            FROM orders
+            WHERE status = 'PROCESSED' AND accrual > 0
+            GROUP BY user_id
+        ),
+        w AS (
+            SELECT user_id, SUM(sum) AS withdrawn
+            FROM withdrawals
+            GROUP BY user_id
+        ),
+        u AS (
+            SELECT user_id FROM l
+            UNION SELECT user_id FROM accounts
+            UNION SELECT user_id FROM o
+            UNION SELECT user_id FROM w
+        )
+        SELECT
+            u.user_id,
+            COALESCE(l.balance, 0), COALESCE(a.balance, 0),
+            COALESCE(l.withdrawn_net, 0), COALESCE(a.withdrawn, 0),
+            COALESCE(o.accrued, 0), COALESCE(l.accrued, 0),
+            COALESCE(w.withdrawn, 0), COALESCE(l.withdrawn_gross, 0)
+        FROM u
+        LEFT JOIN l ON l.user_id = u.user_id
+        LEFT JOIN accounts a ON a.user_id = u.user_id
+        LEFT JOIN o ON o.user_id = u.user_id
+        LEFT JOIN w ON w.user_id = u.user_id
+        ORDER BY u.user_id
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check ledger: %w", err)
+	}
+	defer rows.Close()
+
+	var mismatches []models.LedgerMismatch
+	for rows.Next() {
+		var (
+			userID                            int
+			ledgerBalance, accountBalance     money.Amount
+			ledgerWithdrawn, accountWithdrawn money.Amount
+			ordersAccrued, ledgerAccrued      money.Amount
+			withdrawalsSum, ledgerWithdrawals money.Amount
+		)
+		if err := rows.Scan(&userID,
+			&ledgerBalance, &accountBalance,
+			&ledgerWithdrawn, &accountWithdrawn,
+			&ordersAccrued, &ledgerAccrued,
+			&withdrawalsSum, &ledgerWithdrawals,
+		); err != nil {
+			return nil, err
+		}
+
+		// ожидаемое - по журналу, кроме заказов и списаний: там первичны их таблицы
+		pairs := []models.LedgerMismatch{
+			{UserID: userID, Check: models.LedgerCheckBalance, Expected: ledgerBalance, Actual: accountBalance},
+			{UserID: userID, Check: models.LedgerCheckWithdrawn, Expected: ledgerWithdrawn, Actual: accountWithdrawn},
+			{UserID: userID, Check: models.LedgerCheckAccruals, Expected: ordersAccrued, Actual: ledgerAccrued},
+			{UserID: userID, Check: models.LedgerCheckWithdrawals, Expected: withdrawalsSum, Actual: ledgerWithdrawals},
+		}
+		for _, m := range pairs {
+			if m.Expected != m.Actual {
+				mismatches = append(mismatches, m)
+			}
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return mismatches, nil
+}
@@ -0,0 +1,201 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/ledger"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPost_Credit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`INSERT INTO accounts`).
+		WithArgs(1, money.FromCents(50050), money.Zero).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("600.50"))
+	mock.ExpectQuery(`INSERT INTO ledger_entries`).
+		WithArgs(1, models.LedgerEntryAccrual, money.FromCents(50050), money.FromCents(60050), models.LedgerAccountAccruals,
+			"12345678903", sqlmock.AnyArg(), sqlmock.AnyArg(), "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(10, time.Now()))
+	// приход - новая партия баллов
+	mock.ExpectExec(`INSERT INTO point_lots`).
+		WithArgs(1, int64(10), money.FromCents(50050)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	entry, err := ledger.Post(context.Background(), db, models.LedgerEntry{
+		UserID:      1,
+		Type:        models.LedgerEntryAccrual,
+		Amount:      money.FromCents(50050),
+		OrderNumber: "12345678903",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), entry.ID)
+	assert.Equal(t, money.FromCents(60050), entry.BalanceAfter)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPost_Rejects(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	// нулевая сумма и неизвестный тип без системного счёта
+	_, err = ledger.Post(context.Background(), db, models.LedgerEntry{UserID: 1, Type: models.LedgerEntryAccrual})
+	assert.ErrorIs(t, err, ledger.ErrInvalidEntry)
+	_, err = ledger.Post(context.Background(), db, models.LedgerEntry{UserID: 1, Type: "gift", Amount: money.FromUnits(1)})
+	assert.ErrorIs(t, err, ledger.ErrInvalidEntry)
+
+	// расход сверх остатка: счёт не обновился, проводка не пишется
+	mock.ExpectQuery(`UPDATE accounts`).
+		WithArgs(1, money.FromUnits(-100), money.Zero).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}))
+
+	_, err = ledger.Post(context.Background(), db, models.LedgerEntry{UserID: 1, Type: models.LedgerEntryAdjustment, Amount: money.FromUnits(-100)})
+	assert.ErrorIs(t, err, ledger.ErrInsufficientFunds)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReverse(t *testing.T) {
+	entryRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "user_id", "entry_type", "amount", "contra_account", "order_number", "withdrawal_id"}).
+			AddRow(10, 1, models.LedgerEntryAccrual, "500.00", models.LedgerAccountAccruals, "12345678903", nil)
+	}
+
+	t.Run("Exceeds remaining amount", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery(`SELECT .* FROM ledger_entries\s+WHERE id = \$1\s+FOR UPDATE`).
+			WithArgs(int64(10)).WillReturnRows(entryRows())
+		mock.ExpectQuery(`SELECT COALESCE\(SUM\(amount\), 0\) FROM ledger_entries WHERE reverses_entry_id`).
+			WithArgs(int64(10)).WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow("-400.00"))
+
+		_, err = ledger.Reverse(context.Background(), db, 10, money.FromUnits(150), "")
+		assert.ErrorIs(t, err, ledger.ErrReversalExceedsSum)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Remaining amount by default", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery(`FOR UPDATE`).WithArgs(int64(10)).WillReturnRows(entryRows())
+		mock.ExpectQuery(`reverses_entry_id`).
+			WithArgs(int64(10)).WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow("-400.00"))
+		mock.ExpectQuery(`UPDATE accounts`).
+			WithArgs(1, money.FromUnits(-100), money.Zero).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("0.00"))
+		mock.ExpectQuery(`INSERT INTO ledger_entries`).
+			WithArgs(1, models.LedgerEntryReversal, money.FromUnits(-100), money.Zero, models.LedgerAccountAccruals,
+				"12345678903", sqlmock.AnyArg(), sqlmock.AnyArg(), "wrong accrual").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(11, time.Now()))
+		// расход гасит партии по порядку начисления
+		mock.ExpectExec(`UPDATE point_lots(.|\n)*INSERT INTO point_lot_consumptions`).
+			WithArgs(1, int64(11), money.FromUnits(100)).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		entry, err := ledger.Reverse(context.Background(), db, 10, 0, "wrong accrual")
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), *entry.ReversesEntryID)
+		assert.Equal(t, money.FromUnits(-100), entry.Amount)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Reversal cannot be reversed", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery(`FOR UPDATE`).WithArgs(int64(11)).WillReturnRows(
+			sqlmock.NewRows([]string{"id", "user_id", "entry_type", "amount", "contra_account", "order_number", "withdrawal_id"}).
+				AddRow(11, 1, models.LedgerEntryReversal, "-100.00", models.LedgerAccountAccruals, nil, nil))
+
+		_, err = ledger.Reverse(context.Background(), db, 11, 0, "")
+		assert.ErrorIs(t, err, ledger.ErrNotReversible)
+	})
+}
+
+func TestReverse_RestoresLots(t *testing.T) {
+	expiryRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "user_id", "entry_type", "amount", "contra_account", "order_number", "withdrawal_id"}).
+			AddRow(20, 1, models.LedgerEntryExpiration, "-80.00", models.LedgerAccountExpirations, nil, nil)
+	}
+	expectCredit := func(mock sqlmock.Sqlmock, amount money.Amount) {
+		mock.ExpectQuery(`FOR UPDATE`).WithArgs(int64(20)).WillReturnRows(expiryRows())
+		mock.ExpectQuery(`reverses_entry_id`).
+			WithArgs(int64(20)).WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow("0.00"))
+		mock.ExpectQuery(`INSERT INTO accounts`).
+			WithArgs(1, amount, money.Zero).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(amount.String()))
+		mock.ExpectQuery(`INSERT INTO ledger_entries`).
+			WithArgs(1, models.LedgerEntryReversal, amount, amount, models.LedgerAccountExpirations,
+				"", sqlmock.AnyArg(), sqlmock.AnyArg(), "expired by mistake").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(21, time.Now()))
+	}
+
+	t.Run("Expiry goes back to the burned lots", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		expectCredit(mock, money.FromUnits(80))
+		// сгоревшее возвращается в те же партии со старой датой начисления, новой партии нет
+		mock.ExpectQuery(`UPDATE point_lots p\s+SET remaining = p.remaining \+(.|\n)*INSERT INTO point_lot_consumptions`).
+			WithArgs(int64(21), int64(20), money.FromUnits(80)).
+			WillReturnRows(sqlmock.NewRows([]string{"restored"}).AddRow("80.00"))
+
+		_, err = ledger.Reverse(context.Background(), db, 20, 0, "expired by mistake")
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Amount without consumed lots becomes a new lot", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		expectCredit(mock, money.FromUnits(80))
+		// расход старше партий погасил только часть
+		mock.ExpectQuery(`UPDATE point_lots p\s+SET remaining = p.remaining \+`).
+			WithArgs(int64(21), int64(20), money.FromUnits(80)).
+			WillReturnRows(sqlmock.NewRows([]string{"restored"}).AddRow("30.00"))
+		mock.ExpectExec(`INSERT INTO point_lots \(user_id, entry_id, amount, remaining\)`).
+			WithArgs(1, int64(21), money.FromUnits(50)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err = ledger.Reverse(context.Background(), db, 20, 0, "expired by mistake")
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCheck(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	columns := []string{"user_id", "lb", "ab", "lw", "aw", "oa", "la", "ws", "lws"}
+	mock.ExpectQuery(`WITH l AS`).WillReturnRows(sqlmock.NewRows(columns).
+		AddRow(1, 250.0, 250.0, 50.0, 50.0, 300.0, 300.0, 50.0, 50.0).
+		// остаток счёта поправили в обход журнала, начисление по заказу не проведено
+		AddRow(2, 100.0, 150.0, 0.0, 0.0, 200.0, 100.0, 0.0, 0.0))
+
+	mismatches, err := ledger.Check(context.Background(), db)
+	require.NoError(t, err)
+	assert.Equal(t, []models.LedgerMismatch{
+		{UserID: 2, Check: models.LedgerCheckBalance, Expected: money.FromUnits(100), Actual: money.FromUnits(150)},
+		{UserID: 2, Check: models.LedgerCheckAccruals, Expected: money.FromUnits(200), Actual: money.FromUnits(100)},
+	}, mismatches)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -10,6 +10,10 @@ import (
 	"strings"
 	"time"
 
+	"go-musthave-diploma-tpl/internal/gophermart/ledger"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/pkg/money"
+
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"go.uber.org/zap"
@@ -20,14 +24,44 @@ type OrderListener struct {
 	logger               *zap.SugaredLogger
 	db                   *sql.DB
 	accrualSystemAddress string
+	tiers                models.TierPolicy
+	referralBonus        money.Amount
 }
 
-func NewOrderListener(dbURI, accrualSystemAddress string, logger *zap.SugaredLogger) *OrderListener {
-	return &OrderListener{
+// Option - необязательная настройка обработчика заказов
+type Option func(*OrderListener)
+
+// WithTiers - начисления умножаются на множитель уровня пользователя
+func WithTiers(p models.TierPolicy) Option {
+	return func(ol *OrderListener) {
+		ol.tiers = p
+	}
+}
+
+// WithReferralBonus - бонус обоим участникам приглашения за первый обработанный заказ приглашённого
+func WithReferralBonus(bonus money.Amount) Option {
+	return func(ol *OrderListener) {
+		ol.referralBonus = bonus
+	}
+}
+
+// WithDB - готовое подключение к базе вместо открытия по dbURI при запуске
+func WithDB(db *sql.DB) Option {
+	return func(ol *OrderListener) {
+		ol.db = db
+	}
+}
+
+func NewOrderListener(dbURI, accrualSystemAddress string, logger *zap.SugaredLogger, opts ...Option) *OrderListener {
+	ol := &OrderListener{
 		dbURI:                dbURI,
 		accrualSystemAddress: accrualSystemAddress,
 		logger:               logger,
 	}
+	for _, opt := range opts {
+		opt(ol)
+	}
+	return ol
 }
 
 func (ol *OrderListener) Start(ctx context.Context) {
@@ -35,10 +69,12 @@ func (ol *OrderListener) Start(ctx context.Context) {
 	dsn := strings.Trim(ol.dbURI, `"`)
 	ol.logger.Infof("Sanitized Database URI: %s", dsn)
 
-	var err error
-	ol.db, err = sql.Open("pgx", dsn)
-	if err != nil {
-		ol.logger.Fatalf("Failed to open database: %v", err)
+	if ol.db == nil {
+		var err error
+		ol.db, err = sql.Open("pgx", dsn)
+		if err != nil {
+			ol.logger.Fatalf("Failed to open database: %v", err)
+		}
 	}
 
 	if err := ol.db.Ping(); err != nil {
@@ -80,7 +116,7 @@ func (ol *OrderListener) loadExistingOrders(ctx context.Context) {
 
 		// ВАЖНО: запускаем обработку заказа в отдельной горутине, без WorkerPool
 		go func(j Job) {
-			if err := ol.processOrder(ctx, j); err != nil {
+			if err := ol.ProcessOrder(ctx, j); err != nil {
 				ol.logger.Errorf("failed to process existing order %s: %v", j.Number, err)
 			}
 		}(job)
@@ -140,7 +176,7 @@ func (ol *OrderListener) listenNotifications(ctx context.Context) {
 
 		// Снова — запускаем обработку без WorkerPool
 		go func(j Job) {
-			if err := ol.processOrder(ctx, j); err != nil {
+			if err := ol.ProcessOrder(ctx, j); err != nil {
 				ol.logger.Errorf("failed to process notified order %s: %v", j.Number, err)
 			}
 		}(job)
@@ -151,7 +187,9 @@ func (ol *OrderListener) listenNotifications(ctx context.Context) {
 // ОБРАБОТКА ЗАКАЗА
 // --------------------------------------------
 
-func (ol *OrderListener) processOrder(ctx context.Context, job Job) error {
+// ProcessOrder опрашивает систему начислений, пока заказ не получит финальный статус,
+// и сохраняет каждый полученный статус
+func (ol *OrderListener) ProcessOrder(ctx context.Context, job Job) error {
 	ol.logger.Infof("Start processing order %s (uid=%d)", job.Number, job.OrderID)
 
 	for {
@@ -234,20 +272,154 @@ func (ol *OrderListener) queryAccrualService(ctx context.Context, number string)
 	}
 }
 
-func (ol *OrderListener) updateOrderStatus(ctx context.Context, uid int, status string, accrual float64) error {
-	res, err := ol.db.ExecContext(ctx,
-		`UPDATE orders SET status=$1, accrual=$2, uploaded_at=NOW() WHERE uid=$3`,
-		status, accrual, uid)
+// updateOrderStatus обновляет заказ, а для PROCESSED в той же транзакции проводит начисление
+func (ol *OrderListener) updateOrderStatus(ctx context.Context, uid int, status string, accrual money.Amount) error {
+	tx, err := ol.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("db begin failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	// начисление от системы расчёта умножается на множитель уровня, в заказ пишется итоговое
+	var note string
+	if status == models.OrderStatusProcessed && accrual > 0 && ol.tiers.Enabled() {
+		var tierName string
+		err = tx.QueryRowContext(ctx, `
+            SELECT COALESCE(t.tier, '')
+            FROM orders o
+            LEFT JOIN user_tiers t ON t.user_id = o.user_id
+            WHERE o.uid = $1
+        `, uid).Scan(&tierName)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("db tier lookup failed: %w", err)
+		}
+		tier := ol.tiers.ByName(tierName)
+		if multiplied := tier.Apply(accrual); multiplied != accrual {
+			note = fmt.Sprintf("tier %s x%s, base accrual %s", tier.Name, tier.Multiplier, accrual)
+			accrual = multiplied
+		}
+	}
+
+	// погашенный промокод-множитель умножает начисление следующих заказов пользователя
+	if status == models.OrderStatusProcessed && accrual > 0 {
+		multiplied, promoNote, err := ol.applyPromoMultiplier(ctx, tx, uid, accrual)
+		if err != nil {
+			return err
+		}
+		if promoNote != "" {
+			note = strings.TrimPrefix(note+"; "+promoNote, "; ")
+			accrual = multiplied
+		}
+	}
+
+	// обработанный заказ больше не меняется: начисление по нему уже проведено.
+	// uploaded_at - время загрузки, по нему листаются страницы заказов, поэтому не трогаем
+	var (
+		userID int
+		number string
+	)
+	err = tx.QueryRowContext(ctx,
+		`UPDATE orders SET status=$1, accrual=$2, updated_at=NOW()
+         WHERE uid=$3 AND status <> 'PROCESSED'
+         RETURNING user_id, number`,
+		status, accrual, uid).Scan(&userID, &number)
+	if err == sql.ErrNoRows {
+		ol.logger.Infof("Order %d not found or already processed, skipping", uid)
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("db update failed: %w", err)
 	}
 
-	n, _ := res.RowsAffected()
-	if n == 0 {
-		return fmt.Errorf("order not found uid=%d", uid)
+	if status == models.OrderStatusProcessed && accrual > 0 {
+		if _, err := ledger.Post(ctx, tx, models.LedgerEntry{
+			UserID:      userID,
+			Type:        models.LedgerEntryAccrual,
+			Amount:      accrual,
+			OrderNumber: number,
+			Note:        note,
+		}); err != nil {
+			return fmt.Errorf("ledger post failed: %w", err)
+		}
+	}
+
+	if status == models.OrderStatusProcessed && ol.referralBonus > 0 {
+		if err := ol.rewardReferral(ctx, tx, userID, number); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("db commit failed: %w", err)
+	}
+
+	ol.logger.Infof("Order %d updated: status=%s, accrual=%s", uid, status, accrual)
+	return nil
+}
+
+// applyPromoMultiplier умножает начисление по самому раннему погашению промокода-множителя,
+// у которого остались заказы, и уменьшает их счётчик. Без такого погашения начисление не меняется
+func (ol *OrderListener) applyPromoMultiplier(ctx context.Context, tx *sql.Tx, uid int, accrual money.Amount) (money.Amount, string, error) {
+	var (
+		redemptionID int64
+		code         string
+		multiplier   money.Amount
+	)
+	err := tx.QueryRowContext(ctx, `
+        SELECT r.id, p.code, p.multiplier
+        FROM promo_redemptions r
+        JOIN promo_codes p ON p.id = r.promo_id
+        WHERE r.user_id = (SELECT user_id FROM orders WHERE uid = $1) AND r.orders_left > 0
+        ORDER BY r.created_at, r.id
+        LIMIT 1
+        FOR UPDATE OF r
+    `, uid).Scan(&redemptionID, &code, &multiplier)
+	if err == sql.ErrNoRows {
+		return accrual, "", nil
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("db promo lookup failed: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        UPDATE promo_redemptions SET orders_left = orders_left - 1 WHERE id = $1
+    `, redemptionID); err != nil {
+		return 0, "", fmt.Errorf("db promo update failed: %w", err)
+	}
+
+	multiplied := accrual.MulDiv(multiplier.Cents(), 100, money.HalfEven)
+	return multiplied, fmt.Sprintf("promo %s x%s, accrual before promo %s", code, multiplier, accrual), nil
+}
+
+// rewardReferral начисляет бонус приглашённому и пригласившему, если это первый обработанный заказ
+// приглашённого. Строка приглашения помечается в той же транзакции, поэтому бонус выплачивается один раз
+func (ol *OrderListener) rewardReferral(ctx context.Context, tx *sql.Tx, userID int, number string) error {
+	var referrerID int
+	err := tx.QueryRowContext(ctx, `
+        UPDATE referrals SET bonus = $2, order_number = $3, rewarded_at = NOW()
+        WHERE referee_id = $1 AND rewarded_at IS NULL
+        RETURNING referrer_id
+    `, userID, ol.referralBonus, number).Scan(&referrerID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("db referral update failed: %w", err)
+	}
+
+	note := fmt.Sprintf("referral bonus, first order %s", number)
+	for _, id := range []int{userID, referrerID} {
+		if _, err := ledger.Post(ctx, tx, models.LedgerEntry{
+			UserID: id,
+			Type:   models.LedgerEntryReferral,
+			Amount: ol.referralBonus,
+			Note:   note,
+		}); err != nil {
+			return fmt.Errorf("ledger post failed: %w", err)
+		}
 	}
 
-	ol.logger.Infof("Order %d updated: status=%s, accrual=%.2f", uid, status, accrual)
+	ol.logger.Infof("Referral bonus %s paid to users %d and %d", ol.referralBonus, userID, referrerID)
 	return nil
 }
 
@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/listener"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testOrderNumber = "12345678903"
+
+var testTiers = models.TierPolicy{Tiers: []models.Tier{
+	{Name: models.TierBronze, Multiplier: money.FromUnits(1)},
+	{Name: models.TierGold, Threshold: money.FromUnits(1000), Multiplier: money.FromCents(150)},
+}}
+
+// newTestListener - обработчик на sqlmock, система начислений отвечает заказу PROCESSED с начислением accrual
+func newTestListener(t *testing.T, accrual money.Amount, opts ...listener.Option) (*listener.OrderListener, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	accrualSystem := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/orders/"+testOrderNumber, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"order":%s,"status":"%s","accrual":%s}`, testOrderNumber, models.OrderStatusProcessed, accrual)
+	}))
+	t.Cleanup(accrualSystem.Close)
+
+	opts = append(opts, listener.WithDB(db))
+	return listener.NewOrderListener("", accrualSystem.URL, zap.NewNop().Sugar(), opts...), mock
+}
+
+func processTestOrder(ol *listener.OrderListener) error {
+	return ol.ProcessOrder(context.Background(), listener.Job{
+		OrderID: 7,
+		UserID:  1,
+		Number:  testOrderNumber,
+		Status:  models.OrderStatusNew,
+	})
+}
+
+func expectTier(mock sqlmock.Sqlmock, uid int, tier string) {
+	mock.ExpectQuery(`SELECT COALESCE\(t.tier, ''\)`).
+		WithArgs(uid).
+		WillReturnRows(sqlmock.NewRows([]string{"tier"}).AddRow(tier))
+}
+
+func expectNoPromo(mock sqlmock.Sqlmock, uid int) {
+	mock.ExpectQuery(`FROM promo_redemptions`).
+		WithArgs(uid).
+		WillReturnError(sql.ErrNoRows)
+}
+
+func expectOrderUpdate(mock sqlmock.Sqlmock, uid int, accrual money.Amount) {
+	mock.ExpectQuery(`UPDATE orders SET status=\$1`).
+		WithArgs(models.OrderStatusProcessed, accrual, uid).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "number"}).AddRow(1, testOrderNumber))
+}
+
+// expectCredit - проводка прихода через ledger.Post: счёт, журнал, партия баллов
+func expectCredit(mock sqlmock.Sqlmock, userID int, entryType, contra, order string, amount money.Amount, note string, entryID int64) {
+	mock.ExpectQuery(`INSERT INTO accounts`).
+		WithArgs(userID, amount, money.Zero).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(amount.String()))
+	mock.ExpectQuery(`INSERT INTO ledger_entries`).
+		WithArgs(userID, entryType, amount, amount, contra, order, sqlmock.AnyArg(), sqlmock.AnyArg(), note).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(entryID, time.Now()))
+	mock.ExpectExec(`INSERT INTO point_lots`).
+		WithArgs(userID, entryID, amount).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestProcessOrder_TierMultiplier(t *testing.T) {
+	ol, mock := newTestListener(t, money.FromUnits(100), listener.WithTiers(testTiers))
+
+	mock.ExpectBegin()
+	expectTier(mock, 7, models.TierGold)
+	expectNoPromo(mock, 7)
+	expectOrderUpdate(mock, 7, money.FromUnits(150))
+	expectCredit(mock, 1, models.LedgerEntryAccrual, models.LedgerAccountAccruals, testOrderNumber,
+		money.FromUnits(150), "tier gold x1.50, base accrual 100.00", 10)
+	mock.ExpectCommit()
+
+	err := processTestOrder(ol)
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProcessOrder_PromoOnTopOfTier(t *testing.T) {
+	ol, mock := newTestListener(t, money.FromUnits(100), listener.WithTiers(testTiers))
+
+	mock.ExpectBegin()
+	expectTier(mock, 7, models.TierGold)
+	// промокод умножает уже увеличенное уровнем начисление и тратит один заказ погашения
+	mock.ExpectQuery(`FROM promo_redemptions`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code", "multiplier"}).AddRow(3, "DOUBLE", "2.00"))
+	mock.ExpectExec(`UPDATE promo_redemptions SET orders_left = orders_left - 1`).
+		WithArgs(int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectOrderUpdate(mock, 7, money.FromUnits(300))
+	expectCredit(mock, 1, models.LedgerEntryAccrual, models.LedgerAccountAccruals, testOrderNumber,
+		money.FromUnits(300), "tier gold x1.50, base accrual 100.00; promo DOUBLE x2.00, accrual before promo 150.00", 10)
+	mock.ExpectCommit()
+
+	err := processTestOrder(ol)
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProcessOrder_ReferralBonus(t *testing.T) {
+	bonus := money.FromUnits(50)
+	note := "referral bonus, first order " + testOrderNumber
+
+	t.Run("First processed order pays both users", func(t *testing.T) {
+		ol, mock := newTestListener(t, money.FromUnits(100), listener.WithReferralBonus(bonus))
+
+		mock.ExpectBegin()
+		expectNoPromo(mock, 7)
+		expectOrderUpdate(mock, 7, money.FromUnits(100))
+		expectCredit(mock, 1, models.LedgerEntryAccrual, models.LedgerAccountAccruals, testOrderNumber,
+			money.FromUnits(100), "", 10)
+		mock.ExpectQuery(`UPDATE referrals SET bonus = \$2`).
+			WithArgs(1, bonus, testOrderNumber).
+			WillReturnRows(sqlmock.NewRows([]string{"referrer_id"}).AddRow(2))
+		expectCredit(mock, 1, models.LedgerEntryReferral, models.LedgerAccountReferrals, "", bonus, note, 11)
+		expectCredit(mock, 2, models.LedgerEntryReferral, models.LedgerAccountReferrals, "", bonus, note, 12)
+		mock.ExpectCommit()
+
+		err := processTestOrder(ol)
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Already rewarded referral pays nothing", func(t *testing.T) {
+		ol, mock := newTestListener(t, money.FromUnits(100), listener.WithReferralBonus(bonus))
+
+		mock.ExpectBegin()
+		expectNoPromo(mock, 7)
+		expectOrderUpdate(mock, 7, money.FromUnits(100))
+		expectCredit(mock, 1, models.LedgerEntryAccrual, models.LedgerAccountAccruals, testOrderNumber,
+			money.FromUnits(100), "", 10)
+		// строка приглашения уже помечена: UPDATE ничего не вернул, бонусных проводок нет
+		mock.ExpectQuery(`UPDATE referrals SET bonus = \$2`).
+			WithArgs(1, bonus, testOrderNumber).
+			WillReturnRows(sqlmock.NewRows([]string{"referrer_id"}))
+		mock.ExpectCommit()
+
+		err := processTestOrder(ol)
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestProcessOrder_AlreadyProcessed(t *testing.T) {
+	ol, mock := newTestListener(t, money.FromUnits(100), listener.WithTiers(testTiers), listener.WithReferralBonus(money.FromUnits(50)))
+
+	mock.ExpectBegin()
+	expectTier(mock, 7, models.TierGold)
+	mock.ExpectQuery(`FROM promo_redemptions`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code", "multiplier"}).AddRow(3, "DOUBLE", "2.00"))
+	mock.ExpectExec(`UPDATE promo_redemptions SET orders_left = orders_left - 1`).
+		WithArgs(int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// заказ уже PROCESSED: UPDATE его не нашёл - ни проводок, ни бонуса,
+	// а списание заказа промокода откатывается вместе с транзакцией
+	mock.ExpectQuery(`UPDATE orders SET status=\$1`).
+		WithArgs(models.OrderStatusProcessed, money.FromUnits(300), 7).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "number"}))
+	mock.ExpectRollback()
+
+	err := processTestOrder(ol)
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,6 +1,10 @@
 package listener
 
-import "time"
+import (
+	"time"
+
+	"go-musthave-diploma-tpl/pkg/money"
+)
 
 type Job struct {
 	OrderID   int       `json:"order_id"`
@@ -12,7 +16,7 @@ type Job struct {
 }
 
 type AccrualResponse struct {
-	Order   int64   `json:"order"`
-	Status  string  `json:"status"`
-	Accrual float64 `json:"accrual,omitempty"`
+	Order   int64        `json:"order"`
+	Status  string       `json:"status"`
+	Accrual money.Amount `json:"accrual,omitempty"`
 }
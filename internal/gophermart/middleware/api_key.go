@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+)
+
+// apiKeyHeader - заголовок с персональным API-ключом
+const apiKeyHeader = "X-API-Key"
+
+func apiKeyFromHeader(w http.ResponseWriter, token string, repo *service.GofemartService) (*models.APIKey, bool) {
+	key, err := repo.AuthenticateAPIKey(token)
+	if errors.Is(err, service.ErrInvalidAPIKey) {
+		http.Error(w, "invalid api key", http.StatusUnauthorized)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return key, true
+}
+
+// GetAPIKey - ключ, по которому прошёл запрос; false - вход по куке или Bearer-токену
+func GetAPIKey(ctx context.Context) (*models.APIKey, bool) {
+	key, ok := ctx.Value(APIKeyKey).(*models.APIKey)
+	return key, ok
+}
+
+// RequireScope пускает запрос по API-ключу, только если ключу разрешена область доступа.
+// Вход по куке или Bearer-токену не ограничивается
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key, ok := GetAPIKey(r.Context()); ok && !key.HasScope(scope) {
+				http.Error(w, "api key scope "+scope+" required", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireAPIKey пускает запрос только по API-ключу с областью доступа: маршрут для сервера партнёра,
+// владелец аккаунта сам его вызвать не может
+func RequireAPIKey(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := GetAPIKey(r.Context())
+			if !ok || !key.HasScope(scope) {
+				http.Error(w, "api key scope "+scope+" required", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// DenyAPIKey закрывает маршрут для входа по API-ключу: управление аккаунтом,
+// сессиями и самими ключами доступно только владельцу, а не серверу партнёра
+func DenyAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := GetAPIKey(r.Context()); ok {
+			http.Error(w, "not available with api key", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
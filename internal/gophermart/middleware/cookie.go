@@ -6,112 +6,281 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"go-musthave-diploma-tpl/internal/gophermart/config"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
 	"go-musthave-diploma-tpl/internal/gophermart/service"
 )
 
 type contextKey string
 
-const UserIDKey contextKey = "userID"
+const (
+	UserIDKey    contextKey = "userID"
+	SessionIDKey contextKey = "sessionID"
+	UserRoleKey  contextKey = "userRole"
+	APIKeyKey    contextKey = "apiKey"
+)
+
+// cookieName - имя куки сессии
+const cookieName = "userID"
+
+// Keyring - связка ключей шифрования куки: активным шифруем,
+// любым из связки расшифровываем, так ключи можно менять без разлогина
+type Keyring struct {
+	activeID string
+	aeads    map[string]cipher.AEAD
+	// порядок нужен для старых куки без идентификатора ключа
+	order []string
+}
+
+var keyring atomic.Pointer[Keyring]
+
+func init() {
+	k, err := NewKeyring([]config.CookieKey{{ID: config.DefaultCookieKeyID, Secret: []byte(config.EncryptionKey)}})
+	if err != nil {
+		panic("failed to init default cookie keyring: " + err.Error())
+	}
+	keyring.Store(k)
+}
+
+func NewKeyring(keys []config.CookieKey) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("keyring must contain at least one key")
+	}
 
-var encryptionKey = []byte(config.EncryptionKey)
+	k := &Keyring{
+		activeID: keys[0].ID,
+		aeads:    make(map[string]cipher.AEAD, len(keys)),
+	}
+	for _, key := range keys {
+		if strings.Contains(key.ID, ".") {
+			return nil, fmt.Errorf("key id %q must not contain '.'", key.ID)
+		}
+		block, err := aes.NewCipher(key.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", key.ID, err)
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", key.ID, err)
+		}
+		k.aeads[key.ID] = gcm
+		k.order = append(k.order, key.ID)
+	}
+	return k, nil
+}
 
+// SetKeyring заменяет связку ключей, вызывается из main при старте
+func SetKeyring(k *Keyring) {
+	keyring.Store(k)
+}
+
+// AccessCookieMiddleware пускает запрос с валидной кукой userID,
+// с заголовком Authorization: Bearer <access-токен> или X-API-Key
 func AccessCookieMiddleware(repo *service.GofemartService) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Получаем куки
-			cookie, err := r.Cookie("userID")
-			if err != nil {
-				http.Error(w, "authentication required", http.StatusUnauthorized)
-				return
+			var (
+				userID    int
+				sessionID int
+				apiKey    *models.APIKey
+				ok        bool
+			)
+			if token := r.Header.Get(apiKeyHeader); token != "" {
+				apiKey, ok = apiKeyFromHeader(w, token, repo)
+				if ok {
+					userID = apiKey.UserID
+				}
+			} else if scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " "); strings.EqualFold(scheme, "Bearer") {
+				userID, ok = userIDFromBearer(w, token, repo)
+			} else {
+				// другие схемы (например, Basic от прокси) не наши - входим по куке
+				userID, sessionID, ok = userIDFromCookie(w, r, repo)
 			}
-
-			// Проверяем срок жизни куки
-			if !cookie.Expires.IsZero() && cookie.Expires.Before(time.Now()) {
-				http.Error(w, "cookie expired", http.StatusUnauthorized)
+			if !ok {
 				return
 			}
 
-			// Пытаемся расшифровать куки
-			userIDStr, err := decrypt(cookie.Value)
+			// Проверяем что пользователь существует в БД
+			user, err := repo.GetUserByID(userID)
 			if err != nil {
-				http.Error(w, "invalid authentication cookie", http.StatusUnauthorized)
+				http.Error(w, "internal server error", http.StatusInternalServerError)
 				return
 			}
-
-			// Конвертируем строку в число (ID пользователя)
-			userID, err := strconv.Atoi(userIDStr)
-			if err != nil {
-				http.Error(w, "invalid user ID in cookie", http.StatusUnauthorized)
+			if user == nil {
+				http.Error(w, "user not found", http.StatusUnauthorized)
 				return
 			}
-
-			// Проверяем что пользователь существует в БД
-			user, err := repo.GetUserByID(userID)
-			if err != nil || user == nil {
-				http.Error(w, "user not found", http.StatusUnauthorized)
+			// заблокированного не пускаем даже с ещё живым access-токеном
+			if user.Blocked() {
+				http.Error(w, "account is blocked", http.StatusForbidden)
 				return
 			}
 
-			// Всё ок - передаем userID в контекст
-			ctx := context.WithValue(r.Context(), UserIDKey, userIDStr)
+			// Всё ок - передаем userID, роль (и сессию, если вход по куке) в контекст
+			ctx := context.WithValue(r.Context(), UserIDKey, strconv.Itoa(userID))
+			ctx = context.WithValue(ctx, UserRoleKey, user.Role)
+			if sessionID != 0 {
+				ctx = context.WithValue(ctx, SessionIDKey, sessionID)
+			}
+			if apiKey != nil {
+				ctx = context.WithValue(ctx, APIKeyKey, apiKey)
+			}
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+func userIDFromBearer(w http.ResponseWriter, token string, repo *service.GofemartService) (int, bool) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		http.Error(w, "invalid authorization header", http.StatusUnauthorized)
+		return 0, false
+	}
+
+	userID, err := repo.ParseAccessToken(token)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		http.Error(w, "invalid access token", http.StatusUnauthorized)
+		return 0, false
+	}
+	return userID, true
+}
+
+func userIDFromCookie(w http.ResponseWriter, r *http.Request, repo *service.GofemartService) (int, int, bool) {
+	// Получаем куки
+	cookie, err := r.Cookie(cookieName)
+	if err != nil {
+		http.Error(w, "authentication required", http.StatusUnauthorized)
+		return 0, 0, false
+	}
+
+	// Проверяем срок жизни куки
+	if !cookie.Expires.IsZero() && cookie.Expires.Before(time.Now()) {
+		http.Error(w, "cookie expired", http.StatusUnauthorized)
+		return 0, 0, false
+	}
+
+	// Пытаемся расшифровать куки
+	value, err := decrypt(cookie.Value)
+	if err != nil {
+		http.Error(w, "invalid authentication cookie", http.StatusUnauthorized)
+		return 0, 0, false
+	}
+
+	// В куке "<userID>:<идентификатор сессии>", куки без сессии больше не принимаются
+	userIDStr, sessionToken, found := strings.Cut(value, ":")
+	if !found {
+		http.Error(w, "session required, please log in again", http.StatusUnauthorized)
+		return 0, 0, false
+	}
+
+	// Конвертируем строку в число (ID пользователя)
+	userID, err := strconv.Atoi(userIDStr)
+	if err != nil {
+		http.Error(w, "invalid user ID in cookie", http.StatusUnauthorized)
+		return 0, 0, false
+	}
+
+	// Сессия должна быть не отозвана и не истекла
+	sessionID, err := repo.ValidateSession(userID, sessionToken)
+	if errors.Is(err, service.ErrSessionNotActive) {
+		http.Error(w, "session expired or revoked", http.StatusUnauthorized)
+		return 0, 0, false
+	}
+	// сбой базы - не повод разлогинивать всех пользователей
+	if err != nil {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return 0, 0, false
+	}
+
+	return userID, sessionID, true
+}
+
 // SetEncryptedCookie - публичная функция для установки куки из хендлеров
-// Используется только при успешной регистрации/логине
-func SetEncryptedCookie(w http.ResponseWriter, userID string) {
-	encrypted, err := Encrypt(userID)
+// Используется только при успешной регистрации/логине, в куку шифруется
+// ID пользователя и секретный идентификатор серверной сессии
+func SetEncryptedCookie(w http.ResponseWriter, userID, sessionToken string, expiresAt time.Time) error {
+	encrypted, err := Encrypt(userID + ":" + sessionToken)
 	if err != nil {
-		encrypted = userID
+		return err
 	}
 
 	http.SetCookie(w, &http.Cookie{
-		Name:     "userID",
+		Name:     cookieName,
 		Value:    encrypted,
 		Path:     "/",
-		Expires:  time.Now().Add(8 * time.Hour),
+		Expires:  expiresAt,
 		HttpOnly: true,
 		Secure:   false,
 		SameSite: http.SameSiteLaxMode,
 	})
+	return nil
 }
 
+// ClearCookie - удаление куки при выходе
+func ClearCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		Expires:  time.Unix(0, 0),
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Encrypt шифрует значение активным ключом.
+// Формат: <id ключа>.<base64(nonce|ciphertext)>, id ключа также идёт в AAD
 func Encrypt(userID string) (string, error) {
-	block, err := aes.NewCipher(encryptionKey)
-	if err != nil {
-		return "", err
-	}
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
-	}
+	k := keyring.Load()
+	gcm := k.aeads[k.activeID]
+
 	nonce := make([]byte, gcm.NonceSize())
 	if _, err := rand.Read(nonce); err != nil {
 		return "", err
 	}
 
-	cipherText := gcm.Seal(nonce, nonce, []byte(userID), nil)
-	return base64.URLEncoding.EncodeToString(cipherText), nil
+	cipherText := gcm.Seal(nonce, nonce, []byte(userID), []byte(k.activeID))
+	return k.activeID + "." + base64.URLEncoding.EncodeToString(cipherText), nil
 }
 
 func decrypt(val string) (string, error) {
-	data, err := base64.URLEncoding.DecodeString(val)
-	if err != nil {
-		return "", err
+	k := keyring.Load()
+
+	keyID, payload, ok := strings.Cut(val, ".")
+	if !ok {
+		// кука старого формата без идентификатора - пробуем все ключи
+		return decryptLegacy(k, val)
 	}
-	block, err := aes.NewCipher(encryptionKey)
-	if err != nil {
-		return "", err
+
+	gcm, found := k.aeads[keyID]
+	if !found {
+		return "", fmt.Errorf("unknown key id %q", keyID)
+	}
+	return openSealed(gcm, payload, []byte(keyID))
+}
+
+func decryptLegacy(k *Keyring, val string) (string, error) {
+	for _, id := range k.order {
+		if plainText, err := openSealed(k.aeads[id], val, nil); err == nil {
+			return plainText, nil
+		}
 	}
-	gcm, err := cipher.NewGCM(block)
+	return "", errors.New("cookie does not match any key")
+}
+
+func openSealed(gcm cipher.AEAD, val string, additionalData []byte) (string, error) {
+	data, err := base64.URLEncoding.DecodeString(val)
 	if err != nil {
 		return "", err
 	}
@@ -120,11 +289,17 @@ func decrypt(val string) (string, error) {
 		return "", fmt.Errorf("ciphertext to short")
 	}
 	nonce, cipherText := data[:nonceSize], data[nonceSize:]
-	plainText, err := gcm.Open(nil, nonce, cipherText, nil)
+	plainText, err := gcm.Open(nil, nonce, cipherText, additionalData)
 	if err != nil {
 		return "", err
 	}
-	return string(plainText), err
+	return string(plainText), nil
+}
+
+// GetSessionID - ID серверной сессии, если запрос пришёл с кукой
+func GetSessionID(ctx context.Context) (int, bool) {
+	sessionID, ok := ctx.Value(SessionIDKey).(int)
+	return sessionID, ok
 }
 
 func GetUserID(ctx context.Context) (string, error) {
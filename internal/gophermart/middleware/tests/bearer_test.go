@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	middlewareDir "go-musthave-diploma-tpl/internal/gophermart/middleware"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+	serviceMocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBearerMiddleware(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+	gofemartService := service.NewGofemartService(mockRepo, "http://localhost:8081")
+
+	mockRepo.EXPECT().CreateRefreshToken(123, gomock.Any(), gomock.Any()).Return(nil)
+	pair, err := gofemartService.IssueTokens(123)
+	require.NoError(t, err)
+
+	middleware := middlewareDir.AccessCookieMiddleware(gofemartService)
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, _ := middlewareDir.GetUserID(r.Context())
+		assert.Equal(t, "123", userID)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	t.Run("Valid access token", func(t *testing.T) {
+		mockRepo.EXPECT().
+			GetUserByID(123).
+			Return(&models.User{ID: 123, Login: "testuser"}, nil)
+
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("Invalid access token", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer invalid.token.value")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Contains(t, rr.Header().Get("WWW-Authenticate"), "invalid_token")
+	})
+
+	t.Run("Refresh token is not accepted as access token", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+pair.RefreshToken)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("Empty bearer token", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer ")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+
+	t.Run("Other scheme without cookie", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		assert.Contains(t, rr.Body.String(), "authentication required")
+	})
+
+	t.Run("Other scheme falls back to the session cookie", func(t *testing.T) {
+		mockRepo.EXPECT().TouchSession(123, service.HashToken("session")).Return(5, nil)
+		mockRepo.EXPECT().
+			GetUserByID(123).
+			Return(&models.User{ID: 123, Login: "testuser"}, nil)
+
+		req := httptest.NewRequest("GET", "/", nil)
+		// Basic дописывает прокси перед сервисом
+		req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+		encrypted, err := middlewareDir.Encrypt("123:session")
+		require.NoError(t, err)
+		req.AddCookie(&http.Cookie{Name: "userID", Value: encrypted})
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
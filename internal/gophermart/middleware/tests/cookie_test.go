@@ -2,9 +2,11 @@ package tests
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	middlewareDir "go-musthave-diploma-tpl/internal/gophermart/middleware"
 	"go-musthave-diploma-tpl/internal/gophermart/models"
@@ -21,6 +23,9 @@ func TestCookieMiddleware_Success(t *testing.T) {
 	mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
 	gofemartService := service.NewGofemartService(mockRepo, "http://localhost:8081")
 
+	mockRepo.EXPECT().
+		TouchSession(123, service.HashToken("session")).
+		Return(5, nil)
 	mockRepo.EXPECT().
 		GetUserByID(123).
 		Return(&models.User{ID: 123, Login: "testuser"}, nil)
@@ -31,11 +36,15 @@ func TestCookieMiddleware_Success(t *testing.T) {
 		if userID != "123" {
 			t.Errorf("expected userID 123, got %s", userID)
 		}
+		sessionID, ok := middlewareDir.GetSessionID(r.Context())
+		if !ok || sessionID != 5 {
+			t.Errorf("expected sessionID 5, got %d", sessionID)
+		}
 		w.WriteHeader(http.StatusOK)
 	}))
 
 	req := httptest.NewRequest("GET", "/", nil)
-	encrypted, _ := middlewareDir.Encrypt("123")
+	encrypted, _ := middlewareDir.Encrypt("123:session")
 	req.AddCookie(&http.Cookie{Name: "userID", Value: encrypted})
 
 	rr := httptest.NewRecorder()
@@ -53,6 +62,9 @@ func TestCookieMiddleware_UserNotFound(t *testing.T) {
 	mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
 	gofemartService := service.NewGofemartService(mockRepo, "http://localhost:8081")
 
+	mockRepo.EXPECT().
+		TouchSession(999, service.HashToken("session")).
+		Return(1, nil)
 	mockRepo.EXPECT().
 		GetUserByID(999).
 		Return(nil, nil)
@@ -63,7 +75,7 @@ func TestCookieMiddleware_UserNotFound(t *testing.T) {
 	}))
 
 	req := httptest.NewRequest("GET", "/", nil)
-	encrypted, _ := middlewareDir.Encrypt("999")
+	encrypted, _ := middlewareDir.Encrypt("999:session")
 	req.AddCookie(&http.Cookie{Name: "userID", Value: encrypted})
 
 	rr := httptest.NewRecorder()
@@ -119,17 +131,114 @@ func TestCookieMiddleware_InvalidEncryption(t *testing.T) {
 	}
 }
 
+func TestCookieMiddleware_RevokedSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+	gofemartService := service.NewGofemartService(mockRepo, "http://localhost:8081")
+
+	// сессия отозвана (logout) или истекла
+	mockRepo.EXPECT().
+		TouchSession(123, service.HashToken("session")).
+		Return(0, nil)
+
+	middleware := middlewareDir.AccessCookieMiddleware(gofemartService)
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called with revoked session")
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	encrypted, _ := middlewareDir.Encrypt("123:session")
+	req.AddCookie(&http.Cookie{Name: "userID", Value: encrypted})
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", rr.Code)
+	}
+}
+
+func TestCookieMiddleware_SessionStoreFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+	gofemartService := service.NewGofemartService(mockRepo, "http://localhost:8081")
+
+	// база недоступна - это не отозванная сессия
+	mockRepo.EXPECT().
+		TouchSession(123, service.HashToken("session")).
+		Return(0, errors.New("connection refused"))
+
+	middleware := middlewareDir.AccessCookieMiddleware(gofemartService)
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called when session check fails")
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	encrypted, _ := middlewareDir.Encrypt("123:session")
+	req.AddCookie(&http.Cookie{Name: "userID", Value: encrypted})
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", rr.Code)
+	}
+}
+
+func TestCookieMiddleware_CookieWithoutSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+	gofemartService := service.NewGofemartService(mockRepo, "http://localhost:8081")
+
+	middleware := middlewareDir.AccessCookieMiddleware(gofemartService)
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not be called without session")
+	}))
+
+	// кука старого формата - только ID пользователя
+	req := httptest.NewRequest("GET", "/", nil)
+	encrypted, _ := middlewareDir.Encrypt("123")
+	req.AddCookie(&http.Cookie{Name: "userID", Value: encrypted})
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401, got %d", rr.Code)
+	}
+}
+
 func TestSetEncryptedCookie(t *testing.T) {
 	rr := httptest.NewRecorder()
 	defer rr.Result().Body.Close()
 
-	middlewareDir.SetEncryptedCookie(rr, "123")
+	expiresAt := time.Now().Add(time.Hour)
+	if err := middlewareDir.SetEncryptedCookie(rr, "123", "session", expiresAt); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
 
 	if rr.Header().Get("Set-Cookie") == "" {
 		t.Error("cookie should be set in response header")
 	}
 }
 
+func TestClearCookie(t *testing.T) {
+	rr := httptest.NewRecorder()
+
+	middlewareDir.ClearCookie(rr)
+
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("expected expired userID cookie, got %v", cookies)
+	}
+}
+
 func TestGetUserID(t *testing.T) {
 	t.Run("Successfully retrieved userID from context", func(t *testing.T) {
 		ctx := context.WithValue(context.Background(), middlewareDir.UserIDKey, "123")
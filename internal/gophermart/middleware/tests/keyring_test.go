@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-musthave-diploma-tpl/internal/gophermart/config"
+	middlewareDir "go-musthave-diploma-tpl/internal/gophermart/middleware"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+	serviceMocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	oldKey = config.CookieKey{ID: "k1", Secret: []byte("old-key-old-key-old-key-old-key!")}
+	newKey = config.CookieKey{ID: "k2", Secret: []byte("new-key-new-key-new-key-new-key!")}
+)
+
+func setKeyring(t *testing.T, keys ...config.CookieKey) {
+	t.Helper()
+
+	k, err := middlewareDir.NewKeyring(keys)
+	require.NoError(t, err)
+	middlewareDir.SetKeyring(k)
+}
+
+func restoreDefaultKeyring(t *testing.T) {
+	t.Cleanup(func() {
+		setKeyring(t, config.CookieKey{ID: config.DefaultCookieKeyID, Secret: []byte(config.EncryptionKey)})
+	})
+}
+
+// прогоняем куку через AccessCookieMiddleware и возвращаем код ответа
+func serveWithCookie(t *testing.T, value string, expectUser bool) int {
+	t.Helper()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+	if expectUser {
+		mockRepo.EXPECT().
+			TouchSession(123, service.HashToken("session")).
+			Return(1, nil)
+		mockRepo.EXPECT().
+			GetUserByID(123).
+			Return(&models.User{ID: 123, Login: "testuser"}, nil)
+	}
+
+	handler := middlewareDir.AccessCookieMiddleware(service.NewGofemartService(mockRepo, "http://localhost:8081"))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: "userID", Value: value})
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	return rr.Code
+}
+
+func TestKeyring_CookieCarriesKeyID(t *testing.T) {
+	restoreDefaultKeyring(t)
+	setKeyring(t, newKey, oldKey)
+
+	encrypted, err := middlewareDir.Encrypt("123:session")
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(encrypted, "k2."), "cookie should start with active key id, got %s", encrypted)
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	restoreDefaultKeyring(t)
+
+	// кука выпущена старым ключом
+	setKeyring(t, oldKey)
+	encrypted, err := middlewareDir.Encrypt("123:session")
+	require.NoError(t, err)
+
+	// ротация: новый ключ активный, старый остаётся для расшифровки
+	setKeyring(t, newKey, oldKey)
+	assert.Equal(t, http.StatusOK, serveWithCookie(t, encrypted, true))
+
+	// старый ключ выведен из связки - кука больше не принимается
+	setKeyring(t, newKey)
+	assert.Equal(t, http.StatusUnauthorized, serveWithCookie(t, encrypted, false))
+}
+
+func TestKeyring_KeyIDIsAuthenticated(t *testing.T) {
+	restoreDefaultKeyring(t)
+	setKeyring(t, newKey, oldKey)
+
+	encrypted, err := middlewareDir.Encrypt("123:session")
+	require.NoError(t, err)
+
+	// подмена идентификатора ключа ломает проверку подлинности
+	tampered := "k1." + strings.TrimPrefix(encrypted, "k2.")
+	assert.Equal(t, http.StatusUnauthorized, serveWithCookie(t, tampered, false))
+}
+
+func TestKeyring_LegacyCookieWithoutKeyID(t *testing.T) {
+	restoreDefaultKeyring(t)
+
+	// кука старого формата, зашифрованная встроенным ключом без идентификатора
+	legacy := legacyEncrypt(t, []byte(config.EncryptionKey), "123:session")
+
+	setKeyring(t, newKey, config.CookieKey{ID: config.DefaultCookieKeyID, Secret: []byte(config.EncryptionKey)})
+	assert.Equal(t, http.StatusOK, serveWithCookie(t, legacy, true))
+}
+
+func TestNewKeyring_Invalid(t *testing.T) {
+	_, err := middlewareDir.NewKeyring(nil)
+	assert.Error(t, err)
+
+	_, err = middlewareDir.NewKeyring([]config.CookieKey{{ID: "a.b", Secret: newKey.Secret}})
+	assert.Error(t, err)
+
+	_, err = middlewareDir.NewKeyring([]config.CookieKey{{ID: "short", Secret: []byte("short")}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,27 @@
+package tests
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// legacyEncrypt - шифрование куки в формате до ротации ключей (без идентификатора и AAD)
+func legacyEncrypt(t *testing.T, key []byte, value string) string {
+	t.Helper()
+
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	require.NoError(t, err)
+
+	return base64.URLEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(value), nil))
+}
@@ -0,0 +1,4 @@
+ALTER TABLE users DROP COLUMN IF EXISTS blocked_at;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
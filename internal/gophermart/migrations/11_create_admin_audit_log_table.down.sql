@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_admin_audit_log_target_user_id;
+DROP INDEX IF EXISTS idx_admin_audit_log_actor_id;
+
+DROP TABLE IF EXISTS admin_audit_log;
@@ -0,0 +1,70 @@
+-- счёт пользователя: текущий остаток и сумма списаний, меняются только вместе с проводкой
+CREATE TABLE IF NOT EXISTS accounts (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id),
+    balance NUMERIC(14,2) NOT NULL DEFAULT 0,
+    withdrawn NUMERIC(14,2) NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- журнал проводок: только добавление. Каждая проводка - движение между счётом
+-- пользователя и корреспондирующим системным счётом (accruals, redemptions, adjustments),
+-- amount > 0 - приход на счёт пользователя, amount < 0 - расход
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    entry_type VARCHAR(32) NOT NULL,
+    amount NUMERIC(14,2) NOT NULL,
+    balance_after NUMERIC(14,2) NOT NULL,
+    contra_account VARCHAR(32) NOT NULL,
+    order_number VARCHAR(255),
+    withdrawal_id INTEGER REFERENCES withdrawals(uid),
+    reverses_entry_id BIGINT REFERENCES ledger_entries(id),
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT ledger_entries_amount_check CHECK (amount <> 0),
+    CONSTRAINT ledger_entries_type_check CHECK (entry_type IN ('accrual', 'withdrawal', 'reversal', 'adjustment'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_user_id ON ledger_entries(user_id, id);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_reverses ON ledger_entries(reverses_entry_id) WHERE reverses_entry_id IS NOT NULL;
+-- начисление по заказу проводится один раз
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ledger_entries_accrual_order
+    ON ledger_entries(order_number) WHERE entry_type = 'accrual';
+
+-- переносим историю: начисления по обработанным заказам и списания
+INSERT INTO ledger_entries (user_id, entry_type, amount, balance_after, contra_account, order_number, withdrawal_id, created_at)
+SELECT
+    user_id, entry_type, amount,
+    SUM(amount) OVER (PARTITION BY user_id ORDER BY created_at, entry_type, ref),
+    contra_account, order_number, withdrawal_id, created_at
+FROM (
+    SELECT user_id, 'accrual' AS entry_type, accrual AS amount, 'accruals' AS contra_account,
+           number AS order_number, NULL::INTEGER AS withdrawal_id, uploaded_at AS created_at, uid AS ref
+    FROM orders
+    WHERE status = 'PROCESSED' AND accrual > 0
+    UNION ALL
+    SELECT user_id, 'withdrawal', -sum, 'redemptions', order_number, uid, processed_at, uid
+    FROM withdrawals
+    WHERE sum > 0
+) history
+ORDER BY created_at, entry_type, ref;
+
+INSERT INTO accounts (user_id, balance, withdrawn)
+SELECT
+    user_id,
+    SUM(amount),
+    COALESCE(-SUM(amount) FILTER (WHERE entry_type = 'withdrawal'), 0)
+FROM ledger_entries
+GROUP BY user_id;
+
+-- журнал неизменяем: исправления - только встречными проводками
+CREATE OR REPLACE FUNCTION ledger_entries_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'ledger_entries is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_ledger_entries_append_only ON ledger_entries;
+CREATE TRIGGER trg_ledger_entries_append_only
+BEFORE UPDATE OR DELETE ON ledger_entries
+FOR EACH ROW EXECUTE FUNCTION ledger_entries_append_only();
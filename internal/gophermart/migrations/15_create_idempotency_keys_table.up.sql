@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- операция, к которой относится ключ: withdraw
+    scope VARCHAR(32) NOT NULL,
+    idem_key VARCHAR(255) NOT NULL,
+    -- SHA-256 от содержимого запроса: тот же ключ с другим телом отклоняется
+    fingerprint VARCHAR(64) NOT NULL,
+    -- сохранённый ответ; NULL - запрос ещё выполняется
+    status_code INTEGER,
+    response_body TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (user_id, scope, idem_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
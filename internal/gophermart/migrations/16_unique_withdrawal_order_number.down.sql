@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_withdrawals_order_number;
+CREATE INDEX IF NOT EXISTS idx_withdrawals_order_number ON withdrawals(order_number);
+
+-- повторным списаниям возвращаются исходные номера
+UPDATE withdrawals w
+SET order_number = d.order_number
+FROM withdrawal_order_duplicates d
+WHERE d.withdrawal_id = w.uid AND w.order_number = d.renamed_to;
+
+DROP TABLE IF EXISTS withdrawal_order_duplicates;
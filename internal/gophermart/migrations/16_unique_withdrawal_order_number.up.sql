@@ -0,0 +1,29 @@
+-- один номер заказа оплачивается баллами только один раз.
+-- До этой миграции повторная оплата была возможна, поэтому дубли сначала выносятся в отчёт:
+-- первое списание по номеру остаётся как есть, у повторных к номеру дописывается "#<uid>".
+-- Списания не удаляются - баланс и журнал проводок от них зависят
+CREATE TABLE IF NOT EXISTS withdrawal_order_duplicates (
+    withdrawal_id INTEGER PRIMARY KEY REFERENCES withdrawals(uid),
+    user_id INTEGER NOT NULL,
+    order_number VARCHAR(255) NOT NULL,
+    renamed_to VARCHAR(255) NOT NULL,
+    found_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO withdrawal_order_duplicates (withdrawal_id, user_id, order_number, renamed_to)
+SELECT uid, user_id, order_number, order_number || '#' || uid
+FROM (
+    SELECT uid, user_id, order_number,
+           ROW_NUMBER() OVER (PARTITION BY order_number ORDER BY processed_at, uid) AS n
+    FROM withdrawals
+) w
+WHERE n > 1
+ON CONFLICT (withdrawal_id) DO NOTHING;
+
+UPDATE withdrawals w
+SET order_number = d.renamed_to
+FROM withdrawal_order_duplicates d
+WHERE d.withdrawal_id = w.uid AND w.order_number = d.order_number;
+
+DROP INDEX IF EXISTS idx_withdrawals_order_number;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_withdrawals_order_number ON withdrawals(order_number);
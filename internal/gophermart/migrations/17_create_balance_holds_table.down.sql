@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_balance_holds_expires_at;
+DROP INDEX IF EXISTS idx_balance_holds_active_order;
+DROP INDEX IF EXISTS idx_balance_holds_user_id;
+
+DROP TABLE IF EXISTS balance_holds;
+
+ALTER TABLE accounts DROP COLUMN IF EXISTS held;
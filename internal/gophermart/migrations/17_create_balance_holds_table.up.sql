@@ -0,0 +1,24 @@
+-- зарезервированные баллы: входят в остаток счёта, но потратить их нельзя
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS held NUMERIC(14,2) NOT NULL DEFAULT 0;
+
+-- резерв под заказ: active -> captured (стал списанием) | voided (отменён) | expired (истёк)
+CREATE TABLE IF NOT EXISTS balance_holds (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    order_number VARCHAR(255) NOT NULL,
+    amount NUMERIC(14,2) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'active',
+    withdrawal_id INTEGER REFERENCES withdrawals(uid),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    closed_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT balance_holds_amount_check CHECK (amount > 0),
+    CONSTRAINT balance_holds_status_check CHECK (status IN ('active', 'captured', 'voided', 'expired'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_balance_holds_user_id ON balance_holds(user_id);
+-- на заказ - не больше одного действующего резерва
+CREATE UNIQUE INDEX IF NOT EXISTS idx_balance_holds_active_order
+    ON balance_holds(order_number) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_balance_holds_expires_at
+    ON balance_holds(expires_at) WHERE status = 'active';
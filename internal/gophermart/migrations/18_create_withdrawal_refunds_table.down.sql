@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_withdrawal_refunds_withdrawal_id;
+
+DROP TABLE IF EXISTS withdrawal_refunds;
@@ -0,0 +1,19 @@
+-- возвраты баллов по списаниям: частичные или полный, сумма возвратов не больше списания.
+-- Баланс восстанавливает сторно-проводка ledger_entry_id
+CREATE TABLE IF NOT EXISTS withdrawal_refunds (
+    id SERIAL PRIMARY KEY,
+    withdrawal_id INTEGER NOT NULL REFERENCES withdrawals(uid),
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    amount NUMERIC(14,2) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    -- кто вернул: сотрудник (actor_id) или сервер партнёра по API-ключу (api_key_id)
+    source VARCHAR(16) NOT NULL,
+    actor_id INTEGER REFERENCES users(id),
+    api_key_id INTEGER REFERENCES api_keys(id),
+    ledger_entry_id BIGINT NOT NULL REFERENCES ledger_entries(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT withdrawal_refunds_amount_check CHECK (amount > 0),
+    CONSTRAINT withdrawal_refunds_source_check CHECK (source IN ('staff', 'partner'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_withdrawal_refunds_withdrawal_id ON withdrawal_refunds(withdrawal_id);
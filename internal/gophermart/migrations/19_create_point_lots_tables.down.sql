@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_point_lot_consumptions_lot_id;
+DROP TABLE IF EXISTS point_lot_consumptions;
+
+DROP INDEX IF EXISTS idx_point_lots_open;
+DROP TABLE IF EXISTS point_lots;
+
+-- проводки сгорания из журнала не удалить, поэтому старое ограничение только для новых строк
+ALTER TABLE ledger_entries DROP CONSTRAINT IF EXISTS ledger_entries_type_check;
+ALTER TABLE ledger_entries ADD CONSTRAINT ledger_entries_type_check
+    CHECK (entry_type IN ('accrual', 'withdrawal', 'reversal', 'adjustment')) NOT VALID;
@@ -0,0 +1,37 @@
+-- партии баллов: каждый приход на счёт - отдельная партия со своим сроком сгорания,
+-- расход гасит партии по порядку начисления (FIFO). Сумма remaining по пользователю равна остатку счёта
+CREATE TABLE IF NOT EXISTS point_lots (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    entry_id BIGINT REFERENCES ledger_entries(id),
+    amount NUMERIC(14,2) NOT NULL,
+    remaining NUMERIC(14,2) NOT NULL,
+    earned_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT point_lots_amount_check CHECK (amount > 0),
+    CONSTRAINT point_lots_remaining_check CHECK (remaining >= 0 AND remaining <= amount)
+);
+
+CREATE INDEX IF NOT EXISTS idx_point_lots_open
+    ON point_lots(user_id, earned_at, id) WHERE remaining > 0;
+
+-- какие партии и на сколько погасила проводка расхода: по ним видно, что именно сгорело или потрачено
+CREATE TABLE IF NOT EXISTS point_lot_consumptions (
+    entry_id BIGINT NOT NULL REFERENCES ledger_entries(id),
+    lot_id BIGINT NOT NULL REFERENCES point_lots(id),
+    amount NUMERIC(14,2) NOT NULL,
+    PRIMARY KEY (entry_id, lot_id),
+    CONSTRAINT point_lot_consumptions_amount_check CHECK (amount > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_point_lot_consumptions_lot_id ON point_lot_consumptions(lot_id);
+
+-- сгорание баллов - отдельный тип проводки на системный счёт expirations
+ALTER TABLE ledger_entries DROP CONSTRAINT IF EXISTS ledger_entries_type_check;
+ALTER TABLE ledger_entries ADD CONSTRAINT ledger_entries_type_check
+    CHECK (entry_type IN ('accrual', 'withdrawal', 'reversal', 'adjustment', 'expiration'));
+
+-- текущие остатки переносим одной партией на пользователя, срок считается с момента миграции
+INSERT INTO point_lots (user_id, amount, remaining)
+SELECT user_id, balance, balance
+FROM accounts
+WHERE balance > 0;
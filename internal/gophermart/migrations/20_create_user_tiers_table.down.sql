@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_ledger_entries_user_created;
+DROP INDEX IF EXISTS idx_user_tiers_next_evaluation_at;
+
+DROP TABLE IF EXISTS user_tiers;
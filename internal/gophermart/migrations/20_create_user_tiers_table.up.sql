@@ -0,0 +1,12 @@
+-- уровень программы лояльности: пересчитывается фоновой задачей по начислениям за скользящее окно
+CREATE TABLE IF NOT EXISTS user_tiers (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id),
+    tier VARCHAR(32) NOT NULL,
+    earned NUMERIC(14,2) NOT NULL DEFAULT 0,
+    evaluated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    next_evaluation_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_tiers_next_evaluation_at ON user_tiers(next_evaluation_at);
+-- начисления пользователя за окно
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_user_created ON ledger_entries(user_id, created_at);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_transfers_to_user;
+DROP INDEX IF EXISTS idx_transfers_from_user;
+DROP TABLE IF EXISTS transfers;
+
+-- проводки переводов из журнала не удалить, поэтому старое ограничение только для новых строк
+ALTER TABLE ledger_entries DROP CONSTRAINT IF EXISTS ledger_entries_type_check;
+ALTER TABLE ledger_entries ADD CONSTRAINT ledger_entries_type_check
+    CHECK (entry_type IN ('accrual', 'withdrawal', 'reversal', 'adjustment', 'expiration')) NOT VALID;
@@ -0,0 +1,21 @@
+-- перевод баллов между пользователями: две проводки transfer через системный счёт transfers
+CREATE TABLE IF NOT EXISTS transfers (
+    id BIGSERIAL PRIMARY KEY,
+    from_user_id INTEGER NOT NULL REFERENCES users(id),
+    to_user_id INTEGER NOT NULL REFERENCES users(id),
+    amount NUMERIC(14,2) NOT NULL,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    debit_entry_id BIGINT NOT NULL REFERENCES ledger_entries(id),
+    credit_entry_id BIGINT NOT NULL REFERENCES ledger_entries(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT transfers_amount_check CHECK (amount > 0),
+    CONSTRAINT transfers_users_check CHECK (from_user_id <> to_user_id)
+);
+
+-- история и дневной лимит отправителя, история получателя
+CREATE INDEX IF NOT EXISTS idx_transfers_from_user ON transfers(from_user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_transfers_to_user ON transfers(to_user_id, created_at);
+
+ALTER TABLE ledger_entries DROP CONSTRAINT IF EXISTS ledger_entries_type_check;
+ALTER TABLE ledger_entries ADD CONSTRAINT ledger_entries_type_check
+    CHECK (entry_type IN ('accrual', 'withdrawal', 'reversal', 'adjustment', 'expiration', 'transfer'));
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_referrals_referrer;
+DROP TABLE IF EXISTS referrals;
+
+DROP INDEX IF EXISTS idx_users_referral_code;
+ALTER TABLE users DROP COLUMN IF EXISTS referral_code;
+
+-- реферальные проводки из журнала не удалить, поэтому старое ограничение только для новых строк
+ALTER TABLE ledger_entries DROP CONSTRAINT IF EXISTS ledger_entries_type_check;
+ALTER TABLE ledger_entries ADD CONSTRAINT ledger_entries_type_check
+    CHECK (entry_type IN ('accrual', 'withdrawal', 'reversal', 'adjustment', 'expiration', 'transfer')) NOT VALID;
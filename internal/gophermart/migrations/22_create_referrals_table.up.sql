@@ -0,0 +1,24 @@
+-- реферальный код пользователя: 10 шестнадцатеричных знаков, у существующих пользователей заполняется сразу
+ALTER TABLE users ADD COLUMN IF NOT EXISTS referral_code VARCHAR(16);
+UPDATE users SET referral_code = upper(substr(md5(random()::text || id::text), 1, 10)) WHERE referral_code IS NULL;
+ALTER TABLE users ALTER COLUMN referral_code SET DEFAULT upper(substr(md5(random()::text || clock_timestamp()::text), 1, 10));
+ALTER TABLE users ALTER COLUMN referral_code SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_referral_code ON users(referral_code);
+
+-- кто кого пригласил: у приглашённого один пригласивший, бонус выплачивается один раз
+-- после первого обработанного заказа приглашённого
+CREATE TABLE IF NOT EXISTS referrals (
+    referee_id INTEGER PRIMARY KEY REFERENCES users(id),
+    referrer_id INTEGER NOT NULL REFERENCES users(id),
+    bonus NUMERIC(14,2),
+    order_number VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    rewarded_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT referrals_users_check CHECK (referee_id <> referrer_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_referrals_referrer ON referrals(referrer_id, created_at);
+
+ALTER TABLE ledger_entries DROP CONSTRAINT IF EXISTS ledger_entries_type_check;
+ALTER TABLE ledger_entries ADD CONSTRAINT ledger_entries_type_check
+    CHECK (entry_type IN ('accrual', 'withdrawal', 'reversal', 'adjustment', 'expiration', 'transfer', 'referral'));
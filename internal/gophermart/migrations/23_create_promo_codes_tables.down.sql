@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_promo_redemptions_active;
+DROP INDEX IF EXISTS idx_promo_redemptions_promo_user;
+DROP TABLE IF EXISTS promo_redemptions;
+DROP TABLE IF EXISTS promo_codes;
+
+-- проводки по промокодам из журнала не удалить, поэтому старое ограничение только для новых строк
+ALTER TABLE ledger_entries DROP CONSTRAINT IF EXISTS ledger_entries_type_check;
+ALTER TABLE ledger_entries ADD CONSTRAINT ledger_entries_type_check
+    CHECK (entry_type IN ('accrual', 'withdrawal', 'reversal', 'adjustment', 'expiration', 'transfer', 'referral')) NOT VALID;
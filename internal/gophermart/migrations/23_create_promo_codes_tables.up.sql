@@ -0,0 +1,43 @@
+-- промокоды: points - разовое начисление, multiplier - множитель начисления следующих orders заказов
+CREATE TABLE IF NOT EXISTS promo_codes (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(64) UNIQUE NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    points NUMERIC(14,2) NOT NULL DEFAULT 0,
+    multiplier NUMERIC(6,2) NOT NULL DEFAULT 1,
+    orders INTEGER NOT NULL DEFAULT 0,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    -- 0 - без ограничения
+    max_redemptions INTEGER NOT NULL DEFAULT 0,
+    per_user_limit INTEGER NOT NULL DEFAULT 1,
+    redemptions INTEGER NOT NULL DEFAULT 0,
+    created_by INTEGER REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    disabled_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT promo_codes_kind_check CHECK (
+        kind = 'points' AND points > 0
+        OR kind = 'multiplier' AND multiplier > 1 AND orders > 0
+    ),
+    CONSTRAINT promo_codes_limits_check CHECK (max_redemptions >= 0 AND per_user_limit > 0),
+    CONSTRAINT promo_codes_window_check CHECK (ends_at IS NULL OR ends_at > starts_at)
+);
+
+-- погашения: по разовому начислению - проводка, по множителю - сколько заказов ещё умножится
+CREATE TABLE IF NOT EXISTS promo_redemptions (
+    id BIGSERIAL PRIMARY KEY,
+    promo_id INTEGER NOT NULL REFERENCES promo_codes(id),
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    ledger_entry_id BIGINT REFERENCES ledger_entries(id),
+    orders_left INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT promo_redemptions_orders_check CHECK (orders_left >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_promo_redemptions_promo_user ON promo_redemptions(promo_id, user_id);
+-- действующие множители пользователя для обработчика заказов
+CREATE INDEX IF NOT EXISTS idx_promo_redemptions_active ON promo_redemptions(user_id, created_at) WHERE orders_left > 0;
+
+ALTER TABLE ledger_entries DROP CONSTRAINT IF EXISTS ledger_entries_type_check;
+ALTER TABLE ledger_entries ADD CONSTRAINT ledger_entries_type_check
+    CHECK (entry_type IN ('accrual', 'withdrawal', 'reversal', 'adjustment', 'expiration', 'transfer', 'referral', 'promo'));
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_orders_user_uploaded;
+ALTER TABLE orders DROP COLUMN IF EXISTS updated_at;
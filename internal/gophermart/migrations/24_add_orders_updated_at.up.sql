@@ -0,0 +1,8 @@
+-- uploaded_at - время загрузки заказа и больше не меняется, смена статуса пишется в updated_at.
+-- У уже обработанных заказов uploaded_at раньше перезаписывался, исходное время не восстановить
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE;
+UPDATE orders SET updated_at = uploaded_at WHERE updated_at IS NULL;
+ALTER TABLE orders ALTER COLUMN updated_at SET DEFAULT CURRENT_TIMESTAMP;
+
+-- страницы заказов пользователя по ключу (uploaded_at, uid)
+CREATE INDEX IF NOT EXISTS idx_orders_user_uploaded ON orders(user_id, uploaded_at, uid);
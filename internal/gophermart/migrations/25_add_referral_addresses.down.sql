@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_referrals_referrer_ip;
+DROP INDEX IF EXISTS idx_sessions_user_ip;
+ALTER TABLE referrals DROP COLUMN IF EXISTS referee_ip;
+ALTER TABLE sessions DROP COLUMN IF EXISTS ip;
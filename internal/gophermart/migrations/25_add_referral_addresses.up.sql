@@ -0,0 +1,7 @@
+-- адреса клиентов: регистрация по приглашению с адреса пригласившего
+-- или другого его приглашённого отклоняется
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS ip VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE referrals ADD COLUMN IF NOT EXISTS referee_ip VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_ip ON sessions(user_id, ip);
+CREATE INDEX IF NOT EXISTS idx_referrals_referrer_ip ON referrals(referrer_id, referee_ip);
@@ -0,0 +1,3 @@
+DELETE FROM point_lot_consumptions WHERE amount < 0;
+ALTER TABLE point_lot_consumptions DROP CONSTRAINT IF EXISTS point_lot_consumptions_amount_check;
+ALTER TABLE point_lot_consumptions ADD CONSTRAINT point_lot_consumptions_amount_check CHECK (amount > 0);
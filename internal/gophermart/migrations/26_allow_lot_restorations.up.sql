@@ -0,0 +1,4 @@
+-- сторно расхода возвращает баллы в погашенные им партии: строка с отрицательной суммой.
+-- Партия сохраняет дату начисления, поэтому ошибочно сгоревшие баллы не получают новый срок
+ALTER TABLE point_lot_consumptions DROP CONSTRAINT IF EXISTS point_lot_consumptions_amount_check;
+ALTER TABLE point_lot_consumptions ADD CONSTRAINT point_lot_consumptions_amount_check CHECK (amount <> 0);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_promo_redemptions_user_history;
+ALTER TABLE promo_redemptions DROP COLUMN IF EXISTS history_id;
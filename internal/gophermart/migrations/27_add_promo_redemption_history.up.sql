@@ -0,0 +1,15 @@
+-- погашение множителя не двигает баланс и не пишет проводку, но видно в истории баланса.
+-- history_id берётся из последовательности журнала, поэтому погашение встаёт между проводками
+-- в порядке проведения и листается тем же курсором по id
+ALTER TABLE promo_redemptions ADD COLUMN IF NOT EXISTS history_id BIGINT;
+
+-- ранее погашенные множители получают номера по порядку погашения
+UPDATE promo_redemptions r SET history_id = h.history_id
+FROM (
+    SELECT id, nextval(pg_get_serial_sequence('ledger_entries', 'id')) AS history_id
+    FROM (SELECT id FROM promo_redemptions WHERE ledger_entry_id IS NULL ORDER BY created_at, id) ordered
+) h
+WHERE r.id = h.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_promo_redemptions_user_history
+    ON promo_redemptions(user_id, history_id) WHERE history_id IS NOT NULL;
@@ -0,0 +1,3 @@
+-- хэши bcrypt/argon2 хранятся вместе с параметрами и солью,
+-- старые строки с SHA-256 перехэшируются при следующем входе
+ALTER TABLE users ALTER COLUMN password_hash TYPE TEXT;
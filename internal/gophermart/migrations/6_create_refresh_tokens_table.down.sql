@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_refresh_tokens_user_id;
+
+DROP TABLE IF EXISTS refresh_tokens;
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// действия поддержки и администраторов для журнала
+const (
+	AuditSearchUsers   = "search_users"
+	AuditViewOrders    = "view_orders"
+	AuditViewBalance   = "view_balance"
+	AuditViewWithdraws = "view_withdrawals"
+	AuditBlockUser     = "block_user"
+	AuditUnblockUser   = "unblock_user"
+	AuditSetRole       = "set_role"
+	AuditUnlockLogin   = "unlock_login"
+	AuditRequeueOrder  = "requeue_order"
+	AuditViewAuditLog  = "view_audit_log"
+	AuditViewLedger    = "view_ledger"
+	AuditAdjustBalance = "adjust_balance"
+	AuditReverseEntry  = "reverse_ledger_entry"
+	AuditCheckLedger   = "check_ledger"
+	AuditRefund        = "refund_withdrawal"
+	AuditIssueAPIKey   = "issue_api_key"
+	AuditExpirePoints  = "expire_points"
+	AuditCreatePromo   = "create_promo_code"
+	AuditDisablePromo  = "disable_promo_code"
+)
+
+// AuditEntry - запись журнала действий администратора.
+// ActorID = 0 - действие выполнено из командной строки сервера
+type AuditEntry struct {
+	ID           int       `json:"id" db:"id"`
+	ActorID      int       `json:"actor_id" db:"actor_id"`
+	Action       string    `json:"action" db:"action"`
+	TargetUserID *int      `json:"target_user_id,omitempty" db:"target_user_id"`
+	Details      string    `json:"details,omitempty" db:"details"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+type SetRoleRequest struct {
+	Role string `json:"role"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// области доступа API-ключей
+const (
+	ScopeOrdersRead      = "orders:read"
+	ScopeOrdersWrite     = "orders:write"
+	ScopeBalanceRead     = "balance:read"
+	ScopeWithdrawalsRead = "withdrawals:read"
+	// ScopeWithdrawalsRefund - возврат баллов по списаниям, ключ с ней выпускает только администратор
+	ScopeWithdrawalsRefund = "withdrawals:refund"
+)
+
+// APIKeyPrefix - с этого начинается любой ключ, так его проще найти в логах и конфигах
+const APIKeyPrefix = "gm_"
+
+// APIKey - персональный ключ для обращений к API с серверов партнёров
+type APIKey struct {
+	ID         int        `json:"id" db:"id"`
+	UserID     int        `json:"-" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	Scopes     []string   `json:"scopes" db:"scopes"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+}
+
+// HasScope - разрешена ли ключу область доступа
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// CreateAPIKeyResponse - созданный ключ, значение Key больше нигде не показывается
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -2,15 +2,27 @@ package models
 
 import (
 	"time"
+
+	"go-musthave-diploma-tpl/pkg/money"
 )
 
+// Balance - current можно потратить, held зарезервировано под заказы и тратить нельзя
 type Balance struct {
-	Current   float64 `json:"current" db:"current"`
-	Withdrawn float64 `json:"withdrawn" db:"sum"`
+	Current   money.Amount `json:"current" db:"current"`
+	Held      money.Amount `json:"held" db:"held"`
+	Withdrawn money.Amount `json:"withdrawn" db:"sum"`
+	// ExpiringSoon - баллы, которые скоро сгорят, по срокам. Пусто, если баллы не сгорают
+	ExpiringSoon []ExpiringPoints `json:"expiring_soon,omitempty" db:"-"`
 }
 
 type WithdrawBalance struct {
-	Order       string    `json:"order" db:"order_number"`
-	Sum         float64   `json:"sum" db:"sum"`
-	ProcessedAt time.Time `json:"processed_at" db:"processed_at"`
+	Order       string       `json:"order" db:"order_number"`
+	Sum         money.Amount `json:"sum" db:"sum"`
+	ProcessedAt time.Time    `json:"processed_at" db:"processed_at"`
+	// Status - PROCESSED, после возвратов PARTIALLY_REFUNDED или REFUNDED
+	Status   string             `json:"status,omitempty" db:"-"`
+	Refunded money.Amount       `json:"refunded,omitempty" db:"-"`
+	Refunds  []WithdrawalRefund `json:"refunds,omitempty" db:"-"`
+	// IdempotencyKey - ключ из заголовка Idempotency-Key, ответ сохраняется вместе со списанием
+	IdempotencyKey string `json:"-" db:"-"`
 }
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go-musthave-diploma-tpl/pkg/money"
+)
+
+// политики сгорания баллов
+const (
+	// PointsExpiryNone - баллы не сгорают
+	PointsExpiryNone = "none"
+	// PointsExpiryFixed - каждая партия сгорает через Lifetime после начисления
+	PointsExpiryFixed = "fixed"
+	// PointsExpiryRolling - все баллы сгорают через Lifetime без начислений и списаний, любое из них продлевает срок
+	PointsExpiryRolling = "rolling"
+)
+
+// PointsExpiry - политика сгорания баллов
+type PointsExpiry struct {
+	Policy   string
+	Lifetime time.Duration
+}
+
+func (p PointsExpiry) Enabled() bool {
+	return (p.Policy == PointsExpiryFixed || p.Policy == PointsExpiryRolling) && p.Lifetime > 0
+}
+
+// ExpiringPoints - сколько баллов сгорит в указанный момент
+type ExpiringPoints struct {
+	Amount    money.Amount `json:"amount" db:"amount"`
+	ExpiresAt time.Time    `json:"expires_at" db:"expires_at"`
+}
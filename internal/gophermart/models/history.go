@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"go-musthave-diploma-tpl/pkg/money"
+)
+
+// BalanceHistoryEntry - движение баллов в истории пользователя: проводка журнала без служебных полей
+type BalanceHistoryEntry struct {
+	ID           int64        `json:"id"`
+	Type         string       `json:"type"`
+	Amount       money.Amount `json:"amount"`
+	BalanceAfter money.Amount `json:"balance_after"`
+	OrderNumber  string       `json:"order,omitempty"`
+	Note         string       `json:"note,omitempty"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+// BalanceHistory - страница истории, новые движения первыми. NextCursor пустой на последней странице
+type BalanceHistory struct {
+	Entries    []BalanceHistoryEntry `json:"entries"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+// BalanceHistoryRequest - параметры страницы истории. Пустые поля - без ограничения
+type BalanceHistoryRequest struct {
+	Cursor string
+	// [From, To) по времени проводки
+	From  *time.Time
+	To    *time.Time
+	Types []string
+	Limit int
+}
+
+// BalanceHistoryFilter - выборка проводок пользователя: BeforeID > 0 - только проводки раньше неё
+type BalanceHistoryFilter struct {
+	UserID   int
+	BeforeID int64
+	From     *time.Time
+	To       *time.Time
+	Types    []string
+	Limit    int
+}
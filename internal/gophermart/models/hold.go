@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go-musthave-diploma-tpl/pkg/money"
+)
+
+// статусы резерва баллов
+const (
+	HoldStatusActive   = "active"
+	HoldStatusCaptured = "captured"
+	HoldStatusVoided   = "voided"
+	HoldStatusExpired  = "expired"
+)
+
+// Hold - баллы, зарезервированные под заказ до подтверждения оплаты
+type Hold struct {
+	ID        int          `json:"id" db:"id"`
+	UserID    int          `json:"-" db:"user_id"`
+	Order     string       `json:"order" db:"order_number"`
+	Amount    money.Amount `json:"amount" db:"amount"`
+	Status    string       `json:"status" db:"status"`
+	CreatedAt time.Time    `json:"created_at" db:"created_at"`
+	ExpiresAt time.Time    `json:"expires_at" db:"expires_at"`
+	ClosedAt  *time.Time   `json:"closed_at,omitempty" db:"closed_at"`
+}
+
+type CreateHoldRequest struct {
+	Order  string       `json:"order"`
+	Amount money.Amount `json:"amount"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// области ключей идемпотентности: один и тот же ключ в разных операциях независим
+const (
+	IdempotencyScopeWithdraw = "withdraw"
+	IdempotencyScopeTransfer = "transfer"
+)
+
+// IdempotencyKeyMaxLen - длина заголовка Idempotency-Key
+const IdempotencyKeyMaxLen = 255
+
+// IdempotentResponse - сохранённый ответ, который повторяется на запросы с тем же ключом
+type IdempotentResponse struct {
+	StatusCode int    `db:"status_code"`
+	Body       string `db:"response_body"`
+}
+
+// WithdrawSucceeded - ответ на успешное списание, сохраняется в одной транзакции со списанием
+var WithdrawSucceeded = IdempotentResponse{StatusCode: 200, Body: "{}"}
+
+// IdempotencyKey - ключ идемпотентности пользователя. Response == nil - запрос ещё выполняется
+type IdempotencyKey struct {
+	UserID      int                 `db:"user_id"`
+	Scope       string              `db:"scope"`
+	Key         string              `db:"idem_key"`
+	Fingerprint string              `db:"fingerprint"`
+	Response    *IdempotentResponse `db:"-"`
+	CreatedAt   time.Time           `db:"created_at"`
+	ExpiresAt   time.Time           `db:"expires_at"`
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"go-musthave-diploma-tpl/pkg/money"
+)
+
+// типы проводок журнала баллов
+const (
+	LedgerEntryAccrual    = "accrual"
+	LedgerEntryWithdrawal = "withdrawal"
+	LedgerEntryReversal   = "reversal"
+	LedgerEntryAdjustment = "adjustment"
+	LedgerEntryExpiration = "expiration"
+	LedgerEntryTransfer   = "transfer"
+	LedgerEntryReferral   = "referral"
+	LedgerEntryPromo      = "promo"
+)
+
+// LedgerEntryTypes - все типы проводок, для проверки фильтров
+var LedgerEntryTypes = []string{
+	LedgerEntryAccrual, LedgerEntryWithdrawal, LedgerEntryReversal, LedgerEntryAdjustment,
+	LedgerEntryExpiration, LedgerEntryTransfer, LedgerEntryReferral, LedgerEntryPromo,
+}
+
+// корреспондирующие системные счета
+const (
+	LedgerAccountAccruals    = "accruals"
+	LedgerAccountRedemptions = "redemptions"
+	LedgerAccountAdjustments = "adjustments"
+	LedgerAccountExpirations = "expirations"
+	LedgerAccountTransfers   = "transfers"
+	LedgerAccountReferrals   = "referrals"
+	LedgerAccountPromotions  = "promotions"
+)
+
+// LedgerEntry - проводка: движение баллов между счётом пользователя и системным счётом.
+// Amount > 0 - приход на счёт пользователя, Amount < 0 - расход
+type LedgerEntry struct {
+	ID              int64        `json:"id" db:"id"`
+	UserID          int          `json:"-" db:"user_id"`
+	Type            string       `json:"type" db:"entry_type"`
+	Amount          money.Amount `json:"amount" db:"amount"`
+	BalanceAfter    money.Amount `json:"balance_after" db:"balance_after"`
+	ContraAccount   string       `json:"contra_account" db:"contra_account"`
+	OrderNumber     string       `json:"order,omitempty" db:"order_number"`
+	WithdrawalID    *int         `json:"-" db:"withdrawal_id"`
+	ReversesEntryID *int64       `json:"reverses_entry_id,omitempty" db:"reverses_entry_id"`
+	Note            string       `json:"note,omitempty" db:"note"`
+	CreatedAt       time.Time    `json:"created_at" db:"created_at"`
+
+	// LotsFromEntryID - приход повторяет сроки партий, погашенных этой проводкой расхода (перевод)
+	LotsFromEntryID *int64 `json:"-" db:"-"`
+}
+
+// LedgerMismatch - расхождение журнала с остатком счёта, заказами или списаниями
+type LedgerMismatch struct {
+	UserID   int          `json:"user_id"`
+	Check    string       `json:"check"`
+	Expected money.Amount `json:"expected"`
+	Actual   money.Amount `json:"actual"`
+}
+
+// проверки сверки журнала
+const (
+	LedgerCheckBalance     = "account_balance"
+	LedgerCheckWithdrawn   = "account_withdrawn"
+	LedgerCheckAccruals    = "accruals_vs_orders"
+	LedgerCheckWithdrawals = "withdrawals_vs_ledger"
+)
+
+// AdjustBalanceRequest - ручная корректировка баланса администратором
+type AdjustBalanceRequest struct {
+	Amount money.Amount `json:"amount"`
+	Note   string       `json:"note"`
+}
+
+// ReverseEntryRequest - сторно проводки, нулевая сумма - на весь остаток
+type ReverseEntryRequest struct {
+	Amount money.Amount `json:"amount"`
+	Note   string       `json:"note"`
+}
@@ -0,0 +1,7 @@
+package models
+
+// области учёта неудачных попыток входа
+const (
+	LoginAttemptScopeLogin = "login"
+	LoginAttemptScopeIP    = "ip"
+)
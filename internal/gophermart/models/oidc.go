@@ -0,0 +1,15 @@
+package models
+
+// UserIdentity - привязка внешней учётной записи провайдера OpenID Connect к пользователю
+type UserIdentity struct {
+	UserID  int    `json:"-" db:"user_id"`
+	Issuer  string `json:"issuer" db:"issuer"`
+	Subject string `json:"subject" db:"subject"`
+	Email   string `json:"email" db:"email"`
+}
+
+// OIDCLoginState - то, что нужно сохранить между редиректом к провайдеру и callback
+type OIDCLoginState struct {
+	CodeVerifier string
+	Nonce        string
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// порядок заказов по времени загрузки
+const (
+	OrdersSortDesc = "desc"
+	OrdersSortAsc  = "asc"
+)
+
+// OrdersPage - страница заказов. Отдаётся вместо массива, только если клиент передал параметры выборки
+type OrdersPage struct {
+	Orders     []Order `json:"orders"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// OrdersRequest - параметры страницы заказов. Пустые поля - без ограничения, Sort по умолчанию desc
+type OrdersRequest struct {
+	Cursor   string
+	Statuses []string
+	// [From, To) по времени загрузки
+	From  *time.Time
+	To    *time.Time
+	Sort  string
+	Limit int
+}
+
+// OrdersFilter - выборка заказов пользователя. AfterUploadedAt и AfterUID - ключ последнего заказа
+// предыдущей страницы, следующая начинается строго после него в порядке сортировки
+type OrdersFilter struct {
+	UserID          int
+	Statuses        []string
+	From            *time.Time
+	To              *time.Time
+	Asc             bool
+	AfterUploadedAt *time.Time
+	AfterUID        int
+	Limit           int
+}
@@ -2,15 +2,17 @@ package models
 
 import (
 	"time"
+
+	"go-musthave-diploma-tpl/pkg/money"
 )
 
 type Order struct {
-	UID        int       `json:"-" db:"uid"`
-	UserID     int       `json:"-" db:"user_id"`
-	Number     string    `json:"number" db:"number"`
-	Status     string    `json:"status" db:"status"`
-	Accrual    float64   `json:"accrual,omitempty" db:"accrual"`
-	UploadedAt time.Time `json:"uploaded_at" db:"uploaded_at"`
+	UID        int          `json:"-" db:"uid"`
+	UserID     int          `json:"-" db:"user_id"`
+	Number     string       `json:"number" db:"number"`
+	Status     string       `json:"status" db:"status"`
+	Accrual    money.Amount `json:"accrual,omitempty" db:"accrual"`
+	UploadedAt time.Time    `json:"uploaded_at" db:"uploaded_at"`
 }
 
 // статусы заказов
@@ -20,3 +22,6 @@ const (
 	OrderStatusInvalid    = "INVALID"
 	OrderStatusProcessed  = "PROCESSED"
 )
+
+// OrderStatuses - все статусы заказа, для проверки фильтров
+var OrderStatuses = []string{OrderStatusNew, OrderStatusProcessing, OrderStatusInvalid, OrderStatusProcessed}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"go-musthave-diploma-tpl/pkg/money"
+)
+
+// виды промокодов
+const (
+	// PromoKindPoints - разовое начисление Points баллов
+	PromoKindPoints = "points"
+	// PromoKindMultiplier - начисление следующих Orders обработанных заказов умножается на Multiplier
+	PromoKindMultiplier = "multiplier"
+)
+
+// PromoCode - промокод маркетинговой кампании
+type PromoCode struct {
+	ID   int    `json:"id" db:"id"`
+	Code string `json:"code" db:"code"`
+	Kind string `json:"kind" db:"kind"`
+	// Points - начисление для PromoKindPoints
+	Points money.Amount `json:"points,omitempty" db:"points"`
+	// Multiplier и Orders - для PromoKindMultiplier, 1.5 хранится как 150 сотых
+	Multiplier money.Amount `json:"multiplier,omitempty" db:"multiplier"`
+	Orders     int          `json:"orders,omitempty" db:"orders"`
+	// погасить код можно с StartsAt до EndsAt, EndsAt == nil - бессрочно
+	StartsAt time.Time  `json:"starts_at" db:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at,omitempty" db:"ends_at"`
+	// MaxRedemptions - погашений всего, 0 - без ограничения; PerUserLimit - погашений одним пользователем
+	MaxRedemptions int        `json:"max_redemptions" db:"max_redemptions"`
+	PerUserLimit   int        `json:"per_user_limit" db:"per_user_limit"`
+	Redemptions    int        `json:"redemptions" db:"redemptions"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	DisabledAt     *time.Time `json:"disabled_at,omitempty" db:"disabled_at"`
+}
+
+// ActiveAt - код можно погасить в момент t (без учёта лимитов)
+func (p PromoCode) ActiveAt(t time.Time) bool {
+	return p.DisabledAt == nil && !t.Before(p.StartsAt) && (p.EndsAt == nil || t.Before(*p.EndsAt))
+}
+
+// CreatePromoCodeRequest - новый промокод. Пустой StartsAt - действует сразу, PerUserLimit 0 - один раз на пользователя
+type CreatePromoCodeRequest struct {
+	Code           string       `json:"code"`
+	Kind           string       `json:"kind"`
+	Points         money.Amount `json:"points"`
+	Multiplier     money.Amount `json:"multiplier"`
+	Orders         int          `json:"orders"`
+	StartsAt       *time.Time   `json:"starts_at"`
+	EndsAt         *time.Time   `json:"ends_at"`
+	MaxRedemptions int          `json:"max_redemptions"`
+	PerUserLimit   int          `json:"per_user_limit"`
+}
+
+type RedeemPromoRequest struct {
+	Code string `json:"code"`
+}
+
+// PromoRedemption - погашение промокода пользователем
+type PromoRedemption struct {
+	ID     int64        `json:"id" db:"id"`
+	Code   string       `json:"code" db:"code"`
+	Kind   string       `json:"kind" db:"kind"`
+	Points money.Amount `json:"points,omitempty" db:"points"`
+	// Multiplier и OrdersLeft - сколько ещё заказов будет умножено
+	Multiplier    money.Amount `json:"multiplier,omitempty" db:"multiplier"`
+	OrdersLeft    int          `json:"orders_left,omitempty" db:"orders_left"`
+	LedgerEntryID *int64       `json:"ledger_entry_id,omitempty" db:"ledger_entry_id"`
+	CreatedAt     time.Time    `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"go-musthave-diploma-tpl/pkg/money"
+)
+
+// статусы реферального бонуса
+const (
+	// ReferralStatusPending - приглашённый ещё не сделал обработанного заказа
+	ReferralStatusPending  = "pending"
+	ReferralStatusRewarded = "rewarded"
+)
+
+// Referral - приглашённый пользователь глазами пригласившего
+type Referral struct {
+	// Login - логин приглашённого, скрытый маской
+	Login        string        `json:"login" db:"login"`
+	Status       string        `json:"status" db:"-"`
+	Bonus        *money.Amount `json:"bonus,omitempty" db:"bonus"`
+	RegisteredAt time.Time     `json:"registered_at" db:"created_at"`
+	RewardedAt   *time.Time    `json:"rewarded_at,omitempty" db:"rewarded_at"`
+}
+
+// ReferralsResponse - реферальный код пользователя и приглашённые по нему
+type ReferralsResponse struct {
+	Code      string     `json:"code"`
+	Referrals []Referral `json:"referrals"`
+}
+
+// ReferralLimits - защита от приглашения самого себя вторым аккаунтом, ноль - без ограничения
+type ReferralLimits struct {
+	// DailyCount - сколько приглашённых пользователь может привести за сутки
+	DailyCount int
+	// AddressWindow - сколько помнить адреса пригласившего и его приглашённых: регистрация по коду
+	// с такого адреса отклоняется
+	AddressWindow time.Duration
+}
+
+// ReferralSignup - регистрация по реферальному коду: код, адрес клиента и ограничения пригласившего
+type ReferralSignup struct {
+	Code   string
+	IP     string
+	Limits ReferralLimits
+}
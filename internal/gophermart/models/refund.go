@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go-musthave-diploma-tpl/pkg/money"
+)
+
+// статусы списания по возвратам
+const (
+	WithdrawalStatusProcessed         = "PROCESSED"
+	WithdrawalStatusPartiallyRefunded = "PARTIALLY_REFUNDED"
+	WithdrawalStatusRefunded          = "REFUNDED"
+)
+
+// кто оформил возврат
+const (
+	RefundSourceStaff   = "staff"
+	RefundSourcePartner = "partner"
+)
+
+// WithdrawalRefund - возврат баллов по списанию
+type WithdrawalRefund struct {
+	ID          int          `json:"id" db:"id"`
+	Order       string       `json:"order" db:"order_number"`
+	Amount      money.Amount `json:"amount" db:"amount"`
+	Reason      string       `json:"reason,omitempty" db:"reason"`
+	ProcessedAt time.Time    `json:"processed_at" db:"created_at"`
+
+	UserID   int    `json:"-" db:"user_id"`
+	Source   string `json:"-" db:"source"`
+	ActorID  *int   `json:"-" db:"actor_id"`
+	APIKeyID *int   `json:"-" db:"api_key_id"`
+}
+
+// RefundRequest - сумма возврата, 0 - весь ещё не возвращённый остаток списания
+type RefundRequest struct {
+	Amount money.Amount `json:"amount"`
+	Reason string       `json:"reason"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Session - серверная сессия пользователя, её идентификатор зашифрован в куке
+type Session struct {
+	ID         int       `json:"id" db:"id"`
+	UserID     int       `json:"-" db:"user_id"`
+	UserAgent  string    `json:"user_agent" db:"user_agent"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at" db:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+	Current    bool      `json:"current" db:"-"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go-musthave-diploma-tpl/pkg/money"
+)
+
+// форматы выписки
+const (
+	StatementFormatCSV = "csv"
+	StatementFormatPDF = "pdf"
+)
+
+// виды строк выписки. Остальные проводки журнала (переводы, бонусы, корректировки, сгорание,
+// сторно) попадают в выписку под своим типом проводки
+const (
+	StatementLineAccrual    = LedgerEntryAccrual
+	StatementLineWithdrawal = LedgerEntryWithdrawal
+	// StatementLineRefund - возврат баллов по списанию (сторно проводки списания)
+	StatementLineRefund = "refund"
+)
+
+// StatementHeader - шапка выписки за период [From, To)
+type StatementHeader struct {
+	Login   string
+	From    time.Time
+	To      time.Time
+	Opening money.Amount
+}
+
+// StatementLine - проводка журнала баллов. Amount со знаком, Balance - остаток после строки
+type StatementLine struct {
+	Date    time.Time
+	Type    string
+	Order   string
+	Amount  money.Amount
+	Balance money.Amount
+}
+
+// StatementSummary - итоги за период. Other - все прочие движения со знаком,
+// поэтому Opening + Accrued - Withdrawn + Refunded + Other = Closing
+type StatementSummary struct {
+	Accrued   money.Amount
+	Withdrawn money.Amount
+	Refunded  money.Amount
+	Other     money.Amount
+	Closing   money.Amount
+}
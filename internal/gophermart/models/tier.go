@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+
+	"go-musthave-diploma-tpl/pkg/money"
+)
+
+// уровни программы лояльности по умолчанию
+const (
+	TierBronze = "bronze"
+	TierSilver = "silver"
+	TierGold   = "gold"
+)
+
+// Tier - уровень: с какой суммы начислений за окно он присваивается и во сколько раз увеличивает начисления
+type Tier struct {
+	Name      string       `json:"name"`
+	Threshold money.Amount `json:"threshold"`
+	// Multiplier - множитель с точностью до сотых: 1.25 - начисления больше на четверть
+	Multiplier money.Amount `json:"multiplier"`
+}
+
+// Apply - начисление с учётом множителя уровня
+func (t Tier) Apply(accrual money.Amount) money.Amount {
+	return accrual.MulDiv(t.Multiplier.Cents(), 100, money.HalfEven)
+}
+
+// TierPolicy - уровни по возрастанию порога, окно подсчёта начислений и период пересмотра уровня
+type TierPolicy struct {
+	Tiers  []Tier
+	Window time.Duration
+	Review time.Duration
+}
+
+func (p TierPolicy) Enabled() bool {
+	return len(p.Tiers) > 0
+}
+
+// ForEarned - уровень для суммы начислений за окно и следующий за ним, nil - уровень высший
+func (p TierPolicy) ForEarned(earned money.Amount) (Tier, *Tier) {
+	current := 0
+	for i, t := range p.Tiers {
+		if earned >= t.Threshold {
+			current = i
+		}
+	}
+	if current+1 < len(p.Tiers) {
+		return p.Tiers[current], &p.Tiers[current+1]
+	}
+	return p.Tiers[current], nil
+}
+
+// ByName - уровень по названию. Уровня нет в настройках (переименовали) - первый, без множителя
+func (p TierPolicy) ByName(name string) Tier {
+	for _, t := range p.Tiers {
+		if t.Name == name {
+			return t
+		}
+	}
+	if len(p.Tiers) > 0 && name == "" {
+		return p.Tiers[0]
+	}
+	return Tier{Name: name, Multiplier: money.FromUnits(1)}
+}
+
+// UserTier - присвоенный пользователю уровень и сумма начислений, по которой он посчитан
+type UserTier struct {
+	UserID           int          `db:"user_id"`
+	Tier             string       `db:"tier"`
+	Earned           money.Amount `db:"earned"`
+	EvaluatedAt      time.Time    `db:"evaluated_at"`
+	NextEvaluationAt time.Time    `db:"next_evaluation_at"`
+}
+
+// TierStatus - ответ GET /api/user/tier
+type TierStatus struct {
+	Tier       string       `json:"tier"`
+	Multiplier money.Amount `json:"multiplier"`
+	// Earned - начисления за окно на момент последнего пересмотра
+	Earned           money.Amount  `json:"earned"`
+	Next             *TierProgress `json:"next,omitempty"`
+	EvaluatedAt      time.Time     `json:"evaluated_at"`
+	NextEvaluationAt time.Time     `json:"next_evaluation_at"`
+}
+
+// TierProgress - сколько осталось до следующего уровня
+type TierProgress struct {
+	Tier      string       `json:"tier"`
+	Threshold money.Amount `json:"threshold"`
+	Remaining money.Amount `json:"remaining"`
+	// Percent - пройденная часть пути от порога текущего уровня до следующего, 0-100
+	Percent int `json:"percent"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// TOTP - настройки второго фактора пользователя
+type TOTP struct {
+	UserID      int    `json:"-" db:"user_id"`
+	Secret      string `json:"-" db:"secret"`
+	Enabled     bool   `json:"enabled" db:"enabled"`
+	LastCounter int64  `json:"-" db:"last_counter"`
+}
+
+type TOTPSetupResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type TOTPCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type TOTPConfirmResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// LoginRequest - вход по паролю, либо второй шаг: challenge из первого ответа и код
+type LoginRequest struct {
+	Login     string `json:"login"`
+	Password  string `json:"password"`
+	Challenge string `json:"challenge,omitempty"`
+	Code      string `json:"code,omitempty"`
+}
+
+// LoginChallenge - ответ на первый шаг входа, когда включена 2FA
+type LoginChallenge struct {
+	Status    string    `json:"status"`
+	Challenge string    `json:"challenge"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// LoginChallengeStatusTOTP - статус незавершённого входа
+const LoginChallengeStatusTOTP = "totp_required"
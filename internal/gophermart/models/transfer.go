@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"go-musthave-diploma-tpl/pkg/money"
+)
+
+// направление перевода для пользователя, который смотрит историю
+const (
+	TransferDirectionOut = "out"
+	TransferDirectionIn  = "in"
+)
+
+// TransferNoteMaxLen - длина комментария к переводу в символах
+const TransferNoteMaxLen = 255
+
+// Transfer - перевод баллов другому пользователю, глазами одного из участников
+type Transfer struct {
+	ID        int64  `json:"id" db:"id"`
+	Direction string `json:"direction" db:"-"`
+	// Counterparty - логин второго участника
+	Counterparty string       `json:"counterparty" db:"-"`
+	Amount       money.Amount `json:"amount" db:"amount"`
+	Note         string       `json:"note,omitempty" db:"note"`
+	CreatedAt    time.Time    `json:"created_at" db:"created_at"`
+
+	FromUserID int `json:"-" db:"from_user_id"`
+	ToUserID   int `json:"-" db:"to_user_id"`
+	// IdempotencyKey - ключ из заголовка Idempotency-Key, ответ сохраняется вместе с переводом
+	IdempotencyKey string `json:"-" db:"-"`
+}
+
+// TransferRequest - перевод на логин получателя
+type TransferRequest struct {
+	To     string       `json:"to"`
+	Amount money.Amount `json:"amount"`
+	Note   string       `json:"note"`
+}
+
+// TransferLimits - ограничения отправителя за последние сутки, ноль - без ограничения
+type TransferLimits struct {
+	DailyAmount money.Amount
+	DailyCount  int
+}
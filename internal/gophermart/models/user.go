@@ -2,16 +2,44 @@ package models
 
 import (
 	"time"
+
+	"go-musthave-diploma-tpl/pkg/password"
 )
 
 type RegisterRequest struct {
 	Login    string `json:"login"`
 	Password string `json:"password"`
+	// ReferralCode - код пригласившего пользователя, необязательный
+	ReferralCode string `json:"referral_code,omitempty"`
 }
 
+// роли пользователей
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+)
+
 type User struct {
-	ID           int       `json:"id" db:"id"`
-	Login        string    `json:"login" db:"login"`
-	PasswordHash string    `json:"-" db:"password_hash"`
-	CreatedAt    time.Time `json:"-" db:"created_at"`
+	ID           int        `json:"id" db:"id"`
+	Login        string     `json:"login" db:"login"`
+	PasswordHash string     `json:"-" db:"password_hash"`
+	Role         string     `json:"role" db:"role"`
+	BlockedAt    *time.Time `json:"blocked_at,omitempty" db:"blocked_at"`
+	CreatedAt    time.Time  `json:"-" db:"created_at"`
+}
+
+func (u *User) Blocked() bool {
+	return u.BlockedAt != nil
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// PasswordPolicyResponse - ответ 400 со списком нарушенных правил политики паролей
+type PasswordPolicyResponse struct {
+	Error      string               `json:"error"`
+	Violations []password.Violation `json:"violations"`
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"strings"
 
+	pgconnv4 "github.com/jackc/pgconn"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
 )
@@ -28,19 +29,25 @@ func (c *PostgresErrorClassifier) Classify(err error) ErrorClassification {
 
 	var pgErr *pgconn.PgError
 	if errors.As(err, &pgErr) {
-		return c.classifyPostgresError(pgErr)
+		return c.classifyPostgresError(pgErr.Code)
+	}
+
+	// database/sql работает через pgx/v4, его ошибки - из github.com/jackc/pgconn
+	var pgErrV4 *pgconnv4.PgError
+	if errors.As(err, &pgErrV4) {
+		return c.classifyPostgresError(pgErrV4.Code)
 	}
 
 	return NonRetriable
 }
 
-func (c *PostgresErrorClassifier) classifyPostgresError(pgErr *pgconn.PgError) ErrorClassification {
-	if strings.HasPrefix(pgErr.Code, "08") {
+func (c *PostgresErrorClassifier) classifyPostgresError(code string) ErrorClassification {
+	if strings.HasPrefix(code, "08") {
 		return Retriable
 	}
 
 	// Другие повторяемые ошибки PostgreSQL
-	switch pgErr.Code {
+	switch code {
 	case pgerrcode.SerializationFailure,
 		pgerrcode.DeadlockDetected,
 		pgerrcode.AdminShutdown,
@@ -0,0 +1,50 @@
+// Package pgtest - настоящая база Gophermart для интеграционных тестов
+package pgtest
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratePostgres "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	_ "github.com/jackc/pgx/v4/stdlib"
+	"github.com/stretchr/testify/require"
+)
+
+// EnvDatabaseURI - переменная с адресом тестовой базы
+const EnvDatabaseURI = "TEST_DATABASE_URI"
+
+// Open - база из TEST_DATABASE_URI с применёнными миграциями. Без переменной тест пропускается.
+// Данные между тестами не чистятся: тесты заводят своих пользователей с уникальными логинами
+func Open(t testing.TB) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv(EnvDatabaseURI)
+	if dsn == "" {
+		t.Skip(EnvDatabaseURI + " is not set")
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	driver, err := migratePostgres.WithInstance(db, &migratePostgres.Config{
+		MigrationsTable: "gophermart_schema_migrations",
+	})
+	require.NoError(t, err)
+	m, err := migrate.NewWithDatabaseInstance("file://"+migrationsDir(), "postgres", driver)
+	require.NoError(t, err)
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+	return db
+}
+
+// migrationsDir - каталог миграций относительно этого файла, тесты запускаются из разных пакетов
+func migrationsDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "..", "migrations")
+}
@@ -1,19 +1,26 @@
 package postgres
 
 import (
-	"crypto/sha256"
+	"context"
 	"database/sql"
-	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	db "go-musthave-diploma-tpl/internal/gophermart/config/db"
 	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/ledger"
 	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/pkg/money"
 	logger "go-musthave-diploma-tpl/pkg/runtime/logger"
+	"strings"
+	"time"
 )
 
 var castomLogger = logger.NewHTTPLogger().Sugar()
 
+// sessionTouchInterval - как часто обновлять last_seen_at сессии
+const sessionTouchInterval = time.Minute
+
 type PostgresStorage struct {
 	DB              *sql.DB
 	errorClassifier *PostgresErrorClassifier
@@ -26,11 +33,6 @@ func New() *PostgresStorage {
 	}
 }
 
-func HashPassword(password string) string {
-	hash := sha256.Sum256([]byte(password))
-	return hex.EncodeToString(hash[:])
-}
-
 func (ps *PostgresStorage) GetUserByLogin(login string) (*models.User, error) {
 	var user models.User
 
@@ -38,10 +40,12 @@ func (ps *PostgresStorage) GetUserByLogin(login string) (*models.User, error) {
 					id, 
 					login, 
 					password_hash, 
+					role, 
+					blocked_at, 
 					created_at 
 				FROM users 
 				WHERE login = $1`
-	err := ps.DB.QueryRow(query, login).Scan(&user.ID, &user.Login, &user.PasswordHash, &user.CreatedAt)
+	err := scanUser(ps.DB.QueryRow(query, login), &user)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -54,14 +58,9 @@ func (ps *PostgresStorage) GetUserByLogin(login string) (*models.User, error) {
 
 func (ps *PostgresStorage) GetUserByID(id int) (*models.User, error) {
 	var user models.User
-	query := `SELECT id, login, password_hash, created_at FROM users WHERE id = $1`
+	query := `SELECT id, login, password_hash, role, blocked_at, created_at FROM users WHERE id = $1`
 
-	err := ps.DB.QueryRow(query, id).Scan(
-		&user.ID,
-		&user.Login,
-		&user.PasswordHash,
-		&user.CreatedAt,
-	)
+	err := scanUser(ps.DB.QueryRow(query, id), &user)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -74,31 +73,25 @@ func (ps *PostgresStorage) GetUserByID(id int) (*models.User, error) {
 	return &user, nil
 }
 
-func (ps *PostgresStorage) GetUserByLoginAndPassword(login, password string) (*models.User, error) {
-	var user models.User
-	hashedPassword := HashPassword(password)
-
-	query := `SELECT 
-					id, 
-					login, 
-					password_hash, 
-					created_at 
-				FROM users 
-				WHERE login = $1 
-					AND password_hash = $2`
-	err := ps.DB.QueryRow(query, login, hashedPassword).Scan(&user.ID, &user.Login, &user.PasswordHash, &user.CreatedAt)
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
-	if err != nil {
-		castomLogger.Infof("failed to get user by login and password: %v", err)
-		return nil, fmt.Errorf("failed to get user by login and password: %w", err)
-	}
+// scanner - общее у *sql.Row и *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
 
-	return &user, nil
+// scanUser читает колонки id, login, password_hash, role, blocked_at, created_at
+func scanUser(row scanner, user *models.User) error {
+	return row.Scan(
+		&user.ID,
+		&user.Login,
+		&user.PasswordHash,
+		&user.Role,
+		&user.BlockedAt,
+		&user.CreatedAt,
+	)
 }
 
-func (ps *PostgresStorage) CreateUser(login, password string) (*models.User, error) {
+// CreateUser - создание пользователя, пароль приходит уже захэшированным сервисом
+func (ps *PostgresStorage) CreateUser(login, passwordHash string) (*models.User, error) {
 	existingUser, err := ps.GetUserByLogin(login)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check user existence: %w", err)
@@ -108,19 +101,12 @@ func (ps *PostgresStorage) CreateUser(login, password string) (*models.User, err
 		return nil, errors.New("login already exists")
 	}
 
-	hashedPassword := HashPassword(password)
-
 	var user models.User
 	query := `INSERT INTO users (login, password_hash) 
               VALUES ($1, $2) 
-              RETURNING id, login, password_hash, created_at`
+              RETURNING id, login, password_hash, role, blocked_at, created_at`
 
-	err = ps.DB.QueryRow(query, login, hashedPassword).Scan(
-		&user.ID,
-		&user.Login,
-		&user.PasswordHash,
-		&user.CreatedAt,
-	)
+	err = scanUser(ps.DB.QueryRow(query, login, passwordHash), &user)
 
 	if err != nil {
 		castomLogger.Infof("failed to create user: %v", err)
@@ -130,6 +116,62 @@ func (ps *PostgresStorage) CreateUser(login, password string) (*models.User, err
 	return &user, nil
 }
 
+// UpdatePasswordHash - замена хэша пароля, используется при перехэшировании при входе
+func (ps *PostgresStorage) UpdatePasswordHash(userID int, passwordHash string) error {
+	res, err := ps.DB.Exec(`UPDATE users SET password_hash = $1 WHERE id = $2`, passwordHash, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update password hash: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update password hash: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("user not found id=%d", userID)
+	}
+
+	return nil
+}
+
+// ChangePassword меняет хэш пароля и в той же транзакции отзывает все сессии
+// и refresh-токены пользователя, кроме сессии keepSessionID (0 - отозвать все)
+func (ps *PostgresStorage) ChangePassword(userID int, passwordHash string, keepSessionID int) error {
+	tx, err := ps.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE users SET password_hash = $1 WHERE id = $2`, passwordHash, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update password hash: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update password hash: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("user not found id=%d", userID)
+	}
+
+	if _, err := tx.Exec(`
+        UPDATE sessions SET revoked_at = NOW()
+        WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+    `, userID, keepSessionID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+        UPDATE refresh_tokens SET revoked_at = NOW()
+        WHERE user_id = $1 AND revoked_at IS NULL
+    `, userID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	return tx.Commit()
+}
+
 func (ps *PostgresStorage) CreateOrder(userID int, orderNumber string) error {
 	query := `
         WITH inserted AS (
@@ -195,32 +237,18 @@ func (ps *PostgresStorage) GetOrders(userID int) ([]models.Order, error) {
 	return orders, nil
 }
 
+// GetBalance - остаток и сумма списаний из строки счёта, пересчёт по истории не нужен
 func (ps *PostgresStorage) GetBalance(userID int) (models.Balance, error) {
 	var balance models.Balance
 
-	query := `
-        SELECT
-            COALESCE((
-                SELECT SUM(accrual)
-                FROM orders
-                WHERE user_id = $1 AND status = 'PROCESSED'
-            ), 0)
-            -
-            COALESCE((
-                SELECT SUM(sum)
-                FROM withdrawals
-                WHERE user_id = $1
-            ), 0) AS current,
-            COALESCE((
-                SELECT SUM(sum)
-                FROM withdrawals
-                WHERE user_id = $1
-            ), 0) AS withdrawn
-    `
-
-	err := ps.DB.QueryRow(query, userID).Scan(&balance.Current, &balance.Withdrawn)
+	err := ps.DB.QueryRow(`
+        SELECT balance - held AS current, held, withdrawn
+        FROM accounts
+        WHERE user_id = $1
+    `, userID).Scan(&balance.Current, &balance.Held, &balance.Withdrawn)
+	// счёт заводится первой проводкой
 	if err == sql.ErrNoRows {
-		return models.Balance{Current: 0, Withdrawn: 0}, nil
+		return models.Balance{}, nil
 	}
 	if err != nil {
 		return models.Balance{}, fmt.Errorf("failed to get balance: %w", err)
@@ -229,68 +257,162 @@ func (ps *PostgresStorage) GetBalance(userID int) (models.Balance, error) {
 	return balance, nil
 }
 
+// Withdraw пишет списание и проводку по нему в одной транзакции.
+// Остаток проверяется и уменьшается одним UPDATE строки счёта
 func (ps *PostgresStorage) Withdraw(userID int, withdraw models.WithdrawBalance) error {
-	tx, err := ps.DB.Begin()
+	ctx := context.Background()
+	// при конфликте с параллельной транзакцией списание повторяется целиком
+	return ps.inTx(ctx, nil, func(tx *sql.Tx) error {
+		return withdrawTx(ctx, tx, userID, withdraw)
+	})
+}
+
+func withdrawTx(ctx context.Context, tx *sql.Tx, userID int, withdraw models.WithdrawBalance) error {
+	if withdraw.IdempotencyKey != "" {
+		err := completeIdempotencyKey(ctx, tx, userID, models.IdempotencyScopeWithdraw, withdraw.IdempotencyKey, models.WithdrawSucceeded)
+		if err != nil {
+			return err
+		}
+	}
+
+	available, err := lockAvailable(ctx, tx, userID)
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback()
+	if err := checkPaymentOrder(ctx, tx, withdraw.Order); err != nil {
+		return err
+	}
+	if available < withdraw.Sum {
+		return handler.ErrLackOfFunds
+	}
 
-	// считаем баланс как в GetBalance
-	var balance float64
-	err = tx.QueryRow(`
-        SELECT 
-            COALESCE((
-                SELECT SUM(accrual) 
-                FROM orders 
-                WHERE user_id = $1 AND status = 'PROCESSED'
-            ), 0) 
-            - COALESCE((
-                SELECT SUM(sum) 
-                FROM withdrawals 
-                WHERE user_id = $1
-            ), 0) AS balance
-    `, userID).Scan(&balance)
+	_, err = insertWithdrawal(ctx, tx, userID, withdraw.Order, withdraw.Sum)
+	return err
+}
+
+// completeIdempotencyKey сохраняет ответ по ключу идемпотентности в той же транзакции, что и сама операция:
+// параллельный повтор ждёт блокировку строки ключа и после коммита не найдёт её незавершённой
+func completeIdempotencyKey(ctx context.Context, tx *sql.Tx, userID int, scope, key string, resp models.IdempotentResponse) error {
+	res, err := tx.ExecContext(ctx, `
+        UPDATE idempotency_keys SET status_code = $4, response_body = $5
+        WHERE user_id = $1 AND scope = $2 AND idem_key = $3 AND status_code IS NULL
+    `, userID, scope, key, resp.StatusCode, resp.Body)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return handler.ErrIdempotencyConflict
 	}
+	return nil
+}
 
-	if balance-withdraw.Sum < 0 {
-		return handler.ErrLackOfFunds
+// lockAvailable блокирует счёт до конца транзакции и возвращает сумму, которую можно потратить.
+// Списания и резервы одного пользователя выполняются по очереди: параллельный запрос ждёт
+// и видит уже уменьшенный остаток
+func lockAvailable(ctx context.Context, tx *sql.Tx, userID int) (money.Amount, error) {
+	var available money.Amount
+	err := tx.QueryRowContext(ctx, `
+        SELECT balance - held FROM accounts WHERE user_id = $1 FOR UPDATE
+    `, userID).Scan(&available)
+	// счёт заводится первой проводкой
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to lock account: %w", err)
+	}
+	return available, nil
+}
+
+// checkPaymentOrder - баллами оплачивается новый заказ: номер не должен быть заказом на начисление,
+// уже оплаченным заказом или заказом с действующим резервом
+func checkPaymentOrder(ctx context.Context, tx *sql.Tx, order string) error {
+	var accrualOrder, alreadyPaid, onHold bool
+	err := tx.QueryRowContext(ctx, `
+        SELECT
+            EXISTS (SELECT 1 FROM orders WHERE number = $1),
+            EXISTS (SELECT 1 FROM withdrawals WHERE order_number = $1),
+            EXISTS (SELECT 1 FROM balance_holds WHERE order_number = $1 AND status = 'active')
+    `, order).Scan(&accrualOrder, &alreadyPaid, &onHold)
+	if err != nil {
+		return fmt.Errorf("failed to check order number: %w", err)
+	}
+	switch {
+	case accrualOrder:
+		return handler.ErrAccrualOrderWithdrawal
+	case alreadyPaid:
+		return handler.ErrOrderAlreadyPaid
+	case onHold:
+		return handler.ErrOrderOnHold
 	}
+	return nil
+}
 
-	// просто пишем факт списания, без проверки, что заказ существует в orders
-	_, err = tx.Exec(`
+// insertWithdrawal пишет списание и проводку по нему
+func insertWithdrawal(ctx context.Context, tx *sql.Tx, userID int, order string, sum money.Amount) (int, error) {
+	// параллельную оплату того же заказа другим пользователем не пропустит уникальный индекс
+	var withdrawalID int
+	err := tx.QueryRowContext(ctx, `
         INSERT INTO withdrawals (user_id, order_number, sum)
         VALUES ($1, $2, $3)
-    `, userID, withdraw.Order, withdraw.Sum)
+        ON CONFLICT (order_number) DO NOTHING
+        RETURNING uid
+    `, userID, order, sum).Scan(&withdrawalID)
+	if err == sql.ErrNoRows {
+		return 0, handler.ErrOrderAlreadyPaid
+	}
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	return tx.Commit()
+	_, err = ledger.Post(ctx, tx, models.LedgerEntry{
+		UserID:       userID,
+		Type:         models.LedgerEntryWithdrawal,
+		Amount:       -sum,
+		OrderNumber:  order,
+		WithdrawalID: &withdrawalID,
+	})
+	if err != nil {
+		return 0, ledgerError(err)
+	}
+	return withdrawalID, nil
 }
 
 func (ps *PostgresStorage) Withdrawals(userID int) ([]models.WithdrawBalance, error) {
 	rows, err := ps.DB.Query(`	SELECT 
-									order_number,
-									sum,
-									processed_at
-								FROM withdrawals
-								WHERE user_id = $1
-								ORDER BY processed_at DESC
+									w.order_number,
+									w.sum,
+									w.processed_at,
+									COALESCE(r.refunded, 0)
+								FROM withdrawals w
+								LEFT JOIN (
+									SELECT withdrawal_id, SUM(amount) AS refunded
+									FROM withdrawal_refunds
+									GROUP BY withdrawal_id
+								) r ON r.withdrawal_id = w.uid
+								WHERE w.user_id = $1
+								ORDER BY w.processed_at DESC
 							`, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var withdrawals []models.WithdrawBalance
+	var (
+		withdrawals []models.WithdrawBalance
+		refunded    bool
+	)
 	for rows.Next() {
 		var w models.WithdrawBalance
-		if err := rows.Scan(&w.Order, &w.Sum, &w.ProcessedAt); err != nil {
+		if err := rows.Scan(&w.Order, &w.Sum, &w.ProcessedAt, &w.Refunded); err != nil {
 			return nil, err
 		}
+		switch {
+		case w.Refunded == 0:
+			w.Status = models.WithdrawalStatusProcessed
+		case w.Refunded < w.Sum:
+			w.Status = models.WithdrawalStatusPartiallyRefunded
+		default:
+			w.Status = models.WithdrawalStatusRefunded
+		}
+		refunded = refunded || w.Refunded > 0
 		withdrawals = append(withdrawals, w)
 	}
 
@@ -298,5 +420,2045 @@ func (ps *PostgresStorage) Withdrawals(userID int) ([]models.WithdrawBalance, er
 		return nil, err
 	}
 
+	// сами возвраты нужны, только если они есть
+	if refunded {
+		if err := ps.attachRefunds(userID, withdrawals); err != nil {
+			return nil, err
+		}
+	}
+
 	return withdrawals, nil
 }
+
+func (ps *PostgresStorage) attachRefunds(userID int, withdrawals []models.WithdrawBalance) error {
+	rows, err := ps.DB.Query(`
+        SELECT r.id, w.order_number, r.amount, r.reason, r.created_at
+        FROM withdrawal_refunds r
+        JOIN withdrawals w ON w.uid = r.withdrawal_id
+        WHERE r.user_id = $1
+        ORDER BY r.created_at, r.id
+    `, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get refunds: %w", err)
+	}
+	defer rows.Close()
+
+	byOrder := make(map[string]int, len(withdrawals))
+	for i, w := range withdrawals {
+		byOrder[w.Order] = i
+	}
+	for rows.Next() {
+		var r models.WithdrawalRefund
+		if err := rows.Scan(&r.ID, &r.Order, &r.Amount, &r.Reason, &r.ProcessedAt); err != nil {
+			return err
+		}
+		if i, ok := byOrder[r.Order]; ok {
+			withdrawals[i].Refunds = append(withdrawals[i].Refunds, r)
+		}
+	}
+	return rows.Err()
+}
+
+// CreateRefreshToken - сохранение хэша нового refresh-токена
+func (ps *PostgresStorage) CreateRefreshToken(userID int, tokenHash string, expiresAt time.Time) error {
+	_, err := ps.DB.Exec(`
+        INSERT INTO refresh_tokens (user_id, token_hash, expires_at)
+        VALUES ($1, $2, $3)
+    `, userID, tokenHash, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	return nil
+}
+
+// RotateRefreshToken отзывает предъявленный токен и сохраняет новый в одной транзакции.
+// Повторное предъявление уже отозванного токена означает утечку -
+// тогда отзываем все токены пользователя
+func (ps *PostgresStorage) RotateRefreshToken(oldHash, newHash string, expiresAt time.Time) (int, error) {
+	tx, err := ps.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var (
+		userID    int
+		tokenExp  time.Time
+		revokedAt sql.NullTime
+	)
+	err = tx.QueryRow(`
+        SELECT user_id, expires_at, revoked_at
+        FROM refresh_tokens
+        WHERE token_hash = $1
+        FOR UPDATE
+    `, oldHash).Scan(&userID, &tokenExp, &revokedAt)
+	if err == sql.ErrNoRows {
+		return 0, handler.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if revokedAt.Valid {
+		if _, err := tx.Exec(`
+            UPDATE refresh_tokens SET revoked_at = NOW()
+            WHERE user_id = $1 AND revoked_at IS NULL
+        `, userID); err != nil {
+			return 0, err
+		}
+		if err := tx.Commit(); err != nil {
+			return 0, err
+		}
+		castomLogger.Infof("refresh token reuse detected for user %d, all tokens revoked", userID)
+		return 0, handler.ErrInvalidRefreshToken
+	}
+
+	if !tokenExp.After(time.Now()) {
+		return 0, handler.ErrInvalidRefreshToken
+	}
+
+	if _, err := tx.Exec(`UPDATE refresh_tokens SET revoked_at = NOW() WHERE token_hash = $1`, oldHash); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(`
+        INSERT INTO refresh_tokens (user_id, token_hash, expires_at)
+        VALUES ($1, $2, $3)
+    `, userID, newHash, expiresAt); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// RevokeRefreshToken - отзыв одного refresh-токена (выход в режиме токенов)
+func (ps *PostgresStorage) RevokeRefreshToken(tokenHash string) error {
+	_, err := ps.DB.Exec(`
+        UPDATE refresh_tokens SET revoked_at = NOW()
+        WHERE token_hash = $1 AND revoked_at IS NULL
+    `, tokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	return nil
+}
+
+// CreateSession - новая серверная сессия
+func (ps *PostgresStorage) CreateSession(userID int, tokenHash, userAgent, ip string, expiresAt time.Time) error {
+	_, err := ps.DB.Exec(`
+        INSERT INTO sessions (user_id, token_hash, user_agent, ip, expires_at)
+        VALUES ($1, $2, $3, $4, $5)
+    `, userID, tokenHash, userAgent, ip, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// TouchSession проверяет, что сессия активна, и обновляет время последней активности.
+// Возвращает ID сессии или 0, если сессия отозвана, истекла или не найдена
+func (ps *PostgresStorage) TouchSession(userID int, tokenHash string) (int, error) {
+	var (
+		sessionID  int
+		lastSeenAt time.Time
+	)
+	err := ps.DB.QueryRow(`
+        SELECT id, last_seen_at
+        FROM sessions
+        WHERE token_hash = $1
+            AND user_id = $2
+            AND revoked_at IS NULL
+            AND expires_at > NOW()
+    `, tokenHash, userID).Scan(&sessionID, &lastSeenAt)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to check session: %w", err)
+	}
+
+	// не пишем в базу на каждый запрос
+	if time.Since(lastSeenAt) >= sessionTouchInterval {
+		if _, err := ps.DB.Exec(`UPDATE sessions SET last_seen_at = NOW() WHERE id = $1`, sessionID); err != nil {
+			castomLogger.Infof("failed to update session last seen: %v", err)
+		}
+	}
+
+	return sessionID, nil
+}
+
+// RevokeSession - выход из одной сессии
+func (ps *PostgresStorage) RevokeSession(userID, sessionID int) error {
+	_, err := ps.DB.Exec(`
+        UPDATE sessions SET revoked_at = NOW()
+        WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+    `, sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+// RevokeAllSessions - выход отовсюду: отзываем все сессии и refresh-токены пользователя
+func (ps *PostgresStorage) RevokeAllSessions(userID int) error {
+	tx, err := ps.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+        UPDATE sessions SET revoked_at = NOW()
+        WHERE user_id = $1 AND revoked_at IS NULL
+    `, userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+        UPDATE refresh_tokens SET revoked_at = NOW()
+        WHERE user_id = $1 AND revoked_at IS NULL
+    `, userID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// ActiveSessions - список действующих сессий пользователя
+func (ps *PostgresStorage) ActiveSessions(userID int) ([]models.Session, error) {
+	rows, err := ps.DB.Query(`
+        SELECT id, user_id, user_agent, created_at, last_seen_at, expires_at
+        FROM sessions
+        WHERE user_id = $1
+            AND revoked_at IS NULL
+            AND expires_at > NOW()
+        ORDER BY last_seen_at DESC
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// LoginLockedUntil - до какого момента заблокирован вход по логину или адресу, нулевое время - не заблокирован
+func (ps *PostgresStorage) LoginLockedUntil(login, ip string) (time.Time, error) {
+	var lockedUntil sql.NullTime
+	err := ps.DB.QueryRow(`
+        SELECT MAX(locked_until) FROM login_attempts
+        WHERE (scope = $1 AND key = $2) OR (scope = $3 AND key = $4)
+    `, models.LoginAttemptScopeLogin, login, models.LoginAttemptScopeIP, ip).Scan(&lockedUntil)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to check login lock: %w", err)
+	}
+	if !lockedUntil.Valid {
+		return time.Time{}, nil
+	}
+	return lockedUntil.Time, nil
+}
+
+// RecordLoginFailure увеличивает счётчик неудач и возвращает его значение.
+// Если с прошлой неудачи прошло больше window, счёт начинается заново
+func (ps *PostgresStorage) RecordLoginFailure(scope, key string, window time.Duration) (int, error) {
+	var failures int
+	err := ps.DB.QueryRow(`
+        INSERT INTO login_attempts (scope, key, failures, last_failure_at)
+        VALUES ($1, $2, 1, NOW())
+        ON CONFLICT (scope, key) DO UPDATE SET
+            failures = CASE
+                WHEN login_attempts.last_failure_at < NOW() - make_interval(secs => $3) THEN 1
+                ELSE login_attempts.failures + 1
+            END,
+            last_failure_at = NOW()
+        RETURNING failures
+    `, scope, key, window.Seconds()).Scan(&failures)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record login failure: %w", err)
+	}
+	return failures, nil
+}
+
+func (ps *PostgresStorage) LockLogin(scope, key string, until time.Time) error {
+	_, err := ps.DB.Exec(`
+        UPDATE login_attempts SET locked_until = $3
+        WHERE scope = $1 AND key = $2
+    `, scope, key, until)
+	if err != nil {
+		return fmt.Errorf("failed to lock login: %w", err)
+	}
+	return nil
+}
+
+// ResetLoginAttempts снимает блокировку и обнуляет счётчик
+func (ps *PostgresStorage) ResetLoginAttempts(scope, key string) error {
+	_, err := ps.DB.Exec(`DELETE FROM login_attempts WHERE scope = $1 AND key = $2`, scope, key)
+	if err != nil {
+		return fmt.Errorf("failed to reset login attempts: %w", err)
+	}
+	return nil
+}
+
+// GetTOTP - настройки второго фактора, nil - пользователь 2FA не настраивал
+func (ps *PostgresStorage) GetTOTP(userID int) (*models.TOTP, error) {
+	var t models.TOTP
+	err := ps.DB.QueryRow(`
+        SELECT user_id, secret, enabled, last_counter FROM user_totp WHERE user_id = $1
+    `, userID).Scan(&t.UserID, &t.Secret, &t.Enabled, &t.LastCounter)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get totp: %w", err)
+	}
+	return &t, nil
+}
+
+// SaveTOTPSecret сохраняет неподтверждённый секрет, подключённую 2FA не перезаписывает
+func (ps *PostgresStorage) SaveTOTPSecret(userID int, secret string) error {
+	res, err := ps.DB.Exec(`
+        INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+        ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, created_at = NOW()
+        WHERE user_totp.enabled = FALSE
+    `, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to save totp secret: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to save totp secret: %w", err)
+	}
+	if n == 0 {
+		return handler.ErrTOTPAlreadyEnabled
+	}
+	return nil
+}
+
+// EnableTOTP включает 2FA и заменяет коды восстановления
+func (ps *PostgresStorage) EnableTOTP(userID int, counter int64, recoveryCodeHashes []string) error {
+	tx, err := ps.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+        UPDATE user_totp SET enabled = TRUE, last_counter = $2, confirmed_at = NOW()
+        WHERE user_id = $1 AND enabled = FALSE
+    `, userID, counter)
+	if err != nil {
+		return fmt.Errorf("failed to enable totp: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to enable totp: %w", err)
+	}
+	if n == 0 {
+		return handler.ErrTOTPAlreadyEnabled
+	}
+
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.Exec(`
+            INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)
+        `, userID, hash); err != nil {
+			return fmt.Errorf("failed to save recovery code: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseTOTPCounter отмечает шаг как использованный; false - код этого или более позднего шага уже был
+func (ps *PostgresStorage) UseTOTPCounter(userID int, counter int64) (bool, error) {
+	res, err := ps.DB.Exec(`
+        UPDATE user_totp SET last_counter = $2
+        WHERE user_id = $1 AND enabled = TRUE AND last_counter < $2
+    `, userID, counter)
+	if err != nil {
+		return false, fmt.Errorf("failed to use totp code: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to use totp code: %w", err)
+	}
+	return n == 1, nil
+}
+
+// UseRecoveryCode гасит код восстановления; false - кода нет или он уже использован
+func (ps *PostgresStorage) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	res, err := ps.DB.Exec(`
+        UPDATE recovery_codes SET used_at = NOW()
+        WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+    `, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	return n == 1, nil
+}
+
+func (ps *PostgresStorage) CreateLoginChallenge(userID int, tokenHash string, expiresAt time.Time) error {
+	_, err := ps.DB.Exec(`
+        INSERT INTO login_challenges (user_id, token_hash, expires_at) VALUES ($1, $2, $3)
+    `, userID, tokenHash, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create login challenge: %w", err)
+	}
+	return nil
+}
+
+// LoginChallengeUser - владелец незавершённого входа, 0 - вход истёк, завершён или исчерпал попытки
+func (ps *PostgresStorage) LoginChallengeUser(tokenHash string, maxAttempts int) (int, error) {
+	var userID int
+	err := ps.DB.QueryRow(`
+        SELECT user_id FROM login_challenges
+        WHERE token_hash = $1 AND completed_at IS NULL AND expires_at > NOW() AND attempts < $2
+    `, tokenHash, maxAttempts).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get login challenge: %w", err)
+	}
+	return userID, nil
+}
+
+func (ps *PostgresStorage) FailLoginChallenge(tokenHash string) error {
+	_, err := ps.DB.Exec(`UPDATE login_challenges SET attempts = attempts + 1 WHERE token_hash = $1`, tokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to update login challenge: %w", err)
+	}
+	return nil
+}
+
+// CompleteLoginChallenge закрывает вход; false - его уже закрыл параллельный запрос
+func (ps *PostgresStorage) CompleteLoginChallenge(tokenHash string) (bool, error) {
+	res, err := ps.DB.Exec(`
+        UPDATE login_challenges SET completed_at = NOW()
+        WHERE token_hash = $1 AND completed_at IS NULL
+    `, tokenHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to complete login challenge: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to complete login challenge: %w", err)
+	}
+	return n == 1, nil
+}
+
+// SearchUsers - поиск пользователей по части логина
+func (ps *PostgresStorage) SearchUsers(query string, limit int) ([]models.User, error) {
+	rows, err := ps.DB.Query(`
+        SELECT id, login, password_hash, role, blocked_at, created_at FROM users
+        WHERE login ILIKE '%' || $1 || '%' ESCAPE '\'
+        ORDER BY id
+        LIMIT $2
+    `, escapeLike(query), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return users, nil
+}
+
+// likeEscaper экранирует спецсимволы LIKE, чтобы поиск "a_b" не находил "axb"
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// SetUserBlocked блокирует или разблокирует пользователя. При блокировке
+// отзываются все его сессии и refresh-токены. Запись в журнал - в той же транзакции
+func (ps *PostgresStorage) SetUserBlocked(userID int, blocked bool, entry models.AuditEntry) error {
+	tx, err := ps.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE users SET blocked_at = NULL WHERE id = $1`
+	if blocked {
+		query = `UPDATE users SET blocked_at = COALESCE(blocked_at, NOW()) WHERE id = $1`
+	}
+	res, err := tx.Exec(query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	} else if n == 0 {
+		return handler.ErrUserNotFound
+	}
+
+	if blocked {
+		if _, err := tx.Exec(`
+            UPDATE sessions SET revoked_at = NOW()
+            WHERE user_id = $1 AND revoked_at IS NULL
+        `, userID); err != nil {
+			return fmt.Errorf("failed to revoke sessions: %w", err)
+		}
+		if _, err := tx.Exec(`
+            UPDATE refresh_tokens SET revoked_at = NOW()
+            WHERE user_id = $1 AND revoked_at IS NULL
+        `, userID); err != nil {
+			return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+		}
+	}
+
+	if err := insertAudit(tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetUserRole меняет роль пользователя с записью в журнал
+func (ps *PostgresStorage) SetUserRole(userID int, role string, entry models.AuditEntry) error {
+	tx, err := ps.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE users SET role = $2 WHERE id = $1`, userID, role)
+	if err != nil {
+		return fmt.Errorf("failed to update user role: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to update user role: %w", err)
+	} else if n == 0 {
+		return handler.ErrUserNotFound
+	}
+
+	if err := insertAudit(tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RequeueOrder возвращает необработанный заказ в статус NEW и будит слушателя
+// тем же уведомлением new_orders, что и триггер на вставку
+func (ps *PostgresStorage) RequeueOrder(number string, entry models.AuditEntry) error {
+	tx, err := ps.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var (
+		uid, userID int
+		status      string
+		uploadedAt  time.Time
+	)
+	err = tx.QueryRow(`
+        SELECT uid, user_id, status, uploaded_at FROM orders WHERE number = $1 FOR UPDATE
+    `, number).Scan(&uid, &userID, &status, &uploadedAt)
+	if err == sql.ErrNoRows {
+		return handler.ErrOrderNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get order: %w", err)
+	}
+	// начисление по обработанному заказу уже могло быть потрачено
+	if status == "PROCESSED" {
+		return handler.ErrOrderAlreadyProcessed
+	}
+
+	if _, err := tx.Exec(`UPDATE orders SET status = 'NEW', accrual = 0 WHERE uid = $1`, uid); err != nil {
+		return fmt.Errorf("failed to requeue order: %w", err)
+	}
+
+	// уведомление уйдёт слушателю только после коммита
+	if _, err := tx.Exec(`
+        SELECT pg_notify('new_orders', json_build_object(
+            'order_id', $1::int, 'user_id', $2::int, 'number', $3::text, 'status', 'NEW', 'created_at', $4::timestamptz
+        )::text)
+    `, uid, userID, number, uploadedAt); err != nil {
+		return fmt.Errorf("failed to notify listener: %w", err)
+	}
+
+	entry.TargetUserID = &userID
+	if err := insertAudit(tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RecordAudit - запись в журнал действий администратора
+func (ps *PostgresStorage) RecordAudit(entry models.AuditEntry) error {
+	return insertAudit(ps.DB, entry)
+}
+
+// execer - общее у *sql.DB и *sql.Tx
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func insertAudit(db execer, entry models.AuditEntry) error {
+	_, err := db.Exec(`
+        INSERT INTO admin_audit_log (actor_id, action, target_user_id, details)
+        VALUES (NULLIF($1, 0), $2, $3, $4)
+    `, entry.ActorID, entry.Action, entry.TargetUserID, entry.Details)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+// AuditLog - последние записи журнала, новые первыми
+func (ps *PostgresStorage) AuditLog(limit int) ([]models.AuditEntry, error) {
+	rows, err := ps.DB.Query(`
+        SELECT id, COALESCE(actor_id, 0), action, target_user_id, details, created_at
+        FROM admin_audit_log
+        ORDER BY id DESC
+        LIMIT $1
+    `, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &e.TargetUserID, &e.Details, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return entries, nil
+}
+
+// CreateAPIKey сохраняет хэш нового API-ключа, заполняет ID и время создания
+func (ps *PostgresStorage) CreateAPIKey(key *models.APIKey, keyHash string) error {
+	err := ps.DB.QueryRow(`
+        INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, created_at
+    `, key.UserID, key.Name, key.Prefix, keyHash, strings.Join(key.Scopes, ","), key.ExpiresAt).
+		Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+	return nil
+}
+
+// APIKeys - неотозванные ключи пользователя, истёкшие тоже показываются
+func (ps *PostgresStorage) APIKeys(userID int) ([]models.APIKey, error) {
+	rows, err := ps.DB.Query(`
+        SELECT id, user_id, name, prefix, scopes, created_at, last_used_at, expires_at
+        FROM api_keys
+        WHERE user_id = $1 AND revoked_at IS NULL
+        ORDER BY id
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		var k models.APIKey
+		if err := scanAPIKey(rows, &k); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// scanAPIKey читает колонки id, user_id, name, prefix, scopes, created_at, last_used_at, expires_at
+func scanAPIKey(row scanner, k *models.APIKey) error {
+	var (
+		scopes     string
+		lastUsedAt sql.NullTime
+		expiresAt  sql.NullTime
+	)
+	if err := row.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &scopes, &k.CreatedAt, &lastUsedAt, &expiresAt); err != nil {
+		return err
+	}
+	k.Scopes = strings.Split(scopes, ",")
+	if lastUsedAt.Valid {
+		k.LastUsedAt = &lastUsedAt.Time
+	}
+	if expiresAt.Valid {
+		k.ExpiresAt = &expiresAt.Time
+	}
+	return nil
+}
+
+// RevokeAPIKey отзывает ключ пользователя; чужой или уже отозванный ключ - handler.ErrAPIKeyNotFound
+func (ps *PostgresStorage) RevokeAPIKey(userID, keyID int) error {
+	res, err := ps.DB.Exec(`
+        UPDATE api_keys SET revoked_at = NOW()
+        WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+    `, keyID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	} else if n == 0 {
+		return handler.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// UseAPIKey находит действующий ключ по хэшу и отмечает время использования.
+// nil - ключа нет, он отозван или истёк
+func (ps *PostgresStorage) UseAPIKey(keyHash string) (*models.APIKey, error) {
+	var k models.APIKey
+	err := scanAPIKey(ps.DB.QueryRow(`
+        UPDATE api_keys SET last_used_at = NOW()
+        WHERE key_hash = $1
+            AND revoked_at IS NULL
+            AND (expires_at IS NULL OR expires_at > NOW())
+        RETURNING id, user_id, name, prefix, scopes, created_at, last_used_at, expires_at
+    `, keyHash), &k)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check api key: %w", err)
+	}
+	return &k, nil
+}
+
+// CreateOIDCState сохраняет начатый вход через провайдера
+func (ps *PostgresStorage) CreateOIDCState(stateHash string, state models.OIDCLoginState, expiresAt time.Time) error {
+	_, err := ps.DB.Exec(`
+        INSERT INTO oidc_login_states (state_hash, code_verifier, nonce, expires_at)
+        VALUES ($1, $2, $3, $4)
+    `, stateHash, state.CodeVerifier, state.Nonce, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create oidc state: %w", err)
+	}
+	return nil
+}
+
+// ConsumeOIDCState забирает state один раз; nil - state неизвестен, уже использован или истёк
+func (ps *PostgresStorage) ConsumeOIDCState(stateHash string) (*models.OIDCLoginState, error) {
+	var (
+		state     models.OIDCLoginState
+		expiresAt time.Time
+	)
+	err := ps.DB.QueryRow(`
+        DELETE FROM oidc_login_states WHERE state_hash = $1
+        RETURNING code_verifier, nonce, expires_at
+    `, stateHash).Scan(&state.CodeVerifier, &state.Nonce, &expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume oidc state: %w", err)
+	}
+
+	// заодно чистим брошенные входы
+	if _, err := ps.DB.Exec(`DELETE FROM oidc_login_states WHERE expires_at < NOW()`); err != nil {
+		castomLogger.Infof("failed to clean up oidc states: %v", err)
+	}
+
+	if !expiresAt.After(time.Now()) {
+		return nil, nil
+	}
+	return &state, nil
+}
+
+// GetUserByIdentity - пользователь, к которому привязана внешняя учётная запись, nil - не привязана
+func (ps *PostgresStorage) GetUserByIdentity(issuer, subject string) (*models.User, error) {
+	var user models.User
+	err := scanUser(ps.DB.QueryRow(`
+        UPDATE user_identities ui SET last_login_at = NOW()
+        FROM users u
+        WHERE ui.user_id = u.id AND ui.issuer = $1 AND ui.subject = $2
+        RETURNING u.id, u.login, u.password_hash, u.role, u.blocked_at, u.created_at
+    `, issuer, subject), &user)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by identity: %w", err)
+	}
+	return &user, nil
+}
+
+// CreateUserWithIdentity заводит пользователя без пароля и привязывает к нему внешнюю учётную запись.
+// Занятый логин - handler.ErrLoginAlreadyExists
+func (ps *PostgresStorage) CreateUserWithIdentity(login string, identity models.UserIdentity) (*models.User, error) {
+	tx, err := ps.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var user models.User
+	// пустой хэш не совпадёт ни с одним паролем: войти можно только через провайдера
+	err = scanUser(tx.QueryRow(`
+        INSERT INTO users (login, password_hash) VALUES ($1, '')
+        ON CONFLICT (login) DO NOTHING
+        RETURNING id, login, password_hash, role, blocked_at, created_at
+    `, login), &user)
+	if err == sql.ErrNoRows {
+		return nil, handler.ErrLoginAlreadyExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+        INSERT INTO user_identities (user_id, issuer, subject, email)
+        VALUES ($1, $2, $3, $4)
+    `, user.ID, identity.Issuer, identity.Subject, identity.Email); err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ledgerError переводит ошибки журнала в ошибки, которые понимает хендлер
+func ledgerError(err error) error {
+	switch {
+	case errors.Is(err, ledger.ErrInsufficientFunds):
+		return handler.ErrLackOfFunds
+	case errors.Is(err, ledger.ErrEntryNotFound):
+		return handler.ErrLedgerEntryNotFound
+	case errors.Is(err, ledger.ErrNotReversible), errors.Is(err, ledger.ErrReversalExceedsSum):
+		return fmt.Errorf("%w: %v", handler.ErrInvalidReversal, err)
+	case errors.Is(err, ledger.ErrInvalidEntry):
+		return handler.ErrInvalidAmount
+	}
+	return err
+}
+
+// AdjustBalance - ручная корректировка баланса с записью в журнал администратора
+func (ps *PostgresStorage) AdjustBalance(userID int, amount money.Amount, note string, entry models.AuditEntry) (models.LedgerEntry, error) {
+	ctx := context.Background()
+
+	tx, err := ps.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return models.LedgerEntry{}, err
+	}
+	defer tx.Rollback()
+
+	posted, err := ledger.Post(ctx, tx, models.LedgerEntry{
+		UserID: userID,
+		Type:   models.LedgerEntryAdjustment,
+		Amount: amount,
+		Note:   note,
+	})
+	if err != nil {
+		return models.LedgerEntry{}, ledgerError(err)
+	}
+
+	entry.Details = fmt.Sprintf("entry=%d amount=%s %s", posted.ID, posted.Amount, entry.Details)
+	if err := insertAudit(tx, entry); err != nil {
+		return models.LedgerEntry{}, err
+	}
+	return posted, tx.Commit()
+}
+
+// ReverseLedgerEntry сторнирует проводку с записью в журнал администратора
+func (ps *PostgresStorage) ReverseLedgerEntry(entryID int64, amount money.Amount, note string, entry models.AuditEntry) (models.LedgerEntry, error) {
+	ctx := context.Background()
+
+	tx, err := ps.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return models.LedgerEntry{}, err
+	}
+	defer tx.Rollback()
+
+	posted, err := ledger.Reverse(ctx, tx, entryID, amount, note)
+	if err != nil {
+		return models.LedgerEntry{}, ledgerError(err)
+	}
+
+	entry.TargetUserID = &posted.UserID
+	entry.Details = fmt.Sprintf("entry=%d reverses=%d amount=%s", posted.ID, entryID, posted.Amount)
+	if err := insertAudit(tx, entry); err != nil {
+		return models.LedgerEntry{}, err
+	}
+	return posted, tx.Commit()
+}
+
+// LedgerEntries - последние проводки пользователя, новые первыми
+func (ps *PostgresStorage) LedgerEntries(userID, limit int) ([]models.LedgerEntry, error) {
+	rows, err := ps.DB.Query(`
+        SELECT id, user_id, entry_type, amount, balance_after, contra_account,
+               COALESCE(order_number, ''), withdrawal_id, reverses_entry_id, note, created_at
+        FROM ledger_entries
+        WHERE user_id = $1
+        ORDER BY id DESC
+        LIMIT $2
+    `, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.LedgerEntry
+	for rows.Next() {
+		var e models.LedgerEntry
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Type, &e.Amount, &e.BalanceAfter, &e.ContraAccount,
+			&e.OrderNumber, &e.WithdrawalID, &e.ReversesEntryID, &e.Note, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// CheckLedger - сверка журнала с остатками, заказами и списаниями
+func (ps *PostgresStorage) CheckLedger() ([]models.LedgerMismatch, error) {
+	return ledger.Check(context.Background(), ps.DB)
+}
+
+// ReserveIdempotencyKey занимает ключ под запрос. nil - ключ свободен (или истёк) и занят этим запросом,
+// иначе возвращается действующая запись: с сохранённым ответом или ещё выполняющаяся
+func (ps *PostgresStorage) ReserveIdempotencyKey(k models.IdempotencyKey) (*models.IdempotencyKey, error) {
+	err := ps.DB.QueryRow(`
+        INSERT INTO idempotency_keys (user_id, scope, idem_key, fingerprint, expires_at)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (user_id, scope, idem_key) DO UPDATE SET
+            fingerprint = EXCLUDED.fingerprint,
+            status_code = NULL,
+            response_body = NULL,
+            created_at = NOW(),
+            expires_at = EXCLUDED.expires_at
+        WHERE idempotency_keys.expires_at <= NOW()
+        RETURNING created_at
+    `, k.UserID, k.Scope, k.Key, k.Fingerprint, k.ExpiresAt).Scan(&k.CreatedAt)
+	if err == nil {
+		return nil, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	var (
+		existing   = models.IdempotencyKey{UserID: k.UserID, Scope: k.Scope, Key: k.Key}
+		statusCode sql.NullInt64
+		body       sql.NullString
+	)
+	err = ps.DB.QueryRow(`
+        SELECT fingerprint, status_code, response_body, created_at, expires_at
+        FROM idempotency_keys
+        WHERE user_id = $1 AND scope = $2 AND idem_key = $3
+    `, k.UserID, k.Scope, k.Key).Scan(&existing.Fingerprint, &statusCode, &body, &existing.CreatedAt, &existing.ExpiresAt)
+	if err == sql.ErrNoRows {
+		// ключ освободили между запросами - занимаем заново
+		return ps.ReserveIdempotencyKey(k)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	if statusCode.Valid {
+		existing.Response = &models.IdempotentResponse{StatusCode: int(statusCode.Int64), Body: body.String}
+	}
+	return &existing, nil
+}
+
+// SaveIdempotentResponse сохраняет ответ на запрос, если он ещё не сохранён
+func (ps *PostgresStorage) SaveIdempotentResponse(userID int, scope, key string, resp models.IdempotentResponse) error {
+	_, err := ps.DB.Exec(`
+        UPDATE idempotency_keys SET status_code = $4, response_body = $5
+        WHERE user_id = $1 AND scope = $2 AND idem_key = $3 AND status_code IS NULL
+    `, userID, scope, key, resp.StatusCode, resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to save idempotent response: %w", err)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey освобождает незавершённый ключ, чтобы повтор выполнился заново
+func (ps *PostgresStorage) ReleaseIdempotencyKey(userID int, scope, key string) error {
+	_, err := ps.DB.Exec(`
+        DELETE FROM idempotency_keys
+        WHERE user_id = $1 AND scope = $2 AND idem_key = $3 AND status_code IS NULL
+    `, userID, scope, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys удаляет истёкшие ключи, возвращает их число
+func (ps *PostgresStorage) DeleteExpiredIdempotencyKeys() (int64, error) {
+	res, err := ps.DB.Exec(`DELETE FROM idempotency_keys WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// CreateHold резервирует баллы под заказ: они остаются на счёте, но потратить их нельзя
+func (ps *PostgresStorage) CreateHold(hold models.Hold) (models.Hold, error) {
+	ctx := context.Background()
+	err := ps.inTx(ctx, nil, func(tx *sql.Tx) error {
+		available, err := lockAvailable(ctx, tx, hold.UserID)
+		if err != nil {
+			return err
+		}
+		if err := checkPaymentOrder(ctx, tx, hold.Order); err != nil {
+			return err
+		}
+		if available < hold.Amount {
+			return handler.ErrLackOfFunds
+		}
+
+		err = tx.QueryRowContext(ctx, `
+            INSERT INTO balance_holds (user_id, order_number, amount, expires_at)
+            VALUES ($1, $2, $3, $4)
+            ON CONFLICT (order_number) WHERE status = 'active' DO NOTHING
+            RETURNING id, status, created_at
+        `, hold.UserID, hold.Order, hold.Amount, hold.ExpiresAt).Scan(&hold.ID, &hold.Status, &hold.CreatedAt)
+		if err == sql.ErrNoRows {
+			return handler.ErrOrderOnHold
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create hold: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+            UPDATE accounts SET held = held + $2, updated_at = NOW() WHERE user_id = $1
+        `, hold.UserID, hold.Amount)
+		if err != nil {
+			return fmt.Errorf("failed to update held amount: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Hold{}, err
+	}
+	return hold, nil
+}
+
+// CaptureHold превращает действующий резерв в списание
+func (ps *PostgresStorage) CaptureHold(userID, holdID int) (models.Hold, error) {
+	ctx := context.Background()
+	var hold models.Hold
+	err := ps.inTx(ctx, nil, func(tx *sql.Tx) error {
+		var err error
+		hold, err = lockActiveHold(ctx, tx, userID, holdID)
+		if err != nil {
+			return err
+		}
+
+		// резерв снимается до проводки: списываются те самые зарезервированные баллы
+		if err := releaseHeld(ctx, tx, userID, hold.Amount); err != nil {
+			return err
+		}
+		withdrawalID, err := insertWithdrawal(ctx, tx, userID, hold.Order, hold.Amount)
+		if err != nil {
+			return err
+		}
+
+		return tx.QueryRowContext(ctx, `
+            UPDATE balance_holds SET status = $2, withdrawal_id = $3, closed_at = NOW()
+            WHERE id = $1
+            RETURNING status, closed_at
+        `, holdID, models.HoldStatusCaptured, withdrawalID).Scan(&hold.Status, &hold.ClosedAt)
+	})
+	if err != nil {
+		return models.Hold{}, err
+	}
+	return hold, nil
+}
+
+// VoidHold отменяет действующий резерв и возвращает баллы в доступный остаток
+func (ps *PostgresStorage) VoidHold(userID, holdID int) (models.Hold, error) {
+	ctx := context.Background()
+	var hold models.Hold
+	err := ps.inTx(ctx, nil, func(tx *sql.Tx) error {
+		var err error
+		hold, err = lockActiveHold(ctx, tx, userID, holdID)
+		if err != nil {
+			return err
+		}
+		if err := releaseHeld(ctx, tx, userID, hold.Amount); err != nil {
+			return err
+		}
+
+		return tx.QueryRowContext(ctx, `
+            UPDATE balance_holds SET status = $2, closed_at = NOW()
+            WHERE id = $1
+            RETURNING status, closed_at
+        `, holdID, models.HoldStatusVoided).Scan(&hold.Status, &hold.ClosedAt)
+	})
+	if err != nil {
+		return models.Hold{}, err
+	}
+	return hold, nil
+}
+
+// lockActiveHold блокирует резерв пользователя. Истёкший, но ещё не снятый резерв уже не действует
+func lockActiveHold(ctx context.Context, tx *sql.Tx, userID, holdID int) (models.Hold, error) {
+	var hold models.Hold
+	err := tx.QueryRowContext(ctx, `
+        SELECT id, user_id, order_number, amount, status, created_at, expires_at
+        FROM balance_holds
+        WHERE id = $1 AND user_id = $2
+        FOR UPDATE
+    `, holdID, userID).Scan(&hold.ID, &hold.UserID, &hold.Order, &hold.Amount, &hold.Status, &hold.CreatedAt, &hold.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return models.Hold{}, handler.ErrHoldNotFound
+	}
+	if err != nil {
+		return models.Hold{}, fmt.Errorf("failed to get hold: %w", err)
+	}
+	if hold.Status != models.HoldStatusActive || !hold.ExpiresAt.After(time.Now()) {
+		return models.Hold{}, handler.ErrHoldNotActive
+	}
+	return hold, nil
+}
+
+func releaseHeld(ctx context.Context, tx *sql.Tx, userID int, amount money.Amount) error {
+	_, err := tx.ExecContext(ctx, `
+        UPDATE accounts SET held = held - $2, updated_at = NOW() WHERE user_id = $1
+    `, userID, amount)
+	if err != nil {
+		return fmt.Errorf("failed to update held amount: %w", err)
+	}
+	return nil
+}
+
+// Holds - резервы пользователя, новые первыми
+func (ps *PostgresStorage) Holds(userID int) ([]models.Hold, error) {
+	rows, err := ps.DB.Query(`
+        SELECT id, user_id, order_number, amount, status, created_at, expires_at, closed_at
+        FROM balance_holds
+        WHERE user_id = $1
+        ORDER BY created_at DESC, id DESC
+    `, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get holds: %w", err)
+	}
+	defer rows.Close()
+
+	var holds []models.Hold
+	for rows.Next() {
+		var h models.Hold
+		if err := rows.Scan(&h.ID, &h.UserID, &h.Order, &h.Amount, &h.Status, &h.CreatedAt, &h.ExpiresAt, &h.ClosedAt); err != nil {
+			return nil, err
+		}
+		holds = append(holds, h)
+	}
+	return holds, rows.Err()
+}
+
+// ExpireHolds снимает истёкшие резервы и возвращает их баллы в доступный остаток
+func (ps *PostgresStorage) ExpireHolds() (int64, error) {
+	var expired int64
+	err := ps.DB.QueryRow(`
+        WITH expired AS (
+            UPDATE balance_holds SET status = 'expired', closed_at = NOW()
+            WHERE status = 'active' AND expires_at <= NOW()
+            RETURNING user_id, amount
+        ),
+        released AS (
+            UPDATE accounts a SET held = a.held - e.amount, updated_at = NOW()
+            FROM (SELECT user_id, SUM(amount) AS amount FROM expired GROUP BY user_id) e
+            WHERE a.user_id = e.user_id
+        )
+        SELECT COUNT(*) FROM expired
+    `).Scan(&expired)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire holds: %w", err)
+	}
+	return expired, nil
+}
+
+// RefundWithdrawal возвращает баллы по списанию сторно-проводкой в одной транзакции с записью возврата.
+// refund.UserID = 0 - списание любого пользователя (сотрудник), иначе только его собственное (партнёр).
+// entry - запись журнала администратора, nil - не записывать
+func (ps *PostgresStorage) RefundWithdrawal(refund models.WithdrawalRefund, entry *models.AuditEntry) (models.WithdrawalRefund, error) {
+	ctx := context.Background()
+	result := refund
+	err := ps.inTx(ctx, nil, func(tx *sql.Tx) error {
+		var withdrawalID int
+		var ledgerEntryID int64
+		// проводка списания блокируется в ledger.Reverse: параллельные возвраты не превысят сумму
+		err := tx.QueryRowContext(ctx, `
+            SELECT w.uid, w.user_id, l.id
+            FROM withdrawals w
+            JOIN ledger_entries l ON l.withdrawal_id = w.uid AND l.entry_type = 'withdrawal'
+            WHERE w.order_number = $1 AND ($2 = 0 OR w.user_id = $2)
+        `, refund.Order, refund.UserID).Scan(&withdrawalID, &result.UserID, &ledgerEntryID)
+		if err == sql.ErrNoRows {
+			return handler.ErrWithdrawalNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get withdrawal: %w", err)
+		}
+
+		posted, err := ledger.Reverse(ctx, tx, ledgerEntryID, refund.Amount, refund.Reason)
+		if errors.Is(err, ledger.ErrReversalExceedsSum) {
+			return handler.ErrRefundExceedsWithdrawal
+		}
+		if err != nil {
+			return ledgerError(err)
+		}
+		result.Amount = posted.Amount
+
+		err = tx.QueryRowContext(ctx, `
+            INSERT INTO withdrawal_refunds
+                (withdrawal_id, user_id, amount, reason, source, actor_id, api_key_id, ledger_entry_id)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+            RETURNING id, created_at
+        `, withdrawalID, result.UserID, posted.Amount, refund.Reason, refund.Source,
+			refund.ActorID, refund.APIKeyID, posted.ID).Scan(&result.ID, &result.ProcessedAt)
+		if err != nil {
+			return fmt.Errorf("failed to record refund: %w", err)
+		}
+
+		if entry != nil {
+			audit := *entry
+			audit.TargetUserID = &result.UserID
+			audit.Details = fmt.Sprintf("order=%s amount=%s entry=%d", refund.Order, posted.Amount, posted.ID)
+			if err := insertAudit(tx, audit); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return models.WithdrawalRefund{}, err
+	}
+	return result, nil
+}
+
+// lotDeadline - SQL-выражение срока сгорания партии l по политике, $1 - срок жизни баллов в секундах.
+// rolling: срок отсчитывается от последнего начисления или списания, но не раньше начисления самой партии
+func lotDeadline(policy string) string {
+	if policy == models.PointsExpiryRolling {
+		return `GREATEST(l.earned_at, (
+                SELECT MAX(le.created_at) FROM ledger_entries le
+                WHERE le.user_id = l.user_id AND le.entry_type IN ('accrual', 'withdrawal')
+            )) + make_interval(secs => $1)`
+	}
+	return `l.earned_at + make_interval(secs => $1)`
+}
+
+// ExpiringPoints - остатки открытых партий пользователя, которые сгорят не позже until, по срокам
+func (ps *PostgresStorage) ExpiringPoints(userID int, policy models.PointsExpiry, until time.Time) ([]models.ExpiringPoints, error) {
+	rows, err := ps.DB.Query(`
+        SELECT expires_at, SUM(remaining)
+        FROM (
+            SELECT `+lotDeadline(policy.Policy)+` AS expires_at, l.remaining
+            FROM point_lots l
+            WHERE l.user_id = $2 AND l.remaining > 0
+        ) lots
+        WHERE expires_at <= $3
+        GROUP BY expires_at
+        ORDER BY expires_at
+    `, policy.Lifetime.Seconds(), userID, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expiring points: %w", err)
+	}
+	defer rows.Close()
+
+	var expiring []models.ExpiringPoints
+	for rows.Next() {
+		var p models.ExpiringPoints
+		if err := rows.Scan(&p.ExpiresAt, &p.Amount); err != nil {
+			return nil, err
+		}
+		expiring = append(expiring, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return expiring, nil
+}
+
+// expireBatchSize - сколько пользователей обрабатывает один запуск сгорания баллов
+const expireBatchSize = 500
+
+// ExpirePoints списывает сгоревшие к now баллы проводками expiration, по транзакции на пользователя.
+// Какие партии сгорели, видно по point_lot_consumptions проводки, действие пишется в журнал администратора.
+// Ошибочное сгорание отменяется сторно проводки: вернувшиеся баллы приходят новой партией.
+// Возвращает число проведённых сгораний
+func (ps *PostgresStorage) ExpirePoints(policy models.PointsExpiry, now time.Time) (int, error) {
+	ctx := context.Background()
+
+	// самые давние сроки первыми. Пользователи без свободных баллов (всё в резерве) пропускаются:
+	// проводки у них не будет, и без этого условия они занимали бы пакет при каждом запуске
+	rows, err := ps.DB.QueryContext(ctx, `
+        SELECT d.user_id
+        FROM (
+            SELECT l.user_id, `+lotDeadline(policy.Policy)+` AS deadline
+            FROM point_lots l
+            WHERE l.remaining > 0
+        ) d
+        JOIN accounts a ON a.user_id = d.user_id
+        WHERE d.deadline <= $2 AND a.balance - a.held > 0
+        GROUP BY d.user_id
+        ORDER BY MIN(d.deadline), d.user_id
+        LIMIT $3
+    `, policy.Lifetime.Seconds(), now, expireBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find expired points: %w", err)
+	}
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var (
+		expired int
+		errs    []error
+	)
+	for _, userID := range userIDs {
+		posted, err := ps.expireUserPoints(ctx, userID, policy, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("user %d: %w", userID, err))
+			continue
+		}
+		if posted {
+			expired++
+		}
+	}
+	return expired, errors.Join(errs...)
+}
+
+func (ps *PostgresStorage) expireUserPoints(ctx context.Context, userID int, policy models.PointsExpiry, now time.Time) (bool, error) {
+	posted := false
+	err := ps.inTx(ctx, nil, func(tx *sql.Tx) error {
+		posted = false
+		// блокировка счёта: партии не меняются до конца транзакции
+		available, err := lockAvailable(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		var expired money.Amount
+		err = tx.QueryRowContext(ctx, `
+            SELECT COALESCE(SUM(l.remaining), 0)
+            FROM point_lots l
+            WHERE l.user_id = $2 AND l.remaining > 0 AND `+lotDeadline(policy.Policy)+` <= $3
+        `, policy.Lifetime.Seconds(), userID, now).Scan(&expired)
+		if err != nil {
+			return fmt.Errorf("failed to sum expired points: %w", err)
+		}
+
+		// зарезервированные баллы не сгорают, пока резерв не закрыт
+		amount := min(expired, available)
+		if amount <= 0 {
+			return nil
+		}
+
+		// сгоревшие партии - самые ранние, проводка гасит их первыми
+		entry, err := ledger.Post(ctx, tx, models.LedgerEntry{
+			UserID: userID,
+			Type:   models.LedgerEntryExpiration,
+			Amount: -amount,
+			Note:   fmt.Sprintf("points expired (%s policy)", policy.Policy),
+		})
+		if err != nil {
+			return ledgerError(err)
+		}
+
+		if err := insertAudit(tx, models.AuditEntry{
+			Action:       models.AuditExpirePoints,
+			TargetUserID: &userID,
+			Details:      fmt.Sprintf("entry=%d amount=%s policy=%s", entry.ID, amount, policy.Policy),
+		}); err != nil {
+			return err
+		}
+		posted = true
+		return nil
+	})
+	return posted, err
+}
+
+// TierEarned - начисления пользователя с момента since за вычетом их сторно
+func (ps *PostgresStorage) TierEarned(userID int, since time.Time) (money.Amount, error) {
+	var earned money.Amount
+	err := ps.DB.QueryRow(`
+        SELECT COALESCE(SUM(e.amount), 0)
+        FROM ledger_entries e
+        LEFT JOIN ledger_entries o ON o.id = e.reverses_entry_id
+        WHERE e.user_id = $1 AND e.created_at > $2
+          AND (e.entry_type = 'accrual' OR o.entry_type = 'accrual')
+    `, userID, since).Scan(&earned)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum accruals: %w", err)
+	}
+	return earned, nil
+}
+
+// UserTier - присвоенный уровень, nil - уровень ещё не считался
+func (ps *PostgresStorage) UserTier(userID int) (*models.UserTier, error) {
+	t := models.UserTier{UserID: userID}
+	err := ps.DB.QueryRow(`
+        SELECT tier, earned, evaluated_at, next_evaluation_at
+        FROM user_tiers
+        WHERE user_id = $1
+    `, userID).Scan(&t.Tier, &t.Earned, &t.EvaluatedAt, &t.NextEvaluationAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tier: %w", err)
+	}
+	return &t, nil
+}
+
+// SaveUserTier сохраняет пересчитанный уровень
+func (ps *PostgresStorage) SaveUserTier(t models.UserTier) error {
+	_, err := ps.DB.Exec(`
+        INSERT INTO user_tiers (user_id, tier, earned, evaluated_at, next_evaluation_at)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (user_id) DO UPDATE SET
+            tier = EXCLUDED.tier,
+            earned = EXCLUDED.earned,
+            evaluated_at = EXCLUDED.evaluated_at,
+            next_evaluation_at = EXCLUDED.next_evaluation_at
+    `, t.UserID, t.Tier, t.Earned, t.EvaluatedAt, t.NextEvaluationAt)
+	if err != nil {
+		return fmt.Errorf("failed to save tier: %w", err)
+	}
+	return nil
+}
+
+// TiersDue - пользователи, чей уровень пора пересмотреть или ещё не считался
+func (ps *PostgresStorage) TiersDue(now time.Time, limit int) ([]int, error) {
+	rows, err := ps.DB.Query(`
+        SELECT u.id
+        FROM users u
+        LEFT JOIN user_tiers t ON t.user_id = u.id
+        WHERE t.user_id IS NULL OR t.next_evaluation_at <= $1
+        ORDER BY t.next_evaluation_at NULLS FIRST, u.id
+        LIMIT $2
+    `, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tiers due: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return userIDs, nil
+}
+
+// Transfer переводит баллы пользователю с логином t.Counterparty: списание у отправителя,
+// начисление получателю и запись о переводе - в одной транзакции
+func (ps *PostgresStorage) Transfer(t models.Transfer, limits models.TransferLimits) (models.Transfer, error) {
+	ctx := context.Background()
+	result := t
+	err := ps.inTx(ctx, nil, func(tx *sql.Tx) error {
+		result = t
+		err := tx.QueryRowContext(ctx, `
+            SELECT id FROM users WHERE login = $1 AND blocked_at IS NULL
+        `, t.Counterparty).Scan(&result.ToUserID)
+		if err == sql.ErrNoRows {
+			return handler.ErrRecipientNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get recipient: %w", err)
+		}
+		if result.ToUserID == t.FromUserID {
+			return handler.ErrTransferToSelf
+		}
+
+		// встречные переводы блокируют счета в одном порядке и не ждут друг друга
+		rows, err := tx.QueryContext(ctx, `
+            SELECT user_id, balance - held FROM accounts
+            WHERE user_id IN ($1, $2)
+            ORDER BY user_id
+            FOR UPDATE
+        `, t.FromUserID, result.ToUserID)
+		if err != nil {
+			return fmt.Errorf("failed to lock accounts: %w", err)
+		}
+		var available money.Amount
+		for rows.Next() {
+			var (
+				userID int
+				amount money.Amount
+			)
+			if err := rows.Scan(&userID, &amount); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to lock accounts: %w", err)
+			}
+			if userID == t.FromUserID {
+				available = amount
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to lock accounts: %w", err)
+		}
+
+		// счёт отправителя уже заблокирован, параллельный перевод увидит и эту запись
+		var (
+			sent  money.Amount
+			count int
+		)
+		err = tx.QueryRowContext(ctx, `
+            SELECT COALESCE(SUM(amount), 0), COUNT(*)
+            FROM transfers
+            WHERE from_user_id = $1 AND created_at > NOW() - INTERVAL '24 hours'
+        `, t.FromUserID).Scan(&sent, &count)
+		if err != nil {
+			return fmt.Errorf("failed to get transfer totals: %w", err)
+		}
+		if limits.DailyAmount > 0 && sent+t.Amount > limits.DailyAmount ||
+			limits.DailyCount > 0 && count >= limits.DailyCount {
+			return handler.ErrTransferLimitExceeded
+		}
+		if available < t.Amount {
+			return handler.ErrLackOfFunds
+		}
+
+		debit, err := ledger.Post(ctx, tx, models.LedgerEntry{
+			UserID: t.FromUserID,
+			Type:   models.LedgerEntryTransfer,
+			Amount: -t.Amount,
+			Note:   t.Note,
+		})
+		if err != nil {
+			return ledgerError(err)
+		}
+		credit, err := ledger.Post(ctx, tx, models.LedgerEntry{
+			UserID:          result.ToUserID,
+			Type:            models.LedgerEntryTransfer,
+			Amount:          t.Amount,
+			Note:            t.Note,
+			LotsFromEntryID: &debit.ID,
+		})
+		if err != nil {
+			return ledgerError(err)
+		}
+
+		err = tx.QueryRowContext(ctx, `
+            INSERT INTO transfers (from_user_id, to_user_id, amount, note, debit_entry_id, credit_entry_id)
+            VALUES ($1, $2, $3, $4, $5, $6)
+            RETURNING id, created_at
+        `, t.FromUserID, result.ToUserID, t.Amount, t.Note, debit.ID, credit.ID).Scan(&result.ID, &result.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to record transfer: %w", err)
+		}
+		result.Direction = models.TransferDirectionOut
+
+		if t.IdempotencyKey != "" {
+			body, err := json.Marshal(result)
+			if err != nil {
+				return err
+			}
+			resp := models.IdempotentResponse{StatusCode: 201, Body: string(body)}
+			if err := completeIdempotencyKey(ctx, tx, t.FromUserID, models.IdempotencyScopeTransfer, t.IdempotencyKey, resp); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Transfer{}, err
+	}
+	return result, nil
+}
+
+// Transfers - отправленные и полученные переводы пользователя, новые первыми
+func (ps *PostgresStorage) Transfers(userID int) ([]models.Transfer, error) {
+	rows, err := ps.DB.Query(`
+        SELECT
+            t.id,
+            CASE WHEN t.from_user_id = $1 THEN 'out' ELSE 'in' END,
+            u.login,
+            t.amount,
+            t.note,
+            t.created_at,
+            t.from_user_id,
+            t.to_user_id
+        FROM transfers t
+        JOIN users u ON u.id = CASE WHEN t.from_user_id = $1 THEN t.to_user_id ELSE t.from_user_id END
+        WHERE t.from_user_id = $1 OR t.to_user_id = $1
+        ORDER BY t.created_at DESC, t.id DESC
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transfers []models.Transfer
+	for rows.Next() {
+		var t models.Transfer
+		if err := rows.Scan(&t.ID, &t.Direction, &t.Counterparty, &t.Amount, &t.Note,
+			&t.CreatedAt, &t.FromUserID, &t.ToUserID); err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, rows.Err()
+}
+
+// CreateReferredUser создаёт пользователя, приглашённого по реферальному коду, в одной транзакции со связью
+func (ps *PostgresStorage) CreateReferredUser(login, passwordHash string, signup models.ReferralSignup) (*models.User, error) {
+	ctx := context.Background()
+	var user models.User
+	err := ps.inTx(ctx, nil, func(tx *sql.Tx) error {
+		// строка пригласившего блокируется: параллельные регистрации по коду считаются по очереди
+		var referrerID int
+		err := tx.QueryRowContext(ctx, `
+            SELECT id FROM users WHERE referral_code = $1 AND blocked_at IS NULL FOR UPDATE
+        `, signup.Code).Scan(&referrerID)
+		if err == sql.ErrNoRows {
+			return handler.ErrInvalidReferralCode
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get referrer: %w", err)
+		}
+
+		if err := checkReferralSignup(ctx, tx, referrerID, signup); err != nil {
+			return err
+		}
+
+		err = scanUser(tx.QueryRowContext(ctx, `
+            INSERT INTO users (login, password_hash) VALUES ($1, $2)
+            ON CONFLICT (login) DO NOTHING
+            RETURNING id, login, password_hash, role, blocked_at, created_at
+        `, login, passwordHash), &user)
+		if err == sql.ErrNoRows {
+			return handler.ErrLoginAlreadyExists
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+
+		// приглашённый только что создан, поэтому сам никого не приглашал и не владеет кодом:
+		// цепочка приглашений не может замкнуться, а приглашение самого себя видно только по адресу
+		if _, err := tx.ExecContext(ctx, `
+            INSERT INTO referrals (referee_id, referrer_id, referee_ip) VALUES ($1, $2, $3)
+        `, user.ID, referrerID, signup.IP); err != nil {
+			return fmt.Errorf("failed to record referral: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// checkReferralSignup отклоняет регистрацию по коду с адреса, с которого за окно входил пригласивший
+// или регистрировался другой его приглашённый, и сверх суточного числа приглашений
+func checkReferralSignup(ctx context.Context, tx *sql.Tx, referrerID int, signup models.ReferralSignup) error {
+	var (
+		sameAddress bool
+		today       int
+	)
+	err := tx.QueryRowContext(ctx, `
+        SELECT
+            $2 <> '' AND $3 > 0 AND (
+                EXISTS (SELECT 1 FROM sessions
+                        WHERE user_id = $1 AND ip = $2 AND created_at > NOW() - make_interval(secs => $3))
+                OR EXISTS (SELECT 1 FROM referrals
+                           WHERE referrer_id = $1 AND referee_ip = $2 AND created_at > NOW() - make_interval(secs => $3))
+            ),
+            (SELECT COUNT(*) FROM referrals WHERE referrer_id = $1 AND created_at > NOW() - INTERVAL '24 hours')
+    `, referrerID, signup.IP, signup.Limits.AddressWindow.Seconds()).Scan(&sameAddress, &today)
+	if err != nil {
+		return fmt.Errorf("failed to check referral signup: %w", err)
+	}
+	if sameAddress {
+		return handler.ErrSelfReferral
+	}
+	if signup.Limits.DailyCount > 0 && today >= signup.Limits.DailyCount {
+		return handler.ErrReferralLimitExceeded
+	}
+	return nil
+}
+
+// ReferralCode - реферальный код пользователя
+func (ps *PostgresStorage) ReferralCode(userID int) (string, error) {
+	var code string
+	err := ps.DB.QueryRow(`SELECT referral_code FROM users WHERE id = $1`, userID).Scan(&code)
+	if err != nil {
+		return "", fmt.Errorf("failed to get referral code: %w", err)
+	}
+	return code, nil
+}
+
+// Referrals - пользователи, приглашённые userID, новые первыми. Логины возвращаются как есть
+func (ps *PostgresStorage) Referrals(userID int) ([]models.Referral, error) {
+	rows, err := ps.DB.Query(`
+        SELECT u.login, r.bonus, r.created_at, r.rewarded_at
+        FROM referrals r
+        JOIN users u ON u.id = r.referee_id
+        WHERE r.referrer_id = $1
+        ORDER BY r.created_at DESC, r.referee_id DESC
+    `, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var referrals []models.Referral
+	for rows.Next() {
+		var r models.Referral
+		if err := rows.Scan(&r.Login, &r.Bonus, &r.RegisteredAt, &r.RewardedAt); err != nil {
+			return nil, err
+		}
+		r.Status = models.ReferralStatusPending
+		if r.RewardedAt != nil {
+			r.Status = models.ReferralStatusRewarded
+		}
+		referrals = append(referrals, r)
+	}
+	return referrals, rows.Err()
+}
+
+// promoCodeColumns - колонки promo_codes в порядке scanPromoCode
+const promoCodeColumns = `id, code, kind, points, multiplier, orders, starts_at, ends_at,
+            max_redemptions, per_user_limit, redemptions, created_at, disabled_at`
+
+func scanPromoCode(row scanner, p *models.PromoCode) error {
+	return row.Scan(&p.ID, &p.Code, &p.Kind, &p.Points, &p.Multiplier, &p.Orders, &p.StartsAt, &p.EndsAt,
+		&p.MaxRedemptions, &p.PerUserLimit, &p.Redemptions, &p.CreatedAt, &p.DisabledAt)
+}
+
+// CreatePromoCode заводит промокод с записью в журнал администратора. Занятый код - handler.ErrPromoCodeExists
+func (ps *PostgresStorage) CreatePromoCode(p models.PromoCode, entry models.AuditEntry) (models.PromoCode, error) {
+	ctx := context.Background()
+	var created models.PromoCode
+	err := ps.inTx(ctx, nil, func(tx *sql.Tx) error {
+		err := scanPromoCode(tx.QueryRowContext(ctx, `
+            INSERT INTO promo_codes
+                (code, kind, points, multiplier, orders, starts_at, ends_at, max_redemptions, per_user_limit, created_by)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, 0))
+            ON CONFLICT (code) DO NOTHING
+            RETURNING `+promoCodeColumns,
+			p.Code, p.Kind, p.Points, p.Multiplier, p.Orders, p.StartsAt, p.EndsAt,
+			p.MaxRedemptions, p.PerUserLimit, entry.ActorID), &created)
+		if err == sql.ErrNoRows {
+			return handler.ErrPromoCodeExists
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create promo code: %w", err)
+		}
+
+		entry.Details = fmt.Sprintf("code=%s kind=%s", created.Code, created.Kind)
+		return insertAudit(tx, entry)
+	})
+	if err != nil {
+		return models.PromoCode{}, err
+	}
+	return created, nil
+}
+
+// PromoCodes - все промокоды, новые первыми
+func (ps *PostgresStorage) PromoCodes() ([]models.PromoCode, error) {
+	rows, err := ps.DB.Query(`SELECT ` + promoCodeColumns + ` FROM promo_codes ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var codes []models.PromoCode
+	for rows.Next() {
+		var p models.PromoCode
+		if err := scanPromoCode(rows, &p); err != nil {
+			return nil, err
+		}
+		codes = append(codes, p)
+	}
+	return codes, rows.Err()
+}
+
+// DisablePromoCode выключает промокод: новые погашения невозможны, уже погашенные множители действуют
+func (ps *PostgresStorage) DisablePromoCode(code string, entry models.AuditEntry) (models.PromoCode, error) {
+	ctx := context.Background()
+	var disabled models.PromoCode
+	err := ps.inTx(ctx, nil, func(tx *sql.Tx) error {
+		err := scanPromoCode(tx.QueryRowContext(ctx, `
+            UPDATE promo_codes SET disabled_at = COALESCE(disabled_at, NOW())
+            WHERE code = $1
+            RETURNING `+promoCodeColumns, code), &disabled)
+		if err == sql.ErrNoRows {
+			return handler.ErrPromoCodeNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to disable promo code: %w", err)
+		}
+
+		entry.Details = "code=" + code
+		return insertAudit(tx, entry)
+	})
+	if err != nil {
+		return models.PromoCode{}, err
+	}
+	return disabled, nil
+}
+
+// RedeemPromoCode гасит промокод: проверка срока и лимитов, начисление и запись о погашении - в одной транзакции.
+// Строка кода блокируется, поэтому параллельные погашения не превысят лимиты
+func (ps *PostgresStorage) RedeemPromoCode(userID int, code string, now time.Time) (models.PromoRedemption, error) {
+	ctx := context.Background()
+	var redemption models.PromoRedemption
+	err := ps.inTx(ctx, nil, func(tx *sql.Tx) error {
+		var promo models.PromoCode
+		err := scanPromoCode(tx.QueryRowContext(ctx, `
+            SELECT `+promoCodeColumns+` FROM promo_codes WHERE code = $1 FOR UPDATE
+        `, code), &promo)
+		if err == sql.ErrNoRows {
+			return handler.ErrPromoCodeNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get promo code: %w", err)
+		}
+		if !promo.ActiveAt(now) {
+			return handler.ErrPromoCodeInactive
+		}
+		if promo.MaxRedemptions > 0 && promo.Redemptions >= promo.MaxRedemptions {
+			return handler.ErrPromoCodeExhausted
+		}
+
+		var redeemed int
+		err = tx.QueryRowContext(ctx, `
+            SELECT COUNT(*) FROM promo_redemptions WHERE promo_id = $1 AND user_id = $2
+        `, promo.ID, userID).Scan(&redeemed)
+		if err != nil {
+			return fmt.Errorf("failed to count redemptions: %w", err)
+		}
+		if redeemed >= promo.PerUserLimit {
+			return handler.ErrPromoCodeAlreadyRedeemed
+		}
+
+		redemption = models.PromoRedemption{Code: promo.Code, Kind: promo.Kind}
+		switch promo.Kind {
+		case models.PromoKindPoints:
+			posted, err := ledger.Post(ctx, tx, models.LedgerEntry{
+				UserID: userID,
+				Type:   models.LedgerEntryPromo,
+				Amount: promo.Points,
+				Note:   "promo code " + promo.Code,
+			})
+			if err != nil {
+				return ledgerError(err)
+			}
+			redemption.Points = promo.Points
+			redemption.LedgerEntryID = &posted.ID
+		case models.PromoKindMultiplier:
+			redemption.Multiplier = promo.Multiplier
+			redemption.OrdersLeft = promo.Orders
+		}
+
+		// погашение без проводки получает номер из последовательности журнала для истории баланса
+		err = tx.QueryRowContext(ctx, `
+            INSERT INTO promo_redemptions (promo_id, user_id, ledger_entry_id, orders_left, history_id)
+            VALUES ($1, $2, $3, $4,
+                    CASE WHEN $3::BIGINT IS NULL THEN nextval(pg_get_serial_sequence('ledger_entries', 'id')) END)
+            RETURNING id, created_at
+        `, promo.ID, userID, redemption.LedgerEntryID, redemption.OrdersLeft).Scan(&redemption.ID, &redemption.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to record redemption: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+            UPDATE promo_codes SET redemptions = redemptions + 1 WHERE id = $1
+        `, promo.ID); err != nil {
+			return fmt.Errorf("failed to count redemption: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.PromoRedemption{}, err
+	}
+	return redemption, nil
+}
+
+// BalanceHistory - проводки пользователя по фильтру, новые первыми. Порядок по id совпадает с порядком
+// проведения: проводки одного счёта пишутся под блокировкой его строки, поэтому balance_after идёт подряд.
+// Погашения множителей проводок не пишут и попадают в историю событием promo с нулевой суммой
+// под своим history_id и остатком предыдущей проводки
+func (ps *PostgresStorage) BalanceHistory(f models.BalanceHistoryFilter) ([]models.LedgerEntry, error) {
+	rows, err := ps.DB.Query(`
+        SELECT id, entry_type, amount, balance_after, order_number, note, created_at
+        FROM (
+            SELECT id, entry_type, amount, balance_after, COALESCE(order_number, '') AS order_number, note, created_at
+            FROM ledger_entries
+            WHERE user_id = $1
+            UNION ALL
+            SELECT r.history_id, 'promo', 0, COALESCE((
+                       SELECT le.balance_after FROM ledger_entries le
+                       WHERE le.user_id = r.user_id AND le.id < r.history_id
+                       ORDER BY le.id DESC LIMIT 1
+                   ), 0),
+                   '', 'promo code ' || p.code || ' x' || p.multiplier || ' for ' || p.orders || ' orders', r.created_at
+            FROM promo_redemptions r
+            JOIN promo_codes p ON p.id = r.promo_id
+            WHERE r.user_id = $1 AND r.history_id IS NOT NULL
+        ) history
+        WHERE ($2 = 0 OR id < $2)
+          AND ($3::timestamptz IS NULL OR created_at >= $3)
+          AND ($4::timestamptz IS NULL OR created_at < $4)
+          AND ($5 = '' OR entry_type = ANY(string_to_array($5, ',')))
+        ORDER BY id DESC
+        LIMIT $6
+    `, f.UserID, f.BeforeID, f.From, f.To, strings.Join(f.Types, ","), f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.LedgerEntry
+	for rows.Next() {
+		e := models.LedgerEntry{UserID: f.UserID}
+		if err := rows.Scan(&e.ID, &e.Type, &e.Amount, &e.BalanceAfter, &e.OrderNumber, &e.Note, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// StatementOpeningBalance - остаток на момент before по журналу баллов
+func (ps *PostgresStorage) StatementOpeningBalance(userID int, before time.Time) (money.Amount, error) {
+	var opening money.Amount
+	err := ps.DB.QueryRow(`
+        SELECT COALESCE(SUM(amount), 0) FROM ledger_entries WHERE user_id = $1 AND created_at < $2
+    `, userID, before).Scan(&opening)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get opening balance: %w", err)
+	}
+	return opening, nil
+}
+
+// StatementLines передаёт в fn проводки журнала за [from, to) по порядку. Сторно списания - строка
+// возврата. Строки читаются из курсора по одной, поэтому выписка за любой период не загружается
+// в память. Balance заполняет вызывающий
+func (ps *PostgresStorage) StatementLines(ctx context.Context, userID int, from, to time.Time, fn func(models.StatementLine) error) error {
+	rows, err := ps.DB.QueryContext(ctx, `
+        SELECT created_at,
+               CASE WHEN entry_type = 'reversal' AND withdrawal_id IS NOT NULL THEN 'refund' ELSE entry_type END,
+               COALESCE(order_number, ''),
+               amount
+        FROM ledger_entries
+        WHERE user_id = $1 AND created_at >= $2 AND created_at < $3
+        ORDER BY created_at, id
+    `, userID, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to get statement lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line models.StatementLine
+		if err := rows.Scan(&line.Date, &line.Type, &line.Order, &line.Amount); err != nil {
+			return err
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// OrdersPage - заказы пользователя страницей по ключу (uploaded_at, uid). Ключ не меняется: время
+// загрузки при смене статуса не перезаписывается, а новые заказы загружены позже и уже выданные
+// страницы не сдвигают, поэтому заказ не пропадает и не повторяется, в отличие от OFFSET
+func (ps *PostgresStorage) OrdersPage(f models.OrdersFilter) ([]models.Order, error) {
+	direction, after := "DESC", "<"
+	if f.Asc {
+		direction, after = "ASC", ">"
+	}
+	rows, err := ps.DB.Query(`
+        SELECT uid, number, status, accrual, uploaded_at
+        FROM orders
+        WHERE user_id = $1
+          AND ($2 = '' OR status = ANY(string_to_array($2, ',')))
+          AND ($3::timestamptz IS NULL OR uploaded_at >= $3)
+          AND ($4::timestamptz IS NULL OR uploaded_at < $4)
+          AND ($5::timestamptz IS NULL OR (uploaded_at, uid) `+after+` ($5, $6))
+        ORDER BY uploaded_at `+direction+`, uid `+direction+`
+        LIMIT $7
+    `, f.UserID, strings.Join(f.Statuses, ","), f.From, f.To, f.AfterUploadedAt, f.AfterUID, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []models.Order
+	for rows.Next() {
+		order := models.Order{UserID: f.UserID}
+		if err := rows.Scan(&order.UID, &order.Number, &order.Status, &order.Accrual, &order.UploadedAt); err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
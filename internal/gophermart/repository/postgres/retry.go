@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	// txMaxAttempts - сколько раз выполняется транзакция при повторяемых ошибках
+	txMaxAttempts = 3
+	// txRetryDelay - пауза перед повтором, растёт с номером попытки
+	txRetryDelay = 20 * time.Millisecond
+)
+
+// inTx выполняет fn в транзакции и повторяет её целиком, если ошибка повторяемая
+// (конфликт сериализации, взаимоблокировка, обрыв соединения). Ошибка коммита не повторяется:
+// транзакция могла успеть примениться
+func (ps *PostgresStorage) inTx(ctx context.Context, opts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	for attempt := 1; ; attempt++ {
+		commitFailed, err := ps.tryTx(ctx, opts, fn)
+		if err == nil || commitFailed || attempt == txMaxAttempts || ps.errorClassifier.Classify(err) != Retriable {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * txRetryDelay):
+		}
+	}
+}
+
+func (ps *PostgresStorage) tryTx(ctx context.Context, opts *sql.TxOptions, fn func(tx *sql.Tx) error) (commitFailed bool, err error) {
+	tx, err := ps.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return true, err
+	}
+	return false, nil
+}
@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresStorage_SetUserBlocked(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock: %v", err)
+	}
+	defer db.Close()
+
+	storage := newTestStorage(db)
+	target := 2
+	entry := models.AuditEntry{ActorID: 1, Action: models.AuditBlockUser, TargetUserID: &target}
+
+	// блокировка отзывает сессии и refresh-токены в той же транзакции, что и запись аудита
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE users SET blocked_at = COALESCE\(blocked_at, NOW\(\)\)`).
+		WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE sessions SET revoked_at = NOW\(\)`).
+		WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`UPDATE refresh_tokens SET revoked_at = NOW\(\)`).
+		WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO admin_audit_log`).
+		WithArgs(1, models.AuditBlockUser, &target, "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, storage.SetUserBlocked(2, true, entry))
+
+	// несуществующий пользователь
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE users SET blocked_at = NULL`).
+		WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err = storage.SetUserBlocked(3, false, models.AuditEntry{ActorID: 1, Action: models.AuditUnblockUser})
+	assert.ErrorIs(t, err, handler.ErrUserNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_RequeueOrder(t *testing.T) {
+	uploadedAt := time.Now()
+	entry := models.AuditEntry{ActorID: 1, Action: models.AuditRequeueOrder, Details: "order=12345678903"}
+
+	tests := []struct {
+		name        string
+		mockSetup   func(mock sqlmock.Sqlmock)
+		expectedErr error
+	}{
+		{
+			name: "Stuck order is requeued",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT uid, user_id, status, uploaded_at FROM orders WHERE number = \$1 FOR UPDATE`).
+					WithArgs("12345678903").
+					WillReturnRows(sqlmock.NewRows([]string{"uid", "user_id", "status", "uploaded_at"}).
+						AddRow(7, 2, "PROCESSING", uploadedAt))
+				mock.ExpectExec(`UPDATE orders SET status = 'NEW', accrual = 0 WHERE uid = \$1`).
+					WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`SELECT pg_notify\('new_orders'`).
+					WithArgs(7, 2, "12345678903", uploadedAt).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`INSERT INTO admin_audit_log`).
+					WithArgs(1, models.AuditRequeueOrder, sqlmock.AnyArg(), "order=12345678903").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "Processed order is not requeued",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT uid, user_id, status, uploaded_at FROM orders`).
+					WithArgs("12345678903").
+					WillReturnRows(sqlmock.NewRows([]string{"uid", "user_id", "status", "uploaded_at"}).
+						AddRow(7, 2, "PROCESSED", uploadedAt))
+				mock.ExpectRollback()
+			},
+			expectedErr: handler.ErrOrderAlreadyProcessed,
+		},
+		{
+			name: "Unknown order",
+			mockSetup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT uid, user_id, status, uploaded_at FROM orders`).
+					WithArgs("12345678903").
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			expectedErr: handler.ErrOrderNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("Error creating mock: %v", err)
+			}
+			defer db.Close()
+
+			storage := newTestStorage(db)
+			tt.mockSetup(mock)
+
+			err = storage.RequeueOrder("12345678903", entry)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgresStorage_SearchUsers_EscapesWildcards(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock: %v", err)
+	}
+	defer db.Close()
+
+	// "_" и "%" ищутся буквально, а не как шаблон
+	mock.ExpectQuery(`WHERE login ILIKE '%' \|\| \$1 \|\| '%' ESCAPE '\\'`).
+		WithArgs(`a\_b\%c\\d`, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password_hash", "role", "blocked_at", "created_at"}).
+			AddRow(1, `a_b%c\d`, "hash", models.RoleUser, nil, time.Now()))
+
+	users, err := newTestStorage(db).SearchUsers(`a_b%c\d`, 20)
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
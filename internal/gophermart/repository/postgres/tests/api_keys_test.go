@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var apiKeyColumns = []string{"id", "user_id", "name", "prefix", "scopes", "created_at", "last_used_at", "expires_at"}
+
+func TestPostgresStorage_CreateAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock: %v", err)
+	}
+	defer db.Close()
+
+	storage := newTestStorage(db)
+	createdAt := time.Now()
+
+	mock.ExpectQuery(`INSERT INTO api_keys`).
+		WithArgs(1, "shop", "gm_abcdefg", "hash", "orders:write,balance:read", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, createdAt))
+
+	key := models.APIKey{UserID: 1, Name: "shop", Prefix: "gm_abcdefg", Scopes: []string{models.ScopeOrdersWrite, models.ScopeBalanceRead}}
+	require.NoError(t, storage.CreateAPIKey(&key, "hash"))
+	assert.Equal(t, 3, key.ID)
+	assert.Equal(t, createdAt, key.CreatedAt)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_UseAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock: %v", err)
+	}
+	defer db.Close()
+
+	storage := newTestStorage(db)
+	now := time.Now()
+
+	mock.ExpectQuery(`UPDATE api_keys SET last_used_at = NOW\(\)`).
+		WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows(apiKeyColumns).
+			AddRow(3, 1, "shop", "gm_abcdefg", "orders:write", now, now, nil))
+	mock.ExpectQuery(`UPDATE api_keys SET last_used_at = NOW\(\)`).
+		WithArgs("revoked").
+		WillReturnError(sql.ErrNoRows)
+
+	key, err := storage.UseAPIKey("hash")
+	require.NoError(t, err)
+	require.NotNil(t, key)
+	assert.Equal(t, 1, key.UserID)
+	assert.Equal(t, []string{models.ScopeOrdersWrite}, key.Scopes)
+	assert.Nil(t, key.ExpiresAt)
+
+	key, err = storage.UseAPIKey("revoked")
+	assert.NoError(t, err)
+	assert.Nil(t, key)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_RevokeAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock: %v", err)
+	}
+	defer db.Close()
+
+	storage := newTestStorage(db)
+
+	mock.ExpectExec(`UPDATE api_keys SET revoked_at = NOW\(\)`).
+		WithArgs(3, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	// чужой ключ
+	mock.ExpectExec(`UPDATE api_keys SET revoked_at = NOW\(\)`).
+		WithArgs(3, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, storage.RevokeAPIKey(1, 3))
+	assert.ErrorIs(t, storage.RevokeAPIKey(2, 3), handler.ErrAPIKeyNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -29,23 +29,23 @@ func TestPostgresStorage_CreateUser_Success(t *testing.T) {
 
 	storage := newTestStorage(db)
 
-	// вычисляем реальный хеш
-	expectedHash := postgres.HashPassword("password123")
+	// хэш считает сервис, репозиторий сохраняет его как есть
+	expectedHash := "$2a$10$abcdefghijklmnopqrstuuN5bSWuGpUxG7r0xw0eCUvEy.Pz8h7XW"
 	createdAt := time.Now()
 
 	// проверяем что пользователь не существует
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, login, password_hash, created_at FROM users WHERE login = $1`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, login, password_hash, role, blocked_at, created_at FROM users WHERE login = $1`)).
 		WithArgs("newuser").
 		WillReturnError(sql.ErrNoRows) // Пользователь не существует
 
 	// ожидаем успешное создание пользователя
-	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO users (login, password_hash) VALUES ($1, $2) RETURNING id, login, password_hash, created_at`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO users (login, password_hash) VALUES ($1, $2) RETURNING id, login, password_hash, role, blocked_at, created_at`)).
 		WithArgs("newuser", expectedHash).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password_hash", "created_at"}).
-			AddRow(1, "newuser", expectedHash, createdAt))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password_hash", "role", "blocked_at", "created_at"}).
+			AddRow(1, "newuser", expectedHash, "user", nil, createdAt))
 
 	// выполняем тестируемый метод
-	user, err := storage.CreateUser("newuser", "password123")
+	user, err := storage.CreateUser("newuser", expectedHash)
 
 	// сравниваем результаты ожиданий с реальными
 	assert.NoError(t, err)
@@ -69,12 +69,12 @@ func TestPostgresStorage_CreateUser_LoginExists(t *testing.T) {
 
 	createdAt := time.Now()
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, login, password_hash, created_at FROM users WHERE login = $1`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, login, password_hash, role, blocked_at, created_at FROM users WHERE login = $1`)).
 		WithArgs("existinguser").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password_hash", "created_at"}).
-			AddRow(1, "existinguser", "hash", createdAt))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password_hash", "role", "blocked_at", "created_at"}).
+			AddRow(1, "existinguser", "hash", "user", nil, createdAt))
 
-	user, err := storage.CreateUser("existinguser", "password123")
+	user, err := storage.CreateUser("existinguser", "hash")
 
 	assert.Error(t, err)
 	assert.Nil(t, user)
@@ -82,8 +82,8 @@ func TestPostgresStorage_CreateUser_LoginExists(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-// удачная авторизация по логину и паролю
-func TestPostgresStorage_GetUserByLoginAndPassword_Success(t *testing.T) {
+// перехэширование пароля
+func TestPostgresStorage_UpdatePasswordHash_Success(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("Error creating mock: %v", err)
@@ -92,25 +92,18 @@ func TestPostgresStorage_GetUserByLoginAndPassword_Success(t *testing.T) {
 
 	storage := newTestStorage(db)
 
-	expectedHash := postgres.HashPassword("correctpassword")
-	createdAt := time.Now()
-
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, login, password_hash, created_at FROM users WHERE login = $1 AND password_hash = $2`)).
-		WithArgs("testuser", expectedHash).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password_hash", "created_at"}).
-			AddRow(1, "testuser", expectedHash, createdAt))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET password_hash = $1 WHERE id = $2`)).
+		WithArgs("new-hash", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	user, err := storage.GetUserByLoginAndPassword("testuser", "correctpassword")
+	err = storage.UpdatePasswordHash(1, "new-hash")
 
 	assert.NoError(t, err)
-	assert.NotNil(t, user)
-	assert.Equal(t, 1, user.ID)
-	assert.Equal(t, "testuser", user.Login)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-// неудачная авторизация, не существует
-func TestPostgresStorage_GetUserByLoginAndPassword_WrongPassword(t *testing.T) {
+// перехэширование, пользователь не найден
+func TestPostgresStorage_UpdatePasswordHash_NotFound(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("Error creating mock: %v", err)
@@ -119,15 +112,56 @@ func TestPostgresStorage_GetUserByLoginAndPassword_WrongPassword(t *testing.T) {
 
 	storage := newTestStorage(db)
 
-	wrongPasswordHash := postgres.HashPassword("wrongpassword")
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET password_hash = $1 WHERE id = $2`)).
+		WithArgs("new-hash", 42).
+		WillReturnResult(sqlmock.NewResult(0, 0))
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, login, password_hash, created_at FROM users WHERE login = $1 AND password_hash = $2`)).
-		WithArgs("testuser", wrongPasswordHash).
-		WillReturnError(sql.ErrNoRows)
+	err = storage.UpdatePasswordHash(42, "new-hash")
 
-	user, err := storage.GetUserByLoginAndPassword("testuser", "wrongpassword")
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
 
-	assert.NoError(t, err)
-	assert.Nil(t, user)
+func TestPostgresStorage_ChangePassword(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock: %v", err)
+	}
+	defer db.Close()
+
+	storage := newTestStorage(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE users SET password_hash = \$1 WHERE id = \$2`).
+		WithArgs("new-hash", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE sessions SET revoked_at`).
+		WithArgs(1, 5).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE refresh_tokens SET revoked_at`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	assert.NoError(t, storage.ChangePassword(1, "new-hash", 5))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_ChangePassword_UserNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock: %v", err)
+	}
+	defer db.Close()
+
+	storage := newTestStorage(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE users SET password_hash = \$1 WHERE id = \$2`).
+		WithArgs("new-hash", 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	assert.Error(t, storage.ChangePassword(1, "new-hash", 5))
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/pkg/money"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -28,15 +29,16 @@ func TestPostgresStorage_GetBalance(t *testing.T) {
 			name:   "Successful balance receipt",
 			userID: 1,
 			setupMock: func() {
-				rows := sqlmock.NewRows([]string{"current", "withdrawn"}).
-					AddRow(500.5, 42.0)
+				rows := sqlmock.NewRows([]string{"current", "held", "withdrawn"}).
+					AddRow(500.5, "20.00", 42.0)
 				mock.ExpectQuery(`SELECT`).
 					WithArgs(1).
 					WillReturnRows(rows)
 			},
 			expectedResult: models.Balance{
-				Current:   500.5,
-				Withdrawn: 42,
+				Current:   money.FromCents(50050),
+				Held:      money.FromUnits(20),
+				Withdrawn: money.FromUnits(42),
 			},
 			expectError: false,
 		},
@@ -44,8 +46,8 @@ func TestPostgresStorage_GetBalance(t *testing.T) {
 			name:   "Empty balance",
 			userID: 2,
 			setupMock: func() {
-				rows := sqlmock.NewRows([]string{"current", "withdrawn"}).
-					AddRow(0, 0)
+				rows := sqlmock.NewRows([]string{"current", "held", "withdrawn"}).
+					AddRow(0, 0, 0)
 				mock.ExpectQuery(`SELECT`).
 					WithArgs(2).
 					WillReturnRows(rows)
@@ -71,7 +73,7 @@ func TestPostgresStorage_GetBalance(t *testing.T) {
 			name:   "No rows (returns zeros)",
 			userID: 4,
 			setupMock: func() {
-				rows := sqlmock.NewRows([]string{"current", "withdrawn"})
+				rows := sqlmock.NewRows([]string{"current", "held", "withdrawn"})
 				mock.ExpectQuery(`SELECT`).
 					WithArgs(4).
 					WillReturnRows(rows)
@@ -7,6 +7,7 @@ import (
 
 	postgresError "go-musthave-diploma-tpl/internal/gophermart/repository/postgres"
 
+	pgconnv4 "github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
 )
@@ -79,6 +80,16 @@ func TestPostgresErrorClassifier_Classify(t *testing.T) {
 			},
 			expected: postgresError.NonRetriable,
 		},
+		{
+			name:     "pgx/v4 serialization failure - Retriable",
+			err:      fmt.Errorf("failed to update account: %w", &pgconnv4.PgError{Code: "40001"}),
+			expected: postgresError.Retriable,
+		},
+		{
+			name:     "pgx/v4 unique violation - NonRetriable",
+			err:      &pgconnv4.PgError{Code: "23505"},
+			expected: postgresError.NonRetriable,
+		},
 	}
 
 	for _, tt := range tests {
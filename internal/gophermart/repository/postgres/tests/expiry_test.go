@@ -0,0 +1,211 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/ledger"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/repository/postgres/pgtest"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStorage_ExpiringPoints(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	policy := models.PointsExpiry{Policy: models.PointsExpiryRolling, Lifetime: 24 * time.Hour}
+	until := time.Now().Add(time.Hour)
+	soon := time.Now().Add(30 * time.Minute)
+
+	// rolling: срок отсчитывается от последнего начисления или списания
+	mock.ExpectQuery(`GREATEST\(l.earned_at(.|\n)*entry_type IN \('accrual', 'withdrawal'\)`).
+		WithArgs(float64(86400), 1, until).
+		WillReturnRows(sqlmock.NewRows([]string{"expires_at", "sum"}).AddRow(soon, "120.50"))
+
+	expiring, err := newTestStorage(db).ExpiringPoints(1, policy, until)
+	require.NoError(t, err)
+	assert.Equal(t, []models.ExpiringPoints{{Amount: money.FromCents(12050), ExpiresAt: soon}}, expiring)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_ExpirePoints(t *testing.T) {
+	policy := models.PointsExpiry{Policy: models.PointsExpiryFixed, Lifetime: 24 * time.Hour}
+	now := time.Now()
+
+	t.Run("expired lots are debited", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		// пакет - по самым давним срокам и только у кого есть свободные баллы
+		mock.ExpectQuery(`WHERE d.deadline <= \$2 AND a.balance - a.held > 0\s+GROUP BY d.user_id\s+ORDER BY MIN\(d.deadline\), d.user_id`).
+			WithArgs(float64(86400), now, 500).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1).AddRow(2))
+
+		// у первого пользователя сгорело больше, чем свободно: зарезервированное не трогаем
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT balance - held FROM accounts`).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"available"}).AddRow("80.00"))
+		mock.ExpectQuery(`SELECT COALESCE\(SUM\(l.remaining\), 0\)`).
+			WithArgs(float64(86400), 1, now).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow("100.00"))
+		mock.ExpectQuery(`UPDATE accounts`).
+			WithArgs(1, money.FromUnits(-80), money.Zero).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("20.00"))
+		mock.ExpectQuery(`INSERT INTO ledger_entries`).
+			WithArgs(1, models.LedgerEntryExpiration, money.FromUnits(-80), money.FromUnits(20), models.LedgerAccountExpirations,
+				"", sqlmock.AnyArg(), sqlmock.AnyArg(), "points expired (fixed policy)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(50, time.Now()))
+		mock.ExpectExec(`INSERT INTO point_lot_consumptions`).
+			WithArgs(1, int64(50), money.FromUnits(80)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO admin_audit_log`).
+			WithArgs(0, models.AuditExpirePoints, 1, "entry=50 amount=80.00 policy=fixed").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		// второй успел всё потратить после выборки: проводки нет
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT balance - held FROM accounts`).
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"available"}).AddRow("0.00"))
+		mock.ExpectQuery(`SELECT COALESCE\(SUM\(l.remaining\), 0\)`).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow("0.00"))
+		mock.ExpectCommit()
+
+		expired, err := newTestStorage(db).ExpirePoints(policy, now)
+		require.NoError(t, err)
+		assert.Equal(t, 1, expired)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failure of one user does not stop others", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery(`GROUP BY d.user_id`).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1).AddRow(2))
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT balance - held FROM accounts`).
+			WithArgs(1).
+			WillReturnError(assert.AnError)
+		mock.ExpectRollback()
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT balance - held FROM accounts`).
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"available"}).AddRow("0.00"))
+		mock.ExpectQuery(`SELECT COALESCE\(SUM\(l.remaining\), 0\)`).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow("0.00"))
+		mock.ExpectCommit()
+
+		expired, err := newTestStorage(db).ExpirePoints(policy, now)
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.ErrorContains(t, err, "user 1")
+		assert.Zero(t, expired)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// списание гасит раньше начисленные баллы, сгорают только остатки просроченных партий
+func TestPostgresStorage_ExpirePointsFIFO(t *testing.T) {
+	db := pgtest.Open(t)
+	storage := newTestStorage(db)
+	ctx := context.Background()
+
+	user, err := storage.CreateUser(fmt.Sprintf("expiry-fifo-%d", time.Now().UnixNano()), "hash")
+	require.NoError(t, err)
+	old, err := ledger.Post(ctx, db, models.LedgerEntry{UserID: user.ID, Type: models.LedgerEntryAccrual, Amount: money.FromUnits(100)})
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE point_lots SET earned_at = NOW() - INTERVAL '2 days' WHERE entry_id = $1`, old.ID)
+	require.NoError(t, err)
+	_, err = ledger.Post(ctx, db, models.LedgerEntry{UserID: user.ID, Type: models.LedgerEntryAccrual, Amount: money.FromUnits(50)})
+	require.NoError(t, err)
+
+	require.NoError(t, storage.Withdraw(user.ID, models.WithdrawBalance{
+		Order: fmt.Sprintf("%d2001", user.ID),
+		Sum:   money.FromUnits(30),
+	}))
+
+	policy := models.PointsExpiry{Policy: models.PointsExpiryFixed, Lifetime: 24 * time.Hour}
+	_, err = storage.ExpirePoints(policy, time.Now())
+	require.NoError(t, err)
+
+	balance, err := storage.GetBalance(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.FromUnits(50), balance.Current)
+
+	var burned money.Amount
+	require.NoError(t, db.QueryRow(`
+        SELECT -amount FROM ledger_entries WHERE user_id = $1 AND entry_type = 'expiration'
+    `, user.ID).Scan(&burned))
+	assert.Equal(t, money.FromUnits(70), burned)
+
+	mismatches, err := storage.CheckLedger()
+	require.NoError(t, err)
+	for _, m := range mismatches {
+		assert.NotEqual(t, user.ID, m.UserID, "ledger does not match account: %+v", m)
+	}
+}
+
+// сторно ошибочного сгорания возвращает баллы в ту же партию: срок сгорания остаётся прежним
+func TestPostgresStorage_ReverseExpiryRestoresLot(t *testing.T) {
+	db := pgtest.Open(t)
+	storage := newTestStorage(db)
+	ctx := context.Background()
+
+	user, err := storage.CreateUser(fmt.Sprintf("expiry-reverse-%d", time.Now().UnixNano()), "hash")
+	require.NoError(t, err)
+	accrual, err := ledger.Post(ctx, db, models.LedgerEntry{UserID: user.ID, Type: models.LedgerEntryAccrual, Amount: money.FromUnits(100)})
+	require.NoError(t, err)
+	earnedAt := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	_, err = db.Exec(`UPDATE point_lots SET earned_at = $2 WHERE entry_id = $1`, accrual.ID, earnedAt)
+	require.NoError(t, err)
+
+	policy := models.PointsExpiry{Policy: models.PointsExpiryFixed, Lifetime: 24 * time.Hour}
+	_, err = storage.ExpirePoints(policy, time.Now())
+	require.NoError(t, err)
+
+	var expiryID int64
+	require.NoError(t, db.QueryRow(`
+        SELECT id FROM ledger_entries WHERE user_id = $1 AND entry_type = 'expiration'
+    `, user.ID).Scan(&expiryID))
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	_, err = ledger.Reverse(ctx, tx, expiryID, 0, "expired by mistake")
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	// баллы снова на счёте, партия одна и с прежней датой начисления
+	var (
+		lots     int
+		restored time.Time
+	)
+	require.NoError(t, db.QueryRow(`
+        SELECT COUNT(*), MIN(earned_at) FROM point_lots WHERE user_id = $1 AND remaining > 0
+    `, user.ID).Scan(&lots, &restored))
+	assert.Equal(t, 1, lots)
+	assert.True(t, earnedAt.Equal(restored), "earned_at %s, want %s", restored, earnedAt)
+
+	// срок сгорания прежний: партия уже просрочена, а не получила новый год жизни
+	expiring, err := storage.ExpiringPoints(user.ID, policy, time.Now())
+	require.NoError(t, err)
+	require.Len(t, expiring, 1)
+	assert.Equal(t, money.FromUnits(100), expiring[0].Amount)
+	assert.True(t, earnedAt.Add(24*time.Hour).Equal(expiring[0].ExpiresAt))
+
+	mismatches, err := storage.CheckLedger()
+	require.NoError(t, err)
+	for _, m := range mismatches {
+		assert.NotEqual(t, user.ID, m.UserID, "ledger does not match account: %+v", m)
+	}
+}
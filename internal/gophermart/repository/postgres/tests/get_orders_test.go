@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/pkg/money"
 )
 
 // Успешное получение заказов
@@ -27,7 +28,7 @@ func TestPostgresStorage_GetOrders_Success(t *testing.T) {
 		{
 			Number:     "1234567890",
 			Status:     "PROCESSED",
-			Accrual:    100.5,
+			Accrual:    money.FromCents(10050),
 			UploadedAt: time.Now().Add(-24 * time.Hour),
 		},
 		{
@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/ledger"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/repository/postgres/pgtest"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStorage_BalanceHistory(t *testing.T) {
+	columns := []string{"id", "entry_type", "amount", "balance_after", "order_number", "note", "created_at"}
+	now := time.Now()
+
+	t.Run("filters", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		from := now.Add(-24 * time.Hour)
+		mock.ExpectQuery(`FROM ledger_entries\s+WHERE user_id = \$1(.|\n)*\) history\s+WHERE \(\$2 = 0 OR id < \$2\)`).
+			WithArgs(1, int64(40), from, nil, "accrual,withdrawal", 11).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(39, models.LedgerEntryWithdrawal, "-20.00", "80.00", "2377225624", "", now).
+				AddRow(38, models.LedgerEntryAccrual, "100.00", "100.00", "12345678903", "", now.Add(-time.Hour)))
+
+		entries, err := newTestStorage(db).BalanceHistory(models.BalanceHistoryFilter{
+			UserID:   1,
+			BeforeID: 40,
+			From:     &from,
+			Types:    []string{models.LedgerEntryAccrual, models.LedgerEntryWithdrawal},
+			Limit:    11,
+		})
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, int64(39), entries[0].ID)
+		assert.Equal(t, money.FromUnits(80), entries[0].BalanceAfter)
+		assert.Equal(t, "12345678903", entries[1].OrderNumber)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("first page without filters", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery(`FROM ledger_entries`).
+			WithArgs(1, int64(0), nil, nil, "", 51).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		entries, err := newTestStorage(db).BalanceHistory(models.BalanceHistoryFilter{UserID: 1, Limit: 51})
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("multiplier redemption", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		// погашение множителя приходит из promo_redemptions событием с нулевой суммой
+		mock.ExpectQuery(`UNION ALL\s+SELECT r.history_id, 'promo', 0(.|\n)*FROM promo_redemptions r`).
+			WithArgs(1, int64(0), nil, nil, "promo", 11).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(41, models.LedgerEntryPromo, "0.00", "80.00", "", "promo code DOUBLE x2.00 for 3 orders", now))
+
+		entries, err := newTestStorage(db).BalanceHistory(models.BalanceHistoryFilter{
+			UserID: 1,
+			Types:  []string{models.LedgerEntryPromo},
+			Limit:  11,
+		})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.True(t, entries[0].Amount.IsZero())
+		assert.Equal(t, money.FromUnits(80), entries[0].BalanceAfter)
+		assert.Equal(t, "promo code DOUBLE x2.00 for 3 orders", entries[0].Note)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// погашение множителя встаёт в историю между проводками и листается тем же курсором
+func TestPostgresStorage_BalanceHistoryMultiplierRedemption(t *testing.T) {
+	db := pgtest.Open(t)
+	storage := newTestStorage(db)
+	ctx := context.Background()
+
+	user, err := storage.CreateUser(fmt.Sprintf("history-promo-%d", time.Now().UnixNano()), "hash")
+	require.NoError(t, err)
+	code := fmt.Sprintf("X2-%d", user.ID)
+	_, err = db.Exec(`INSERT INTO promo_codes (code, kind, multiplier, orders) VALUES ($1, 'multiplier', 2, 3)`, code)
+	require.NoError(t, err)
+
+	before, err := ledger.Post(ctx, db, models.LedgerEntry{UserID: user.ID, Type: models.LedgerEntryAccrual, Amount: money.FromUnits(100)})
+	require.NoError(t, err)
+	_, err = storage.RedeemPromoCode(user.ID, code, time.Now())
+	require.NoError(t, err)
+	after, err := ledger.Post(ctx, db, models.LedgerEntry{UserID: user.ID, Type: models.LedgerEntryAccrual, Amount: money.FromUnits(20)})
+	require.NoError(t, err)
+
+	entries, err := storage.BalanceHistory(models.BalanceHistoryFilter{UserID: user.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, after.ID, entries[0].ID)
+	assert.Equal(t, before.ID, entries[2].ID)
+
+	promo := entries[1]
+	assert.Equal(t, models.LedgerEntryPromo, promo.Type)
+	assert.True(t, promo.Amount.IsZero())
+	assert.Equal(t, money.FromUnits(100), promo.BalanceAfter)
+	assert.Equal(t, "promo code "+code+" x2.00 for 3 orders", promo.Note)
+
+	// следующая страница после погашения начинается с предыдущей проводки
+	page, err := storage.BalanceHistory(models.BalanceHistoryFilter{UserID: user.ID, BeforeID: promo.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, before.ID, page[0].ID)
+}
@@ -0,0 +1,190 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStorage_CreateHold(t *testing.T) {
+	hold := models.Hold{UserID: 1, Order: "2377225624", Amount: money.FromUnits(300), ExpiresAt: time.Now().Add(time.Hour)}
+
+	t.Run("points are held", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		// доступно - остаток за вычетом уже зарезервированного
+		mock.ExpectQuery(`SELECT balance - held FROM accounts`).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"available"}).AddRow("300.00"))
+		mock.ExpectQuery(`SELECT\s+EXISTS`).
+			WithArgs("2377225624").
+			WillReturnRows(sqlmock.NewRows([]string{"accrual_order", "already_paid", "on_hold"}).AddRow(false, false, false))
+		mock.ExpectQuery(`INSERT INTO balance_holds`).
+			WithArgs(1, "2377225624", money.FromUnits(300), hold.ExpiresAt).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "created_at"}).AddRow(3, models.HoldStatusActive, time.Now()))
+		mock.ExpectExec(`UPDATE accounts SET held = held \+ \$2`).
+			WithArgs(1, money.FromUnits(300)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		created, err := newTestStorage(db).CreateHold(hold)
+		require.NoError(t, err)
+		assert.Equal(t, 3, created.ID)
+		assert.Equal(t, models.HoldStatusActive, created.Status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("held points cannot be spent", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT balance - held FROM accounts`).
+			WillReturnRows(sqlmock.NewRows([]string{"available"}).AddRow("299.99"))
+		mock.ExpectQuery(`SELECT\s+EXISTS`).
+			WillReturnRows(sqlmock.NewRows([]string{"accrual_order", "already_paid", "on_hold"}).AddRow(false, false, false))
+		mock.ExpectRollback()
+
+		_, err = newTestStorage(db).CreateHold(hold)
+		assert.Equal(t, handler.ErrLackOfFunds, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("order already on hold", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT balance - held FROM accounts`).
+			WillReturnRows(sqlmock.NewRows([]string{"available"}).AddRow("1000.00"))
+		mock.ExpectQuery(`SELECT\s+EXISTS`).
+			WillReturnRows(sqlmock.NewRows([]string{"accrual_order", "already_paid", "on_hold"}).AddRow(false, false, true))
+		mock.ExpectRollback()
+
+		_, err = newTestStorage(db).CreateHold(hold)
+		assert.Equal(t, handler.ErrOrderOnHold, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresStorage_CaptureHold(t *testing.T) {
+	holdColumns := []string{"id", "user_id", "order_number", "amount", "status", "created_at", "expires_at"}
+
+	t.Run("hold becomes a withdrawal", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT .* FROM balance_holds`).
+			WithArgs(3, 1).
+			WillReturnRows(sqlmock.NewRows(holdColumns).
+				AddRow(3, 1, "2377225624", "300.00", models.HoldStatusActive, time.Now(), time.Now().Add(time.Hour)))
+		mock.ExpectExec(`UPDATE accounts SET held = held - \$2`).
+			WithArgs(1, money.FromUnits(300)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`INSERT INTO withdrawals`).
+			WithArgs(1, "2377225624", money.FromUnits(300)).
+			WillReturnRows(sqlmock.NewRows([]string{"uid"}).AddRow(9))
+		mock.ExpectQuery(`UPDATE accounts`).
+			WithArgs(1, money.FromUnits(-300), money.FromUnits(300)).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("0.00"))
+		mock.ExpectQuery(`INSERT INTO ledger_entries`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+		mock.ExpectExec(`point_lots`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`UPDATE balance_holds SET status`).
+			WithArgs(3, models.HoldStatusCaptured, 9).
+			WillReturnRows(sqlmock.NewRows([]string{"status", "closed_at"}).AddRow(models.HoldStatusCaptured, time.Now()))
+		mock.ExpectCommit()
+
+		hold, err := newTestStorage(db).CaptureHold(1, 3)
+		require.NoError(t, err)
+		assert.Equal(t, models.HoldStatusCaptured, hold.Status)
+		assert.NotNil(t, hold.ClosedAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("expired hold cannot be captured", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT .* FROM balance_holds`).
+			WillReturnRows(sqlmock.NewRows(holdColumns).
+				AddRow(3, 1, "2377225624", "300.00", models.HoldStatusActive, time.Now().Add(-time.Hour), time.Now().Add(-time.Minute)))
+		mock.ExpectRollback()
+
+		_, err = newTestStorage(db).CaptureHold(1, 3)
+		assert.Equal(t, handler.ErrHoldNotActive, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("hold of another user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT .* FROM balance_holds`).
+			WithArgs(3, 2).
+			WillReturnRows(sqlmock.NewRows(holdColumns))
+		mock.ExpectRollback()
+
+		_, err = newTestStorage(db).CaptureHold(2, 3)
+		assert.Equal(t, handler.ErrHoldNotFound, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresStorage_VoidHold(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT .* FROM balance_holds`).
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "order_number", "amount", "status", "created_at", "expires_at"}).
+			AddRow(3, 1, "2377225624", "300.00", models.HoldStatusActive, time.Now(), time.Now().Add(time.Hour)))
+	mock.ExpectExec(`UPDATE accounts SET held = held - \$2`).
+		WithArgs(1, money.FromUnits(300)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`UPDATE balance_holds SET status`).
+		WithArgs(3, models.HoldStatusVoided).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "closed_at"}).AddRow(models.HoldStatusVoided, time.Now()))
+	mock.ExpectCommit()
+
+	hold, err := newTestStorage(db).VoidHold(1, 3)
+	require.NoError(t, err)
+	assert.Equal(t, models.HoldStatusVoided, hold.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_ExpireHolds(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	// статус резерва и held на счёте меняются одним оператором
+	mock.ExpectQuery(`WITH expired AS \(\s+UPDATE balance_holds SET status = 'expired'.*UPDATE accounts a SET held = a.held - e.amount`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	n, err := newTestStorage(db).ExpireHolds()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
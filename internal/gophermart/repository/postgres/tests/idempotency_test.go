@@ -0,0 +1,142 @@
+package postgres
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/repository/postgres"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStorage_ReserveIdempotencyKey(t *testing.T) {
+	key := models.IdempotencyKey{
+		UserID:      1,
+		Scope:       models.IdempotencyScopeWithdraw,
+		Key:         "k-1",
+		Fingerprint: "fp",
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+
+	t.Run("new key is reserved", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery(`INSERT INTO idempotency_keys`).
+			WithArgs(1, models.IdempotencyScopeWithdraw, "k-1", "fp", key.ExpiresAt).
+			WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+
+		existing, err := (&postgres.PostgresStorage{DB: db}).ReserveIdempotencyKey(key)
+		require.NoError(t, err)
+		assert.Nil(t, existing)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("completed key returns stored response", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery(`INSERT INTO idempotency_keys`).
+			WillReturnRows(sqlmock.NewRows([]string{"created_at"}))
+		mock.ExpectQuery(`SELECT fingerprint, status_code, response_body, created_at, expires_at`).
+			WithArgs(1, models.IdempotencyScopeWithdraw, "k-1").
+			WillReturnRows(sqlmock.NewRows([]string{"fingerprint", "status_code", "response_body", "created_at", "expires_at"}).
+				AddRow("fp", 200, "{}", time.Now(), key.ExpiresAt))
+
+		existing, err := (&postgres.PostgresStorage{DB: db}).ReserveIdempotencyKey(key)
+		require.NoError(t, err)
+		require.NotNil(t, existing)
+		assert.Equal(t, "fp", existing.Fingerprint)
+		assert.Equal(t, &models.WithdrawSucceeded, existing.Response)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("key in progress has no response", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery(`INSERT INTO idempotency_keys`).
+			WillReturnRows(sqlmock.NewRows([]string{"created_at"}))
+		mock.ExpectQuery(`SELECT fingerprint, status_code, response_body, created_at, expires_at`).
+			WillReturnRows(sqlmock.NewRows([]string{"fingerprint", "status_code", "response_body", "created_at", "expires_at"}).
+				AddRow("fp", nil, nil, time.Now(), key.ExpiresAt))
+
+		existing, err := (&postgres.PostgresStorage{DB: db}).ReserveIdempotencyKey(key)
+		require.NoError(t, err)
+		require.NotNil(t, existing)
+		assert.Nil(t, existing.Response)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresStorage_WithdrawWithIdempotencyKey(t *testing.T) {
+	withdraw := models.WithdrawBalance{Order: "2377225624", Sum: money.FromUnits(751), IdempotencyKey: "k-1"}
+
+	t.Run("response is stored with the withdrawal", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE idempotency_keys SET status_code`).
+			WithArgs(1, models.IdempotencyScopeWithdraw, "k-1", 200, "{}").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`SELECT balance - held FROM accounts`).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("1000.00"))
+		mock.ExpectQuery(`SELECT\s+EXISTS`).
+			WillReturnRows(sqlmock.NewRows([]string{"accrual_order", "already_paid", "on_hold"}).AddRow(false, false, false))
+		mock.ExpectQuery(`INSERT INTO withdrawals`).
+			WithArgs(1, "2377225624", money.FromUnits(751)).
+			WillReturnRows(sqlmock.NewRows([]string{"uid"}).AddRow(7))
+		mock.ExpectQuery(`UPDATE accounts`).
+			WithArgs(1, money.FromUnits(-751), money.FromUnits(751)).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("249.00"))
+		mock.ExpectQuery(`INSERT INTO ledger_entries`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+		mock.ExpectExec(`point_lots`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, (&postgres.PostgresStorage{DB: db}).Withdraw(1, withdraw))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("duplicate request does not withdraw twice", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE idempotency_keys SET status_code`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err = (&postgres.PostgresStorage{DB: db}).Withdraw(1, withdraw)
+		assert.ErrorIs(t, err, handler.ErrIdempotencyConflict)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE idempotency_keys SET status_code`).
+			WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		err = (&postgres.PostgresStorage{DB: db}).Withdraw(1, withdraw)
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
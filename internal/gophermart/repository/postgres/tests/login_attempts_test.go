@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresStorage_LoginLockedUntil(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock: %v", err)
+	}
+	defer db.Close()
+
+	storage := newTestStorage(db)
+	until := time.Now().Add(time.Minute)
+
+	mock.ExpectQuery(`SELECT MAX\(locked_until\) FROM login_attempts`).
+		WithArgs(models.LoginAttemptScopeLogin, "user", models.LoginAttemptScopeIP, "10.0.0.1").
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(until))
+	mock.ExpectQuery(`SELECT MAX\(locked_until\) FROM login_attempts`).
+		WithArgs(models.LoginAttemptScopeLogin, "other", models.LoginAttemptScopeIP, "10.0.0.1").
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+
+	lockedUntil, err := storage.LoginLockedUntil("user", "10.0.0.1")
+	assert.NoError(t, err)
+	assert.True(t, lockedUntil.Equal(until))
+
+	lockedUntil, err = storage.LoginLockedUntil("other", "10.0.0.1")
+	assert.NoError(t, err)
+	assert.True(t, lockedUntil.IsZero())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_RecordLoginFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock: %v", err)
+	}
+	defer db.Close()
+
+	storage := newTestStorage(db)
+
+	mock.ExpectQuery(`INSERT INTO login_attempts`).
+		WithArgs(models.LoginAttemptScopeLogin, "user", time.Hour.Seconds()).
+		WillReturnRows(sqlmock.NewRows([]string{"failures"}).AddRow(3))
+	mock.ExpectQuery(`INSERT INTO login_attempts`).
+		WithArgs(models.LoginAttemptScopeIP, "10.0.0.1", time.Hour.Seconds()).
+		WillReturnError(sql.ErrConnDone)
+
+	failures, err := storage.RecordLoginFailure(models.LoginAttemptScopeLogin, "user", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, failures)
+
+	_, err = storage.RecordLoginFailure(models.LoginAttemptScopeIP, "10.0.0.1", time.Hour)
+	assert.Error(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_ResetLoginAttempts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock: %v", err)
+	}
+	defer db.Close()
+
+	storage := newTestStorage(db)
+
+	mock.ExpectExec(`DELETE FROM login_attempts WHERE scope = \$1 AND key = \$2`).
+		WithArgs(models.LoginAttemptScopeLogin, "user").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, storage.ResetLoginAttempts(models.LoginAttemptScopeLogin, "user"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
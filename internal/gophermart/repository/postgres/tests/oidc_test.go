@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStorage_ConsumeOIDCState(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock: %v", err)
+	}
+	defer db.Close()
+
+	storage := newTestStorage(db)
+
+	mock.ExpectQuery(`DELETE FROM oidc_login_states WHERE state_hash = \$1`).
+		WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows([]string{"code_verifier", "nonce", "expires_at"}).
+			AddRow("verifier", "nonce", time.Now().Add(time.Minute)))
+	mock.ExpectExec(`DELETE FROM oidc_login_states WHERE expires_at < NOW\(\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	// повторное использование state
+	mock.ExpectQuery(`DELETE FROM oidc_login_states WHERE state_hash = \$1`).
+		WithArgs("hash").
+		WillReturnError(sql.ErrNoRows)
+
+	state, err := storage.ConsumeOIDCState("hash")
+	require.NoError(t, err)
+	assert.Equal(t, &models.OIDCLoginState{CodeVerifier: "verifier", Nonce: "nonce"}, state)
+
+	state, err = storage.ConsumeOIDCState("hash")
+	assert.NoError(t, err)
+	assert.Nil(t, state)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_CreateUserWithIdentity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock: %v", err)
+	}
+	defer db.Close()
+
+	storage := newTestStorage(db)
+	identity := models.UserIdentity{Issuer: "https://idp.example", Subject: "sub", Email: "user@example.com"}
+	createdAt := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO users \(login, password_hash\) VALUES \(\$1, ''\)`).
+		WithArgs("user").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password_hash", "role", "blocked_at", "created_at"}).
+			AddRow(5, "user", "", "user", nil, createdAt))
+	mock.ExpectExec(`INSERT INTO user_identities`).
+		WithArgs(5, identity.Issuer, identity.Subject, identity.Email).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	user, err := storage.CreateUserWithIdentity("user", identity)
+	require.NoError(t, err)
+	assert.Equal(t, 5, user.ID)
+	assert.Empty(t, user.PasswordHash)
+
+	// логин занят локальным пользователем
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO users`).
+		WithArgs("alice").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	_, err = storage.CreateUserWithIdentity("alice", identity)
+	assert.ErrorIs(t, err, handler.ErrLoginAlreadyExists)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
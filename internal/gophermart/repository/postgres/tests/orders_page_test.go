@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStorage_OrdersPage(t *testing.T) {
+	columns := []string{"uid", "number", "status", "accrual", "uploaded_at"}
+	now := time.Now()
+
+	t.Run("newest first after cursor", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		after := now.Add(-time.Hour)
+		mock.ExpectQuery(`\(uploaded_at, uid\) < \(\$5, \$6\)\)\s+ORDER BY uploaded_at DESC, uid DESC`).
+			WithArgs(1, "PROCESSED", nil, nil, after, 12, 3).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(11, "12345678903", models.OrderStatusProcessed, "500.00", now.Add(-2*time.Hour)).
+				AddRow(10, "2377225624", models.OrderStatusProcessed, "10.50", now.Add(-3*time.Hour)))
+
+		orders, err := newTestStorage(db).OrdersPage(models.OrdersFilter{
+			UserID:          1,
+			Statuses:        []string{models.OrderStatusProcessed},
+			AfterUploadedAt: &after,
+			AfterUID:        12,
+			Limit:           3,
+		})
+		require.NoError(t, err)
+		require.Len(t, orders, 2)
+		assert.Equal(t, 11, orders[0].UID)
+		assert.Equal(t, money.FromCents(1050), orders[1].Accrual)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("oldest first in range", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		from, to := now.Add(-48*time.Hour), now
+		mock.ExpectQuery(`ORDER BY uploaded_at ASC, uid ASC`).
+			WithArgs(1, "", from, to, nil, 0, 51).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		orders, err := newTestStorage(db).OrdersPage(models.OrdersFilter{UserID: 1, From: &from, To: &to, Asc: true, Limit: 51})
+		require.NoError(t, err)
+		assert.Empty(t, orders)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
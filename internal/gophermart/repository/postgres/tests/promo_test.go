@@ -0,0 +1,200 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var promoColumns = []string{"id", "code", "kind", "points", "multiplier", "orders", "starts_at", "ends_at",
+	"max_redemptions", "per_user_limit", "redemptions", "created_at", "disabled_at"}
+
+func TestPostgresStorage_CreatePromoCode(t *testing.T) {
+	promo := models.PromoCode{
+		Code:         "WELCOME",
+		Kind:         models.PromoKindPoints,
+		Points:       money.FromUnits(50),
+		Multiplier:   money.FromUnits(1),
+		StartsAt:     time.Now(),
+		PerUserLimit: 1,
+	}
+	entry := models.AuditEntry{ActorID: 9, Action: models.AuditCreatePromo}
+
+	t.Run("created", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO promo_codes`).
+			WithArgs("WELCOME", models.PromoKindPoints, money.FromUnits(50), money.FromUnits(1), 0,
+				promo.StartsAt, nil, 0, 1, 9).
+			WillReturnRows(sqlmock.NewRows(promoColumns).
+				AddRow(1, "WELCOME", "points", "50.00", "1.00", 0, promo.StartsAt, nil, 0, 1, 0, time.Now(), nil))
+		mock.ExpectExec(`INSERT INTO admin_audit_log`).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		created, err := newTestStorage(db).CreatePromoCode(promo, entry)
+		require.NoError(t, err)
+		assert.Equal(t, 1, created.ID)
+		assert.Equal(t, money.FromUnits(50), created.Points)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("code taken", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO promo_codes`).
+			WillReturnRows(sqlmock.NewRows(promoColumns))
+		mock.ExpectRollback()
+
+		_, err = newTestStorage(db).CreatePromoCode(promo, entry)
+		assert.ErrorIs(t, err, handler.ErrPromoCodeExists)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresStorage_RedeemPromoCode(t *testing.T) {
+	now := time.Now()
+	started := now.Add(-time.Hour)
+	ended := now.Add(-time.Minute)
+
+	expectPromo := func(mock sqlmock.Sqlmock, kind, points, multiplier string, orders int, endsAt *time.Time, max, perUser, redemptions int) {
+		mock.ExpectQuery(`FROM promo_codes WHERE code = \$1 FOR UPDATE`).
+			WithArgs("SPRING").
+			WillReturnRows(sqlmock.NewRows(promoColumns).
+				AddRow(4, "SPRING", kind, points, multiplier, orders, started, endsAt, max, perUser, redemptions, started, nil))
+	}
+	expectRedeemed := func(mock sqlmock.Sqlmock, count int) {
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM promo_redemptions`).
+			WithArgs(4, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+	}
+
+	t.Run("points", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		expectPromo(mock, models.PromoKindPoints, "50.00", "1.00", 0, nil, 100, 1, 10)
+		expectRedeemed(mock, 0)
+		mock.ExpectQuery(`INSERT INTO accounts`).
+			WithArgs(1, money.FromUnits(50), money.Zero).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("150.00"))
+		mock.ExpectQuery(`INSERT INTO ledger_entries`).
+			WithArgs(1, models.LedgerEntryPromo, money.FromUnits(50), money.FromUnits(150), models.LedgerAccountPromotions,
+				"", nil, nil, "promo code SPRING").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(30, now))
+		mock.ExpectExec(`point_lots`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`INSERT INTO promo_redemptions`).
+			WithArgs(4, 1, sqlmock.AnyArg(), 0).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, now))
+		mock.ExpectExec(`UPDATE promo_codes SET redemptions = redemptions \+ 1`).
+			WithArgs(4).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		redemption, err := newTestStorage(db).RedeemPromoCode(1, "SPRING", now)
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), redemption.ID)
+		assert.Equal(t, money.FromUnits(50), redemption.Points)
+		require.NotNil(t, redemption.LedgerEntryID)
+		assert.Equal(t, int64(30), *redemption.LedgerEntryID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("multiplier", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		expectPromo(mock, models.PromoKindMultiplier, "0.00", "2.00", 3, nil, 0, 1, 0)
+		expectRedeemed(mock, 0)
+		// проводки нет: номер в истории баланса берётся из последовательности журнала
+		mock.ExpectQuery(`INSERT INTO promo_redemptions \(promo_id, user_id, ledger_entry_id, orders_left, history_id\)(.|\n)*nextval\(pg_get_serial_sequence\('ledger_entries', 'id'\)\)`).
+			WithArgs(4, 1, nil, 3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(8, now))
+		mock.ExpectExec(`UPDATE promo_codes SET redemptions`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		redemption, err := newTestStorage(db).RedeemPromoCode(1, "SPRING", now)
+		require.NoError(t, err)
+		assert.Equal(t, money.FromUnits(2), redemption.Multiplier)
+		assert.Equal(t, 3, redemption.OrdersLeft)
+		assert.Nil(t, redemption.LedgerEntryID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		expectPromo(mock, models.PromoKindPoints, "50.00", "1.00", 0, &ended, 0, 1, 0)
+		mock.ExpectRollback()
+
+		_, err = newTestStorage(db).RedeemPromoCode(1, "SPRING", now)
+		assert.ErrorIs(t, err, handler.ErrPromoCodeInactive)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("total limit reached", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		expectPromo(mock, models.PromoKindPoints, "50.00", "1.00", 0, nil, 10, 1, 10)
+		mock.ExpectRollback()
+
+		_, err = newTestStorage(db).RedeemPromoCode(1, "SPRING", now)
+		assert.ErrorIs(t, err, handler.ErrPromoCodeExhausted)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("per-user limit reached", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		expectPromo(mock, models.PromoKindPoints, "50.00", "1.00", 0, nil, 0, 2, 5)
+		expectRedeemed(mock, 2)
+		mock.ExpectRollback()
+
+		_, err = newTestStorage(db).RedeemPromoCode(1, "SPRING", now)
+		assert.ErrorIs(t, err, handler.ErrPromoCodeAlreadyRedeemed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown code", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM promo_codes WHERE code = \$1 FOR UPDATE`).
+			WillReturnRows(sqlmock.NewRows(promoColumns))
+		mock.ExpectRollback()
+
+		_, err = newTestStorage(db).RedeemPromoCode(1, "SPRING", now)
+		assert.ErrorIs(t, err, handler.ErrPromoCodeNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,142 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStorage_CreateReferredUser(t *testing.T) {
+	userColumns := []string{"id", "login", "password_hash", "role", "blocked_at", "created_at"}
+	signup := models.ReferralSignup{
+		Code:   "AB12CD34EF",
+		IP:     "203.0.113.7",
+		Limits: models.ReferralLimits{DailyCount: 3, AddressWindow: 24 * time.Hour},
+	}
+	expectReferrer := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`SELECT id FROM users WHERE referral_code = \$1 AND blocked_at IS NULL FOR UPDATE`).
+			WithArgs("AB12CD34EF").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	}
+	expectSignupCheck := func(mock sqlmock.Sqlmock, sameAddress bool, today int) {
+		mock.ExpectQuery(`FROM sessions\s+WHERE user_id = \$1 AND ip = \$2`).
+			WithArgs(1, "203.0.113.7", float64(24*60*60)).
+			WillReturnRows(sqlmock.NewRows([]string{"same_address", "today"}).AddRow(sameAddress, today))
+	}
+
+	t.Run("success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		expectReferrer(mock)
+		expectSignupCheck(mock, false, 2)
+		mock.ExpectQuery(`INSERT INTO users`).
+			WithArgs("friend", "hash").
+			WillReturnRows(sqlmock.NewRows(userColumns).AddRow(2, "friend", "hash", models.RoleUser, nil, time.Now()))
+		mock.ExpectExec(`INSERT INTO referrals \(referee_id, referrer_id, referee_ip\)`).
+			WithArgs(2, 1, "203.0.113.7").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		user, err := newTestStorage(db).CreateReferredUser("friend", "hash", signup)
+		require.NoError(t, err)
+		assert.Equal(t, 2, user.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown code", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id FROM users WHERE referral_code = \$1`).
+			WithArgs("NOPE").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+
+		_, err = newTestStorage(db).CreateReferredUser("friend", "hash", models.ReferralSignup{Code: "NOPE"})
+		assert.ErrorIs(t, err, handler.ErrInvalidReferralCode)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("login taken", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		expectReferrer(mock)
+		expectSignupCheck(mock, false, 0)
+		mock.ExpectQuery(`INSERT INTO users`).
+			WillReturnRows(sqlmock.NewRows(userColumns))
+		mock.ExpectRollback()
+
+		_, err = newTestStorage(db).CreateReferredUser("friend", "hash", signup)
+		assert.ErrorIs(t, err, handler.ErrLoginAlreadyExists)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("address of the referrer or another referee", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		// второй аккаунт с того же адреса: пользователь не создаётся
+		mock.ExpectBegin()
+		expectReferrer(mock)
+		expectSignupCheck(mock, true, 0)
+		mock.ExpectRollback()
+
+		_, err = newTestStorage(db).CreateReferredUser("friend", "hash", signup)
+		assert.ErrorIs(t, err, handler.ErrSelfReferral)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("daily limit of the referrer", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		expectReferrer(mock)
+		expectSignupCheck(mock, false, 3)
+		mock.ExpectRollback()
+
+		_, err = newTestStorage(db).CreateReferredUser("friend", "hash", signup)
+		assert.ErrorIs(t, err, handler.ErrReferralLimitExceeded)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresStorage_Referrals(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`FROM referrals r\s+JOIN users u ON u.id = r.referee_id`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"login", "bonus", "created_at", "rewarded_at"}).
+			AddRow("carol", nil, now, nil).
+			AddRow("bob", "100.00", now.Add(-time.Hour), now))
+
+	referrals, err := newTestStorage(db).Referrals(1)
+	require.NoError(t, err)
+	require.Len(t, referrals, 2)
+	assert.Equal(t, models.ReferralStatusPending, referrals[0].Status)
+	assert.Nil(t, referrals[0].Bonus)
+	assert.Equal(t, models.ReferralStatusRewarded, referrals[1].Status)
+	require.NotNil(t, referrals[1].Bonus)
+	assert.Equal(t, money.FromUnits(100), *referrals[1].Bonus)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
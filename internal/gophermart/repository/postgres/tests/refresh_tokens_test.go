@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresStorage_CreateRefreshToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock: %v", err)
+	}
+	defer db.Close()
+
+	storage := newTestStorage(db)
+	expiresAt := time.Now().Add(time.Hour)
+
+	mock.ExpectExec(`INSERT INTO refresh_tokens`).
+		WithArgs(1, "hash", expiresAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	assert.NoError(t, storage.CreateRefreshToken(1, "hash", expiresAt))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_RotateRefreshToken(t *testing.T) {
+	newExpiresAt := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name           string
+		setupMock      func(mock sqlmock.Sqlmock)
+		expectedUserID int
+		expectedError  error
+	}{
+		{
+			name: "Successful rotation",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT user_id, expires_at, revoked_at FROM refresh_tokens`).
+					WithArgs("old").
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "expires_at", "revoked_at"}).
+						AddRow(7, time.Now().Add(time.Hour), nil))
+				mock.ExpectExec(`UPDATE refresh_tokens SET revoked_at`).
+					WithArgs("old").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`INSERT INTO refresh_tokens`).
+					WithArgs(7, "new", newExpiresAt).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			expectedUserID: 7,
+		},
+		{
+			name: "Unknown token",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT user_id, expires_at, revoked_at FROM refresh_tokens`).
+					WithArgs("old").
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			expectedError: handler.ErrInvalidRefreshToken,
+		},
+		{
+			name: "Expired token",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT user_id, expires_at, revoked_at FROM refresh_tokens`).
+					WithArgs("old").
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "expires_at", "revoked_at"}).
+						AddRow(7, time.Now().Add(-time.Minute), nil))
+				mock.ExpectRollback()
+			},
+			expectedError: handler.ErrInvalidRefreshToken,
+		},
+		{
+			name: "Reused revoked token revokes all user tokens",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT user_id, expires_at, revoked_at FROM refresh_tokens`).
+					WithArgs("old").
+					WillReturnRows(sqlmock.NewRows([]string{"user_id", "expires_at", "revoked_at"}).
+						AddRow(7, time.Now().Add(time.Hour), time.Now().Add(-time.Minute)))
+				mock.ExpectExec(`UPDATE refresh_tokens SET revoked_at = NOW\(\) WHERE user_id = \$1`).
+					WithArgs(7).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectCommit()
+			},
+			expectedError: handler.ErrInvalidRefreshToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("Error creating mock: %v", err)
+			}
+			defer db.Close()
+
+			storage := newTestStorage(db)
+			tt.setupMock(mock)
+
+			userID, err := storage.RotateRefreshToken("old", "new", newExpiresAt)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedUserID, userID)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgresStorage_RevokeRefreshToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock: %v", err)
+	}
+	defer db.Close()
+
+	storage := newTestStorage(db)
+
+	mock.ExpectExec(`UPDATE refresh_tokens SET revoked_at`).
+		WithArgs("hash").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, storage.RevokeRefreshToken("hash"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStorage_RefundWithdrawal(t *testing.T) {
+	refund := models.WithdrawalRefund{
+		Order:  "2377225624",
+		Amount: money.FromUnits(200),
+		Reason: "order cancelled",
+		Source: models.RefundSourceStaff,
+	}
+
+	expectWithdrawal := func(mock sqlmock.Sqlmock, userID int) {
+		mock.ExpectQuery(`SELECT w.uid, w.user_id, l.id`).
+			WithArgs("2377225624", userID).
+			WillReturnRows(sqlmock.NewRows([]string{"uid", "user_id", "id"}).AddRow(7, 1, 40))
+		mock.ExpectQuery(`FROM ledger_entries\s+WHERE id = \$1\s+FOR UPDATE`).
+			WithArgs(int64(40)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "entry_type", "amount", "contra_account", "order_number", "withdrawal_id"}).
+				AddRow(40, 1, models.LedgerEntryWithdrawal, "-500.00", "redemptions", "2377225624", 7))
+	}
+
+	t.Run("partial refund", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		actorID := 9
+		staff := refund
+		staff.ActorID = &actorID
+
+		mock.ExpectBegin()
+		expectWithdrawal(mock, 0)
+		mock.ExpectQuery(`SELECT COALESCE\(SUM\(amount\), 0\) FROM ledger_entries WHERE reverses_entry_id`).
+			WithArgs(int64(40)).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow("100.00"))
+		// возврат возвращает баллы и уменьшает "использовано"
+		mock.ExpectQuery(`INSERT INTO accounts`).
+			WithArgs(1, money.FromUnits(200), money.FromUnits(-200)).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("250.00"))
+		mock.ExpectQuery(`INSERT INTO ledger_entries`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(41, time.Now()))
+		// баллы возвращаются в партии, погашенные списанием, новая партия не заводится
+		mock.ExpectQuery(`UPDATE point_lots p\s+SET remaining = p.remaining \+`).
+			WithArgs(int64(41), int64(40), money.FromUnits(200)).
+			WillReturnRows(sqlmock.NewRows([]string{"restored"}).AddRow("200.00"))
+		mock.ExpectQuery(`INSERT INTO withdrawal_refunds`).
+			WithArgs(7, 1, money.FromUnits(200), "order cancelled", models.RefundSourceStaff, &actorID, nil, int64(41)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, time.Now()))
+		mock.ExpectExec(`INSERT INTO admin_audit_log`).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		result, err := newTestStorage(db).RefundWithdrawal(staff, &models.AuditEntry{ActorID: actorID, Action: models.AuditRefund})
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.ID)
+		assert.Equal(t, 1, result.UserID)
+		assert.Equal(t, money.FromUnits(200), result.Amount)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("refund exceeds withdrawal", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		expectWithdrawal(mock, 1)
+		mock.ExpectQuery(`SELECT COALESCE\(SUM\(amount\), 0\) FROM ledger_entries WHERE reverses_entry_id`).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow("400.00"))
+		mock.ExpectRollback()
+
+		partner := refund
+		partner.UserID = 1
+		_, err = newTestStorage(db).RefundWithdrawal(partner, nil)
+		assert.Equal(t, handler.ErrRefundExceedsWithdrawal, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("withdrawal of another user", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT w.uid, w.user_id, l.id`).
+			WithArgs("2377225624", 2).
+			WillReturnRows(sqlmock.NewRows([]string{"uid", "user_id", "id"}))
+		mock.ExpectRollback()
+
+		partner := refund
+		partner.UserID = 2
+		_, err = newTestStorage(db).RefundWithdrawal(partner, nil)
+		assert.Equal(t, handler.ErrWithdrawalNotFound, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
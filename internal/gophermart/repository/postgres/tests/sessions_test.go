@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresStorage_TouchSession(t *testing.T) {
+	tests := []struct {
+		name       string
+		setupMock  func(mock sqlmock.Sqlmock)
+		expectedID int
+		expectErr  bool
+	}{
+		{
+			name: "Active session seen recently - no update",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT id, last_seen_at FROM sessions`).
+					WithArgs("hash", 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "last_seen_at"}).AddRow(5, time.Now()))
+			},
+			expectedID: 5,
+		},
+		{
+			name: "Active session seen long ago - last_seen updated",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT id, last_seen_at FROM sessions`).
+					WithArgs("hash", 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "last_seen_at"}).AddRow(5, time.Now().Add(-time.Hour)))
+				mock.ExpectExec(`UPDATE sessions SET last_seen_at = NOW\(\) WHERE id = \$1`).
+					WithArgs(5).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedID: 5,
+		},
+		{
+			name: "Revoked or expired session",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT id, last_seen_at FROM sessions`).
+					WithArgs("hash", 1).
+					WillReturnError(sql.ErrNoRows)
+			},
+			expectedID: 0,
+		},
+		{
+			name: "Database error",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT id, last_seen_at FROM sessions`).
+					WithArgs("hash", 1).
+					WillReturnError(sql.ErrConnDone)
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("Error creating mock: %v", err)
+			}
+			defer db.Close()
+
+			storage := newTestStorage(db)
+			tt.setupMock(mock)
+
+			sessionID, err := storage.TouchSession(1, "hash")
+
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedID, sessionID)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestPostgresStorage_RevokeAllSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock: %v", err)
+	}
+	defer db.Close()
+
+	storage := newTestStorage(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE sessions SET revoked_at`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE refresh_tokens SET revoked_at`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, storage.RevokeAllSessions(1))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_ActiveSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock: %v", err)
+	}
+	defer db.Close()
+
+	storage := newTestStorage(db)
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT id, user_id, user_agent, created_at, last_seen_at, expires_at FROM sessions`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "user_agent", "created_at", "last_seen_at", "expires_at"}).
+			AddRow(5, 1, "phone", now, now, now.Add(time.Hour)))
+
+	sessions, err := storage.ActiveSessions(1)
+
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, "phone", sessions[0].UserAgent)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStorage_StatementOpeningBalance(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	before := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT COALESCE\(SUM\(amount\), 0\) FROM ledger_entries WHERE user_id = \$1 AND created_at < \$2`).
+		WithArgs(1, before).
+		WillReturnRows(sqlmock.NewRows([]string{"opening"}).AddRow("350.25"))
+
+	opening, err := newTestStorage(db).StatementOpeningBalance(1, before)
+	require.NoError(t, err)
+	assert.Equal(t, money.FromCents(35025), opening)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_StatementLines(t *testing.T) {
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	columns := []string{"at", "kind", "number", "amount"}
+
+	t.Run("streams lines in order", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery(`FROM ledger_entries\s+WHERE user_id = \$1 AND created_at >= \$2 AND created_at < \$3\s+ORDER BY created_at, id`).
+			WithArgs(1, from, to).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(from.Add(time.Hour), "accrual", "12345678903", "500.50").
+				AddRow(from.Add(2*time.Hour), "withdrawal", "2377225624", "-200.00").
+				AddRow(from.Add(3*time.Hour), "transfer", "", "-50.00"))
+
+		var lines []models.StatementLine
+		err = newTestStorage(db).StatementLines(context.Background(), 1, from, to, func(line models.StatementLine) error {
+			lines = append(lines, line)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Len(t, lines, 3)
+		assert.Equal(t, models.StatementLineAccrual, lines[0].Type)
+		assert.Equal(t, money.FromCents(50050), lines[0].Amount)
+		assert.Equal(t, money.FromUnits(-200), lines[1].Amount)
+		assert.Equal(t, models.LedgerEntryTransfer, lines[2].Type)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("callback error stops reading", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery(`FROM ledger_entries\s+WHERE user_id = \$1 AND created_at >= \$2 AND created_at < \$3\s+ORDER BY created_at, id`).
+			WithArgs(1, from, to).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(from, "accrual", "12345678903", "1.00").
+				AddRow(from, "accrual", "2377225624", "2.00"))
+
+		calls := 0
+		err = newTestStorage(db).StatementLines(context.Background(), 1, from, to, func(models.StatementLine) error {
+			calls++
+			return context.Canceled
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 1, calls)
+	})
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStorage_TierEarned(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	since := time.Now().Add(-24 * time.Hour)
+	// сторно начислений уменьшают сумму
+	mock.ExpectQuery(`LEFT JOIN ledger_entries o ON o.id = e.reverses_entry_id`).
+		WithArgs(1, since).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow("1250.50"))
+
+	earned, err := newTestStorage(db).TierEarned(1, since)
+	require.NoError(t, err)
+	assert.Equal(t, money.FromCents(125050), earned)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_UserTier(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	storage := newTestStorage(db)
+	now := time.Now()
+	ut := models.UserTier{UserID: 1, Tier: models.TierSilver, Earned: money.FromUnits(1500), EvaluatedAt: now, NextEvaluationAt: now.Add(time.Hour)}
+
+	mock.ExpectQuery(`FROM user_tiers`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"tier", "earned", "evaluated_at", "next_evaluation_at"}))
+	mock.ExpectExec(`INSERT INTO user_tiers`).
+		WithArgs(1, models.TierSilver, money.FromUnits(1500), now, now.Add(time.Hour)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`FROM user_tiers`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"tier", "earned", "evaluated_at", "next_evaluation_at"}).
+			AddRow(models.TierSilver, "1500.00", now, now.Add(time.Hour)))
+
+	// уровень ещё не считался
+	got, err := storage.UserTier(1)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	require.NoError(t, storage.SaveUserTier(ut))
+
+	got, err = storage.UserTier(1)
+	require.NoError(t, err)
+	assert.Equal(t, &ut, got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_TiersDue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`t.user_id IS NULL OR t.next_evaluation_at <= \$1`).
+		WithArgs(now, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3).AddRow(1))
+
+	userIDs, err := newTestStorage(db).TiersDue(now, 100)
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 1}, userIDs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
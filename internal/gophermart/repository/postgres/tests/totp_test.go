@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"testing"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresStorage_SaveTOTPSecret_AlreadyEnabled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock: %v", err)
+	}
+	defer db.Close()
+
+	storage := newTestStorage(db)
+
+	mock.ExpectExec(`INSERT INTO user_totp`).
+		WithArgs(1, "SECRET").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = storage.SaveTOTPSecret(1, "SECRET")
+
+	assert.ErrorIs(t, err, handler.ErrTOTPAlreadyEnabled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_EnableTOTP(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock: %v", err)
+	}
+	defer db.Close()
+
+	storage := newTestStorage(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE user_totp SET enabled = TRUE`).
+		WithArgs(1, int64(42)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM recovery_codes WHERE user_id = \$1`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO recovery_codes`).
+		WithArgs(1, "hash1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO recovery_codes`).
+		WithArgs(1, "hash2").
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, storage.EnableTOTP(1, 42, []string{"hash1", "hash2"}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresStorage_UseTOTPCounter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock: %v", err)
+	}
+	defer db.Close()
+
+	storage := newTestStorage(db)
+
+	mock.ExpectExec(`UPDATE user_totp SET last_counter = \$2`).
+		WithArgs(1, int64(100)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE user_totp SET last_counter = \$2`).
+		WithArgs(1, int64(100)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	fresh, err := storage.UseTOTPCounter(1, 100)
+	assert.NoError(t, err)
+	assert.True(t, fresh)
+
+	// повтор того же шага
+	fresh, err = storage.UseTOTPCounter(1, 100)
+	assert.NoError(t, err)
+	assert.False(t, fresh)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,174 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresStorage_Transfer(t *testing.T) {
+	transfer := models.Transfer{
+		FromUserID:   1,
+		Counterparty: "bob",
+		Amount:       money.FromUnits(100),
+		Note:         "за обед",
+	}
+	limits := models.TransferLimits{DailyAmount: money.FromUnits(1000), DailyCount: 5}
+
+	expectLocked := func(mock sqlmock.Sqlmock, available string, sent string, count int) {
+		mock.ExpectQuery(`SELECT id FROM users WHERE login = \$1 AND blocked_at IS NULL`).
+			WithArgs("bob").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectQuery(`FROM accounts\s+WHERE user_id IN \(\$1, \$2\)\s+ORDER BY user_id\s+FOR UPDATE`).
+			WithArgs(1, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "available"}).AddRow(1, available).AddRow(2, "0.00"))
+		mock.ExpectQuery(`FROM transfers\s+WHERE from_user_id = \$1`).
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"sum", "count"}).AddRow(sent, count))
+	}
+
+	t.Run("success", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		withKey := transfer
+		withKey.IdempotencyKey = "key-1"
+
+		mock.ExpectBegin()
+		expectLocked(mock, "500.00", "200.00", 2)
+		mock.ExpectQuery(`UPDATE accounts SET`).
+			WithArgs(1, money.FromUnits(-100), money.Zero).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("400.00"))
+		mock.ExpectQuery(`INSERT INTO ledger_entries`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(10, time.Now()))
+		mock.ExpectExec(`point_lots`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(`INSERT INTO accounts`).
+			WithArgs(2, money.FromUnits(100), money.Zero).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("100.00"))
+		mock.ExpectQuery(`INSERT INTO ledger_entries`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(11, time.Now()))
+		// получатель получает партии со сроками партий отправителя
+		mock.ExpectQuery(`INSERT INTO point_lots \(user_id, entry_id, amount, remaining, earned_at\)`).
+			WithArgs(2, int64(11), int64(10), money.FromUnits(100)).
+			WillReturnRows(sqlmock.NewRows([]string{"copied"}).AddRow("100.00"))
+		mock.ExpectQuery(`INSERT INTO transfers`).
+			WithArgs(1, 2, money.FromUnits(100), "за обед", int64(10), int64(11)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(5, time.Now()))
+		mock.ExpectExec(`UPDATE idempotency_keys SET status_code`).
+			WithArgs(1, models.IdempotencyScopeTransfer, "key-1", 201, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		result, err := newTestStorage(db).Transfer(withKey, limits)
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), result.ID)
+		assert.Equal(t, 2, result.ToUserID)
+		assert.Equal(t, models.TransferDirectionOut, result.Direction)
+		assert.Equal(t, "bob", result.Counterparty)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("recipient not found", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id FROM users WHERE login`).
+			WithArgs("bob").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+
+		_, err = newTestStorage(db).Transfer(transfer, limits)
+		assert.ErrorIs(t, err, handler.ErrRecipientNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("transfer to self", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT id FROM users WHERE login`).
+			WithArgs("bob").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectRollback()
+
+		_, err = newTestStorage(db).Transfer(transfer, limits)
+		assert.ErrorIs(t, err, handler.ErrTransferToSelf)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("daily amount exceeded", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		expectLocked(mock, "5000.00", "950.00", 2)
+		mock.ExpectRollback()
+
+		_, err = newTestStorage(db).Transfer(transfer, limits)
+		assert.ErrorIs(t, err, handler.ErrTransferLimitExceeded)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("daily count exceeded", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		expectLocked(mock, "5000.00", "10.00", 5)
+		mock.ExpectRollback()
+
+		_, err = newTestStorage(db).Transfer(transfer, limits)
+		assert.ErrorIs(t, err, handler.ErrTransferLimitExceeded)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("lack of funds", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		expectLocked(mock, "99.99", "0", 0)
+		mock.ExpectRollback()
+
+		_, err = newTestStorage(db).Transfer(transfer, limits)
+		assert.ErrorIs(t, err, handler.ErrLackOfFunds)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestPostgresStorage_Transfers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`FROM transfers t\s+JOIN users u`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "direction", "login", "amount", "note", "created_at", "from_user_id", "to_user_id"}).
+			AddRow(6, "in", "alice", "50.00", "", now, 3, 1).
+			AddRow(5, "out", "bob", "100.00", "за обед", now.Add(-time.Hour), 1, 2))
+
+	transfers, err := newTestStorage(db).Transfers(1)
+	require.NoError(t, err)
+	require.Len(t, transfers, 2)
+	assert.Equal(t, models.TransferDirectionIn, transfers[0].Direction)
+	assert.Equal(t, "alice", transfers[0].Counterparty)
+	assert.Equal(t, money.FromUnits(100), transfers[1].Amount)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -20,7 +20,7 @@ func TestPostgresStorage_GetUserByLogin_NotFound(t *testing.T) {
 
 	storage := newTestStorage(db)
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, login, password_hash, created_at FROM users WHERE login = $1`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, login, password_hash, role, blocked_at, created_at FROM users WHERE login = $1`)).
 		WithArgs("nonexistent").
 		WillReturnError(sql.ErrNoRows)
 
@@ -43,10 +43,10 @@ func TestPostgresStorage_GetUserByID_Success(t *testing.T) {
 
 	createdAt := time.Now()
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, login, password_hash, created_at FROM users WHERE id = $1`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, login, password_hash, role, blocked_at, created_at FROM users WHERE id = $1`)).
 		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password_hash", "created_at"}).
-			AddRow(1, "testuser", "hash", createdAt))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password_hash", "role", "blocked_at", "created_at"}).
+			AddRow(1, "testuser", "hash", "user", nil, createdAt))
 
 	user, err := storage.GetUserByID(1)
 
@@ -67,7 +67,7 @@ func TestPostgresStorage_GetUserByID_NotFound(t *testing.T) {
 
 	storage := newTestStorage(db)
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, login, password_hash, created_at FROM users WHERE id = $1`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, login, password_hash, role, blocked_at, created_at FROM users WHERE id = $1`)).
 		WithArgs(999).
 		WillReturnError(sql.ErrNoRows)
 
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	"go-musthave-diploma-tpl/internal/gophermart/ledger"
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/repository/postgres/pgtest"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// параллельные списания не уводят остаток в минус: проходят ровно те, на которые хватает баллов
+func TestPostgresStorage_WithdrawConcurrent(t *testing.T) {
+	db := pgtest.Open(t)
+	storage := newTestStorage(db)
+	ctx := context.Background()
+
+	user, err := storage.CreateUser(fmt.Sprintf("withdraw-race-%d", time.Now().UnixNano()), "hash")
+	require.NoError(t, err)
+	_, err = ledger.Post(ctx, db, models.LedgerEntry{
+		UserID: user.ID,
+		Type:   models.LedgerEntryAccrual,
+		Amount: money.FromUnits(100),
+	})
+	require.NoError(t, err)
+
+	const workers = 20
+	var (
+		wg                     sync.WaitGroup
+		mu                     sync.Mutex
+		succeeded, lackOfFunds int
+		unexpected             []error
+	)
+	start := make(chan struct{})
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			err := storage.Withdraw(user.ID, models.WithdrawBalance{
+				Order: fmt.Sprintf("%d%d", user.ID, 1000+i),
+				Sum:   money.FromUnits(10),
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch err {
+			case nil:
+				succeeded++
+			case handler.ErrLackOfFunds:
+				lackOfFunds++
+			default:
+				unexpected = append(unexpected, err)
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	require.Empty(t, unexpected)
+	assert.Equal(t, 10, succeeded)
+	assert.Equal(t, workers-10, lackOfFunds)
+
+	balance, err := storage.GetBalance(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, money.Zero, balance.Current)
+	assert.Equal(t, money.FromUnits(100), balance.Withdrawn)
+
+	// остаток после каждой проводки - вся история счёта, ни одна не ушла в минус
+	var lowest money.Amount
+	require.NoError(t, db.QueryRow(`SELECT MIN(balance_after) FROM ledger_entries WHERE user_id = $1`, user.ID).Scan(&lowest))
+	assert.GreaterOrEqual(t, lowest, money.Zero)
+
+	mismatches, err := storage.CheckLedger()
+	require.NoError(t, err)
+	for _, m := range mismatches {
+		assert.NotEqual(t, user.ID, m.UserID, "ledger does not match account: %+v", m)
+	}
+}
@@ -3,12 +3,15 @@ package postgres
 import (
 	"database/sql"
 	"testing"
+	"time"
 
 	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
 	"go-musthave-diploma-tpl/internal/gophermart/models"
 	"go-musthave-diploma-tpl/internal/gophermart/repository/postgres"
+	"go-musthave-diploma-tpl/pkg/money"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgconn"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -25,20 +28,34 @@ func TestPostgresStorage_Withdraw(t *testing.T) {
 			userID: 1,
 			withdraw: models.WithdrawBalance{
 				Order: "2377225624",
-				Sum:   751,
+				Sum:   money.FromUnits(751),
 			},
 			setupMock: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
 
-				// расчёт баланса
-				mock.ExpectQuery(`SELECT.*balance`).
+				// счёт блокируется до конца транзакции
+				mock.ExpectQuery(`SELECT balance - held FROM accounts`).
 					WithArgs(1).
-					WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(1000.0))
+					WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("1000.00"))
+				mock.ExpectQuery(`SELECT\s+EXISTS`).
+					WillReturnRows(sqlmock.NewRows([]string{"accrual_order", "already_paid", "on_hold"}).AddRow(false, false, false))
 
 				// запись списания
-				mock.ExpectExec(`INSERT INTO withdrawals`).
-					WithArgs(1, "2377225624", 751.0).
-					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery(`INSERT INTO withdrawals`).
+					WithArgs(1, "2377225624", money.FromUnits(751)).
+					WillReturnRows(sqlmock.NewRows([]string{"uid"}).AddRow(7))
+
+				// остаток счёта проверяется и уменьшается одним оператором
+				mock.ExpectQuery(`UPDATE accounts`).
+					WithArgs(1, money.FromUnits(-751), money.FromUnits(751)).
+					WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("249.00"))
+
+				mock.ExpectQuery(`INSERT INTO ledger_entries`).
+					WithArgs(1, models.LedgerEntryWithdrawal, money.FromUnits(-751), money.FromUnits(249), models.LedgerAccountRedemptions,
+						"2377225624", sqlmock.AnyArg(), sqlmock.AnyArg(), "").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, time.Now()))
+				mock.ExpectExec(`point_lots`).
+					WillReturnResult(sqlmock.NewResult(0, 1))
 
 				mock.ExpectCommit()
 			},
@@ -49,32 +66,60 @@ func TestPostgresStorage_Withdraw(t *testing.T) {
 			userID: 1,
 			withdraw: models.WithdrawBalance{
 				Order: "2377225624",
-				Sum:   1000,
+				Sum:   money.FromUnits(1000),
 			},
 			setupMock: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
 
-				// баланс меньше суммы списания
-				mock.ExpectQuery(`SELECT.*balance`).
+				// остатка не хватает - списание не записывается
+				mock.ExpectQuery(`SELECT balance - held FROM accounts`).
 					WithArgs(1).
-					WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(500.0))
+					WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("999.99"))
+				mock.ExpectQuery(`SELECT\s+EXISTS`).
+					WillReturnRows(sqlmock.NewRows([]string{"accrual_order", "already_paid", "on_hold"}).AddRow(false, false, false))
 
 				mock.ExpectRollback()
 			},
 			expectedError: handler.ErrLackOfFunds,
 		},
 		{
-			name:   "Database error when calculating balance",
+			name:   "No account yet",
 			userID: 1,
 			withdraw: models.WithdrawBalance{
 				Order: "2377225624",
-				Sum:   500,
+				Sum:   money.FromUnits(1),
 			},
 			setupMock: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
-
-				mock.ExpectQuery(`SELECT.*balance`).
+				mock.ExpectQuery(`SELECT balance - held FROM accounts`).
+					WillReturnRows(sqlmock.NewRows([]string{"balance"}))
+				mock.ExpectQuery(`SELECT\s+EXISTS`).
+					WillReturnRows(sqlmock.NewRows([]string{"accrual_order", "already_paid", "on_hold"}).AddRow(false, false, false))
+				mock.ExpectRollback()
+			},
+			expectedError: handler.ErrLackOfFunds,
+		},
+		{
+			name:   "Database error when updating account",
+			userID: 1,
+			withdraw: models.WithdrawBalance{
+				Order: "2377225624",
+				Sum:   money.FromUnits(500),
+			},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT balance - held FROM accounts`).
 					WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("1000.00"))
+				mock.ExpectQuery(`SELECT\s+EXISTS`).
+					WillReturnRows(sqlmock.NewRows([]string{"accrual_order", "already_paid", "on_hold"}).AddRow(false, false, false))
+
+				mock.ExpectQuery(`INSERT INTO withdrawals`).
+					WithArgs(1, "2377225624", money.FromUnits(500)).
+					WillReturnRows(sqlmock.NewRows([]string{"uid"}).AddRow(7))
+
+				mock.ExpectQuery(`UPDATE accounts`).
+					WithArgs(1, money.FromUnits(-500), money.FromUnits(500)).
 					WillReturnError(sql.ErrConnDone)
 
 				mock.ExpectRollback()
@@ -86,29 +131,86 @@ func TestPostgresStorage_Withdraw(t *testing.T) {
 			userID: 1,
 			withdraw: models.WithdrawBalance{
 				Order: "2377225624",
-				Sum:   500,
+				Sum:   money.FromUnits(500),
 			},
 			setupMock: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin()
-
-				mock.ExpectQuery(`SELECT.*balance`).
+				mock.ExpectQuery(`SELECT balance - held FROM accounts`).
 					WithArgs(1).
-					WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(1000.0))
+					WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("1000.00"))
+				mock.ExpectQuery(`SELECT\s+EXISTS`).
+					WillReturnRows(sqlmock.NewRows([]string{"accrual_order", "already_paid", "on_hold"}).AddRow(false, false, false))
 
-				mock.ExpectExec(`INSERT INTO withdrawals`).
-					WithArgs(1, "2377225624", 500.0).
+				mock.ExpectQuery(`INSERT INTO withdrawals`).
+					WithArgs(1, "2377225624", money.FromUnits(500)).
 					WillReturnError(sql.ErrConnDone)
 
 				mock.ExpectRollback()
 			},
 			expectedError: sql.ErrConnDone,
 		},
+		{
+			name:   "Order is registered for accrual",
+			userID: 1,
+			withdraw: models.WithdrawBalance{
+				Order: "2377225624",
+				Sum:   money.FromUnits(100),
+			},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT balance - held FROM accounts`).
+					WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("1000.00"))
+				mock.ExpectQuery(`SELECT\s+EXISTS`).
+					WithArgs("2377225624").
+					WillReturnRows(sqlmock.NewRows([]string{"accrual_order", "already_paid", "on_hold"}).AddRow(true, false, false))
+				mock.ExpectRollback()
+			},
+			expectedError: handler.ErrAccrualOrderWithdrawal,
+		},
+		{
+			name:   "Order already paid",
+			userID: 1,
+			withdraw: models.WithdrawBalance{
+				Order: "2377225624",
+				Sum:   money.FromUnits(100),
+			},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				// повтор проверяется раньше остатка: на пустом счёте тоже 409, а не 402
+				mock.ExpectQuery(`SELECT balance - held FROM accounts`).
+					WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("0.00"))
+				mock.ExpectQuery(`SELECT\s+EXISTS`).
+					WillReturnRows(sqlmock.NewRows([]string{"accrual_order", "already_paid", "on_hold"}).AddRow(false, true, false))
+				mock.ExpectRollback()
+			},
+			expectedError: handler.ErrOrderAlreadyPaid,
+		},
+		{
+			name:   "Order paid concurrently by another user",
+			userID: 1,
+			withdraw: models.WithdrawBalance{
+				Order: "2377225624",
+				Sum:   money.FromUnits(100),
+			},
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT balance - held FROM accounts`).
+					WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("1000.00"))
+				mock.ExpectQuery(`SELECT\s+EXISTS`).
+					WillReturnRows(sqlmock.NewRows([]string{"accrual_order", "already_paid", "on_hold"}).AddRow(false, false, false))
+				mock.ExpectQuery(`INSERT INTO withdrawals .* ON CONFLICT \(order_number\) DO NOTHING`).
+					WithArgs(1, "2377225624", money.FromUnits(100)).
+					WillReturnRows(sqlmock.NewRows([]string{"uid"}))
+				mock.ExpectRollback()
+			},
+			expectedError: handler.ErrOrderAlreadyPaid,
+		},
 		{
 			name:   "Transaction begin error",
 			userID: 1,
 			withdraw: models.WithdrawBalance{
 				Order: "2377225624",
-				Sum:   500,
+				Sum:   money.FromUnits(500),
 			},
 			setupMock: func(mock sqlmock.Sqlmock) {
 				mock.ExpectBegin().WillReturnError(sql.ErrConnDone)
@@ -146,3 +248,73 @@ func TestPostgresStorage_Withdraw(t *testing.T) {
 		})
 	}
 }
+
+func TestPostgresStorage_WithdrawRetry(t *testing.T) {
+	withdraw := models.WithdrawBalance{Order: "2377225624", Sum: money.FromUnits(100)}
+	serializationFailure := &pgconn.PgError{Code: "40001"}
+
+	expectWithdraw := func(mock sqlmock.Sqlmock) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT balance - held FROM accounts`).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("100.00"))
+		mock.ExpectQuery(`SELECT\s+EXISTS`).
+			WillReturnRows(sqlmock.NewRows([]string{"accrual_order", "already_paid", "on_hold"}).AddRow(false, false, false))
+	}
+
+	t.Run("conflict is retried", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		expectWithdraw(mock)
+		mock.ExpectQuery(`INSERT INTO withdrawals`).WillReturnError(serializationFailure)
+		mock.ExpectRollback()
+
+		// вторая попытка видит актуальный остаток и проходит
+		expectWithdraw(mock)
+		mock.ExpectQuery(`INSERT INTO withdrawals`).
+			WillReturnRows(sqlmock.NewRows([]string{"uid"}).AddRow(8))
+		mock.ExpectQuery(`UPDATE accounts`).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("0.00"))
+		mock.ExpectQuery(`INSERT INTO ledger_entries`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(2, time.Now()))
+		mock.ExpectExec(`point_lots`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, newTestStorage(db).Withdraw(1, withdraw))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("gives up after the last attempt", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		for i := 0; i < 3; i++ {
+			expectWithdraw(mock)
+			mock.ExpectQuery(`INSERT INTO withdrawals`).WillReturnError(serializationFailure)
+			mock.ExpectRollback()
+		}
+
+		err = newTestStorage(db).Withdraw(1, withdraw)
+		assert.ErrorIs(t, err, serializationFailure)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("business errors are not retried", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT balance - held FROM accounts`).
+			WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow("99.99"))
+		mock.ExpectQuery(`SELECT\s+EXISTS`).
+			WillReturnRows(sqlmock.NewRows([]string{"accrual_order", "already_paid", "on_hold"}).AddRow(false, false, false))
+		mock.ExpectRollback()
+
+		assert.Equal(t, handler.ErrLackOfFunds, newTestStorage(db).Withdraw(1, withdraw))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
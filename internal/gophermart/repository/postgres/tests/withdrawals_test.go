@@ -7,6 +7,7 @@ import (
 
 	"go-musthave-diploma-tpl/internal/gophermart/models"
 	"go-musthave-diploma-tpl/internal/gophermart/repository/postgres"
+	"go-musthave-diploma-tpl/pkg/money"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
@@ -38,29 +39,23 @@ func TestPostgresStorage_Withdrawals(t *testing.T) {
 			name:   "Successful withdrawals retrieval",
 			userID: 1,
 			setupMock: func() {
-				rows := sqlmock.NewRows([]string{"order_number", "sum", "processed_at"}).
-					AddRow("2377225624", 751.50, time1).
-					AddRow("49927398716", 500.25, time2)
+				rows := sqlmock.NewRows([]string{"order_number", "sum", "processed_at", "refunded"}).
+					AddRow("2377225624", 751.50, time1, 0).
+					AddRow("49927398716", 500.25, time2, 0)
 
-				mock.ExpectQuery(`SELECT 
-					order_number,
-					sum,
-					processed_at
-				FROM withdrawals
-				WHERE user_id = \$1
-				ORDER BY processed_at DESC`).
+				mock.ExpectQuery(`FROM withdrawals w\s+LEFT JOIN`).
 					WithArgs(1).
 					WillReturnRows(rows)
 			},
 			expectedResult: []models.WithdrawBalance{
 				{
 					Order:       "2377225624",
-					Sum:         751.50,
+					Sum:         money.FromCents(75150),
 					ProcessedAt: time1,
 				},
 				{
 					Order:       "49927398716",
-					Sum:         500.25,
+					Sum:         money.FromCents(50025),
 					ProcessedAt: time2,
 				},
 			},
@@ -70,15 +65,9 @@ func TestPostgresStorage_Withdrawals(t *testing.T) {
 			name:   "No withdrawals for user",
 			userID: 2,
 			setupMock: func() {
-				rows := sqlmock.NewRows([]string{"order_number", "sum", "processed_at"})
+				rows := sqlmock.NewRows([]string{"order_number", "sum", "processed_at", "refunded"})
 
-				mock.ExpectQuery(`SELECT 
-					order_number,
-					sum,
-					processed_at
-				FROM withdrawals
-				WHERE user_id = \$1
-				ORDER BY processed_at DESC`).
+				mock.ExpectQuery(`FROM withdrawals w\s+LEFT JOIN`).
 					WithArgs(2).
 					WillReturnRows(rows)
 			},
@@ -89,13 +78,7 @@ func TestPostgresStorage_Withdrawals(t *testing.T) {
 			name:   "Database query error",
 			userID: 3,
 			setupMock: func() {
-				mock.ExpectQuery(`SELECT 
-					order_number,
-					sum,
-					processed_at
-				FROM withdrawals
-				WHERE user_id = \$1
-				ORDER BY processed_at DESC`).
+				mock.ExpectQuery(`FROM withdrawals w\s+LEFT JOIN`).
 					WithArgs(3).
 					WillReturnError(sql.ErrConnDone)
 			},
@@ -107,16 +90,10 @@ func TestPostgresStorage_Withdrawals(t *testing.T) {
 			userID: 4,
 			setupMock: func() {
 				// Возвращаем неверный тип данных для суммы
-				rows := sqlmock.NewRows([]string{"order_number", "sum", "processed_at"}).
-					AddRow("1234567890", "not_a_float", time1)
+				rows := sqlmock.NewRows([]string{"order_number", "sum", "processed_at", "refunded"}).
+					AddRow("1234567890", "not_a_float", time1, 0)
 
-				mock.ExpectQuery(`SELECT 
-					order_number,
-					sum,
-					processed_at
-				FROM withdrawals
-				WHERE user_id = \$1
-				ORDER BY processed_at DESC`).
+				mock.ExpectQuery(`FROM withdrawals w\s+LEFT JOIN`).
 					WithArgs(4).
 					WillReturnRows(rows)
 			},
@@ -127,17 +104,11 @@ func TestPostgresStorage_Withdrawals(t *testing.T) {
 			name:   "Error during rows iteration",
 			userID: 5,
 			setupMock: func() {
-				rows := sqlmock.NewRows([]string{"order_number", "sum", "processed_at"}).
-					AddRow("1234567890", 100.0, time1).
+				rows := sqlmock.NewRows([]string{"order_number", "sum", "processed_at", "refunded"}).
+					AddRow("1234567890", 100.0, time1, 0).
 					RowError(0, sql.ErrTxDone) // Ошибка при итерации
 
-				mock.ExpectQuery(`SELECT 
-					order_number,
-					sum,
-					processed_at
-				FROM withdrawals
-				WHERE user_id = \$1
-				ORDER BY processed_at DESC`).
+				mock.ExpectQuery(`FROM withdrawals w\s+LEFT JOIN`).
 					WithArgs(5).
 					WillReturnRows(rows)
 			},
@@ -148,23 +119,17 @@ func TestPostgresStorage_Withdrawals(t *testing.T) {
 			name:   "Single withdrawal",
 			userID: 6,
 			setupMock: func() {
-				rows := sqlmock.NewRows([]string{"order_number", "sum", "processed_at"}).
-					AddRow("1234567890", 300.75, time1)
+				rows := sqlmock.NewRows([]string{"order_number", "sum", "processed_at", "refunded"}).
+					AddRow("1234567890", 300.75, time1, 0)
 
-				mock.ExpectQuery(`SELECT 
-					order_number,
-					sum,
-					processed_at
-				FROM withdrawals
-				WHERE user_id = \$1
-				ORDER BY processed_at DESC`).
+				mock.ExpectQuery(`FROM withdrawals w\s+LEFT JOIN`).
 					WithArgs(6).
 					WillReturnRows(rows)
 			},
 			expectedResult: []models.WithdrawBalance{
 				{
 					Order:       "1234567890",
-					Sum:         300.75,
+					Sum:         money.FromCents(30075),
 					ProcessedAt: time1,
 				},
 			},
@@ -236,16 +201,10 @@ func TestPostgresStorage_Withdrawals_RowsClose(t *testing.T) {
 	storage := &postgres.PostgresStorage{DB: db}
 
 	t.Run("Rows are properly closed", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"order_number", "sum", "processed_at"}).
-			AddRow("1234567890", 100.0, time.Now())
+		rows := sqlmock.NewRows([]string{"order_number", "sum", "processed_at", "refunded"}).
+			AddRow("1234567890", 100.0, time.Now(), 0)
 
-		mock.ExpectQuery(`SELECT 
-			order_number,
-			sum,
-			processed_at
-		FROM withdrawals
-		WHERE user_id = \$1
-		ORDER BY processed_at DESC`).
+		mock.ExpectQuery(`FROM withdrawals w\s+LEFT JOIN`).
 			WithArgs(1).
 			WillReturnRows(rows)
 
@@ -256,10 +215,43 @@ func TestPostgresStorage_Withdrawals_RowsClose(t *testing.T) {
 		if result != nil {
 			assert.Len(t, result, 1)
 			assert.Equal(t, "1234567890", result[0].Order)
-			assert.Equal(t, 100.0, result[0].Sum)
+			assert.Equal(t, money.FromUnits(100), result[0].Sum)
 		}
 
 		// Проверяем что rows были закрыты
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
+
+func TestPostgresStorage_Withdrawals_Refunds(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`FROM withdrawals w\s+LEFT JOIN`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"order_number", "sum", "processed_at", "refunded"}).
+			AddRow("2377225624", "500.00", now, "500.00").
+			AddRow("49927398716", "300.00", now, "100.00").
+			AddRow("12345678903", "50.00", now, "0"))
+	// возвраты подгружаются одним запросом, только если они есть
+	mock.ExpectQuery(`FROM withdrawal_refunds r`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_number", "amount", "reason", "created_at"}).
+			AddRow(1, "2377225624", "500.00", "cancelled", now).
+			AddRow(2, "49927398716", "100.00", "", now))
+
+	result, err := (&postgres.PostgresStorage{DB: db}).Withdrawals(1)
+	require.NoError(t, err)
+	require.Len(t, result, 3)
+
+	assert.Equal(t, models.WithdrawalStatusRefunded, result[0].Status)
+	assert.Equal(t, models.WithdrawalStatusPartiallyRefunded, result[1].Status)
+	assert.Equal(t, money.FromUnits(100), result[1].Refunded)
+	require.Len(t, result[1].Refunds, 1)
+	assert.Equal(t, 2, result[1].Refunds[0].ID)
+	assert.Equal(t, models.WithdrawalStatusProcessed, result[2].Status)
+	assert.Empty(t, result[2].Refunds)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"fmt"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+)
+
+const (
+	// adminSearchLimit - сколько пользователей отдаёт поиск
+	adminSearchLimit = 50
+	// auditLogLimit - сколько последних записей журнала отдаётся
+	auditLogLimit = 100
+)
+
+// ValidRole - известна ли роль
+func ValidRole(role string) bool {
+	switch role {
+	case models.RoleUser, models.RoleSupport, models.RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// audit - запись о просмотре; без неё данные пользователя не отдаются
+func (s *GofemartService) audit(actorID int, action string, targetUserID *int, details string) error {
+	return s.repo.RecordAudit(models.AuditEntry{
+		ActorID:      actorID,
+		Action:       action,
+		TargetUserID: targetUserID,
+		Details:      details,
+	})
+}
+
+func (s *GofemartService) AdminSearchUsers(actorID int, query string) ([]models.User, error) {
+	if err := s.audit(actorID, models.AuditSearchUsers, nil, "query="+query); err != nil {
+		return nil, err
+	}
+	return s.repo.SearchUsers(query, adminSearchLimit)
+}
+
+func (s *GofemartService) AdminUserOrders(actorID, userID int) ([]models.Order, error) {
+	if err := s.audit(actorID, models.AuditViewOrders, &userID, ""); err != nil {
+		return nil, err
+	}
+	return s.repo.GetOrders(userID)
+}
+
+func (s *GofemartService) AdminUserBalance(actorID, userID int) (models.Balance, error) {
+	if err := s.audit(actorID, models.AuditViewBalance, &userID, ""); err != nil {
+		return models.Balance{}, err
+	}
+	return s.repo.GetBalance(userID)
+}
+
+func (s *GofemartService) AdminUserWithdrawals(actorID, userID int) ([]models.WithdrawBalance, error) {
+	if err := s.audit(actorID, models.AuditViewWithdraws, &userID, ""); err != nil {
+		return nil, err
+	}
+	return s.repo.Withdrawals(userID)
+}
+
+// AdminSetBlocked блокирует или разблокирует пользователя, заблокировать себя нельзя
+func (s *GofemartService) AdminSetBlocked(actorID, userID int, blocked bool) error {
+	if blocked && actorID == userID {
+		return ErrSelfAdminAction
+	}
+
+	action := models.AuditUnblockUser
+	if blocked {
+		action = models.AuditBlockUser
+	}
+	return s.repo.SetUserBlocked(userID, blocked, models.AuditEntry{
+		ActorID:      actorID,
+		Action:       action,
+		TargetUserID: &userID,
+	})
+}
+
+// AdminSetRole меняет роль пользователя, снять роль администратора с себя нельзя
+func (s *GofemartService) AdminSetRole(actorID, userID int, role string) error {
+	if !ValidRole(role) {
+		return ErrInvalidRole
+	}
+	if actorID == userID && role != models.RoleAdmin {
+		return ErrSelfAdminAction
+	}
+
+	return s.repo.SetUserRole(userID, role, models.AuditEntry{
+		ActorID:      actorID,
+		Action:       models.AuditSetRole,
+		TargetUserID: &userID,
+		Details:      "role=" + role,
+	})
+}
+
+// SetRoleByLogin - назначение роли из командной строки сервера (первый администратор)
+func (s *GofemartService) SetRoleByLogin(login, role string) error {
+	if !ValidRole(role) {
+		return ErrInvalidRole
+	}
+
+	user, err := s.repo.GetUserByLogin(login)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	return s.repo.SetUserRole(user.ID, role, models.AuditEntry{
+		Action:       models.AuditSetRole,
+		TargetUserID: &user.ID,
+		Details:      "role=" + role + " via command line",
+	})
+}
+
+// AdminUnlockLogin снимает блокировку входа по логину пользователя
+func (s *GofemartService) AdminUnlockLogin(actorID, userID int) error {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	if err := s.audit(actorID, models.AuditUnlockLogin, &userID, ""); err != nil {
+		return err
+	}
+	return s.UnlockLogin(user.Login)
+}
+
+// AdminRequeueOrder ставит необработанный заказ в очередь начислений заново
+func (s *GofemartService) AdminRequeueOrder(actorID int, number string) error {
+	if number == "" {
+		return fmt.Errorf("order number is required")
+	}
+
+	return s.repo.RequeueOrder(number, models.AuditEntry{
+		ActorID: actorID,
+		Action:  models.AuditRequeueOrder,
+		Details: "order=" + number,
+	})
+}
+
+func (s *GofemartService) AdminAuditLog(actorID int) ([]models.AuditEntry, error) {
+	if err := s.audit(actorID, models.AuditViewAuditLog, nil, ""); err != nil {
+		return nil, err
+	}
+	return s.repo.AuditLog(auditLogLimit)
+}
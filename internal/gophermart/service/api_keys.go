@@ -0,0 +1,135 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+)
+
+const (
+	// maxAPIKeysPerUser - сколько действующих ключей может завести пользователь
+	maxAPIKeysPerUser = 20
+	// apiKeyDisplayLen - сколько первых символов ключа видно в списке
+	apiKeyDisplayLen = 10
+)
+
+// apiKeyScopes - известные области доступа; true - область выдаёт только администратор
+var apiKeyScopes = map[string]bool{
+	models.ScopeOrdersRead:        false,
+	models.ScopeOrdersWrite:       false,
+	models.ScopeBalanceRead:       false,
+	models.ScopeWithdrawalsRead:   false,
+	models.ScopeWithdrawalsRefund: true,
+}
+
+// CreateAPIKey выпускает ключ и возвращает его значение, повторно оно нигде не показывается
+func (s *GofemartService) CreateAPIKey(userID int, req models.CreateAPIKeyRequest) (*models.CreateAPIKeyResponse, error) {
+	return s.createAPIKey(userID, req, false)
+}
+
+// AdminCreateAPIKey выпускает ключ пользователю, в том числе с областями, которые он сам получить не может:
+// так сервер партнёра получает право возвращать баллы по списаниям
+func (s *GofemartService) AdminCreateAPIKey(actorID, userID int, req models.CreateAPIKeyRequest) (*models.CreateAPIKeyResponse, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	key, err := s.createAPIKey(userID, req, true)
+	if err != nil {
+		return nil, err
+	}
+	details := fmt.Sprintf("key=%d scopes=%s", key.ID, strings.Join(key.Scopes, ","))
+	if err := s.audit(actorID, models.AuditIssueAPIKey, &userID, details); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (s *GofemartService) createAPIKey(userID int, req models.CreateAPIKeyRequest, privileged bool) (*models.CreateAPIKeyResponse, error) {
+	scopes, err := normalizeScopes(req.Scopes, privileged)
+	if err != nil {
+		return nil, err
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidAPIKeyExpiry
+	}
+
+	keys, err := s.repo.APIKeys(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) >= maxAPIKeysPerUser {
+		return nil, ErrTooManyAPIKeys
+	}
+
+	token, _, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	token = models.APIKeyPrefix + token
+
+	key := models.APIKey{
+		UserID:    userID,
+		Name:      strings.TrimSpace(req.Name),
+		Prefix:    token[:apiKeyDisplayLen],
+		Scopes:    scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := s.repo.CreateAPIKey(&key, HashToken(token)); err != nil {
+		return nil, err
+	}
+
+	return &models.CreateAPIKeyResponse{APIKey: key, Key: token}, nil
+}
+
+// normalizeScopes проверяет области доступа и убирает повторы, хотя бы одна обязательна
+func normalizeScopes(scopes []string, privileged bool) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, ErrInvalidScope
+	}
+
+	seen := make(map[string]struct{}, len(scopes))
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		adminOnly, ok := apiKeyScopes[scope]
+		if !ok || adminOnly && !privileged {
+			return nil, ErrInvalidScope
+		}
+		if _, ok := seen[scope]; ok {
+			continue
+		}
+		seen[scope] = struct{}{}
+		result = append(result, scope)
+	}
+	return result, nil
+}
+
+func (s *GofemartService) APIKeys(userID int) ([]models.APIKey, error) {
+	return s.repo.APIKeys(userID)
+}
+
+func (s *GofemartService) RevokeAPIKey(userID, keyID int) error {
+	return s.repo.RevokeAPIKey(userID, keyID)
+}
+
+// AuthenticateAPIKey - действующий ключ из заголовка X-API-Key
+func (s *GofemartService) AuthenticateAPIKey(token string) (*models.APIKey, error) {
+	if !strings.HasPrefix(token, models.APIKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.UseAPIKey(HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrInvalidAPIKey
+	}
+	return key, nil
+}
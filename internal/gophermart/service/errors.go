@@ -0,0 +1,67 @@
+package service
+
+import "errors"
+
+// ошибки бизнес-логики, хендлер сопоставляет их через errors.Is
+var (
+	ErrLoginAndPasswordRequired = errors.New("login and password are required")
+	ErrInvalidLoginOrPassword   = errors.New("invalid login or password")
+	ErrInvalidRefreshToken      = errors.New("invalid refresh token")
+	ErrInvalidAccessToken       = errors.New("invalid access token")
+	ErrSessionNotActive         = errors.New("session is not active")
+	ErrTooManyLoginAttempts     = errors.New("too many login attempts")
+	ErrPasswordPolicy           = errors.New("password does not meet policy")
+	ErrInvalidCurrentPassword   = errors.New("invalid current password")
+	ErrTOTPAlreadyEnabled       = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotSetUp             = errors.New("two-factor authentication is not set up")
+	ErrInvalidTOTPCode          = errors.New("invalid two-factor code")
+	ErrTOTPRequired             = errors.New("two-factor code required")
+	ErrInvalidLoginChallenge    = errors.New("login challenge is invalid or expired")
+	ErrUserBlocked              = errors.New("account is blocked")
+	ErrUserNotFound             = errors.New("user not found")
+	ErrInvalidRole              = errors.New("invalid role")
+	ErrSelfAdminAction          = errors.New("administrators cannot block or demote themselves")
+	ErrOrderNotFound            = errors.New("order not found")
+	ErrOrderAlreadyProcessed    = errors.New("order is already processed")
+	ErrInvalidAPIKey            = errors.New("invalid api key")
+	ErrAPIKeyNotFound           = errors.New("api key not found")
+	ErrInvalidScope             = errors.New("invalid api key scope")
+	ErrInvalidAPIKeyExpiry      = errors.New("api key expiry must be in the future")
+	ErrTooManyAPIKeys           = errors.New("too many api keys")
+	ErrLoginAlreadyExists       = errors.New("login already exists")
+	ErrOIDCNotConfigured        = errors.New("sign-in with identity provider is not configured")
+	ErrInvalidOIDCState         = errors.New("sign-in state is invalid or expired")
+	ErrOIDCLoginFailed          = errors.New("identity provider sign-in failed")
+	ErrLedgerEntryNotFound      = errors.New("ledger entry not found")
+	ErrInvalidReversal          = errors.New("invalid reversal")
+	ErrInvalidAmount            = errors.New("amount must be a non-zero number of points")
+	ErrInvalidIdempotencyKey    = errors.New("invalid idempotency key")
+	ErrIdempotencyKeyReused     = errors.New("idempotency key was already used with a different request")
+	ErrIdempotencyConflict      = errors.New("request with this idempotency key has already been completed")
+	ErrOrderAlreadyPaid         = errors.New("order has already been paid with points")
+	ErrAccrualOrderWithdrawal   = errors.New("order is registered for accrual and cannot be paid with points")
+	ErrOrderOnHold              = errors.New("order already has points on hold")
+	ErrHoldNotFound             = errors.New("hold not found")
+	ErrHoldNotActive            = errors.New("hold is no longer active")
+	ErrWithdrawalNotFound       = errors.New("withdrawal not found")
+	ErrRefundExceedsWithdrawal  = errors.New("refunds would exceed the withdrawn sum")
+	ErrTiersDisabled            = errors.New("loyalty tiers are not enabled")
+	ErrRecipientNotFound        = errors.New("recipient not found")
+	ErrTransferToSelf           = errors.New("cannot transfer points to yourself")
+	ErrTransferLimitExceeded    = errors.New("daily transfer limit exceeded")
+	ErrTransferNoteTooLong      = errors.New("transfer note is too long")
+	ErrInvalidReferralCode      = errors.New("invalid referral code")
+	ErrSelfReferral             = errors.New("cannot use your own referral code")
+	ErrReferralLimitExceeded    = errors.New("daily referral limit exceeded")
+	ErrInvalidPromoCode         = errors.New("invalid promo code")
+	ErrPromoCodeExists          = errors.New("promo code already exists")
+	ErrPromoCodeNotFound        = errors.New("promo code not found")
+	ErrPromoCodeInactive        = errors.New("promo code is not active")
+	ErrPromoCodeExhausted       = errors.New("promo code redemption limit reached")
+	ErrPromoCodeAlreadyRedeemed = errors.New("promo code already redeemed")
+	ErrInvalidCursor            = errors.New("invalid cursor")
+	ErrInvalidHistoryFilter     = errors.New("invalid history filter")
+	ErrInvalidStatementPeriod   = errors.New("invalid statement period")
+	ErrInvalidStatementFormat   = errors.New("statement format must be csv or pdf")
+	ErrInvalidOrdersFilter      = errors.New("invalid orders filter")
+)
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+)
+
+// DefaultExpiringSoonWindow - за сколько до сгорания баллы попадают в expiring_soon баланса
+const DefaultExpiringSoonWindow = 30 * 24 * time.Hour
+
+// WithPointsExpiry задаёт политику сгорания баллов и срок, за который о сгорании предупреждают.
+// По умолчанию баллы не сгорают
+func WithPointsExpiry(policy models.PointsExpiry, expiringSoon time.Duration) Option {
+	return func(s *GofemartService) {
+		s.pointsExpiry = policy
+		if expiringSoon > 0 {
+			s.expiringSoon = expiringSoon
+		}
+	}
+}
+
+// ValidPointsExpiryPolicy - известна ли политика сгорания
+func ValidPointsExpiryPolicy(policy string) bool {
+	switch policy {
+	case models.PointsExpiryNone, models.PointsExpiryFixed, models.PointsExpiryRolling:
+		return true
+	}
+	return false
+}
+
+func (s *GofemartService) expirePoints() error {
+	n, err := s.repo.ExpirePoints(s.pointsExpiry, time.Now())
+	if n > 0 {
+		castomLogger.Infof("expired points of %d users", n)
+	}
+	return err
+}
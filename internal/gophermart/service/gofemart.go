@@ -2,23 +2,141 @@
 package service
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/pkg/money"
+	"go-musthave-diploma-tpl/pkg/oidc"
+	"go-musthave-diploma-tpl/pkg/password"
+	logger "go-musthave-diploma-tpl/pkg/runtime/logger"
+	"time"
 )
 
+var castomLogger = logger.NewHTTPLogger().Sugar()
+
 // GofemartRepo - интерфейс репозитория
 type GofemartRepo interface {
-	// получение пользователя по логину и паролю
-	GetUserByLoginAndPassword(login, password string) (*models.User, error)
-	// создание пользователя
-	CreateUser(login, password string) (*models.User, error)
+	// получение пользователя по логину, пароль проверяется в сервисе
+	GetUserByLogin(login string) (*models.User, error)
+	// создание пользователя с уже посчитанным хэшем пароля
+	CreateUser(login, passwordHash string) (*models.User, error)
+	// замена хэша пароля (перехэширование старых записей)
+	UpdatePasswordHash(userID int, passwordHash string) error
+	// смена пароля с отзывом остальных сессий и всех refresh-токенов
+	ChangePassword(userID int, passwordHash string, keepSessionID int) error
+	// сохранение refresh-токена
+	CreateRefreshToken(userID int, tokenHash string, expiresAt time.Time) error
+	// ротация refresh-токена, возвращает владельца
+	RotateRefreshToken(oldHash, newHash string, expiresAt time.Time) (int, error)
+	// отзыв refresh-токена
+	RevokeRefreshToken(tokenHash string) error
+	// создание серверной сессии
+	CreateSession(userID int, tokenHash, userAgent, ip string, expiresAt time.Time) error
+	// проверка активности сессии с обновлением last_seen, 0 - сессии нет
+	TouchSession(userID int, tokenHash string) (int, error)
+	// отзыв одной сессии
+	RevokeSession(userID, sessionID int) error
+	// отзыв всех сессий и refresh-токенов пользователя
+	RevokeAllSessions(userID int) error
+	// список активных сессий
+	ActiveSessions(userID int) ([]models.Session, error)
+	// настройки второго фактора, nil - не настраивался
+	GetTOTP(userID int) (*models.TOTP, error)
+	// сохранение неподтверждённого TOTP-секрета
+	SaveTOTPSecret(userID int, secret string) error
+	// включение 2FA с новыми кодами восстановления
+	EnableTOTP(userID int, counter int64, recoveryCodeHashes []string) error
+	// защита от повтора TOTP-кода
+	UseTOTPCounter(userID int, counter int64) (bool, error)
+	// погашение кода восстановления
+	UseRecoveryCode(userID int, codeHash string) (bool, error)
+	// незавершённый вход в ожидании второго фактора
+	CreateLoginChallenge(userID int, tokenHash string, expiresAt time.Time) error
+	LoginChallengeUser(tokenHash string, maxAttempts int) (int, error)
+	FailLoginChallenge(tokenHash string) error
+	CompleteLoginChallenge(tokenHash string) (bool, error)
+	// поиск пользователей по части логина
+	SearchUsers(query string, limit int) ([]models.User, error)
+	// блокировка/разблокировка пользователя с записью в журнал
+	SetUserBlocked(userID int, blocked bool, entry models.AuditEntry) error
+	// смена роли с записью в журнал
+	SetUserRole(userID int, role string, entry models.AuditEntry) error
+	// повторная постановка заказа в очередь начислений с записью в журнал
+	RequeueOrder(number string, entry models.AuditEntry) error
+	// журнал действий администраторов
+	RecordAudit(entry models.AuditEntry) error
+	AuditLog(limit int) ([]models.AuditEntry, error)
+	// персональные API-ключи, хранится только хэш
+	CreateAPIKey(key *models.APIKey, keyHash string) error
+	APIKeys(userID int) ([]models.APIKey, error)
+	RevokeAPIKey(userID, keyID int) error
+	// действующий ключ по хэшу, nil - ключ не найден, отозван или истёк
+	UseAPIKey(keyHash string) (*models.APIKey, error)
+	// вход через провайдера OpenID Connect: начатые входы и привязка внешних учётных записей
+	CreateOIDCState(stateHash string, state models.OIDCLoginState, expiresAt time.Time) error
+	ConsumeOIDCState(stateHash string) (*models.OIDCLoginState, error)
+	GetUserByIdentity(issuer, subject string) (*models.User, error)
+	CreateUserWithIdentity(login string, identity models.UserIdentity) (*models.User, error)
+	// журнал проводок: корректировки и сторно администратором, просмотр и сверка
+	AdjustBalance(userID int, amount money.Amount, note string, entry models.AuditEntry) (models.LedgerEntry, error)
+	ReverseLedgerEntry(entryID int64, amount money.Amount, note string, entry models.AuditEntry) (models.LedgerEntry, error)
+	LedgerEntries(userID, limit int) ([]models.LedgerEntry, error)
+	CheckLedger() ([]models.LedgerMismatch, error)
+	// ключи идемпотентности: занять, сохранить ответ, освободить, удалить истёкшие
+	ReserveIdempotencyKey(k models.IdempotencyKey) (*models.IdempotencyKey, error)
+	SaveIdempotentResponse(userID int, scope, key string, resp models.IdempotentResponse) error
+	ReleaseIdempotencyKey(userID int, scope, key string) error
+	DeleteExpiredIdempotencyKeys() (int64, error)
+	// резервы баллов под заказ: создать, списать, отменить, список, снятие истёкших
+	CreateHold(hold models.Hold) (models.Hold, error)
+	CaptureHold(userID, holdID int) (models.Hold, error)
+	VoidHold(userID, holdID int) (models.Hold, error)
+	Holds(userID int) ([]models.Hold, error)
+	ExpireHolds() (int64, error)
+	// возврат баллов по списанию
+	RefundWithdrawal(refund models.WithdrawalRefund, entry *models.AuditEntry) (models.WithdrawalRefund, error)
+	// сгорание баллов: что сгорит до указанного момента, списание уже сгоревших
+	ExpiringPoints(userID int, policy models.PointsExpiry, until time.Time) ([]models.ExpiringPoints, error)
+	ExpirePoints(policy models.PointsExpiry, now time.Time) (int, error)
+	// уровни программы лояльности: начисления за окно, присвоенный уровень, кого пора пересмотреть
+	TierEarned(userID int, since time.Time) (money.Amount, error)
+	UserTier(userID int) (*models.UserTier, error)
+	SaveUserTier(t models.UserTier) error
+	TiersDue(now time.Time, limit int) ([]int, error)
+	// перевод баллов по логину получателя с проверкой остатка и суточных лимитов
+	Transfer(t models.Transfer, limits models.TransferLimits) (models.Transfer, error)
+	// отправленные и полученные переводы
+	Transfers(userID int) ([]models.Transfer, error)
+	// создание пользователя, приглашённого по реферальному коду
+	CreateReferredUser(login, passwordHash string, signup models.ReferralSignup) (*models.User, error)
+	// реферальный код пользователя и приглашённые им
+	ReferralCode(userID int) (string, error)
+	Referrals(userID int) ([]models.Referral, error)
+	// промокоды: заведение и закрытие с записью в журнал администратора, погашение пользователем
+	CreatePromoCode(p models.PromoCode, entry models.AuditEntry) (models.PromoCode, error)
+	PromoCodes() ([]models.PromoCode, error)
+	DisablePromoCode(code string, entry models.AuditEntry) (models.PromoCode, error)
+	RedeemPromoCode(userID int, code string, now time.Time) (models.PromoRedemption, error)
+	// история движений баллов пользователя постранично
+	BalanceHistory(f models.BalanceHistoryFilter) ([]models.LedgerEntry, error)
+	// выписка по заказам и списаниям: остаток на начало периода и строки по одной
+	StatementOpeningBalance(userID int, before time.Time) (money.Amount, error)
+	StatementLines(ctx context.Context, userID int, from, to time.Time, fn func(models.StatementLine) error) error
+	// до какого момента заблокирован вход по логину или адресу клиента
+	LoginLockedUntil(login, ip string) (time.Time, error)
+	// учёт неудачной попытки входа, возвращает число неудач подряд
+	RecordLoginFailure(scope, key string, window time.Duration) (int, error)
+	// временная блокировка входа
+	LockLogin(scope, key string, until time.Time) error
+	// сброс счётчика и снятие блокировки
+	ResetLoginAttempts(scope, key string) error
 	// получаем пользователя по ID
 	GetUserByID(id int) (*models.User, error)
 	// создание и проверка заказа
 	CreateOrder(userID int, orderNumber string) error
 	// получение заказов по пользвователю
 	GetOrders(userID int) ([]models.Order, error)
+	OrdersPage(f models.OrdersFilter) ([]models.Order, error)
 	// получение баланса
 	GetBalance(userID int) (models.Balance, error)
 	// запрос на списание средств
@@ -31,21 +149,92 @@ type GofemartRepo interface {
 type GofemartService struct {
 	repo             GofemartRepo
 	accrualSystemURL string
+	hasher           *password.Hasher
+	passwordPolicy   *password.Policy
+	tokens           TokenSettings
+	sessionTTL       time.Duration
+	loginThrottle    LoginThrottle
+	twoFactor        TwoFactorSettings
+	oidc             *oidc.Provider
+	idempotencyTTL   time.Duration
+	holdTTL          time.Duration
+	pointsExpiry     models.PointsExpiry
+	expiringSoon     time.Duration
+	tiers            models.TierPolicy
+	transferLimits   models.TransferLimits
+	referralLimits   models.ReferralLimits
+}
+
+// Option - необязательная настройка сервиса
+type Option func(*GofemartService)
+
+// WithPasswordHasher задаёт хэшер паролей (по умолчанию bcrypt со стоимостью password.DefaultCost)
+func WithPasswordHasher(h *password.Hasher) Option {
+	return func(s *GofemartService) {
+		s.hasher = h
+	}
 }
 
-func NewGofemartService(repo GofemartRepo, accrualURL string) *GofemartService {
-	return &GofemartService{
+// WithPasswordPolicy задаёт политику паролей при регистрации и смене пароля
+func WithPasswordPolicy(p *password.Policy) Option {
+	return func(s *GofemartService) {
+		s.passwordPolicy = p
+	}
+}
+
+func NewGofemartService(repo GofemartRepo, accrualURL string, opts ...Option) *GofemartService {
+	s := &GofemartService{
 		repo:             repo,
 		accrualSystemURL: accrualURL,
+		hasher:           password.NewHasher(password.DefaultCost),
+		passwordPolicy:   password.DefaultPolicy(),
+		tokens:           DefaultTokenSettings(),
+		sessionTTL:       DefaultSessionTTL,
+		loginThrottle:    DefaultLoginThrottle(),
+		twoFactor:        DefaultTwoFactorSettings(),
+		idempotencyTTL:   DefaultIdempotencyKeyTTL,
+		holdTTL:          DefaultHoldTTL,
+		pointsExpiry:     models.PointsExpiry{Policy: models.PointsExpiryNone},
+		expiringSoon:     DefaultExpiringSoonWindow,
+		transferLimits:   DefaultTransferLimits(),
+		referralLimits:   DefaultReferralLimits(),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
-func (s *GofemartService) RegisterUser(login, password string) (*models.User, error) {
-	if login == "" || password == "" {
-		return nil, errors.New("login and password are required")
+func (s *GofemartService) RegisterUser(login, plainPassword string) (*models.User, error) {
+	return s.RegisterReferredUser(login, plainPassword, "", "")
+}
+
+// RegisterReferredUser регистрирует пользователя, приглашённого по реферальному коду. Пустой код - обычная
+// регистрация. ip - адрес клиента, по нему отклоняются приглашения самого себя
+func (s *GofemartService) RegisterReferredUser(login, plainPassword, referralCode, ip string) (*models.User, error) {
+	if login == "" || plainPassword == "" {
+		return nil, ErrLoginAndPasswordRequired
+	}
+
+	if err := s.checkPasswordPolicy(login, plainPassword); err != nil {
+		return nil, err
 	}
 
-	user, err := s.repo.CreateUser(login, password)
+	hash, err := s.hasher.Hash(plainPassword)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	var user *models.User
+	if code := normalizeReferralCode(referralCode); code != "" {
+		user, err = s.repo.CreateReferredUser(login, hash, models.ReferralSignup{
+			Code:   code,
+			IP:     ip,
+			Limits: s.referralLimits,
+		})
+	} else {
+		user, err = s.repo.CreateUser(login, hash)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -53,23 +242,53 @@ func (s *GofemartService) RegisterUser(login, password string) (*models.User, er
 	return user, nil
 }
 
-func (s *GofemartService) LoginUser(login, password string) (*models.User, error) {
-	if login == "" || password == "" {
-		return nil, fmt.Errorf("login and password are required")
+func (s *GofemartService) LoginUser(login, plainPassword string) (*models.User, error) {
+	if login == "" || plainPassword == "" {
+		return nil, ErrLoginAndPasswordRequired
 	}
 
-	user, err := s.repo.GetUserByLoginAndPassword(login, password)
+	user, err := s.repo.GetUserByLogin(login)
 	if err != nil {
 		return nil, err
 	}
 
 	if user == nil {
-		return nil, fmt.Errorf("invalid login or password")
+		s.hasher.VerifyDummy(plainPassword)
+		return nil, ErrInvalidLoginOrPassword
+	}
+
+	ok, needsRehash := s.hasher.Verify(user.PasswordHash, plainPassword)
+	if !ok {
+		return nil, ErrInvalidLoginOrPassword
+	}
+
+	if user.Blocked() {
+		return nil, ErrUserBlocked
+	}
+
+	// старый SHA-256 или устаревшая стоимость - прозрачно перехэшируем,
+	// ошибка перехэширования не мешает входу
+	if needsRehash {
+		if err := s.rehashPassword(user, plainPassword); err != nil {
+			castomLogger.Infof("failed to rehash password for user %d: %v", user.ID, err)
+		}
 	}
 
 	return user, nil
 }
 
+func (s *GofemartService) rehashPassword(user *models.User, plainPassword string) error {
+	hash, err := s.hasher.Hash(plainPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdatePasswordHash(user.ID, hash); err != nil {
+		return err
+	}
+	user.PasswordHash = hash
+	return nil
+}
+
 func (s *GofemartService) GetUserByID(userID int) (*models.User, error) {
 	if userID <= 0 {
 		return nil, fmt.Errorf("invalid user ID")
@@ -101,7 +320,16 @@ func (s *GofemartService) GetBalance(userID int) (models.Balance, error) {
 	if userID <= 0 {
 		return models.Balance{}, fmt.Errorf("invalid user ID")
 	}
-	return s.repo.GetBalance(userID)
+	balance, err := s.repo.GetBalance(userID)
+	if err != nil || !s.pointsExpiry.Enabled() {
+		return balance, err
+	}
+
+	balance.ExpiringSoon, err = s.repo.ExpiringPoints(userID, s.pointsExpiry, time.Now().Add(s.expiringSoon))
+	if err != nil {
+		return models.Balance{}, err
+	}
+	return balance, nil
 }
 
 func (s *GofemartService) Withdraw(userID int, withdraw models.WithdrawBalance) error {
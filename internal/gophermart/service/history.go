@@ -0,0 +1,91 @@
+package service
+
+import (
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+)
+
+// размер страницы истории
+const (
+	DefaultHistoryLimit = 50
+	MaxHistoryLimit     = 200
+)
+
+// encodeCursor - курсор непрозрачен для клиента, чтобы формат можно было поменять
+func encodeCursor(value string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(value))
+}
+
+func decodeCursor(cursor string) (string, error) {
+	value, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(value) == 0 {
+		return "", ErrInvalidCursor
+	}
+	return string(value), nil
+}
+
+// BalanceHistory - страница истории движений баллов: начисления, списания, сторно, корректировки
+// и остальные проводки с остатком после каждой
+func (s *GofemartService) BalanceHistory(userID int, req models.BalanceHistoryRequest) (models.BalanceHistory, error) {
+	f := models.BalanceHistoryFilter{
+		UserID: userID,
+		From:   req.From,
+		To:     req.To,
+		Types:  req.Types,
+		Limit:  req.Limit,
+	}
+	switch {
+	case f.Limit == 0:
+		f.Limit = DefaultHistoryLimit
+	case f.Limit < 0 || f.Limit > MaxHistoryLimit:
+		return models.BalanceHistory{}, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidHistoryFilter, MaxHistoryLimit)
+	}
+	for _, t := range f.Types {
+		if !slices.Contains(models.LedgerEntryTypes, t) {
+			return models.BalanceHistory{}, fmt.Errorf("%w: unknown type %q", ErrInvalidHistoryFilter, t)
+		}
+	}
+	if f.From != nil && f.To != nil && !f.To.After(*f.From) {
+		return models.BalanceHistory{}, fmt.Errorf("%w: to must be after from", ErrInvalidHistoryFilter)
+	}
+	if req.Cursor != "" {
+		value, err := decodeCursor(req.Cursor)
+		if err != nil {
+			return models.BalanceHistory{}, err
+		}
+		f.BeforeID, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || f.BeforeID <= 0 {
+			return models.BalanceHistory{}, ErrInvalidCursor
+		}
+	}
+
+	// лишняя запись показывает, есть ли следующая страница
+	limit := f.Limit
+	f.Limit++
+	entries, err := s.repo.BalanceHistory(f)
+	if err != nil {
+		return models.BalanceHistory{}, err
+	}
+
+	history := models.BalanceHistory{Entries: make([]models.BalanceHistoryEntry, 0, min(len(entries), limit))}
+	for i, e := range entries {
+		if i == limit {
+			history.NextCursor = encodeCursor(strconv.FormatInt(entries[i-1].ID, 10))
+			break
+		}
+		history.Entries = append(history.Entries, models.BalanceHistoryEntry{
+			ID:           e.ID,
+			Type:         e.Type,
+			Amount:       e.Amount,
+			BalanceAfter: e.BalanceAfter,
+			OrderNumber:  e.OrderNumber,
+			Note:         e.Note,
+			CreatedAt:    e.CreatedAt,
+		})
+	}
+	return history, nil
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+)
+
+// DefaultHoldTTL - сколько действует резерв баллов, если его не списали и не отменили
+const DefaultHoldTTL = 30 * time.Minute
+
+// WithHoldTTL задаёт срок действия резерва баллов
+func WithHoldTTL(ttl time.Duration) Option {
+	return func(s *GofemartService) {
+		if ttl > 0 {
+			s.holdTTL = ttl
+		}
+	}
+}
+
+// CreateHold резервирует баллы под заказ до подтверждения оплаты
+func (s *GofemartService) CreateHold(userID int, req models.CreateHoldRequest) (models.Hold, error) {
+	if req.Amount <= 0 {
+		return models.Hold{}, ErrInvalidAmount
+	}
+	return s.repo.CreateHold(models.Hold{
+		UserID:    userID,
+		Order:     req.Order,
+		Amount:    req.Amount,
+		ExpiresAt: time.Now().Add(s.holdTTL),
+	})
+}
+
+// CaptureHold списывает зарезервированные баллы
+func (s *GofemartService) CaptureHold(userID, holdID int) (models.Hold, error) {
+	return s.repo.CaptureHold(userID, holdID)
+}
+
+// VoidHold отменяет резерв
+func (s *GofemartService) VoidHold(userID, holdID int) (models.Hold, error) {
+	return s.repo.VoidHold(userID, holdID)
+}
+
+func (s *GofemartService) Holds(userID int) ([]models.Hold, error) {
+	return s.repo.Holds(userID)
+}
+
+func (s *GofemartService) expireHolds() error {
+	n, err := s.repo.ExpireHolds()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		castomLogger.Infof("expired %d balance holds", n)
+	}
+	return nil
+}
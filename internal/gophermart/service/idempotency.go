@@ -0,0 +1,84 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+)
+
+// DefaultIdempotencyKeyTTL - сколько хранится ответ на запрос с ключом идемпотентности
+const DefaultIdempotencyKeyTTL = 24 * time.Hour
+
+// WithIdempotencyKeyTTL задаёт срок хранения ключей идемпотентности
+func WithIdempotencyKeyTTL(ttl time.Duration) Option {
+	return func(s *GofemartService) {
+		if ttl > 0 {
+			s.idempotencyTTL = ttl
+		}
+	}
+}
+
+// BeginIdempotent занимает ключ под запрос. Возвращает сохранённый ответ, если запрос с этим ключом
+// уже выполнен; nil - запрос нужно выполнить. Тот же ключ с другим содержимым - ErrIdempotencyKeyReused
+func (s *GofemartService) BeginIdempotent(userID int, scope, key, fingerprint string) (*models.IdempotentResponse, error) {
+	if !validIdempotencyKey(key) {
+		return nil, ErrInvalidIdempotencyKey
+	}
+
+	existing, err := s.repo.ReserveIdempotencyKey(models.IdempotencyKey{
+		UserID:      userID,
+		Scope:       scope,
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   time.Now().Add(s.idempotencyTTL),
+	})
+	if err != nil || existing == nil {
+		return nil, err
+	}
+	if existing.Fingerprint != fingerprint {
+		return nil, ErrIdempotencyKeyReused
+	}
+	// незавершённый запрос выполняется ещё раз: двойное списание исключает
+	// фиксация ответа в транзакции списания
+	return existing.Response, nil
+}
+
+// FinishIdempotent сохраняет окончательный ответ для повторов
+func (s *GofemartService) FinishIdempotent(userID int, scope, key string, resp models.IdempotentResponse) error {
+	return s.repo.SaveIdempotentResponse(userID, scope, key, resp)
+}
+
+// ReleaseIdempotent освобождает ключ после ответа, который не нужно повторять (ошибка сервера, нет кода 2FA)
+func (s *GofemartService) ReleaseIdempotent(userID int, scope, key string) error {
+	return s.repo.ReleaseIdempotencyKey(userID, scope, key)
+}
+
+// RequestFingerprint - отпечаток содержимого запроса для сравнения повторов
+func RequestFingerprint(parts ...string) string {
+	return HashToken(strings.Join(parts, "\x00"))
+}
+
+func (s *GofemartService) purgeExpiredIdempotencyKeys() error {
+	n, err := s.repo.DeleteExpiredIdempotencyKeys()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		castomLogger.Infof("deleted %d expired idempotency keys", n)
+	}
+	return nil
+}
+
+// validIdempotencyKey - непустой ключ из видимых ASCII-символов
+func validIdempotencyKey(key string) bool {
+	if key == "" || len(key) > models.IdempotencyKeyMaxLen {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// idempotencyCleanupInterval - как часто удаляются истёкшие ключи идемпотентности
+	idempotencyCleanupInterval = time.Hour
+	// holdExpiryInterval - как часто снимаются истёкшие резервы баллов
+	holdExpiryInterval = time.Minute
+	// pointsExpiryInterval - как часто списываются сгоревшие баллы
+	pointsExpiryInterval = time.Hour
+	// tierReviewInterval - как часто ищутся пользователи, чей уровень пора пересмотреть
+	tierReviewInterval = 10 * time.Minute
+)
+
+// StartBackgroundJobs запускает периодические задачи сервиса, они работают до отмены ctx
+func (s *GofemartService) StartBackgroundJobs(ctx context.Context) {
+	go runPeriodically(ctx, idempotencyCleanupInterval, "idempotency keys cleanup", s.purgeExpiredIdempotencyKeys)
+	go runPeriodically(ctx, holdExpiryInterval, "holds expiry", s.expireHolds)
+	if s.pointsExpiry.Enabled() {
+		go runPeriodically(ctx, pointsExpiryInterval, "points expiry", s.expirePoints)
+	}
+	if s.tiers.Enabled() {
+		go runPeriodically(ctx, tierReviewInterval, "tiers review", s.evaluateTiers)
+	}
+}
+
+func runPeriodically(ctx context.Context, interval time.Duration, name string, job func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := job(); err != nil {
+				castomLogger.Infof("%s failed: %v", name, err)
+			}
+		}
+	}
+}
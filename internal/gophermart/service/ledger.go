@@ -0,0 +1,53 @@
+package service
+
+import (
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+)
+
+// ledgerEntriesLimit - сколько последних проводок видит поддержка
+const ledgerEntriesLimit = 100
+
+func (s *GofemartService) AdminLedgerEntries(actorID, userID int) ([]models.LedgerEntry, error) {
+	if err := s.audit(actorID, models.AuditViewLedger, &userID, ""); err != nil {
+		return nil, err
+	}
+	return s.repo.LedgerEntries(userID, ledgerEntriesLimit)
+}
+
+// AdminAdjustBalance - корректировка баланса, списать больше остатка нельзя
+func (s *GofemartService) AdminAdjustBalance(actorID, userID int, req models.AdjustBalanceRequest) (models.LedgerEntry, error) {
+	if req.Amount == 0 {
+		return models.LedgerEntry{}, ErrInvalidAmount
+	}
+
+	return s.repo.AdjustBalance(userID, req.Amount, req.Note, models.AuditEntry{
+		ActorID:      actorID,
+		Action:       models.AuditAdjustBalance,
+		TargetUserID: &userID,
+		Details:      req.Note,
+	})
+}
+
+// AdminReverseEntry сторнирует проводку; нулевая сумма - весь несторнированный остаток
+func (s *GofemartService) AdminReverseEntry(actorID int, entryID int64, req models.ReverseEntryRequest) (models.LedgerEntry, error) {
+	if req.Amount < 0 {
+		return models.LedgerEntry{}, ErrInvalidAmount
+	}
+
+	return s.repo.ReverseLedgerEntry(entryID, req.Amount, req.Note, models.AuditEntry{
+		ActorID: actorID,
+		Action:  models.AuditReverseEntry,
+	})
+}
+
+// CheckLedger - сверка журнала, вызывается из командной строки сервера
+func (s *GofemartService) CheckLedger() ([]models.LedgerMismatch, error) {
+	return s.repo.CheckLedger()
+}
+
+func (s *GofemartService) AdminCheckLedger(actorID int) ([]models.LedgerMismatch, error) {
+	if err := s.audit(actorID, models.AuditCheckLedger, nil, ""); err != nil {
+		return nil, err
+	}
+	return s.repo.CheckLedger()
+}
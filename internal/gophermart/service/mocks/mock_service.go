@@ -5,8 +5,11 @@
 package mocks
 
 import (
+	context "context"
 	models "go-musthave-diploma-tpl/internal/gophermart/models"
+	money "go-musthave-diploma-tpl/pkg/money"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -34,6 +37,212 @@ func (m *MockGofemartRepo) EXPECT() *MockGofemartRepoMockRecorder {
 	return m.recorder
 }
 
+// APIKeys mocks base method.
+func (m *MockGofemartRepo) APIKeys(userID int) ([]models.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "APIKeys", userID)
+	ret0, _ := ret[0].([]models.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// APIKeys indicates an expected call of APIKeys.
+func (mr *MockGofemartRepoMockRecorder) APIKeys(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "APIKeys", reflect.TypeOf((*MockGofemartRepo)(nil).APIKeys), userID)
+}
+
+// ActiveSessions mocks base method.
+func (m *MockGofemartRepo) ActiveSessions(userID int) ([]models.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ActiveSessions", userID)
+	ret0, _ := ret[0].([]models.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ActiveSessions indicates an expected call of ActiveSessions.
+func (mr *MockGofemartRepoMockRecorder) ActiveSessions(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ActiveSessions", reflect.TypeOf((*MockGofemartRepo)(nil).ActiveSessions), userID)
+}
+
+// AdjustBalance mocks base method.
+func (m *MockGofemartRepo) AdjustBalance(userID int, amount money.Amount, note string, entry models.AuditEntry) (models.LedgerEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdjustBalance", userID, amount, note, entry)
+	ret0, _ := ret[0].(models.LedgerEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AdjustBalance indicates an expected call of AdjustBalance.
+func (mr *MockGofemartRepoMockRecorder) AdjustBalance(userID, amount, note, entry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdjustBalance", reflect.TypeOf((*MockGofemartRepo)(nil).AdjustBalance), userID, amount, note, entry)
+}
+
+// AuditLog mocks base method.
+func (m *MockGofemartRepo) AuditLog(limit int) ([]models.AuditEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AuditLog", limit)
+	ret0, _ := ret[0].([]models.AuditEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuditLog indicates an expected call of AuditLog.
+func (mr *MockGofemartRepoMockRecorder) AuditLog(limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuditLog", reflect.TypeOf((*MockGofemartRepo)(nil).AuditLog), limit)
+}
+
+// BalanceHistory mocks base method.
+func (m *MockGofemartRepo) BalanceHistory(f models.BalanceHistoryFilter) ([]models.LedgerEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BalanceHistory", f)
+	ret0, _ := ret[0].([]models.LedgerEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BalanceHistory indicates an expected call of BalanceHistory.
+func (mr *MockGofemartRepoMockRecorder) BalanceHistory(f interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BalanceHistory", reflect.TypeOf((*MockGofemartRepo)(nil).BalanceHistory), f)
+}
+
+// CaptureHold mocks base method.
+func (m *MockGofemartRepo) CaptureHold(userID, holdID int) (models.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureHold", userID, holdID)
+	ret0, _ := ret[0].(models.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureHold indicates an expected call of CaptureHold.
+func (mr *MockGofemartRepoMockRecorder) CaptureHold(userID, holdID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureHold", reflect.TypeOf((*MockGofemartRepo)(nil).CaptureHold), userID, holdID)
+}
+
+// ChangePassword mocks base method.
+func (m *MockGofemartRepo) ChangePassword(userID int, passwordHash string, keepSessionID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePassword", userID, passwordHash, keepSessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockGofemartRepoMockRecorder) ChangePassword(userID, passwordHash, keepSessionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockGofemartRepo)(nil).ChangePassword), userID, passwordHash, keepSessionID)
+}
+
+// CheckLedger mocks base method.
+func (m *MockGofemartRepo) CheckLedger() ([]models.LedgerMismatch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckLedger")
+	ret0, _ := ret[0].([]models.LedgerMismatch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckLedger indicates an expected call of CheckLedger.
+func (mr *MockGofemartRepoMockRecorder) CheckLedger() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckLedger", reflect.TypeOf((*MockGofemartRepo)(nil).CheckLedger))
+}
+
+// CompleteLoginChallenge mocks base method.
+func (m *MockGofemartRepo) CompleteLoginChallenge(tokenHash string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteLoginChallenge", tokenHash)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompleteLoginChallenge indicates an expected call of CompleteLoginChallenge.
+func (mr *MockGofemartRepoMockRecorder) CompleteLoginChallenge(tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteLoginChallenge", reflect.TypeOf((*MockGofemartRepo)(nil).CompleteLoginChallenge), tokenHash)
+}
+
+// ConsumeOIDCState mocks base method.
+func (m *MockGofemartRepo) ConsumeOIDCState(stateHash string) (*models.OIDCLoginState, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeOIDCState", stateHash)
+	ret0, _ := ret[0].(*models.OIDCLoginState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeOIDCState indicates an expected call of ConsumeOIDCState.
+func (mr *MockGofemartRepoMockRecorder) ConsumeOIDCState(stateHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeOIDCState", reflect.TypeOf((*MockGofemartRepo)(nil).ConsumeOIDCState), stateHash)
+}
+
+// CreateAPIKey mocks base method.
+func (m *MockGofemartRepo) CreateAPIKey(key *models.APIKey, keyHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIKey", key, keyHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey.
+func (mr *MockGofemartRepoMockRecorder) CreateAPIKey(key, keyHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockGofemartRepo)(nil).CreateAPIKey), key, keyHash)
+}
+
+// CreateHold mocks base method.
+func (m *MockGofemartRepo) CreateHold(hold models.Hold) (models.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateHold", hold)
+	ret0, _ := ret[0].(models.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateHold indicates an expected call of CreateHold.
+func (mr *MockGofemartRepoMockRecorder) CreateHold(hold interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHold", reflect.TypeOf((*MockGofemartRepo)(nil).CreateHold), hold)
+}
+
+// CreateLoginChallenge mocks base method.
+func (m *MockGofemartRepo) CreateLoginChallenge(userID int, tokenHash string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateLoginChallenge", userID, tokenHash, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateLoginChallenge indicates an expected call of CreateLoginChallenge.
+func (mr *MockGofemartRepoMockRecorder) CreateLoginChallenge(userID, tokenHash, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLoginChallenge", reflect.TypeOf((*MockGofemartRepo)(nil).CreateLoginChallenge), userID, tokenHash, expiresAt)
+}
+
+// CreateOIDCState mocks base method.
+func (m *MockGofemartRepo) CreateOIDCState(stateHash string, state models.OIDCLoginState, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOIDCState", stateHash, state, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOIDCState indicates an expected call of CreateOIDCState.
+func (mr *MockGofemartRepoMockRecorder) CreateOIDCState(stateHash, state, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOIDCState", reflect.TypeOf((*MockGofemartRepo)(nil).CreateOIDCState), stateHash, state, expiresAt)
+}
+
 // CreateOrder mocks base method.
 func (m *MockGofemartRepo) CreateOrder(userID int, orderNumber string) error {
 	m.ctrl.T.Helper()
@@ -48,19 +257,195 @@ func (mr *MockGofemartRepoMockRecorder) CreateOrder(userID, orderNumber interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrder", reflect.TypeOf((*MockGofemartRepo)(nil).CreateOrder), userID, orderNumber)
 }
 
+// CreatePromoCode mocks base method.
+func (m *MockGofemartRepo) CreatePromoCode(p models.PromoCode, entry models.AuditEntry) (models.PromoCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePromoCode", p, entry)
+	ret0, _ := ret[0].(models.PromoCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePromoCode indicates an expected call of CreatePromoCode.
+func (mr *MockGofemartRepoMockRecorder) CreatePromoCode(p, entry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePromoCode", reflect.TypeOf((*MockGofemartRepo)(nil).CreatePromoCode), p, entry)
+}
+
+// CreateReferredUser mocks base method.
+func (m *MockGofemartRepo) CreateReferredUser(login, passwordHash string, signup models.ReferralSignup) (*models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateReferredUser", login, passwordHash, signup)
+	ret0, _ := ret[0].(*models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateReferredUser indicates an expected call of CreateReferredUser.
+func (mr *MockGofemartRepoMockRecorder) CreateReferredUser(login, passwordHash, signup interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateReferredUser", reflect.TypeOf((*MockGofemartRepo)(nil).CreateReferredUser), login, passwordHash, signup)
+}
+
+// CreateRefreshToken mocks base method.
+func (m *MockGofemartRepo) CreateRefreshToken(userID int, tokenHash string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRefreshToken", userID, tokenHash, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateRefreshToken indicates an expected call of CreateRefreshToken.
+func (mr *MockGofemartRepoMockRecorder) CreateRefreshToken(userID, tokenHash, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRefreshToken", reflect.TypeOf((*MockGofemartRepo)(nil).CreateRefreshToken), userID, tokenHash, expiresAt)
+}
+
+// CreateSession mocks base method.
+func (m *MockGofemartRepo) CreateSession(userID int, tokenHash, userAgent, ip string, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSession", userID, tokenHash, userAgent, ip, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateSession indicates an expected call of CreateSession.
+func (mr *MockGofemartRepoMockRecorder) CreateSession(userID, tokenHash, userAgent, ip, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockGofemartRepo)(nil).CreateSession), userID, tokenHash, userAgent, ip, expiresAt)
+}
+
 // CreateUser mocks base method.
-func (m *MockGofemartRepo) CreateUser(login, password string) (*models.User, error) {
+func (m *MockGofemartRepo) CreateUser(login, passwordHash string) (*models.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateUser", login, password)
+	ret := m.ctrl.Call(m, "CreateUser", login, passwordHash)
 	ret0, _ := ret[0].(*models.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateUser indicates an expected call of CreateUser.
-func (mr *MockGofemartRepoMockRecorder) CreateUser(login, password interface{}) *gomock.Call {
+func (mr *MockGofemartRepoMockRecorder) CreateUser(login, passwordHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockGofemartRepo)(nil).CreateUser), login, passwordHash)
+}
+
+// CreateUserWithIdentity mocks base method.
+func (m *MockGofemartRepo) CreateUserWithIdentity(login string, identity models.UserIdentity) (*models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUserWithIdentity", login, identity)
+	ret0, _ := ret[0].(*models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUserWithIdentity indicates an expected call of CreateUserWithIdentity.
+func (mr *MockGofemartRepoMockRecorder) CreateUserWithIdentity(login, identity interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUserWithIdentity", reflect.TypeOf((*MockGofemartRepo)(nil).CreateUserWithIdentity), login, identity)
+}
+
+// DeleteExpiredIdempotencyKeys mocks base method.
+func (m *MockGofemartRepo) DeleteExpiredIdempotencyKeys() (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpiredIdempotencyKeys")
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpiredIdempotencyKeys indicates an expected call of DeleteExpiredIdempotencyKeys.
+func (mr *MockGofemartRepoMockRecorder) DeleteExpiredIdempotencyKeys() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpiredIdempotencyKeys", reflect.TypeOf((*MockGofemartRepo)(nil).DeleteExpiredIdempotencyKeys))
+}
+
+// DisablePromoCode mocks base method.
+func (m *MockGofemartRepo) DisablePromoCode(code string, entry models.AuditEntry) (models.PromoCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisablePromoCode", code, entry)
+	ret0, _ := ret[0].(models.PromoCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DisablePromoCode indicates an expected call of DisablePromoCode.
+func (mr *MockGofemartRepoMockRecorder) DisablePromoCode(code, entry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisablePromoCode", reflect.TypeOf((*MockGofemartRepo)(nil).DisablePromoCode), code, entry)
+}
+
+// EnableTOTP mocks base method.
+func (m *MockGofemartRepo) EnableTOTP(userID int, counter int64, recoveryCodeHashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableTOTP", userID, counter, recoveryCodeHashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableTOTP indicates an expected call of EnableTOTP.
+func (mr *MockGofemartRepoMockRecorder) EnableTOTP(userID, counter, recoveryCodeHashes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTOTP", reflect.TypeOf((*MockGofemartRepo)(nil).EnableTOTP), userID, counter, recoveryCodeHashes)
+}
+
+// ExpireHolds mocks base method.
+func (m *MockGofemartRepo) ExpireHolds() (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireHolds")
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpireHolds indicates an expected call of ExpireHolds.
+func (mr *MockGofemartRepoMockRecorder) ExpireHolds() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireHolds", reflect.TypeOf((*MockGofemartRepo)(nil).ExpireHolds))
+}
+
+// ExpirePoints mocks base method.
+func (m *MockGofemartRepo) ExpirePoints(policy models.PointsExpiry, now time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpirePoints", policy, now)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpirePoints indicates an expected call of ExpirePoints.
+func (mr *MockGofemartRepoMockRecorder) ExpirePoints(policy, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpirePoints", reflect.TypeOf((*MockGofemartRepo)(nil).ExpirePoints), policy, now)
+}
+
+// ExpiringPoints mocks base method.
+func (m *MockGofemartRepo) ExpiringPoints(userID int, policy models.PointsExpiry, until time.Time) ([]models.ExpiringPoints, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpiringPoints", userID, policy, until)
+	ret0, _ := ret[0].([]models.ExpiringPoints)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpiringPoints indicates an expected call of ExpiringPoints.
+func (mr *MockGofemartRepoMockRecorder) ExpiringPoints(userID, policy, until interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpiringPoints", reflect.TypeOf((*MockGofemartRepo)(nil).ExpiringPoints), userID, policy, until)
+}
+
+// FailLoginChallenge mocks base method.
+func (m *MockGofemartRepo) FailLoginChallenge(tokenHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FailLoginChallenge", tokenHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FailLoginChallenge indicates an expected call of FailLoginChallenge.
+func (mr *MockGofemartRepoMockRecorder) FailLoginChallenge(tokenHash interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockGofemartRepo)(nil).CreateUser), login, password)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FailLoginChallenge", reflect.TypeOf((*MockGofemartRepo)(nil).FailLoginChallenge), tokenHash)
 }
 
 // GetBalance mocks base method.
@@ -93,6 +478,21 @@ func (mr *MockGofemartRepoMockRecorder) GetOrders(userID interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrders", reflect.TypeOf((*MockGofemartRepo)(nil).GetOrders), userID)
 }
 
+// GetTOTP mocks base method.
+func (m *MockGofemartRepo) GetTOTP(userID int) (*models.TOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTOTP", userID)
+	ret0, _ := ret[0].(*models.TOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTOTP indicates an expected call of GetTOTP.
+func (mr *MockGofemartRepoMockRecorder) GetTOTP(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTOTP", reflect.TypeOf((*MockGofemartRepo)(nil).GetTOTP), userID)
+}
+
 // GetUserByID mocks base method.
 func (m *MockGofemartRepo) GetUserByID(id int) (*models.User, error) {
 	m.ctrl.T.Helper()
@@ -108,19 +508,648 @@ func (mr *MockGofemartRepoMockRecorder) GetUserByID(id interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockGofemartRepo)(nil).GetUserByID), id)
 }
 
-// GetUserByLoginAndPassword mocks base method.
-func (m *MockGofemartRepo) GetUserByLoginAndPassword(login, password string) (*models.User, error) {
+// GetUserByIdentity mocks base method.
+func (m *MockGofemartRepo) GetUserByIdentity(issuer, subject string) (*models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByIdentity", issuer, subject)
+	ret0, _ := ret[0].(*models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByIdentity indicates an expected call of GetUserByIdentity.
+func (mr *MockGofemartRepoMockRecorder) GetUserByIdentity(issuer, subject interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByIdentity", reflect.TypeOf((*MockGofemartRepo)(nil).GetUserByIdentity), issuer, subject)
+}
+
+// GetUserByLogin mocks base method.
+func (m *MockGofemartRepo) GetUserByLogin(login string) (*models.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserByLoginAndPassword", login, password)
+	ret := m.ctrl.Call(m, "GetUserByLogin", login)
 	ret0, _ := ret[0].(*models.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetUserByLoginAndPassword indicates an expected call of GetUserByLoginAndPassword.
-func (mr *MockGofemartRepoMockRecorder) GetUserByLoginAndPassword(login, password interface{}) *gomock.Call {
+// GetUserByLogin indicates an expected call of GetUserByLogin.
+func (mr *MockGofemartRepoMockRecorder) GetUserByLogin(login interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByLogin", reflect.TypeOf((*MockGofemartRepo)(nil).GetUserByLogin), login)
+}
+
+// Holds mocks base method.
+func (m *MockGofemartRepo) Holds(userID int) ([]models.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Holds", userID)
+	ret0, _ := ret[0].([]models.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Holds indicates an expected call of Holds.
+func (mr *MockGofemartRepoMockRecorder) Holds(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Holds", reflect.TypeOf((*MockGofemartRepo)(nil).Holds), userID)
+}
+
+// LedgerEntries mocks base method.
+func (m *MockGofemartRepo) LedgerEntries(userID, limit int) ([]models.LedgerEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LedgerEntries", userID, limit)
+	ret0, _ := ret[0].([]models.LedgerEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LedgerEntries indicates an expected call of LedgerEntries.
+func (mr *MockGofemartRepoMockRecorder) LedgerEntries(userID, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LedgerEntries", reflect.TypeOf((*MockGofemartRepo)(nil).LedgerEntries), userID, limit)
+}
+
+// LockLogin mocks base method.
+func (m *MockGofemartRepo) LockLogin(scope, key string, until time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockLogin", scope, key, until)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockLogin indicates an expected call of LockLogin.
+func (mr *MockGofemartRepoMockRecorder) LockLogin(scope, key, until interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockLogin", reflect.TypeOf((*MockGofemartRepo)(nil).LockLogin), scope, key, until)
+}
+
+// LoginChallengeUser mocks base method.
+func (m *MockGofemartRepo) LoginChallengeUser(tokenHash string, maxAttempts int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoginChallengeUser", tokenHash, maxAttempts)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoginChallengeUser indicates an expected call of LoginChallengeUser.
+func (mr *MockGofemartRepoMockRecorder) LoginChallengeUser(tokenHash, maxAttempts interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoginChallengeUser", reflect.TypeOf((*MockGofemartRepo)(nil).LoginChallengeUser), tokenHash, maxAttempts)
+}
+
+// LoginLockedUntil mocks base method.
+func (m *MockGofemartRepo) LoginLockedUntil(login, ip string) (time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoginLockedUntil", login, ip)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoginLockedUntil indicates an expected call of LoginLockedUntil.
+func (mr *MockGofemartRepoMockRecorder) LoginLockedUntil(login, ip interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoginLockedUntil", reflect.TypeOf((*MockGofemartRepo)(nil).LoginLockedUntil), login, ip)
+}
+
+// OrdersPage mocks base method.
+func (m *MockGofemartRepo) OrdersPage(f models.OrdersFilter) ([]models.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OrdersPage", f)
+	ret0, _ := ret[0].([]models.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OrdersPage indicates an expected call of OrdersPage.
+func (mr *MockGofemartRepoMockRecorder) OrdersPage(f interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OrdersPage", reflect.TypeOf((*MockGofemartRepo)(nil).OrdersPage), f)
+}
+
+// PromoCodes mocks base method.
+func (m *MockGofemartRepo) PromoCodes() ([]models.PromoCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PromoCodes")
+	ret0, _ := ret[0].([]models.PromoCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PromoCodes indicates an expected call of PromoCodes.
+func (mr *MockGofemartRepoMockRecorder) PromoCodes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PromoCodes", reflect.TypeOf((*MockGofemartRepo)(nil).PromoCodes))
+}
+
+// RecordAudit mocks base method.
+func (m *MockGofemartRepo) RecordAudit(entry models.AuditEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordAudit", entry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordAudit indicates an expected call of RecordAudit.
+func (mr *MockGofemartRepoMockRecorder) RecordAudit(entry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordAudit", reflect.TypeOf((*MockGofemartRepo)(nil).RecordAudit), entry)
+}
+
+// RecordLoginFailure mocks base method.
+func (m *MockGofemartRepo) RecordLoginFailure(scope, key string, window time.Duration) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordLoginFailure", scope, key, window)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecordLoginFailure indicates an expected call of RecordLoginFailure.
+func (mr *MockGofemartRepoMockRecorder) RecordLoginFailure(scope, key, window interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordLoginFailure", reflect.TypeOf((*MockGofemartRepo)(nil).RecordLoginFailure), scope, key, window)
+}
+
+// RedeemPromoCode mocks base method.
+func (m *MockGofemartRepo) RedeemPromoCode(userID int, code string, now time.Time) (models.PromoRedemption, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RedeemPromoCode", userID, code, now)
+	ret0, _ := ret[0].(models.PromoRedemption)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RedeemPromoCode indicates an expected call of RedeemPromoCode.
+func (mr *MockGofemartRepoMockRecorder) RedeemPromoCode(userID, code, now interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RedeemPromoCode", reflect.TypeOf((*MockGofemartRepo)(nil).RedeemPromoCode), userID, code, now)
+}
+
+// ReferralCode mocks base method.
+func (m *MockGofemartRepo) ReferralCode(userID int) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReferralCode", userID)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReferralCode indicates an expected call of ReferralCode.
+func (mr *MockGofemartRepoMockRecorder) ReferralCode(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReferralCode", reflect.TypeOf((*MockGofemartRepo)(nil).ReferralCode), userID)
+}
+
+// Referrals mocks base method.
+func (m *MockGofemartRepo) Referrals(userID int) ([]models.Referral, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Referrals", userID)
+	ret0, _ := ret[0].([]models.Referral)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Referrals indicates an expected call of Referrals.
+func (mr *MockGofemartRepoMockRecorder) Referrals(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Referrals", reflect.TypeOf((*MockGofemartRepo)(nil).Referrals), userID)
+}
+
+// RefundWithdrawal mocks base method.
+func (m *MockGofemartRepo) RefundWithdrawal(refund models.WithdrawalRefund, entry *models.AuditEntry) (models.WithdrawalRefund, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefundWithdrawal", refund, entry)
+	ret0, _ := ret[0].(models.WithdrawalRefund)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefundWithdrawal indicates an expected call of RefundWithdrawal.
+func (mr *MockGofemartRepoMockRecorder) RefundWithdrawal(refund, entry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefundWithdrawal", reflect.TypeOf((*MockGofemartRepo)(nil).RefundWithdrawal), refund, entry)
+}
+
+// ReleaseIdempotencyKey mocks base method.
+func (m *MockGofemartRepo) ReleaseIdempotencyKey(userID int, scope, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseIdempotencyKey", userID, scope, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseIdempotencyKey indicates an expected call of ReleaseIdempotencyKey.
+func (mr *MockGofemartRepoMockRecorder) ReleaseIdempotencyKey(userID, scope, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseIdempotencyKey", reflect.TypeOf((*MockGofemartRepo)(nil).ReleaseIdempotencyKey), userID, scope, key)
+}
+
+// RequeueOrder mocks base method.
+func (m *MockGofemartRepo) RequeueOrder(number string, entry models.AuditEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequeueOrder", number, entry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RequeueOrder indicates an expected call of RequeueOrder.
+func (mr *MockGofemartRepoMockRecorder) RequeueOrder(number, entry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequeueOrder", reflect.TypeOf((*MockGofemartRepo)(nil).RequeueOrder), number, entry)
+}
+
+// ReserveIdempotencyKey mocks base method.
+func (m *MockGofemartRepo) ReserveIdempotencyKey(k models.IdempotencyKey) (*models.IdempotencyKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReserveIdempotencyKey", k)
+	ret0, _ := ret[0].(*models.IdempotencyKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReserveIdempotencyKey indicates an expected call of ReserveIdempotencyKey.
+func (mr *MockGofemartRepoMockRecorder) ReserveIdempotencyKey(k interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReserveIdempotencyKey", reflect.TypeOf((*MockGofemartRepo)(nil).ReserveIdempotencyKey), k)
+}
+
+// ResetLoginAttempts mocks base method.
+func (m *MockGofemartRepo) ResetLoginAttempts(scope, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetLoginAttempts", scope, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetLoginAttempts indicates an expected call of ResetLoginAttempts.
+func (mr *MockGofemartRepoMockRecorder) ResetLoginAttempts(scope, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetLoginAttempts", reflect.TypeOf((*MockGofemartRepo)(nil).ResetLoginAttempts), scope, key)
+}
+
+// ReverseLedgerEntry mocks base method.
+func (m *MockGofemartRepo) ReverseLedgerEntry(entryID int64, amount money.Amount, note string, entry models.AuditEntry) (models.LedgerEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReverseLedgerEntry", entryID, amount, note, entry)
+	ret0, _ := ret[0].(models.LedgerEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReverseLedgerEntry indicates an expected call of ReverseLedgerEntry.
+func (mr *MockGofemartRepoMockRecorder) ReverseLedgerEntry(entryID, amount, note, entry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReverseLedgerEntry", reflect.TypeOf((*MockGofemartRepo)(nil).ReverseLedgerEntry), entryID, amount, note, entry)
+}
+
+// RevokeAPIKey mocks base method.
+func (m *MockGofemartRepo) RevokeAPIKey(userID, keyID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAPIKey", userID, keyID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAPIKey indicates an expected call of RevokeAPIKey.
+func (mr *MockGofemartRepoMockRecorder) RevokeAPIKey(userID, keyID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKey", reflect.TypeOf((*MockGofemartRepo)(nil).RevokeAPIKey), userID, keyID)
+}
+
+// RevokeAllSessions mocks base method.
+func (m *MockGofemartRepo) RevokeAllSessions(userID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAllSessions", userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeAllSessions indicates an expected call of RevokeAllSessions.
+func (mr *MockGofemartRepoMockRecorder) RevokeAllSessions(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAllSessions", reflect.TypeOf((*MockGofemartRepo)(nil).RevokeAllSessions), userID)
+}
+
+// RevokeRefreshToken mocks base method.
+func (m *MockGofemartRepo) RevokeRefreshToken(tokenHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeRefreshToken", tokenHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeRefreshToken indicates an expected call of RevokeRefreshToken.
+func (mr *MockGofemartRepoMockRecorder) RevokeRefreshToken(tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeRefreshToken", reflect.TypeOf((*MockGofemartRepo)(nil).RevokeRefreshToken), tokenHash)
+}
+
+// RevokeSession mocks base method.
+func (m *MockGofemartRepo) RevokeSession(userID, sessionID int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", userID, sessionID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockGofemartRepoMockRecorder) RevokeSession(userID, sessionID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockGofemartRepo)(nil).RevokeSession), userID, sessionID)
+}
+
+// RotateRefreshToken mocks base method.
+func (m *MockGofemartRepo) RotateRefreshToken(oldHash, newHash string, expiresAt time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateRefreshToken", oldHash, newHash, expiresAt)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateRefreshToken indicates an expected call of RotateRefreshToken.
+func (mr *MockGofemartRepoMockRecorder) RotateRefreshToken(oldHash, newHash, expiresAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateRefreshToken", reflect.TypeOf((*MockGofemartRepo)(nil).RotateRefreshToken), oldHash, newHash, expiresAt)
+}
+
+// SaveIdempotentResponse mocks base method.
+func (m *MockGofemartRepo) SaveIdempotentResponse(userID int, scope, key string, resp models.IdempotentResponse) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveIdempotentResponse", userID, scope, key, resp)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveIdempotentResponse indicates an expected call of SaveIdempotentResponse.
+func (mr *MockGofemartRepoMockRecorder) SaveIdempotentResponse(userID, scope, key, resp interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveIdempotentResponse", reflect.TypeOf((*MockGofemartRepo)(nil).SaveIdempotentResponse), userID, scope, key, resp)
+}
+
+// SaveTOTPSecret mocks base method.
+func (m *MockGofemartRepo) SaveTOTPSecret(userID int, secret string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveTOTPSecret", userID, secret)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveTOTPSecret indicates an expected call of SaveTOTPSecret.
+func (mr *MockGofemartRepoMockRecorder) SaveTOTPSecret(userID, secret interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTOTPSecret", reflect.TypeOf((*MockGofemartRepo)(nil).SaveTOTPSecret), userID, secret)
+}
+
+// SaveUserTier mocks base method.
+func (m *MockGofemartRepo) SaveUserTier(t models.UserTier) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveUserTier", t)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveUserTier indicates an expected call of SaveUserTier.
+func (mr *MockGofemartRepoMockRecorder) SaveUserTier(t interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveUserTier", reflect.TypeOf((*MockGofemartRepo)(nil).SaveUserTier), t)
+}
+
+// SearchUsers mocks base method.
+func (m *MockGofemartRepo) SearchUsers(query string, limit int) ([]models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchUsers", query, limit)
+	ret0, _ := ret[0].([]models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchUsers indicates an expected call of SearchUsers.
+func (mr *MockGofemartRepoMockRecorder) SearchUsers(query, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchUsers", reflect.TypeOf((*MockGofemartRepo)(nil).SearchUsers), query, limit)
+}
+
+// SetUserBlocked mocks base method.
+func (m *MockGofemartRepo) SetUserBlocked(userID int, blocked bool, entry models.AuditEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserBlocked", userID, blocked, entry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserBlocked indicates an expected call of SetUserBlocked.
+func (mr *MockGofemartRepoMockRecorder) SetUserBlocked(userID, blocked, entry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserBlocked", reflect.TypeOf((*MockGofemartRepo)(nil).SetUserBlocked), userID, blocked, entry)
+}
+
+// SetUserRole mocks base method.
+func (m *MockGofemartRepo) SetUserRole(userID int, role string, entry models.AuditEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserRole", userID, role, entry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserRole indicates an expected call of SetUserRole.
+func (mr *MockGofemartRepoMockRecorder) SetUserRole(userID, role, entry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserRole", reflect.TypeOf((*MockGofemartRepo)(nil).SetUserRole), userID, role, entry)
+}
+
+// StatementLines mocks base method.
+func (m *MockGofemartRepo) StatementLines(ctx context.Context, userID int, from, to time.Time, fn func(models.StatementLine) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StatementLines", ctx, userID, from, to, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StatementLines indicates an expected call of StatementLines.
+func (mr *MockGofemartRepoMockRecorder) StatementLines(ctx, userID, from, to, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StatementLines", reflect.TypeOf((*MockGofemartRepo)(nil).StatementLines), ctx, userID, from, to, fn)
+}
+
+// StatementOpeningBalance mocks base method.
+func (m *MockGofemartRepo) StatementOpeningBalance(userID int, before time.Time) (money.Amount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StatementOpeningBalance", userID, before)
+	ret0, _ := ret[0].(money.Amount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StatementOpeningBalance indicates an expected call of StatementOpeningBalance.
+func (mr *MockGofemartRepoMockRecorder) StatementOpeningBalance(userID, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StatementOpeningBalance", reflect.TypeOf((*MockGofemartRepo)(nil).StatementOpeningBalance), userID, before)
+}
+
+// TierEarned mocks base method.
+func (m *MockGofemartRepo) TierEarned(userID int, since time.Time) (money.Amount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TierEarned", userID, since)
+	ret0, _ := ret[0].(money.Amount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TierEarned indicates an expected call of TierEarned.
+func (mr *MockGofemartRepoMockRecorder) TierEarned(userID, since interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TierEarned", reflect.TypeOf((*MockGofemartRepo)(nil).TierEarned), userID, since)
+}
+
+// TiersDue mocks base method.
+func (m *MockGofemartRepo) TiersDue(now time.Time, limit int) ([]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TiersDue", now, limit)
+	ret0, _ := ret[0].([]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TiersDue indicates an expected call of TiersDue.
+func (mr *MockGofemartRepoMockRecorder) TiersDue(now, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TiersDue", reflect.TypeOf((*MockGofemartRepo)(nil).TiersDue), now, limit)
+}
+
+// TouchSession mocks base method.
+func (m *MockGofemartRepo) TouchSession(userID int, tokenHash string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchSession", userID, tokenHash)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TouchSession indicates an expected call of TouchSession.
+func (mr *MockGofemartRepoMockRecorder) TouchSession(userID, tokenHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchSession", reflect.TypeOf((*MockGofemartRepo)(nil).TouchSession), userID, tokenHash)
+}
+
+// Transfer mocks base method.
+func (m *MockGofemartRepo) Transfer(t models.Transfer, limits models.TransferLimits) (models.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transfer", t, limits)
+	ret0, _ := ret[0].(models.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Transfer indicates an expected call of Transfer.
+func (mr *MockGofemartRepoMockRecorder) Transfer(t, limits interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfer", reflect.TypeOf((*MockGofemartRepo)(nil).Transfer), t, limits)
+}
+
+// Transfers mocks base method.
+func (m *MockGofemartRepo) Transfers(userID int) ([]models.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transfers", userID)
+	ret0, _ := ret[0].([]models.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Transfers indicates an expected call of Transfers.
+func (mr *MockGofemartRepoMockRecorder) Transfers(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfers", reflect.TypeOf((*MockGofemartRepo)(nil).Transfers), userID)
+}
+
+// UpdatePasswordHash mocks base method.
+func (m *MockGofemartRepo) UpdatePasswordHash(userID int, passwordHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePasswordHash", userID, passwordHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePasswordHash indicates an expected call of UpdatePasswordHash.
+func (mr *MockGofemartRepoMockRecorder) UpdatePasswordHash(userID, passwordHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePasswordHash", reflect.TypeOf((*MockGofemartRepo)(nil).UpdatePasswordHash), userID, passwordHash)
+}
+
+// UseAPIKey mocks base method.
+func (m *MockGofemartRepo) UseAPIKey(keyHash string) (*models.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseAPIKey", keyHash)
+	ret0, _ := ret[0].(*models.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseAPIKey indicates an expected call of UseAPIKey.
+func (mr *MockGofemartRepoMockRecorder) UseAPIKey(keyHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseAPIKey", reflect.TypeOf((*MockGofemartRepo)(nil).UseAPIKey), keyHash)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockGofemartRepo) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", userID, codeHash)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockGofemartRepoMockRecorder) UseRecoveryCode(userID, codeHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockGofemartRepo)(nil).UseRecoveryCode), userID, codeHash)
+}
+
+// UseTOTPCounter mocks base method.
+func (m *MockGofemartRepo) UseTOTPCounter(userID int, counter int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseTOTPCounter", userID, counter)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseTOTPCounter indicates an expected call of UseTOTPCounter.
+func (mr *MockGofemartRepoMockRecorder) UseTOTPCounter(userID, counter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseTOTPCounter", reflect.TypeOf((*MockGofemartRepo)(nil).UseTOTPCounter), userID, counter)
+}
+
+// UserTier mocks base method.
+func (m *MockGofemartRepo) UserTier(userID int) (*models.UserTier, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserTier", userID)
+	ret0, _ := ret[0].(*models.UserTier)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UserTier indicates an expected call of UserTier.
+func (mr *MockGofemartRepoMockRecorder) UserTier(userID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserTier", reflect.TypeOf((*MockGofemartRepo)(nil).UserTier), userID)
+}
+
+// VoidHold mocks base method.
+func (m *MockGofemartRepo) VoidHold(userID, holdID int) (models.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VoidHold", userID, holdID)
+	ret0, _ := ret[0].(models.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VoidHold indicates an expected call of VoidHold.
+func (mr *MockGofemartRepoMockRecorder) VoidHold(userID, holdID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByLoginAndPassword", reflect.TypeOf((*MockGofemartRepo)(nil).GetUserByLoginAndPassword), login, password)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VoidHold", reflect.TypeOf((*MockGofemartRepo)(nil).VoidHold), userID, holdID)
 }
 
 // Withdraw mocks base method.
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/pkg/oidc"
+)
+
+const (
+	// oidcStateTTL - сколько ждём возврата пользователя от провайдера
+	oidcStateTTL = 10 * time.Minute
+	// oidcLoginMaxLen - длина логина, собранного из данных провайдера
+	oidcLoginMaxLen = 64
+)
+
+// WithOIDCProvider включает вход через провайдера OpenID Connect
+func WithOIDCProvider(p *oidc.Provider) Option {
+	return func(s *GofemartService) {
+		s.oidc = p
+	}
+}
+
+func (s *GofemartService) OIDCEnabled() bool {
+	return s.oidc != nil
+}
+
+// StartOIDCLogin начинает вход через провайдера: сохраняет PKCE code_verifier и nonce,
+// возвращает адрес страницы входа провайдера и state, который хендлер привязывает к браузеру
+func (s *GofemartService) StartOIDCLogin() (authURL, state string, err error) {
+	if s.oidc == nil {
+		return "", "", ErrOIDCNotConfigured
+	}
+
+	state, err = oidc.NewState()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := oidc.NewState()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		return "", "", err
+	}
+
+	loginState := models.OIDCLoginState{CodeVerifier: verifier, Nonce: nonce}
+	if err := s.repo.CreateOIDCState(HashToken(state), loginState, time.Now().Add(oidcStateTTL)); err != nil {
+		return "", "", err
+	}
+
+	return s.oidc.AuthCodeURL(state, nonce, oidc.S256Challenge(verifier)), state, nil
+}
+
+// FinishOIDCLogin обменивает код на ID-токен и находит привязанного пользователя.
+// При первом входе пользователь заводится автоматически
+func (s *GofemartService) FinishOIDCLogin(ctx context.Context, state, code string) (*models.User, error) {
+	if s.oidc == nil {
+		return nil, ErrOIDCNotConfigured
+	}
+	if state == "" || code == "" {
+		return nil, ErrInvalidOIDCState
+	}
+
+	loginState, err := s.repo.ConsumeOIDCState(HashToken(state))
+	if err != nil {
+		return nil, err
+	}
+	if loginState == nil {
+		return nil, ErrInvalidOIDCState
+	}
+
+	rawIDToken, err := s.oidc.Exchange(ctx, code, loginState.CodeVerifier)
+	if err != nil {
+		castomLogger.Infof("oidc code exchange failed: %v", err)
+		return nil, ErrOIDCLoginFailed
+	}
+	claims, err := s.oidc.Verify(ctx, rawIDToken, loginState.Nonce, time.Now())
+	if err != nil {
+		castomLogger.Infof("oidc id token rejected: %v", err)
+		return nil, ErrOIDCLoginFailed
+	}
+
+	user, err := s.repo.GetUserByIdentity(claims.Issuer, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		user, err = s.provisionOIDCUser(claims)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if user.Blocked() {
+		return nil, ErrUserBlocked
+	}
+	return user, nil
+}
+
+// provisionOIDCUser заводит пользователя при первом входе. С существующим локальным
+// логином учётная запись не склеивается: иначе чужой аккаунт у провайдера с тем же
+// именем получил бы доступ к баллам
+func (s *GofemartService) provisionOIDCUser(claims oidc.Claims) (*models.User, error) {
+	identity := models.UserIdentity{
+		Issuer:  claims.Issuer,
+		Subject: claims.Subject,
+		Email:   claims.Email,
+	}
+
+	var lastErr error
+	for _, login := range oidcLoginCandidates(claims) {
+		user, err := s.repo.CreateUserWithIdentity(login, identity)
+		if err == nil {
+			return user, nil
+		}
+		if !errors.Is(err, ErrLoginAlreadyExists) {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// oidcLoginCandidates - варианты логина: имя у провайдера, часть почты до @,
+// затем они же с хвостом из subject, чтобы не упереться в занятый логин
+func oidcLoginCandidates(claims oidc.Claims) []string {
+	base := claims.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+	base = strings.TrimSpace(base)
+	if base == "" {
+		base = "user"
+	}
+
+	suffix := HashToken(claims.Issuer + " " + claims.Subject)[:8]
+	return []string{
+		truncateLogin(base, 0),
+		truncateLogin(base, len(suffix)+1) + "-" + suffix,
+		"oidc-" + HashToken(claims.Issuer + " " + claims.Subject)[:16],
+	}
+}
+
+func truncateLogin(login string, reserve int) string {
+	runes := []rune(login)
+	if max := oidcLoginMaxLen - reserve; len(runes) > max {
+		runes = runes[:max]
+	}
+	return string(runes)
+}
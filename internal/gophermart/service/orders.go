@@ -0,0 +1,185 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+)
+
+// размер страницы заказов
+const (
+	DefaultOrdersLimit = 50
+	MaxOrdersLimit     = 200
+)
+
+// OrdersPage - страница заказов с фильтром по статусу и времени загрузки. Курсор помнит порядок
+// сортировки и фильтры: не переданные в запросе берутся из курсора, другие - ErrInvalidCursor,
+// поэтому следующая страница не может перепутать направление или выборку
+func (s *GofemartService) OrdersPage(userID int, req models.OrdersRequest) (models.OrdersPage, error) {
+	if userID <= 0 {
+		return models.OrdersPage{}, fmt.Errorf("invalid user ID")
+	}
+	f := models.OrdersFilter{
+		UserID:   userID,
+		Statuses: normalizeStatuses(req.Statuses),
+		From:     req.From,
+		To:       req.To,
+		Limit:    req.Limit,
+	}
+	switch {
+	case f.Limit == 0:
+		f.Limit = DefaultOrdersLimit
+	case f.Limit < 0 || f.Limit > MaxOrdersLimit:
+		return models.OrdersPage{}, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidOrdersFilter, MaxOrdersLimit)
+	}
+	for _, status := range f.Statuses {
+		if !slices.Contains(models.OrderStatuses, status) {
+			return models.OrdersPage{}, fmt.Errorf("%w: unknown status %q", ErrInvalidOrdersFilter, status)
+		}
+	}
+	if f.From != nil && f.To != nil && !f.To.After(*f.From) {
+		return models.OrdersPage{}, fmt.Errorf("%w: to must be after from", ErrInvalidOrdersFilter)
+	}
+	sort := req.Sort
+	switch sort {
+	case "":
+	case models.OrdersSortAsc, models.OrdersSortDesc:
+	default:
+		return models.OrdersPage{}, fmt.Errorf("%w: sort must be asc or desc", ErrInvalidOrdersFilter)
+	}
+
+	if req.Cursor != "" {
+		c, err := decodeOrdersCursor(req.Cursor)
+		if err != nil {
+			return models.OrdersPage{}, err
+		}
+		if sort != "" && sort != c.sort ||
+			f.Statuses != nil && !slices.Equal(f.Statuses, c.statuses) ||
+			f.From != nil && !sameTime(f.From, c.from) ||
+			f.To != nil && !sameTime(f.To, c.to) {
+			return models.OrdersPage{}, ErrInvalidCursor
+		}
+		sort, f.Statuses, f.From, f.To = c.sort, c.statuses, c.from, c.to
+		f.AfterUploadedAt, f.AfterUID = &c.uploadedAt, c.uid
+	}
+	if sort == "" {
+		sort = models.OrdersSortDesc
+	}
+	f.Asc = sort == models.OrdersSortAsc
+
+	// лишняя запись показывает, есть ли следующая страница
+	limit := f.Limit
+	f.Limit++
+	orders, err := s.repo.OrdersPage(f)
+	if err != nil {
+		return models.OrdersPage{}, err
+	}
+
+	page := models.OrdersPage{Orders: orders[:min(len(orders), limit)]}
+	if page.Orders == nil {
+		page.Orders = []models.Order{}
+	}
+	if len(orders) > limit {
+		last := orders[limit-1]
+		page.NextCursor = encodeOrdersCursor(ordersCursor{
+			sort:       sort,
+			uploadedAt: last.UploadedAt,
+			uid:        last.UID,
+			statuses:   f.Statuses,
+			from:       f.From,
+			to:         f.To,
+		})
+	}
+	return page, nil
+}
+
+// ordersCursor - ключ последнего заказа страницы и выборка, к которой он относится
+type ordersCursor struct {
+	sort       string
+	uploadedAt time.Time
+	uid        int
+	statuses   []string
+	from       *time.Time
+	to         *time.Time
+}
+
+// encodeOrdersCursor - "sort|uploaded_at|uid|статусы через запятую|from|to", пустое поле - без фильтра
+func encodeOrdersCursor(c ordersCursor) string {
+	return encodeCursor(strings.Join([]string{
+		c.sort,
+		c.uploadedAt.UTC().Format(time.RFC3339Nano),
+		strconv.Itoa(c.uid),
+		strings.Join(c.statuses, ","),
+		formatCursorTime(c.from),
+		formatCursorTime(c.to),
+	}, "|"))
+}
+
+func decodeOrdersCursor(cursor string) (ordersCursor, error) {
+	value, err := decodeCursor(cursor)
+	if err != nil {
+		return ordersCursor{}, err
+	}
+	parts := strings.Split(value, "|")
+	if len(parts) != 6 || parts[0] != models.OrdersSortAsc && parts[0] != models.OrdersSortDesc {
+		return ordersCursor{}, ErrInvalidCursor
+	}
+	c := ordersCursor{sort: parts[0]}
+	if c.uploadedAt, err = time.Parse(time.RFC3339Nano, parts[1]); err != nil {
+		return ordersCursor{}, ErrInvalidCursor
+	}
+	if c.uid, err = strconv.Atoi(parts[2]); err != nil || c.uid <= 0 {
+		return ordersCursor{}, ErrInvalidCursor
+	}
+	if parts[3] != "" {
+		c.statuses = strings.Split(parts[3], ",")
+		for _, status := range c.statuses {
+			if !slices.Contains(models.OrderStatuses, status) {
+				return ordersCursor{}, ErrInvalidCursor
+			}
+		}
+	}
+	if c.from, err = parseCursorTime(parts[4]); err != nil {
+		return ordersCursor{}, ErrInvalidCursor
+	}
+	if c.to, err = parseCursorTime(parts[5]); err != nil {
+		return ordersCursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// normalizeStatuses - статусы по алфавиту без повторов, чтобы фильтр из курсора сравнивался с запросом
+func normalizeStatuses(statuses []string) []string {
+	if len(statuses) == 0 {
+		return nil
+	}
+	statuses = slices.Clone(statuses)
+	slices.Sort(statuses)
+	return slices.Compact(statuses)
+}
+
+func formatCursorTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func parseCursorTime(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func sameTime(a, b *time.Time) bool {
+	return a == nil && b == nil || a != nil && b != nil && a.Equal(*b)
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"fmt"
+
+	"go-musthave-diploma-tpl/pkg/password"
+)
+
+// PasswordPolicyError - пароль не прошёл политику, Violations - нарушенные правила
+type PasswordPolicyError struct {
+	Violations []password.Violation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return fmt.Sprintf("%s: %d rule(s) failed", ErrPasswordPolicy, len(e.Violations))
+}
+
+func (e *PasswordPolicyError) Unwrap() error {
+	return ErrPasswordPolicy
+}
+
+func (s *GofemartService) checkPasswordPolicy(login, plainPassword string) error {
+	if violations := s.passwordPolicy.Check(login, plainPassword); len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// ChangePassword меняет пароль после проверки текущего. Все остальные сессии
+// и refresh-токены отзываются, текущая сессия (currentSessionID) остаётся
+func (s *GofemartService) ChangePassword(userID, currentSessionID int, currentPassword, newPassword string) error {
+	if currentPassword == "" || newPassword == "" {
+		return ErrLoginAndPasswordRequired
+	}
+
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("user not found id=%d", userID)
+	}
+
+	if ok, _ := s.hasher.Verify(user.PasswordHash, currentPassword); !ok {
+		return ErrInvalidCurrentPassword
+	}
+
+	if err := s.checkPasswordPolicy(user.Login, newPassword); err != nil {
+		return err
+	}
+
+	hash, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	return s.repo.ChangePassword(userID, hash, currentSessionID)
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/pkg/money"
+)
+
+// promoCodePattern - коды хранятся в верхнем регистре: их диктуют и печатают на листовках
+var promoCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,64}$`)
+
+// maxPromoMultiplier - верхняя граница множителя, защита от опечатки в 150 вместо 1.5
+var maxPromoMultiplier = money.FromUnits(10)
+
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// AdminCreatePromoCode заводит промокод маркетинговой кампании
+func (s *GofemartService) AdminCreatePromoCode(actorID int, req models.CreatePromoCodeRequest) (models.PromoCode, error) {
+	p := models.PromoCode{
+		Code:           normalizePromoCode(req.Code),
+		Kind:           req.Kind,
+		Orders:         req.Orders,
+		StartsAt:       time.Now(),
+		EndsAt:         req.EndsAt,
+		MaxRedemptions: req.MaxRedemptions,
+		PerUserLimit:   req.PerUserLimit,
+	}
+	if req.StartsAt != nil {
+		p.StartsAt = *req.StartsAt
+	}
+	if p.PerUserLimit == 0 {
+		p.PerUserLimit = 1
+	}
+
+	if !promoCodePattern.MatchString(p.Code) {
+		return models.PromoCode{}, fmt.Errorf("%w: code must be 3-64 letters, digits, '-' or '_'", ErrInvalidPromoCode)
+	}
+	switch p.Kind {
+	case models.PromoKindPoints:
+		if req.Points <= 0 {
+			return models.PromoCode{}, fmt.Errorf("%w: points must be positive", ErrInvalidPromoCode)
+		}
+		p.Points = req.Points
+		p.Multiplier = money.FromUnits(1)
+		p.Orders = 0
+	case models.PromoKindMultiplier:
+		if req.Multiplier <= money.FromUnits(1) || req.Multiplier > maxPromoMultiplier {
+			return models.PromoCode{}, fmt.Errorf("%w: multiplier must be above 1 and at most %s", ErrInvalidPromoCode, maxPromoMultiplier)
+		}
+		if req.Orders <= 0 {
+			return models.PromoCode{}, fmt.Errorf("%w: orders must be positive", ErrInvalidPromoCode)
+		}
+		p.Multiplier = req.Multiplier
+	default:
+		return models.PromoCode{}, fmt.Errorf("%w: kind must be %s or %s", ErrInvalidPromoCode, models.PromoKindPoints, models.PromoKindMultiplier)
+	}
+	if p.EndsAt != nil && !p.EndsAt.After(p.StartsAt) {
+		return models.PromoCode{}, fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidPromoCode)
+	}
+	if p.MaxRedemptions < 0 || p.PerUserLimit < 0 {
+		return models.PromoCode{}, fmt.Errorf("%w: limits must not be negative", ErrInvalidPromoCode)
+	}
+
+	return s.repo.CreatePromoCode(p, models.AuditEntry{ActorID: actorID, Action: models.AuditCreatePromo})
+}
+
+func (s *GofemartService) AdminPromoCodes() ([]models.PromoCode, error) {
+	return s.repo.PromoCodes()
+}
+
+// AdminDisablePromoCode закрывает кампанию досрочно
+func (s *GofemartService) AdminDisablePromoCode(actorID int, code string) (models.PromoCode, error) {
+	return s.repo.DisablePromoCode(normalizePromoCode(code), models.AuditEntry{ActorID: actorID, Action: models.AuditDisablePromo})
+}
+
+// RedeemPromoCode гасит промокод пользователя
+func (s *GofemartService) RedeemPromoCode(userID int, code string) (models.PromoRedemption, error) {
+	code = normalizePromoCode(code)
+	if code == "" {
+		return models.PromoRedemption{}, ErrPromoCodeNotFound
+	}
+	return s.repo.RedeemPromoCode(userID, code, time.Now())
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+)
+
+// DefaultReferralLimits - ограничения приглашений одного пользователя
+func DefaultReferralLimits() models.ReferralLimits {
+	return models.ReferralLimits{
+		DailyCount:    10,
+		AddressWindow: 30 * 24 * time.Hour,
+	}
+}
+
+// WithReferralLimits задаёт ограничения приглашений, ноль снимает ограничение
+func WithReferralLimits(limits models.ReferralLimits) Option {
+	return func(s *GofemartService) {
+		if limits.DailyCount >= 0 && limits.AddressWindow >= 0 {
+			s.referralLimits = limits
+		}
+	}
+}
+
+// normalizeReferralCode - коды выдаются в верхнем регистре, пользователь может ввести их как угодно
+func normalizeReferralCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Referrals - реферальный код пользователя и приглашённые им, логины скрыты маской
+func (s *GofemartService) Referrals(userID int) (models.ReferralsResponse, error) {
+	code, err := s.repo.ReferralCode(userID)
+	if err != nil {
+		return models.ReferralsResponse{}, err
+	}
+	referrals, err := s.repo.Referrals(userID)
+	if err != nil {
+		return models.ReferralsResponse{}, err
+	}
+
+	for i := range referrals {
+		referrals[i].Login = MaskLogin(referrals[i].Login)
+	}
+	if referrals == nil {
+		referrals = []models.Referral{}
+	}
+	return models.ReferralsResponse{Code: code, Referrals: referrals}, nil
+}
+
+// MaskLogin оставляет первый и последний символ логина: "alice" - "a***e".
+// Логин из одного-двух символов показывается только первой буквой
+func MaskLogin(login string) string {
+	runes := []rune(login)
+	switch n := len(runes); {
+	case n == 0:
+		return ""
+	case n <= 2:
+		return string(runes[0]) + "***"
+	default:
+		return string(runes[0]) + "***" + string(runes[n-1])
+	}
+}
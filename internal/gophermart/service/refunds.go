@@ -0,0 +1,36 @@
+package service
+
+import (
+	"strings"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+)
+
+// AdminRefundWithdrawal - возврат баллов по списанию сотрудником поддержки или администратором
+func (s *GofemartService) AdminRefundWithdrawal(actorID int, order string, req models.RefundRequest) (models.WithdrawalRefund, error) {
+	if req.Amount < 0 {
+		return models.WithdrawalRefund{}, ErrInvalidAmount
+	}
+	return s.repo.RefundWithdrawal(models.WithdrawalRefund{
+		Order:   order,
+		Amount:  req.Amount,
+		Reason:  strings.TrimSpace(req.Reason),
+		Source:  models.RefundSourceStaff,
+		ActorID: &actorID,
+	}, &models.AuditEntry{ActorID: actorID, Action: models.AuditRefund})
+}
+
+// PartnerRefundWithdrawal - возврат по отменённому заказу сервером партнёра: только списаний владельца ключа
+func (s *GofemartService) PartnerRefundWithdrawal(userID, apiKeyID int, order string, req models.RefundRequest) (models.WithdrawalRefund, error) {
+	if req.Amount < 0 {
+		return models.WithdrawalRefund{}, ErrInvalidAmount
+	}
+	return s.repo.RefundWithdrawal(models.WithdrawalRefund{
+		UserID:   userID,
+		Order:    order,
+		Amount:   req.Amount,
+		Reason:   strings.TrimSpace(req.Reason),
+		Source:   models.RefundSourcePartner,
+		APIKeyID: &apiKeyID,
+	}, nil)
+}
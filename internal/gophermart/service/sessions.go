@@ -0,0 +1,73 @@
+package service
+
+import (
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+)
+
+// DefaultSessionTTL - срок жизни сессии (и куки)
+const DefaultSessionTTL = 8 * time.Hour
+
+// WithSessionTTL задаёт срок жизни серверной сессии
+func WithSessionTTL(ttl time.Duration) Option {
+	return func(s *GofemartService) {
+		if ttl > 0 {
+			s.sessionTTL = ttl
+		}
+	}
+}
+
+// CreateSession заводит серверную сессию и возвращает её секретный идентификатор для куки.
+// Адрес клиента запоминается для проверки регистраций по приглашению
+func (s *GofemartService) CreateSession(userID int, userAgent, ip string) (string, time.Time, error) {
+	token, hash, err := newOpaqueToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expiresAt := time.Now().Add(s.sessionTTL)
+	if err := s.repo.CreateSession(userID, hash, userAgent, ip, expiresAt); err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
+// ValidateSession проверяет, что сессия из куки не отозвана и не истекла, возвращает ID сессии
+func (s *GofemartService) ValidateSession(userID int, token string) (int, error) {
+	if token == "" {
+		return 0, ErrSessionNotActive
+	}
+
+	sessionID, err := s.repo.TouchSession(userID, HashToken(token))
+	if err != nil {
+		return 0, err
+	}
+	if sessionID == 0 {
+		return 0, ErrSessionNotActive
+	}
+	return sessionID, nil
+}
+
+func (s *GofemartService) Logout(userID, sessionID int) error {
+	return s.repo.RevokeSession(userID, sessionID)
+}
+
+// LogoutAll - выход на всех устройствах, включая refresh-токены
+func (s *GofemartService) LogoutAll(userID int) error {
+	return s.repo.RevokeAllSessions(userID)
+}
+
+// Sessions - активные сессии пользователя, текущая помечается флагом Current
+func (s *GofemartService) Sessions(userID, currentSessionID int) ([]models.Session, error) {
+	sessions, err := s.repo.ActiveSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
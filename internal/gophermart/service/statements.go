@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+)
+
+// StatementWriter - формат выписки. Строки приходят по одной в порядке времени
+type StatementWriter interface {
+	Begin(models.StatementHeader) error
+	Line(models.StatementLine) error
+	End(models.StatementSummary) error
+}
+
+// WriteStatement - выписка за [from, to) по журналу баллов: остаток на начало, все проводки
+// с остатком после каждой, итоги и остаток на конец, равный балансу счёта. Строки не копятся в памяти
+func (s *GofemartService) WriteStatement(ctx context.Context, userID int, from, to time.Time, out StatementWriter) error {
+	if !to.After(from) {
+		return fmt.Errorf("%w: to must be after from", ErrInvalidStatementPeriod)
+	}
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	opening, err := s.repo.StatementOpeningBalance(userID, from)
+	if err != nil {
+		return err
+	}
+	if err := out.Begin(models.StatementHeader{Login: user.Login, From: from, To: to, Opening: opening}); err != nil {
+		return err
+	}
+
+	summary := models.StatementSummary{Closing: opening}
+	err = s.repo.StatementLines(ctx, userID, from, to, func(line models.StatementLine) error {
+		switch line.Type {
+		case models.StatementLineAccrual:
+			summary.Accrued += line.Amount
+		case models.StatementLineWithdrawal:
+			summary.Withdrawn -= line.Amount
+		case models.StatementLineRefund:
+			summary.Refunded += line.Amount
+		default:
+			summary.Other += line.Amount
+		}
+		summary.Closing += line.Amount
+		line.Balance = summary.Closing
+		return out.Line(line)
+	})
+	if err != nil {
+		return err
+	}
+	return out.End(summary)
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	serviceTest "go-musthave-diploma-tpl/internal/gophermart/service"
+	mocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGofemartService_AdminUserOrders_AuditFirst(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+
+	// без записи в журнал данные не отдаются
+	mockRepo.EXPECT().RecordAudit(gomock.Any()).Return(errors.New("db down"))
+
+	orders, err := service.AdminUserOrders(1, 2)
+	assert.Error(t, err)
+	assert.Nil(t, orders)
+
+	gomock.InOrder(
+		mockRepo.EXPECT().RecordAudit(gomock.Any()).DoAndReturn(func(e models.AuditEntry) error {
+			assert.Equal(t, models.AuditViewOrders, e.Action)
+			assert.Equal(t, 1, e.ActorID)
+			assert.Equal(t, 2, *e.TargetUserID)
+			return nil
+		}),
+		mockRepo.EXPECT().GetOrders(2).Return([]models.Order{{Number: "12345678903"}}, nil),
+	)
+
+	orders, err = service.AdminUserOrders(1, 2)
+	assert.NoError(t, err)
+	assert.Len(t, orders, 1)
+}
+
+func TestGofemartService_AdminSetRole(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+
+	assert.ErrorIs(t, service.AdminSetRole(1, 2, "root"), serviceTest.ErrInvalidRole)
+	assert.ErrorIs(t, service.AdminSetRole(1, 1, models.RoleUser), serviceTest.ErrSelfAdminAction)
+	assert.ErrorIs(t, service.AdminSetBlocked(1, 1, true), serviceTest.ErrSelfAdminAction)
+
+	mockRepo.EXPECT().SetUserRole(2, models.RoleSupport, gomock.Any()).Return(nil)
+	assert.NoError(t, service.AdminSetRole(1, 2, models.RoleSupport))
+}
+
+func TestGofemartService_SetRoleByLogin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+
+	mockRepo.EXPECT().GetUserByLogin("ghost").Return(nil, nil)
+	assert.ErrorIs(t, service.SetRoleByLogin("ghost", models.RoleAdmin), serviceTest.ErrUserNotFound)
+
+	mockRepo.EXPECT().GetUserByLogin("root").Return(&models.User{ID: 3, Login: "root"}, nil)
+	mockRepo.EXPECT().SetUserRole(3, models.RoleAdmin, gomock.Any()).DoAndReturn(func(_ int, _ string, e models.AuditEntry) error {
+		// назначение из командной строки - без автора
+		assert.Equal(t, 0, e.ActorID)
+		return nil
+	})
+	assert.NoError(t, service.SetRoleByLogin("root", models.RoleAdmin))
+}
+
+func TestGofemartService_LoginUser_Blocked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	hash, _ := serviceTestHasher.Hash("password")
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081",
+		serviceTest.WithPasswordHasher(serviceTestHasher))
+
+	blocked := models.User{ID: 1, Login: "user", PasswordHash: hash}
+	now := blocked.CreatedAt
+	blocked.BlockedAt = &now
+	mockRepo.EXPECT().GetUserByLogin("user").Return(&blocked, nil)
+
+	user, err := service.LoginUser("user", "password")
+	assert.Nil(t, user)
+	assert.ErrorIs(t, err, serviceTest.ErrUserBlocked)
+}
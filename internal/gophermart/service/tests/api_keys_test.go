@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	serviceTest "go-musthave-diploma-tpl/internal/gophermart/service"
+	mocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGofemartService_CreateAPIKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+
+	var storedHash string
+	mockRepo.EXPECT().APIKeys(1).Return(nil, nil)
+	mockRepo.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).DoAndReturn(func(k *models.APIKey, hash string) error {
+		storedHash = hash
+		k.ID = 7
+		return nil
+	})
+
+	resp, err := service.CreateAPIKey(1, models.CreateAPIKeyRequest{
+		Name:   " shop ",
+		Scopes: []string{models.ScopeOrdersWrite, models.ScopeOrdersWrite, models.ScopeBalanceRead},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 7, resp.ID)
+	assert.Equal(t, "shop", resp.Name)
+	assert.Equal(t, []string{models.ScopeOrdersWrite, models.ScopeBalanceRead}, resp.Scopes)
+	assert.True(t, strings.HasPrefix(resp.Key, models.APIKeyPrefix))
+	assert.True(t, strings.HasPrefix(resp.Key, resp.Prefix))
+	// в базе только хэш
+	assert.Equal(t, serviceTest.HashToken(resp.Key), storedHash)
+	assert.NotContains(t, storedHash, resp.Key)
+}
+
+func TestGofemartService_CreateAPIKey_Invalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+	past := time.Now().Add(-time.Hour)
+
+	_, err := service.CreateAPIKey(1, models.CreateAPIKeyRequest{})
+	assert.ErrorIs(t, err, serviceTest.ErrInvalidScope)
+
+	_, err = service.CreateAPIKey(1, models.CreateAPIKeyRequest{Scopes: []string{"admin:*"}})
+	assert.ErrorIs(t, err, serviceTest.ErrInvalidScope)
+
+	_, err = service.CreateAPIKey(1, models.CreateAPIKeyRequest{Scopes: []string{models.ScopeOrdersWrite}, ExpiresAt: &past})
+	assert.ErrorIs(t, err, serviceTest.ErrInvalidAPIKeyExpiry)
+
+	mockRepo.EXPECT().APIKeys(1).Return(make([]models.APIKey, 20), nil)
+	_, err = service.CreateAPIKey(1, models.CreateAPIKeyRequest{Scopes: []string{models.ScopeOrdersWrite}})
+	assert.ErrorIs(t, err, serviceTest.ErrTooManyAPIKeys)
+}
@@ -8,17 +8,22 @@ import (
 	"go-musthave-diploma-tpl/internal/gophermart/models"
 	serviceTest "go-musthave-diploma-tpl/internal/gophermart/service"
 	mocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/password"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// минимальная стоимость bcrypt, чтобы тесты не тормозили
+var serviceTestHasher = password.NewHasher(4)
+
 func TestGofemartService_RegisterUser_Success(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockRepo := mocks.NewMockGofemartRepo(ctrl)
-	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081", serviceTest.WithPasswordHasher(serviceTestHasher))
 
 	// подготовка
 	login, password := "newuser", "password123"
@@ -27,10 +32,16 @@ func TestGofemartService_RegisterUser_Success(t *testing.T) {
 		Login: login,
 	}
 
-	// что мы ожидаем
+	// что мы ожидаем: в репозиторий уходит bcrypt-хэш, а не пароль
 	mockRepo.EXPECT().
-		CreateUser(login, password).
-		Return(expectedUser, nil)
+		CreateUser(login, gomock.Any()).
+		DoAndReturn(func(_ string, hash string) (*models.User, error) {
+			assert.NotEqual(t, password, hash)
+			ok, needsRehash := serviceTestHasher.Verify(hash, password)
+			assert.True(t, ok)
+			assert.False(t, needsRehash)
+			return expectedUser, nil
+		})
 
 	// выполянем регистрацию
 	user, err := service.RegisterUser(login, password)
@@ -47,7 +58,7 @@ func TestGofemartService_RegisterUser_EmptyCredentials(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockRepo := mocks.NewMockGofemartRepo(ctrl)
-	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081", serviceTest.WithPasswordHasher(serviceTestHasher))
 
 	tests := []struct {
 		name     string
@@ -75,12 +86,12 @@ func TestGofemartService_RegisterUser_LoginExists(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockRepo := mocks.NewMockGofemartRepo(ctrl)
-	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081", serviceTest.WithPasswordHasher(serviceTestHasher))
 
 	login, password := "existinguser", "password123"
 
 	mockRepo.EXPECT().
-		CreateUser(login, password).
+		CreateUser(login, gomock.Any()).
 		Return(nil, errors.New(handler.ErrLoginAlreadyExists.Error()))
 
 	user, err := service.RegisterUser(login, password)
@@ -95,16 +106,20 @@ func TestGofemartService_LoginUser_Success(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockRepo := mocks.NewMockGofemartRepo(ctrl)
-	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081", serviceTest.WithPasswordHasher(serviceTestHasher))
 
 	login, password := "testuser", "correctpassword"
+	hash, err := serviceTestHasher.Hash(password)
+	require.NoError(t, err)
 	expectedUser := &models.User{
-		ID:    1,
-		Login: login,
+		ID:           1,
+		Login:        login,
+		PasswordHash: hash,
 	}
 
+	// хэш актуальный - перехэширования нет
 	mockRepo.EXPECT().
-		GetUserByLoginAndPassword(login, password).
+		GetUserByLogin(login).
 		Return(expectedUser, nil)
 
 	user, err := service.LoginUser(login, password)
@@ -120,13 +135,15 @@ func TestGofemartService_LoginUser_InvalidCredentials(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockRepo := mocks.NewMockGofemartRepo(ctrl)
-	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081", serviceTest.WithPasswordHasher(serviceTestHasher))
 
 	login, password := "testuser", "wrongpassword"
+	hash, err := serviceTestHasher.Hash("correctpassword")
+	require.NoError(t, err)
 
 	mockRepo.EXPECT().
-		GetUserByLoginAndPassword(login, password).
-		Return(nil, nil)
+		GetUserByLogin(login).
+		Return(&models.User{ID: 1, Login: login, PasswordHash: hash}, nil)
 
 	user, err := service.LoginUser(login, password)
 
@@ -140,7 +157,7 @@ func TestGofemartService_LoginUser_EmptyCredentials(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockRepo := mocks.NewMockGofemartRepo(ctrl)
-	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081", serviceTest.WithPasswordHasher(serviceTestHasher))
 
 	tests := []struct {
 		name     string
@@ -168,12 +185,12 @@ func TestGofemartService_LoginUser_DatabaseError(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockRepo := mocks.NewMockGofemartRepo(ctrl)
-	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081", serviceTest.WithPasswordHasher(serviceTestHasher))
 
 	login, password := "testuser", "password123"
 
 	mockRepo.EXPECT().
-		GetUserByLoginAndPassword(login, password).
+		GetUserByLogin(login).
 		Return(nil, errors.New("database connection failed"))
 
 	user, err := service.LoginUser(login, password)
@@ -182,3 +199,80 @@ func TestGofemartService_LoginUser_DatabaseError(t *testing.T) {
 	assert.Nil(t, user)
 	assert.Equal(t, "database connection failed", err.Error())
 }
+
+func TestGofemartService_LoginUser_UnknownLogin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081", serviceTest.WithPasswordHasher(serviceTestHasher))
+
+	mockRepo.EXPECT().
+		GetUserByLogin("nobody").
+		Return(nil, nil)
+
+	user, err := service.LoginUser("nobody", "password123")
+
+	assert.ErrorIs(t, err, handler.ErrInvalidLoginOrPassword)
+	assert.Nil(t, user)
+}
+
+func TestGofemartService_LoginUser_RehashLegacy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081", serviceTest.WithPasswordHasher(serviceTestHasher))
+
+	login, password := "olduser", "password123"
+
+	// пользователь со старым несолёным SHA-256
+	mockRepo.EXPECT().
+		GetUserByLogin(login).
+		Return(&models.User{ID: 7, Login: login, PasswordHash: passwordLegacyHash(password)}, nil)
+
+	var newHash string
+	mockRepo.EXPECT().
+		UpdatePasswordHash(7, gomock.Any()).
+		DoAndReturn(func(_ int, hash string) error {
+			newHash = hash
+			return nil
+		})
+
+	user, err := service.LoginUser(login, password)
+
+	require.NoError(t, err)
+	assert.Equal(t, 7, user.ID)
+	assert.Equal(t, newHash, user.PasswordHash)
+
+	ok, needsRehash := serviceTestHasher.Verify(newHash, password)
+	assert.True(t, ok)
+	assert.False(t, needsRehash)
+}
+
+func TestGofemartService_LoginUser_RehashErrorIgnored(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081", serviceTest.WithPasswordHasher(serviceTestHasher))
+
+	login, password := "olduser", "password123"
+
+	mockRepo.EXPECT().
+		GetUserByLogin(login).
+		Return(&models.User{ID: 7, Login: login, PasswordHash: passwordLegacyHash(password)}, nil)
+	mockRepo.EXPECT().
+		UpdatePasswordHash(7, gomock.Any()).
+		Return(errors.New("database connection failed"))
+
+	// вход всё равно успешен
+	user, err := service.LoginUser(login, password)
+
+	require.NoError(t, err)
+	assert.Equal(t, 7, user.ID)
+}
+
+func passwordLegacyHash(p string) string {
+	return password.LegacyHash(p)
+}
@@ -4,10 +4,13 @@ import (
 	"go-musthave-diploma-tpl/internal/gophermart/models"
 	"go-musthave-diploma-tpl/internal/gophermart/service"
 	serviceMocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGofemartService_GetBalance(t *testing.T) {
@@ -29,13 +32,13 @@ func TestGofemartService_GetBalance(t *testing.T) {
 			userID: 1,
 			setupMock: func() {
 				mockRepo.EXPECT().GetBalance(1).Return(models.Balance{
-					Current:   500.5,
-					Withdrawn: 42,
+					Current:   money.FromCents(50050),
+					Withdrawn: money.FromUnits(42),
 				}, nil)
 			},
 			expectedResult: models.Balance{
-				Current:   500.5,
-				Withdrawn: 42,
+				Current:   money.FromCents(50050),
+				Withdrawn: money.FromUnits(42),
 			},
 			expectError: false,
 		},
@@ -89,3 +92,26 @@ func TestGofemartService_GetBalance(t *testing.T) {
 		})
 	}
 }
+
+func TestGofemartService_GetBalanceExpiringSoon(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	policy := models.PointsExpiry{Policy: models.PointsExpiryFixed, Lifetime: 365 * 24 * time.Hour}
+	mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+	svc := service.NewGofemartService(mockRepo, "http://localhost:8081",
+		service.WithPointsExpiry(policy, 7*24*time.Hour))
+
+	expiresAt := time.Now().Add(72 * time.Hour)
+	mockRepo.EXPECT().GetBalance(1).Return(models.Balance{Current: money.FromUnits(500)}, nil)
+	mockRepo.EXPECT().ExpiringPoints(1, policy, gomock.Any()).
+		DoAndReturn(func(_ int, _ models.PointsExpiry, until time.Time) ([]models.ExpiringPoints, error) {
+			assert.WithinDuration(t, time.Now().Add(7*24*time.Hour), until, time.Minute)
+			return []models.ExpiringPoints{{Amount: money.FromUnits(120), ExpiresAt: expiresAt}}, nil
+		})
+
+	balance, err := svc.GetBalance(1)
+	require.NoError(t, err)
+	assert.Equal(t, money.FromUnits(500), balance.Current)
+	assert.Equal(t, []models.ExpiringPoints{{Amount: money.FromUnits(120), ExpiresAt: expiresAt}}, balance.ExpiringSoon)
+}
@@ -0,0 +1,38 @@
+package tests
+
+import (
+	"testing"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	serviceTest "go-musthave-diploma-tpl/internal/gophermart/service"
+	mocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGofemartService_AdminAdjustBalance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+
+	_, err := service.AdminAdjustBalance(1, 2, models.AdjustBalanceRequest{Amount: 0})
+	assert.ErrorIs(t, err, serviceTest.ErrInvalidAmount)
+	_, err = service.AdminReverseEntry(1, 10, models.ReverseEntryRequest{Amount: money.FromUnits(-5)})
+	assert.ErrorIs(t, err, serviceTest.ErrInvalidAmount)
+
+	mockRepo.EXPECT().AdjustBalance(2, money.FromCents(-2550), "goodwill correction", gomock.Any()).
+		DoAndReturn(func(userID int, amount money.Amount, note string, e models.AuditEntry) (models.LedgerEntry, error) {
+			assert.Equal(t, models.AuditAdjustBalance, e.Action)
+			assert.Equal(t, 1, e.ActorID)
+			assert.Equal(t, 2, *e.TargetUserID)
+			return models.LedgerEntry{ID: 3, UserID: userID, Type: models.LedgerEntryAdjustment, Amount: amount}, nil
+		})
+
+	entry, err := service.AdminAdjustBalance(1, 2, models.AdjustBalanceRequest{Amount: money.FromCents(-2550), Note: "goodwill correction"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), entry.ID)
+}
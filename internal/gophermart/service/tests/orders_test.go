@@ -9,6 +9,7 @@ import (
 	"go-musthave-diploma-tpl/internal/gophermart/models"
 	serviceTest "go-musthave-diploma-tpl/internal/gophermart/service"
 	mocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -28,7 +29,7 @@ func TestGofemartService_GetOrders_Success(t *testing.T) {
 			UserID:     userID,
 			Number:     "1234567890",
 			Status:     "PROCESSED",
-			Accrual:    100.5,
+			Accrual:    money.FromCents(10050),
 			UploadedAt: time.Now().Add(-24 * time.Hour),
 		},
 		{
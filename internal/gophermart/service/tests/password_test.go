@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	serviceTest "go-musthave-diploma-tpl/internal/gophermart/service"
+	mocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/password"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGofemartService_RegisterUser_PasswordPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081",
+		serviceTest.WithPasswordHasher(serviceTestHasher),
+		serviceTest.WithPasswordPolicy(password.NewPolicy(8, []string{"qwerty123"})))
+
+	tests := []struct {
+		name     string
+		login    string
+		password string
+		rules    []string
+	}{
+		{"Too short", "user", "short", []string{password.RuleMinLength}},
+		{"Common password", "user", "QWERTY123", []string{password.RuleDenyList}},
+		{"Equals login", "longusername", "longusername", []string{password.RuleEqualsLogin}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// до репозитория дело не доходит
+			user, err := service.RegisterUser(tt.login, tt.password)
+
+			assert.Nil(t, user)
+			require.ErrorIs(t, err, serviceTest.ErrPasswordPolicy)
+
+			var policyErr *serviceTest.PasswordPolicyError
+			require.True(t, errors.As(err, &policyErr))
+			var rules []string
+			for _, v := range policyErr.Violations {
+				rules = append(rules, v.Rule)
+			}
+			assert.Equal(t, tt.rules, rules)
+		})
+	}
+}
+
+func TestGofemartService_ChangePassword(t *testing.T) {
+	currentHash, err := serviceTestHasher.Hash("old-password")
+	require.NoError(t, err)
+	user := &models.User{ID: 1, Login: "user", PasswordHash: currentHash}
+
+	t.Run("Success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mocks.NewMockGofemartRepo(ctrl)
+		service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081", serviceTest.WithPasswordHasher(serviceTestHasher))
+
+		mockRepo.EXPECT().GetUserByID(1).Return(user, nil)
+		mockRepo.EXPECT().
+			ChangePassword(1, gomock.Any(), 7).
+			DoAndReturn(func(_ int, hash string, _ int) error {
+				ok, _ := serviceTestHasher.Verify(hash, "new-password")
+				assert.True(t, ok)
+				return nil
+			})
+
+		assert.NoError(t, service.ChangePassword(1, 7, "old-password", "new-password"))
+	})
+
+	t.Run("Wrong current password", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mocks.NewMockGofemartRepo(ctrl)
+		service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081", serviceTest.WithPasswordHasher(serviceTestHasher))
+
+		mockRepo.EXPECT().GetUserByID(1).Return(user, nil)
+
+		err := service.ChangePassword(1, 7, "wrong-password", "new-password")
+		assert.ErrorIs(t, err, serviceTest.ErrInvalidCurrentPassword)
+	})
+
+	t.Run("Policy violation", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mocks.NewMockGofemartRepo(ctrl)
+		service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081",
+			serviceTest.WithPasswordHasher(serviceTestHasher),
+			serviceTest.WithPasswordPolicy(password.NewPolicy(8, nil)))
+
+		mockRepo.EXPECT().GetUserByID(1).Return(user, nil)
+
+		err := service.ChangePassword(1, 7, "old-password", "short")
+		assert.ErrorIs(t, err, serviceTest.ErrPasswordPolicy)
+	})
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	serviceTest "go-musthave-diploma-tpl/internal/gophermart/service"
+	mocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGofemartService_CreateSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081", serviceTest.WithSessionTTL(time.Hour))
+
+	var storedHash string
+	mockRepo.EXPECT().
+		CreateSession(1, gomock.Any(), "agent", "192.0.2.1", gomock.Any()).
+		DoAndReturn(func(_ int, hash, _, _ string, expiresAt time.Time) error {
+			storedHash = hash
+			assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Minute)
+			return nil
+		})
+
+	token, expiresAt, err := service.CreateSession(1, "agent", "192.0.2.1")
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, serviceTest.HashToken(token), storedHash)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Minute)
+}
+
+func TestGofemartService_ValidateSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+
+	t.Run("Active session", func(t *testing.T) {
+		mockRepo.EXPECT().TouchSession(1, serviceTest.HashToken("token")).Return(5, nil)
+
+		sessionID, err := service.ValidateSession(1, "token")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 5, sessionID)
+	})
+
+	t.Run("Revoked session", func(t *testing.T) {
+		mockRepo.EXPECT().TouchSession(1, serviceTest.HashToken("token")).Return(0, nil)
+
+		_, err := service.ValidateSession(1, "token")
+
+		assert.ErrorIs(t, err, serviceTest.ErrSessionNotActive)
+	})
+
+	t.Run("Empty token", func(t *testing.T) {
+		_, err := service.ValidateSession(1, "")
+
+		assert.ErrorIs(t, err, serviceTest.ErrSessionNotActive)
+	})
+
+	t.Run("Database error", func(t *testing.T) {
+		mockRepo.EXPECT().TouchSession(1, serviceTest.HashToken("token")).Return(0, errors.New("database error"))
+
+		_, err := service.ValidateSession(1, "token")
+
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, handler.ErrInvalidRefreshToken)
+	})
+}
+
+func TestGofemartService_LogoutAll(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+
+	mockRepo.EXPECT().RevokeAllSessions(1).Return(nil)
+
+	assert.NoError(t, service.LogoutAll(1))
+}
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	serviceTest "go-musthave-diploma-tpl/internal/gophermart/service"
+	mocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testLoginThrottle = serviceTest.LoginThrottle{
+	MaxFailures:      5,
+	MaxFailuresPerIP: 20,
+	Delay:            time.Second,
+	Lockout:          15 * time.Minute,
+	Window:           time.Hour,
+}
+
+func TestGofemartService_CheckLoginAllowed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081", serviceTest.WithLoginThrottle(testLoginThrottle))
+
+	t.Run("Not locked", func(t *testing.T) {
+		mockRepo.EXPECT().LoginLockedUntil("user", "10.0.0.1").Return(time.Time{}, nil)
+
+		assert.NoError(t, service.CheckLoginAllowed("user", "10.0.0.1"))
+	})
+
+	t.Run("Lock already expired", func(t *testing.T) {
+		mockRepo.EXPECT().LoginLockedUntil("user", "10.0.0.1").Return(time.Now().Add(-time.Second), nil)
+
+		assert.NoError(t, service.CheckLoginAllowed("user", "10.0.0.1"))
+	})
+
+	t.Run("Locked", func(t *testing.T) {
+		mockRepo.EXPECT().LoginLockedUntil("user", "10.0.0.1").Return(time.Now().Add(time.Minute), nil)
+
+		err := service.CheckLoginAllowed("user", "10.0.0.1")
+
+		require.ErrorIs(t, err, serviceTest.ErrTooManyLoginAttempts)
+		var locked *serviceTest.LoginLockedError
+		require.True(t, errors.As(err, &locked))
+		assert.InDelta(t, time.Minute.Seconds(), locked.RetryAfter.Seconds(), 1)
+	})
+}
+
+func TestGofemartService_RecordLoginFailure(t *testing.T) {
+	tests := []struct {
+		name         string
+		loginFailure int
+		ipFailure    int
+		loginLock    time.Duration
+		ipLock       time.Duration
+	}{
+		{name: "Free attempts", loginFailure: 2, ipFailure: 2},
+		{name: "First delay", loginFailure: 3, ipFailure: 3, loginLock: time.Second},
+		{name: "Delay doubles", loginFailure: 4, ipFailure: 4, loginLock: 2 * time.Second},
+		{name: "Lockout", loginFailure: 5, ipFailure: 5, loginLock: 15 * time.Minute},
+		{name: "Lockout repeats after expiry", loginFailure: 6, ipFailure: 6, loginLock: 15 * time.Minute},
+		{name: "IP delays start later", loginFailure: 1, ipFailure: 12, ipLock: 2 * time.Second},
+		{name: "IP lockout", loginFailure: 1, ipFailure: 20, ipLock: 15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := mocks.NewMockGofemartRepo(ctrl)
+			service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081", serviceTest.WithLoginThrottle(testLoginThrottle))
+
+			mockRepo.EXPECT().RecordLoginFailure(models.LoginAttemptScopeLogin, "user", time.Hour).Return(tt.loginFailure, nil)
+			mockRepo.EXPECT().RecordLoginFailure(models.LoginAttemptScopeIP, "10.0.0.1", time.Hour).Return(tt.ipFailure, nil)
+
+			expectLock := func(scope, key string, lock time.Duration) {
+				if lock == 0 {
+					return
+				}
+				mockRepo.EXPECT().LockLogin(scope, key, gomock.Any()).
+					DoAndReturn(func(_, _ string, until time.Time) error {
+						assert.WithinDuration(t, time.Now().Add(lock), until, time.Second)
+						return nil
+					})
+			}
+			expectLock(models.LoginAttemptScopeLogin, "user", tt.loginLock)
+			expectLock(models.LoginAttemptScopeIP, "10.0.0.1", tt.ipLock)
+
+			assert.NoError(t, service.RecordLoginFailure("user", "10.0.0.1"))
+		})
+	}
+}
+
+func TestGofemartService_RecordLoginFailure_Disabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081",
+		serviceTest.WithLoginThrottle(serviceTest.LoginThrottle{}))
+
+	// учёт выключен - к репозиторию не обращаемся
+	assert.NoError(t, service.RecordLoginFailure("user", "10.0.0.1"))
+}
+
+func TestGofemartService_UnlockLogin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+
+	mockRepo.EXPECT().ResetLoginAttempts(models.LoginAttemptScopeLogin, "user").Return(nil)
+	mockRepo.EXPECT().ResetLoginAttempts(models.LoginAttemptScopeIP, "10.0.0.1").Return(nil)
+
+	assert.NoError(t, service.UnlockLogin("user"))
+	assert.NoError(t, service.UnlockIP("10.0.0.1"))
+	assert.Error(t, service.UnlockLogin(""))
+}
@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+	serviceMocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTiers(t *testing.T) {
+	tiers, err := service.ParseTiers(service.ExampleTiers)
+	require.NoError(t, err)
+	assert.Equal(t, []models.Tier{
+		{Name: models.TierBronze, Threshold: 0, Multiplier: money.FromUnits(1)},
+		{Name: models.TierSilver, Threshold: money.FromUnits(1000), Multiplier: money.FromCents(110)},
+		{Name: models.TierGold, Threshold: money.FromUnits(5000), Multiplier: money.FromCents(125)},
+	}, tiers)
+
+	tiers, err = service.ParseTiers("")
+	require.NoError(t, err)
+	assert.Nil(t, tiers)
+
+	for _, spec := range []string{
+		"bronze:10:1",                // первый уровень не с нуля
+		"bronze:0:1,silver:0:2",      // пороги не возрастают
+		"bronze:0:0",                 // нулевой множитель
+		"bronze:0:1.125",             // множитель точнее сотых
+		"bronze:0",                   // нет множителя
+		"bronze:0:1,gold:abc:2",      // порог не число
+		"bronze:0:1,silver:100:1.1,", // пустой уровень
+	} {
+		_, err := service.ParseTiers(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestGofemartService_Tier(t *testing.T) {
+	tiers, err := service.ParseTiers(service.ExampleTiers)
+	require.NoError(t, err)
+	policy := models.TierPolicy{Tiers: tiers, Window: 30 * 24 * time.Hour, Review: 24 * time.Hour}
+
+	t.Run("progress to the next tier", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+		svc := service.NewGofemartService(mockRepo, "http://localhost:8081", service.WithTierPolicy(policy))
+
+		next := time.Now().Add(5 * time.Hour)
+		mockRepo.EXPECT().UserTier(1).Return(&models.UserTier{
+			UserID:           1,
+			Tier:             models.TierSilver,
+			Earned:           money.FromUnits(2000),
+			NextEvaluationAt: next,
+		}, nil)
+
+		status, err := svc.Tier(1)
+		require.NoError(t, err)
+		assert.Equal(t, models.TierSilver, status.Tier)
+		assert.Equal(t, money.FromCents(110), status.Multiplier)
+		assert.Equal(t, next, status.NextEvaluationAt)
+		require.NotNil(t, status.Next)
+		assert.Equal(t, models.TierGold, status.Next.Tier)
+		assert.Equal(t, money.FromUnits(3000), status.Next.Remaining)
+		// 1000 из 4000 между порогами silver и gold
+		assert.Equal(t, 25, status.Next.Percent)
+	})
+
+	t.Run("first request evaluates the tier", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := serviceMocks.NewMockGofemartRepo(ctrl)
+		svc := service.NewGofemartService(mockRepo, "http://localhost:8081", service.WithTierPolicy(policy))
+
+		mockRepo.EXPECT().UserTier(2).Return(nil, nil)
+		mockRepo.EXPECT().TierEarned(2, gomock.Any()).DoAndReturn(func(_ int, since time.Time) (money.Amount, error) {
+			assert.WithinDuration(t, time.Now().Add(-policy.Window), since, time.Minute)
+			return money.FromUnits(7000), nil
+		})
+		mockRepo.EXPECT().SaveUserTier(gomock.Any()).DoAndReturn(func(ut models.UserTier) error {
+			assert.Equal(t, models.TierGold, ut.Tier)
+			assert.Equal(t, policy.Review, ut.NextEvaluationAt.Sub(ut.EvaluatedAt))
+			return nil
+		})
+
+		status, err := svc.Tier(2)
+		require.NoError(t, err)
+		assert.Equal(t, models.TierGold, status.Tier)
+		// высший уровень: следующего нет
+		assert.Nil(t, status.Next)
+	})
+
+	t.Run("tiers disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		svc := service.NewGofemartService(serviceMocks.NewMockGofemartRepo(ctrl), "http://localhost:8081")
+		_, err := svc.Tier(1)
+		assert.ErrorIs(t, err, service.ErrTiersDisabled)
+	})
+}
+
+func TestTier_Apply(t *testing.T) {
+	silver := models.Tier{Name: models.TierSilver, Multiplier: money.FromCents(110)}
+	assert.Equal(t, money.FromCents(55055), silver.Apply(money.FromCents(50050)))
+	// половина сотой - к чётному
+	assert.Equal(t, money.FromCents(12), models.Tier{Multiplier: money.FromCents(50)}.Apply(money.FromCents(25)))
+}
@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	handler "go-musthave-diploma-tpl/internal/gophermart/handler"
+	serviceTest "go-musthave-diploma-tpl/internal/gophermart/service"
+	mocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTokenService(mockRepo *mocks.MockGofemartRepo, accessTTL time.Duration) *serviceTest.GofemartService {
+	return serviceTest.NewGofemartService(mockRepo, "http://localhost:8081", serviceTest.WithTokenSettings(serviceTest.TokenSettings{
+		Secret:     []byte("test-secret"),
+		AccessTTL:  accessTTL,
+		RefreshTTL: time.Hour,
+	}))
+}
+
+func TestGofemartService_IssueTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := newTokenService(mockRepo, time.Minute)
+
+	var storedHash string
+	mockRepo.EXPECT().
+		CreateRefreshToken(1, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ int, hash string, expiresAt time.Time) error {
+			storedHash = hash
+			assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Minute)
+			return nil
+		})
+
+	pair, err := service.IssueTokens(1)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer", pair.TokenType)
+	assert.Equal(t, int64(60), pair.ExpiresIn)
+	// в базе только хэш refresh-токена
+	assert.NotEqual(t, pair.RefreshToken, storedHash)
+	assert.Equal(t, serviceTest.HashToken(pair.RefreshToken), storedHash)
+
+	userID, err := service.ParseAccessToken(pair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, 1, userID)
+}
+
+func TestGofemartService_IssueTokens_RepoError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := newTokenService(mockRepo, time.Minute)
+
+	mockRepo.EXPECT().
+		CreateRefreshToken(1, gomock.Any(), gomock.Any()).
+		Return(errors.New("database connection failed"))
+
+	_, err := service.IssueTokens(1)
+
+	assert.Error(t, err)
+}
+
+func TestGofemartService_RefreshTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := newTokenService(mockRepo, time.Minute)
+
+	mockRepo.EXPECT().
+		RotateRefreshToken(serviceTest.HashToken("old-refresh"), gomock.Any(), gomock.Any()).
+		Return(5, nil)
+
+	pair, err := service.RefreshTokens("old-refresh")
+
+	require.NoError(t, err)
+	assert.NotEqual(t, "old-refresh", pair.RefreshToken)
+
+	userID, err := service.ParseAccessToken(pair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, 5, userID)
+}
+
+func TestGofemartService_RefreshTokens_Invalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := newTokenService(mockRepo, time.Minute)
+
+	// пустой токен до репозитория не доходит
+	_, err := service.RefreshTokens("")
+	assert.ErrorIs(t, err, handler.ErrInvalidRefreshToken)
+
+	mockRepo.EXPECT().
+		RotateRefreshToken(serviceTest.HashToken("revoked"), gomock.Any(), gomock.Any()).
+		Return(0, handler.ErrInvalidRefreshToken)
+
+	_, err = service.RefreshTokens("revoked")
+	assert.ErrorIs(t, err, handler.ErrInvalidRefreshToken)
+}
+
+func TestGofemartService_ParseAccessToken_Invalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+
+	// токен выпущен сервисом с другим секретом
+	other := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+	mockRepo.EXPECT().CreateRefreshToken(1, gomock.Any(), gomock.Any()).Return(nil)
+	pair, err := other.IssueTokens(1)
+	require.NoError(t, err)
+
+	service := newTokenService(mockRepo, time.Minute)
+	_, err = service.ParseAccessToken(pair.AccessToken)
+	assert.Error(t, err)
+
+	_, err = service.ParseAccessToken("garbage")
+	assert.Error(t, err)
+}
+
+func TestGofemartService_RevokeRefreshToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := newTokenService(mockRepo, time.Minute)
+
+	mockRepo.EXPECT().
+		RevokeRefreshToken(serviceTest.HashToken("refresh")).
+		Return(nil)
+
+	assert.NoError(t, service.RevokeRefreshToken("refresh"))
+	assert.ErrorIs(t, service.RevokeRefreshToken(""), handler.ErrInvalidRefreshToken)
+}
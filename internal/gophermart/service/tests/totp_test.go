@@ -0,0 +1,226 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	serviceTest "go-musthave-diploma-tpl/internal/gophermart/service"
+	mocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
+	"go-musthave-diploma-tpl/pkg/totp"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func currentTOTPCode(t *testing.T) string {
+	code, err := totp.Code(testTOTPSecret, totp.Counter(time.Now()))
+	require.NoError(t, err)
+	return code
+}
+
+func TestGofemartService_SetupTOTP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081",
+		serviceTest.WithTwoFactorSettings(serviceTest.TwoFactorSettings{Issuer: "Shop"}))
+
+	var saved string
+	mockRepo.EXPECT().GetUserByID(1).Return(&models.User{ID: 1, Login: "user"}, nil)
+	mockRepo.EXPECT().SaveTOTPSecret(1, gomock.Any()).
+		DoAndReturn(func(_ int, secret string) error {
+			saved = secret
+			return nil
+		})
+
+	setup, err := service.SetupTOTP(1)
+
+	require.NoError(t, err)
+	assert.Equal(t, saved, setup.Secret)
+	assert.Contains(t, setup.URI, "otpauth://totp/Shop:user?")
+}
+
+func TestGofemartService_ConfirmTOTP(t *testing.T) {
+	t.Run("Valid code enables 2FA", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mocks.NewMockGofemartRepo(ctrl)
+		service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+
+		mockRepo.EXPECT().GetTOTP(1).Return(&models.TOTP{UserID: 1, Secret: testTOTPSecret}, nil)
+		var hashes []string
+		mockRepo.EXPECT().EnableTOTP(1, gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ int, _ int64, h []string) error {
+				hashes = h
+				return nil
+			})
+
+		codes, err := service.ConfirmTOTP(1, currentTOTPCode(t))
+
+		require.NoError(t, err)
+		require.Len(t, codes, 10)
+		require.Len(t, hashes, 10)
+		assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, codes[0])
+		// в базу уходят только хэши
+		assert.NotContains(t, hashes, codes[0])
+	})
+
+	t.Run("Invalid code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mocks.NewMockGofemartRepo(ctrl)
+		service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+
+		mockRepo.EXPECT().GetTOTP(1).Return(&models.TOTP{UserID: 1, Secret: testTOTPSecret}, nil)
+
+		_, err := service.ConfirmTOTP(1, "000000x")
+		assert.ErrorIs(t, err, serviceTest.ErrInvalidTOTPCode)
+	})
+
+	t.Run("Not set up", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mocks.NewMockGofemartRepo(ctrl)
+		service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+
+		mockRepo.EXPECT().GetTOTP(1).Return(nil, nil)
+
+		_, err := service.ConfirmTOTP(1, "123456")
+		assert.ErrorIs(t, err, serviceTest.ErrTOTPNotSetUp)
+	})
+}
+
+func TestGofemartService_LoginChallenge(t *testing.T) {
+	enabled := &models.TOTP{UserID: 1, Secret: testTOTPSecret, Enabled: true}
+
+	t.Run("2FA disabled - no challenge", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mocks.NewMockGofemartRepo(ctrl)
+		service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+
+		mockRepo.EXPECT().GetTOTP(1).Return(nil, nil)
+
+		challenge, err := service.StartLoginChallenge(1)
+		assert.NoError(t, err)
+		assert.Nil(t, challenge)
+	})
+
+	t.Run("TOTP code completes login", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mocks.NewMockGofemartRepo(ctrl)
+		service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+
+		var challengeHash string
+		mockRepo.EXPECT().GetTOTP(1).Return(enabled, nil).Times(2)
+		mockRepo.EXPECT().CreateLoginChallenge(1, gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ int, hash string, _ time.Time) error {
+				challengeHash = hash
+				return nil
+			})
+
+		challenge, err := service.StartLoginChallenge(1)
+		require.NoError(t, err)
+		require.NotNil(t, challenge)
+		assert.Equal(t, models.LoginChallengeStatusTOTP, challenge.Status)
+		assert.Equal(t, serviceTest.HashToken(challenge.Challenge), challengeHash)
+
+		mockRepo.EXPECT().LoginChallengeUser(challengeHash, gomock.Any()).Return(1, nil)
+		mockRepo.EXPECT().UseTOTPCounter(1, gomock.Any()).Return(true, nil)
+		mockRepo.EXPECT().CompleteLoginChallenge(challengeHash).Return(true, nil)
+
+		userID, err := service.CompleteLoginChallenge(challenge.Challenge, currentTOTPCode(t))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, userID)
+	})
+
+	t.Run("Replayed code is rejected and counted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mocks.NewMockGofemartRepo(ctrl)
+		service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+
+		hash := serviceTest.HashToken("challenge")
+		mockRepo.EXPECT().LoginChallengeUser(hash, gomock.Any()).Return(1, nil)
+		mockRepo.EXPECT().GetTOTP(1).Return(enabled, nil)
+		mockRepo.EXPECT().UseTOTPCounter(1, gomock.Any()).Return(false, nil)
+		mockRepo.EXPECT().FailLoginChallenge(hash).Return(nil)
+
+		_, err := service.CompleteLoginChallenge("challenge", currentTOTPCode(t))
+		assert.ErrorIs(t, err, serviceTest.ErrInvalidTOTPCode)
+	})
+
+	t.Run("Recovery code", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mocks.NewMockGofemartRepo(ctrl)
+		service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+
+		hash := serviceTest.HashToken("challenge")
+		mockRepo.EXPECT().LoginChallengeUser(hash, gomock.Any()).Return(1, nil)
+		mockRepo.EXPECT().GetTOTP(1).Return(enabled, nil)
+		// регистр и дефис не важны
+		mockRepo.EXPECT().UseRecoveryCode(1, serviceTest.HashToken("abcdefghij")).Return(true, nil)
+		mockRepo.EXPECT().CompleteLoginChallenge(hash).Return(true, nil)
+
+		userID, err := service.CompleteLoginChallenge("challenge", "ABCDE-FGHIJ")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, userID)
+	})
+
+	t.Run("Expired challenge", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRepo := mocks.NewMockGofemartRepo(ctrl)
+		service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081")
+
+		mockRepo.EXPECT().LoginChallengeUser(gomock.Any(), gomock.Any()).Return(0, nil)
+
+		_, err := service.CompleteLoginChallenge("challenge", "123456")
+		assert.ErrorIs(t, err, serviceTest.ErrInvalidLoginChallenge)
+	})
+}
+
+func TestGofemartService_CheckWithdrawSecondFactor(t *testing.T) {
+	enabled := &models.TOTP{UserID: 1, Secret: testTOTPSecret, Enabled: true}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockGofemartRepo(ctrl)
+	service := serviceTest.NewGofemartService(mockRepo, "http://localhost:8081",
+		serviceTest.WithTwoFactorSettings(serviceTest.TwoFactorSettings{WithdrawThreshold: money.FromUnits(500)}))
+
+	// до порога код не нужен, к репозиторию не ходим
+	assert.NoError(t, service.CheckWithdrawSecondFactor(1, money.FromUnits(500), ""))
+
+	// 2FA не включена
+	mockRepo.EXPECT().GetTOTP(1).Return(nil, nil)
+	assert.NoError(t, service.CheckWithdrawSecondFactor(1, money.FromCents(50001), ""))
+
+	mockRepo.EXPECT().GetTOTP(1).Return(enabled, nil)
+	assert.ErrorIs(t, service.CheckWithdrawSecondFactor(1, money.FromCents(50001), ""), serviceTest.ErrTOTPRequired)
+
+	// коды восстановления для списаний не принимаются
+	mockRepo.EXPECT().GetTOTP(1).Return(enabled, nil)
+	assert.ErrorIs(t, service.CheckWithdrawSecondFactor(1, money.FromCents(50001), "abcde-fghij"), serviceTest.ErrInvalidTOTPCode)
+
+	mockRepo.EXPECT().GetTOTP(1).Return(enabled, nil)
+	mockRepo.EXPECT().UseTOTPCounter(1, gomock.Any()).Return(true, nil)
+	assert.NoError(t, service.CheckWithdrawSecondFactor(1, money.FromCents(50001), currentTOTPCode(t)))
+}
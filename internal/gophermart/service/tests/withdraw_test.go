@@ -7,6 +7,7 @@ import (
 	"go-musthave-diploma-tpl/internal/gophermart/models"
 	serviceTest "go-musthave-diploma-tpl/internal/gophermart/service"
 	mocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -31,13 +32,13 @@ func TestGofemartServiceWithdraw(t *testing.T) {
 			userID: 1,
 			withdraw: models.WithdrawBalance{
 				Order: "2377225624",
-				Sum:   751,
+				Sum:   money.FromUnits(751),
 			},
 			setupMock: func() {
 				mockRepo.EXPECT().
 					Withdraw(1, models.WithdrawBalance{
 						Order: "2377225624",
-						Sum:   751,
+						Sum:   money.FromUnits(751),
 					}).
 					Return(nil)
 			},
@@ -48,13 +49,13 @@ func TestGofemartServiceWithdraw(t *testing.T) {
 			userID: 1,
 			withdraw: models.WithdrawBalance{
 				Order: "2377225624",
-				Sum:   751,
+				Sum:   money.FromUnits(751),
 			},
 			setupMock: func() {
 				mockRepo.EXPECT().
 					Withdraw(1, models.WithdrawBalance{
 						Order: "2377225624",
-						Sum:   751,
+						Sum:   money.FromUnits(751),
 					}).
 					Return(handler.ErrInvalidOrderNumber)
 			},
@@ -65,13 +66,13 @@ func TestGofemartServiceWithdraw(t *testing.T) {
 			userID: 1,
 			withdraw: models.WithdrawBalance{
 				Order: "2377225624",
-				Sum:   751,
+				Sum:   money.FromUnits(751),
 			},
 			setupMock: func() {
 				mockRepo.EXPECT().
 					Withdraw(1, models.WithdrawBalance{
 						Order: "2377225624",
-						Sum:   751,
+						Sum:   money.FromUnits(751),
 					}).
 					Return(handler.ErrLackOfFunds)
 			},
@@ -82,13 +83,13 @@ func TestGofemartServiceWithdraw(t *testing.T) {
 			userID: 1,
 			withdraw: models.WithdrawBalance{
 				Order: "2377225624",
-				Sum:   751,
+				Sum:   money.FromUnits(751),
 			},
 			setupMock: func() {
 				mockRepo.EXPECT().
 					Withdraw(1, models.WithdrawBalance{
 						Order: "2377225624",
-						Sum:   751,
+						Sum:   money.FromUnits(751),
 					}).
 					Return(assert.AnError)
 			},
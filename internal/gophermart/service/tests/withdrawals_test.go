@@ -7,6 +7,7 @@ import (
 	"go-musthave-diploma-tpl/internal/gophermart/models"
 	"go-musthave-diploma-tpl/internal/gophermart/service"
 	mocks "go-musthave-diploma-tpl/internal/gophermart/service/mocks"
+	"go-musthave-diploma-tpl/pkg/money"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -39,12 +40,12 @@ func TestGofemartService_Withdrawals(t *testing.T) {
 				expectedWithdrawals := []models.WithdrawBalance{
 					{
 						Order:       "2377225624",
-						Sum:         751.50,
+						Sum:         money.FromCents(75150),
 						ProcessedAt: time1,
 					},
 					{
 						Order:       "49927398716",
-						Sum:         500.25,
+						Sum:         money.FromCents(50025),
 						ProcessedAt: time2,
 					},
 				}
@@ -53,12 +54,12 @@ func TestGofemartService_Withdrawals(t *testing.T) {
 			expectedResult: []models.WithdrawBalance{
 				{
 					Order:       "2377225624",
-					Sum:         751.50,
+					Sum:         money.FromCents(75150),
 					ProcessedAt: time1,
 				},
 				{
 					Order:       "49927398716",
-					Sum:         500.25,
+					Sum:         money.FromCents(50025),
 					ProcessedAt: time2,
 				},
 			},
@@ -98,7 +99,7 @@ func TestGofemartService_Withdrawals(t *testing.T) {
 				expectedWithdrawals := []models.WithdrawBalance{
 					{
 						Order:       "1234567890",
-						Sum:         300.75,
+						Sum:         money.FromCents(30075),
 						ProcessedAt: time1,
 					},
 				}
@@ -107,7 +108,7 @@ func TestGofemartService_Withdrawals(t *testing.T) {
 			expectedResult: []models.WithdrawBalance{
 				{
 					Order:       "1234567890",
-					Sum:         300.75,
+					Sum:         money.FromCents(30075),
 					ProcessedAt: time1,
 				},
 			},
@@ -160,7 +161,7 @@ func TestGofemartService_Withdrawals_RepositoryCalledCorrectly(t *testing.T) {
 		expectedWithdrawals := []models.WithdrawBalance{
 			{
 				Order:       "1234567890",
-				Sum:         100.0,
+				Sum:         money.FromUnits(100),
 				ProcessedAt: time.Now(),
 			},
 		}
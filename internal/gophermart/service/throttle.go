@@ -0,0 +1,139 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+)
+
+// LoginThrottle - защита входа от перебора паролей.
+// Первые MaxFailures/2 неудач проходят без задержки, дальше каждая неудача
+// блокирует вход на Delay, 2*Delay, 4*Delay..., а на MaxFailures - на Lockout.
+// Нулевой MaxFailures отключает учёт для соответствующей области
+type LoginThrottle struct {
+	// неудач подряд по одному логину до полной блокировки
+	MaxFailures int
+	// неудач подряд с одного адреса (за NAT могут быть разные люди, поэтому больше)
+	MaxFailuresPerIP int
+	// начальная задержка после бесплатных попыток
+	Delay time.Duration
+	// длительность блокировки
+	Lockout time.Duration
+	// через сколько после последней неудачи счётчик начинается заново
+	Window time.Duration
+}
+
+func DefaultLoginThrottle() LoginThrottle {
+	return LoginThrottle{
+		MaxFailures:      5,
+		MaxFailuresPerIP: 20,
+		Delay:            time.Second,
+		Lockout:          15 * time.Minute,
+		Window:           time.Hour,
+	}
+}
+
+// WithLoginThrottle задаёт политику блокировки входа
+func WithLoginThrottle(t LoginThrottle) Option {
+	return func(s *GofemartService) {
+		s.loginThrottle = t
+	}
+}
+
+// LoginLockedError - вход временно заблокирован, повторить можно через RetryAfter
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrTooManyLoginAttempts, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LoginLockedError) Unwrap() error {
+	return ErrTooManyLoginAttempts
+}
+
+// CheckLoginAllowed возвращает *LoginLockedError, если вход по логину или с адреса заблокирован
+func (s *GofemartService) CheckLoginAllowed(login, ip string) error {
+	lockedUntil, err := s.repo.LoginLockedUntil(login, ip)
+	if err != nil {
+		return err
+	}
+
+	if wait := time.Until(lockedUntil); wait > 0 {
+		return &LoginLockedError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// RecordLoginFailure учитывает неудачную попытку и при необходимости блокирует вход.
+// Учитываются и несуществующие логины, чтобы блокировка не выдавала, есть ли пользователь
+func (s *GofemartService) RecordLoginFailure(login, ip string) error {
+	if err := s.recordFailure(models.LoginAttemptScopeLogin, login, s.loginThrottle.MaxFailures); err != nil {
+		return err
+	}
+	if ip == "" {
+		return nil
+	}
+	return s.recordFailure(models.LoginAttemptScopeIP, ip, s.loginThrottle.MaxFailuresPerIP)
+}
+
+func (s *GofemartService) recordFailure(scope, key string, maxFailures int) error {
+	if maxFailures <= 0 {
+		return nil
+	}
+
+	failures, err := s.repo.RecordLoginFailure(scope, key, s.loginThrottle.Window)
+	if err != nil {
+		return err
+	}
+
+	if lock := s.loginThrottle.lockFor(failures, maxFailures); lock > 0 {
+		return s.repo.LockLogin(scope, key, time.Now().Add(lock))
+	}
+	return nil
+}
+
+// lockFor - на сколько заблокировать вход после failures неудач подряд
+func (t LoginThrottle) lockFor(failures, maxFailures int) time.Duration {
+	if failures >= maxFailures {
+		return t.Lockout
+	}
+
+	free := maxFailures / 2
+	if failures <= free || t.Delay <= 0 {
+		return 0
+	}
+
+	delay := t.Delay
+	for i := free + 1; i < failures && delay < t.Lockout; i++ {
+		delay *= 2
+	}
+	if delay > t.Lockout {
+		delay = t.Lockout
+	}
+	return delay
+}
+
+// LoginSucceeded сбрасывает счётчик по логину. Счётчик адреса не сбрасывается:
+// иначе перебор чужих паролей можно было бы чередовать со входом в свой аккаунт
+func (s *GofemartService) LoginSucceeded(login string) error {
+	return s.repo.ResetLoginAttempts(models.LoginAttemptScopeLogin, login)
+}
+
+// UnlockLogin - ручное снятие блокировки входа по логину (для администратора)
+func (s *GofemartService) UnlockLogin(login string) error {
+	if login == "" {
+		return fmt.Errorf("login is required")
+	}
+	return s.repo.ResetLoginAttempts(models.LoginAttemptScopeLogin, login)
+}
+
+// UnlockIP - ручное снятие блокировки входа с адреса клиента
+func (s *GofemartService) UnlockIP(ip string) error {
+	if ip == "" {
+		return fmt.Errorf("ip is required")
+	}
+	return s.repo.ResetLoginAttempts(models.LoginAttemptScopeIP, ip)
+}
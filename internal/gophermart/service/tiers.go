@@ -0,0 +1,151 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/pkg/money"
+)
+
+const (
+	// ExampleTiers - пример уровней "название:порог:множитель" по возрастанию порога.
+	// По умолчанию уровни выключены, оператор включает их явно
+	ExampleTiers = "bronze:0:1,silver:1000:1.1,gold:5000:1.25"
+	// DefaultTierWindow - за какой период считаются начисления для уровня
+	DefaultTierWindow = 365 * 24 * time.Hour
+	// DefaultTierReview - как часто пересматривается уровень пользователя
+	DefaultTierReview = 24 * time.Hour
+)
+
+// tierBatchSize - сколько пользователей пересматривает один запуск задачи
+const tierBatchSize = 500
+
+// ParseTiers разбирает уровни "bronze:0:1,silver:1000:1.1". Пустая строка - уровни выключены.
+// Пороги строго возрастают и начинаются с нуля, множитель положительный
+func ParseTiers(spec string) ([]models.Tier, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	var tiers []models.Tier
+	for _, part := range strings.Split(spec, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ":")
+		if len(fields) != 3 || fields[0] == "" {
+			return nil, fmt.Errorf("tier %q: want name:threshold:multiplier", part)
+		}
+		threshold, err := money.Parse(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("tier %s threshold: %w", fields[0], err)
+		}
+		multiplier, err := money.Parse(fields[2])
+		if err != nil || multiplier <= 0 {
+			return nil, fmt.Errorf("tier %s: multiplier must be a positive number with at most two decimals", fields[0])
+		}
+		if len(tiers) == 0 && threshold != 0 {
+			return nil, fmt.Errorf("tier %s: the first tier must start at 0", fields[0])
+		}
+		if len(tiers) > 0 && threshold <= tiers[len(tiers)-1].Threshold {
+			return nil, fmt.Errorf("tier %s: thresholds must increase", fields[0])
+		}
+		tiers = append(tiers, models.Tier{Name: fields[0], Threshold: threshold, Multiplier: multiplier})
+	}
+	return tiers, nil
+}
+
+// WithTierPolicy включает уровни программы лояльности
+func WithTierPolicy(p models.TierPolicy) Option {
+	return func(s *GofemartService) {
+		if p.Window <= 0 {
+			p.Window = DefaultTierWindow
+		}
+		if p.Review <= 0 {
+			p.Review = DefaultTierReview
+		}
+		s.tiers = p
+	}
+}
+
+// Tier - текущий уровень пользователя, прогресс до следующего и дата пересмотра.
+// Уровень, который ещё не считался, считается сразу
+func (s *GofemartService) Tier(userID int) (models.TierStatus, error) {
+	if !s.tiers.Enabled() {
+		return models.TierStatus{}, ErrTiersDisabled
+	}
+
+	ut, err := s.repo.UserTier(userID)
+	if err != nil {
+		return models.TierStatus{}, err
+	}
+	if ut == nil {
+		evaluated, err := s.evaluateTier(userID, time.Now())
+		if err != nil {
+			return models.TierStatus{}, err
+		}
+		ut = &evaluated
+	}
+
+	tier := s.tiers.ByName(ut.Tier)
+	status := models.TierStatus{
+		Tier:             tier.Name,
+		Multiplier:       tier.Multiplier,
+		Earned:           ut.Earned,
+		EvaluatedAt:      ut.EvaluatedAt,
+		NextEvaluationAt: ut.NextEvaluationAt,
+	}
+	// прогресс - по сумме на момент пересмотра, от порога текущего уровня
+	if _, next := s.tiers.ForEarned(ut.Earned); next != nil {
+		progress := &models.TierProgress{
+			Tier:      next.Name,
+			Threshold: next.Threshold,
+			Remaining: max(next.Threshold-ut.Earned, 0),
+		}
+		if span := next.Threshold - tier.Threshold; span > 0 {
+			done := min(max(ut.Earned-tier.Threshold, 0), span)
+			progress.Percent = int(done.MulDiv(100, int64(span), money.Down))
+		}
+		status.Next = progress
+	}
+	return status, nil
+}
+
+func (s *GofemartService) evaluateTier(userID int, now time.Time) (models.UserTier, error) {
+	earned, err := s.repo.TierEarned(userID, now.Add(-s.tiers.Window))
+	if err != nil {
+		return models.UserTier{}, err
+	}
+	tier, _ := s.tiers.ForEarned(earned)
+	ut := models.UserTier{
+		UserID:           userID,
+		Tier:             tier.Name,
+		Earned:           earned,
+		EvaluatedAt:      now,
+		NextEvaluationAt: now.Add(s.tiers.Review),
+	}
+	if err := s.repo.SaveUserTier(ut); err != nil {
+		return models.UserTier{}, err
+	}
+	return ut, nil
+}
+
+func (s *GofemartService) evaluateTiers() error {
+	now := time.Now()
+	userIDs, err := s.repo.TiersDue(now, tierBatchSize)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, userID := range userIDs {
+		if _, err := s.evaluateTier(userID, now); err != nil {
+			errs = append(errs, fmt.Errorf("user %d: %w", userID, err))
+		}
+	}
+	if len(userIDs) > 0 {
+		castomLogger.Infof("re-evaluated tiers of %d users", len(userIDs)-len(errs))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/pkg/jwt"
+)
+
+const (
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// TokenSettings - настройки режима токенов (для мобильных клиентов)
+type TokenSettings struct {
+	// секрет подписи access-токенов (HS256)
+	Secret     []byte
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+// DefaultTokenSettings - случайный секрет на время жизни процесса:
+// после рестарта access-токены недействительны, клиенты обновляют их по refresh-токену
+func DefaultTokenSettings() TokenSettings {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic("failed to generate token secret: " + err.Error())
+	}
+	return TokenSettings{
+		Secret:     secret,
+		AccessTTL:  DefaultAccessTokenTTL,
+		RefreshTTL: DefaultRefreshTokenTTL,
+	}
+}
+
+// WithTokenSettings задаёт секрет и сроки жизни токенов, пустые поля берутся по умолчанию
+func WithTokenSettings(settings TokenSettings) Option {
+	return func(s *GofemartService) {
+		if len(settings.Secret) > 0 {
+			s.tokens.Secret = settings.Secret
+		}
+		if settings.AccessTTL > 0 {
+			s.tokens.AccessTTL = settings.AccessTTL
+		}
+		if settings.RefreshTTL > 0 {
+			s.tokens.RefreshTTL = settings.RefreshTTL
+		}
+	}
+}
+
+// IssueTokens - выдача пары access/refresh после входа или регистрации
+func (s *GofemartService) IssueTokens(userID int) (models.TokenPair, error) {
+	refresh, refreshHash, err := newOpaqueToken()
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	if err := s.repo.CreateRefreshToken(userID, refreshHash, time.Now().Add(s.tokens.RefreshTTL)); err != nil {
+		return models.TokenPair{}, err
+	}
+
+	return s.tokenPair(userID, refresh)
+}
+
+// RefreshTokens - обмен refresh-токена на новую пару, старый refresh-токен отзывается
+func (s *GofemartService) RefreshTokens(refreshToken string) (models.TokenPair, error) {
+	if refreshToken == "" {
+		return models.TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	refresh, refreshHash, err := newOpaqueToken()
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	userID, err := s.repo.RotateRefreshToken(HashToken(refreshToken), refreshHash, time.Now().Add(s.tokens.RefreshTTL))
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	return s.tokenPair(userID, refresh)
+}
+
+// RevokeRefreshToken - отзыв refresh-токена
+func (s *GofemartService) RevokeRefreshToken(refreshToken string) error {
+	if refreshToken == "" {
+		return ErrInvalidRefreshToken
+	}
+	return s.repo.RevokeRefreshToken(HashToken(refreshToken))
+}
+
+// ParseAccessToken проверяет подпись и срок access-токена и возвращает ID пользователя
+func (s *GofemartService) ParseAccessToken(token string) (int, error) {
+	claims, err := jwt.Parse(token, s.tokens.Secret, time.Now())
+	if err != nil {
+		return 0, ErrInvalidAccessToken
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil || userID <= 0 {
+		return 0, ErrInvalidAccessToken
+	}
+	return userID, nil
+}
+
+func (s *GofemartService) tokenPair(userID int, refresh string) (models.TokenPair, error) {
+	now := time.Now()
+	access, err := jwt.Sign(jwt.Claims{
+		Subject:   strconv.Itoa(userID),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(s.tokens.AccessTTL).Unix(),
+	}, s.tokens.Secret)
+	if err != nil {
+		return models.TokenPair{}, fmt.Errorf("failed to sign access token: %w", err)
+	}
+
+	return models.TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.tokens.AccessTTL.Seconds()),
+	}, nil
+}
+
+// HashToken - в базе храним только SHA-256 от непрозрачного токена
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newOpaqueToken() (token, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashToken(token), nil
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/pkg/money"
+	"go-musthave-diploma-tpl/pkg/totp"
+)
+
+const (
+	// recoveryCodeCount - сколько кодов восстановления выдаётся при включении 2FA
+	recoveryCodeCount = 10
+	// loginChallengeTTL - сколько ждём второй фактор после верного пароля
+	loginChallengeTTL = 5 * time.Minute
+	// loginChallengeAttempts - попыток ввести код на один вход
+	loginChallengeAttempts = 5
+)
+
+// TwoFactorSettings - настройки двухфакторной аутентификации
+type TwoFactorSettings struct {
+	// издатель в otpauth-ссылке, его показывает приложение-аутентификатор
+	Issuer string
+	// списания больше этой суммы требуют свежий TOTP-код (если 2FA включена), 0 - всегда
+	WithdrawThreshold money.Amount
+}
+
+func DefaultTwoFactorSettings() TwoFactorSettings {
+	return TwoFactorSettings{
+		Issuer:            "Gophermart",
+		WithdrawThreshold: money.FromUnits(1000),
+	}
+}
+
+// WithTwoFactorSettings задаёт настройки 2FA
+func WithTwoFactorSettings(settings TwoFactorSettings) Option {
+	return func(s *GofemartService) {
+		if settings.Issuer == "" {
+			settings.Issuer = DefaultTwoFactorSettings().Issuer
+		}
+		s.twoFactor = settings
+	}
+}
+
+// SetupTOTP выпускает новый секрет; 2FA включится только после ConfirmTOTP
+func (s *GofemartService) SetupTOTP(userID int) (models.TOTPSetupResponse, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return models.TOTPSetupResponse{}, err
+	}
+	if user == nil {
+		return models.TOTPSetupResponse{}, fmt.Errorf("user not found id=%d", userID)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return models.TOTPSetupResponse{}, err
+	}
+	if err := s.repo.SaveTOTPSecret(userID, secret); err != nil {
+		return models.TOTPSetupResponse{}, err
+	}
+
+	return models.TOTPSetupResponse{
+		Secret: secret,
+		URI:    totp.URI(s.twoFactor.Issuer, user.Login, secret),
+	}, nil
+}
+
+// ConfirmTOTP включает 2FA по первому коду и возвращает коды восстановления.
+// Коды показываются один раз, в базе хранятся только хэши
+func (s *GofemartService) ConfirmTOTP(userID int, code string) ([]string, error) {
+	t, err := s.repo.GetTOTP(userID)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, ErrTOTPNotSetUp
+	}
+	if t.Enabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	counter, ok := totp.Validate(t.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTOTPCode
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		codes[i], err = newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		hashes[i] = HashToken(normalizeRecoveryCode(codes[i]))
+	}
+
+	if err := s.repo.EnableTOTP(userID, counter, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// TwoFactorEnabled - включена ли у пользователя 2FA
+func (s *GofemartService) TwoFactorEnabled(userID int) (bool, error) {
+	t, err := s.repo.GetTOTP(userID)
+	if err != nil {
+		return false, err
+	}
+	return t != nil && t.Enabled, nil
+}
+
+// verifySecondFactor принимает TOTP-код (каждый шаг - один раз),
+// а при allowRecovery ещё и код восстановления
+func (s *GofemartService) verifySecondFactor(t *models.TOTP, code string, allowRecovery bool) error {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return ErrTOTPRequired
+	}
+
+	if len(code) == totp.Digits {
+		counter, ok := totp.Validate(t.Secret, code, time.Now())
+		if !ok {
+			return ErrInvalidTOTPCode
+		}
+		fresh, err := s.repo.UseTOTPCounter(t.UserID, counter)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return ErrInvalidTOTPCode
+		}
+		return nil
+	}
+	if !allowRecovery {
+		return ErrInvalidTOTPCode
+	}
+
+	used, err := s.repo.UseRecoveryCode(t.UserID, HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTOTPCode
+	}
+	return nil
+}
+
+// StartLoginChallenge вызывается после верного пароля. Если 2FA выключена - возвращает nil,
+// иначе заводит незавершённый вход, который закрывает CompleteLoginChallenge
+func (s *GofemartService) StartLoginChallenge(userID int) (*models.LoginChallenge, error) {
+	enabled, err := s.TwoFactorEnabled(userID)
+	if err != nil || !enabled {
+		return nil, err
+	}
+
+	token, hash, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(loginChallengeTTL)
+	if err := s.repo.CreateLoginChallenge(userID, hash, expiresAt); err != nil {
+		return nil, err
+	}
+
+	return &models.LoginChallenge{
+		Status:    models.LoginChallengeStatusTOTP,
+		Challenge: token,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// LoginChallengeLogin - логин владельца незавершённого входа: коды второго шага учитываются
+// тем же ограничением попыток по логину и адресу, что и пароль
+func (s *GofemartService) LoginChallengeLogin(challenge string) (string, error) {
+	userID, err := s.repo.LoginChallengeUser(HashToken(challenge), loginChallengeAttempts)
+	if err != nil {
+		return "", err
+	}
+	if userID == 0 {
+		return "", ErrInvalidLoginChallenge
+	}
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return "", err
+	}
+	if user == nil {
+		return "", ErrInvalidLoginChallenge
+	}
+	return user.Login, nil
+}
+
+// CompleteLoginChallenge проверяет второй фактор и возвращает пользователя, вход которого завершён
+func (s *GofemartService) CompleteLoginChallenge(challenge, code string) (int, error) {
+	hash := HashToken(challenge)
+	userID, err := s.repo.LoginChallengeUser(hash, loginChallengeAttempts)
+	if err != nil {
+		return 0, err
+	}
+	if userID == 0 {
+		return 0, ErrInvalidLoginChallenge
+	}
+
+	t, err := s.repo.GetTOTP(userID)
+	if err != nil {
+		return 0, err
+	}
+	if t == nil || !t.Enabled {
+		return 0, ErrInvalidLoginChallenge
+	}
+
+	if err := s.verifySecondFactor(t, code, true); err != nil {
+		if err == ErrInvalidTOTPCode || err == ErrTOTPRequired {
+			if ferr := s.repo.FailLoginChallenge(hash); ferr != nil {
+				return 0, ferr
+			}
+		}
+		return 0, err
+	}
+
+	completed, err := s.repo.CompleteLoginChallenge(hash)
+	if err != nil {
+		return 0, err
+	}
+	if !completed {
+		return 0, ErrInvalidLoginChallenge
+	}
+	return userID, nil
+}
+
+// CheckWithdrawSecondFactor требует свежий TOTP-код для крупных списаний, если у пользователя включена 2FA
+func (s *GofemartService) CheckWithdrawSecondFactor(userID int, sum money.Amount, code string) error {
+	if sum <= s.twoFactor.WithdrawThreshold {
+		return nil
+	}
+
+	t, err := s.repo.GetTOTP(userID)
+	if err != nil {
+		return err
+	}
+	if t == nil || !t.Enabled {
+		return nil
+	}
+	// код восстановления здесь не подходит: он для входа без телефона, а не для платежей
+	return s.verifySecondFactor(t, code, false)
+}
+
+// newRecoveryCode - 10 символов base32 в виде xxxxx-xxxxx
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/pkg/money"
+)
+
+// DefaultTransferLimits - суточные лимиты переводов одного отправителя
+func DefaultTransferLimits() models.TransferLimits {
+	return models.TransferLimits{
+		DailyAmount: money.FromUnits(10000),
+		DailyCount:  10,
+	}
+}
+
+// WithTransferLimits задаёт суточные лимиты переводов, ноль снимает ограничение
+func WithTransferLimits(limits models.TransferLimits) Option {
+	return func(s *GofemartService) {
+		if limits.DailyAmount >= 0 && limits.DailyCount >= 0 {
+			s.transferLimits = limits
+		}
+	}
+}
+
+// Transfer переводит баллы другому пользователю по логину
+func (s *GofemartService) Transfer(userID int, req models.TransferRequest, idempotencyKey string) (models.Transfer, error) {
+	if req.Amount <= 0 {
+		return models.Transfer{}, ErrInvalidAmount
+	}
+	to := strings.TrimSpace(req.To)
+	if to == "" {
+		return models.Transfer{}, ErrRecipientNotFound
+	}
+	note := strings.TrimSpace(req.Note)
+	if utf8.RuneCountInString(note) > models.TransferNoteMaxLen {
+		return models.Transfer{}, ErrTransferNoteTooLong
+	}
+	return s.repo.Transfer(models.Transfer{
+		FromUserID:     userID,
+		Counterparty:   to,
+		Amount:         req.Amount,
+		Note:           note,
+		IdempotencyKey: idempotencyKey,
+	}, s.transferLimits)
+}
+
+func (s *GofemartService) Transfers(userID int) ([]models.Transfer, error) {
+	return s.repo.Transfers(userID)
+}
@@ -0,0 +1,34 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+)
+
+// CSV - выписка таблицей: строка остатка на начало, движения и строка остатка на конец
+type CSV struct {
+	w  *csv.Writer
+	to string
+}
+
+func NewCSV(w io.Writer) *CSV {
+	return &CSV{w: csv.NewWriter(w)}
+}
+
+func (c *CSV) Begin(h models.StatementHeader) error {
+	c.to = formatTime(h.To)
+	c.w.Write([]string{"date", "type", "order", "amount", "balance"})
+	return c.w.Write([]string{formatTime(h.From), "opening", "", "", h.Opening.String()})
+}
+
+func (c *CSV) Line(l models.StatementLine) error {
+	return c.w.Write([]string{formatTime(l.Date), l.Type, l.Order, l.Amount.String(), l.Balance.String()})
+}
+
+func (c *CSV) End(s models.StatementSummary) error {
+	c.w.Write([]string{c.to, "closing", "", "", s.Closing.String()})
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,50 @@
+package statement
+
+import (
+	"fmt"
+	"io"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/pkg/pdf"
+)
+
+// колонки таблицы моноширинным шрифтом: дата, вид, заказ, сумма, остаток
+const (
+	pdfRow   = "%-20s  %-10s  %-20s  %12s  %12s"
+	pdfTotal = "%-68s  %12s"
+)
+
+// PDF - выписка документом A4
+type PDF struct {
+	doc *pdf.Writer
+}
+
+func NewPDF(w io.Writer) *PDF {
+	return &PDF{doc: pdf.New(w)}
+}
+
+func (p *PDF) Begin(h models.StatementHeader) error {
+	p.doc.Bold("Account statement")
+	p.doc.Line("Login:   " + h.Login)
+	p.doc.Line("Period:  " + formatTime(h.From) + " - " + formatTime(h.To))
+	p.doc.Space()
+	p.doc.Line(fmt.Sprintf(pdfTotal, "Opening balance", h.Opening))
+	p.doc.Space()
+	p.doc.Line(fmt.Sprintf(pdfRow, "Date", "Type", "Order", "Amount", "Balance"))
+	return nil
+}
+
+func (p *PDF) Line(l models.StatementLine) error {
+	p.doc.Line(fmt.Sprintf(pdfRow, formatTime(l.Date), l.Type, l.Order, l.Amount, l.Balance))
+	return p.doc.Err()
+}
+
+func (p *PDF) End(s models.StatementSummary) error {
+	p.doc.Space()
+	p.doc.Line(fmt.Sprintf(pdfTotal, "Accrued", s.Accrued))
+	p.doc.Line(fmt.Sprintf(pdfTotal, "Withdrawn", s.Withdrawn))
+	p.doc.Line(fmt.Sprintf(pdfTotal, "Refunded", s.Refunded))
+	p.doc.Line(fmt.Sprintf(pdfTotal, "Other (transfers, bonuses, adjustments, expiry)", s.Other))
+	p.doc.Line(fmt.Sprintf(pdfTotal, "Closing balance", s.Closing))
+	return p.doc.Close()
+}
@@ -0,0 +1,35 @@
+// Package statement - выписка по счёту в CSV и PDF. Оба формата пишут в io.Writer по мере
+// поступления строк, поэтому выписка за большой период не собирается в памяти
+package statement
+
+import (
+	"io"
+	"time"
+
+	"go-musthave-diploma-tpl/internal/gophermart/models"
+	"go-musthave-diploma-tpl/internal/gophermart/service"
+)
+
+// New - writer выписки в формате format (models.StatementFormatCSV или models.StatementFormatPDF)
+func New(format string, w io.Writer) (service.StatementWriter, error) {
+	switch format {
+	case models.StatementFormatCSV:
+		return NewCSV(w), nil
+	case models.StatementFormatPDF:
+		return NewPDF(w), nil
+	}
+	return nil, service.ErrInvalidStatementFormat
+}
+
+// ContentType - MIME-тип формата
+func ContentType(format string) string {
+	if format == models.StatementFormatPDF {
+		return "application/pdf"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// даты в выписке - в UTC, чтобы файл не зависел от часового пояса сервера
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,64 @@
+package jwt
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var secret = []byte("test-secret")
+
+func TestSignAndParse(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	claims := Claims{Subject: "42", IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix()}
+
+	token, err := Sign(claims, secret)
+	require.NoError(t, err)
+	assert.Len(t, strings.Split(token, "."), 3)
+
+	parsed, err := Parse(token, secret, now)
+	require.NoError(t, err)
+	assert.Equal(t, claims, parsed)
+}
+
+func TestParse_Errors(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	token, err := Sign(Claims{Subject: "42", IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix()}, secret)
+	require.NoError(t, err)
+
+	t.Run("Wrong secret", func(t *testing.T) {
+		_, err := Parse(token, []byte("other"), now)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		_, err := Parse(token, secret, now.Add(time.Minute))
+		assert.ErrorIs(t, err, ErrTokenExpired)
+	})
+
+	t.Run("Tampered payload", func(t *testing.T) {
+		other, err := Sign(Claims{Subject: "1", ExpiresAt: now.Add(time.Hour).Unix()}, []byte("other"))
+		require.NoError(t, err)
+
+		parts := strings.Split(token, ".")
+		otherParts := strings.Split(other, ".")
+		_, err = Parse(parts[0]+"."+otherParts[1]+"."+parts[2], secret, now)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		_, err := Parse("not-a-token", secret, now)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Without expiration", func(t *testing.T) {
+		noExp, err := Sign(Claims{Subject: "42"}, secret)
+		require.NoError(t, err)
+
+		_, err = Parse(noExp, secret, now)
+		assert.ErrorIs(t, err, ErrTokenExpired)
+	})
+}
@@ -0,0 +1,347 @@
+// Package money - суммы баллов с фиксированной точкой до сотых, как NUMERIC(…, 2) в базе.
+// Хранятся целым числом сотых, поэтому сложение и сравнение точные
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidAmount = errors.New("invalid amount")
+	ErrPrecision     = errors.New("amount has more than two decimal places")
+	ErrOverflow      = errors.New("amount out of range")
+)
+
+// Scale - знаков после запятой
+const Scale = 2
+
+const centsPerUnit = 100
+
+// Rounding - правило округления до сотых
+type Rounding int
+
+const (
+	// HalfEven - половина к чётному (банковское), не копит смещение на больших суммах
+	HalfEven Rounding = iota
+	// HalfUp - половина от нуля, как round() в PostgreSQL
+	HalfUp
+	// Down - отбросить лишнее (к нулю)
+	Down
+)
+
+// Amount - сумма в сотых долях балла
+type Amount int64
+
+// Zero - нулевая сумма
+const Zero Amount = 0
+
+// FromCents - сумма из сотых
+func FromCents(cents int64) Amount {
+	return Amount(cents)
+}
+
+// FromUnits - сумма из целых баллов
+func FromUnits(units int64) Amount {
+	return Amount(units * centsPerUnit)
+}
+
+// FromFloat округляет число с плавающей точкой до сотых. Нужна только на границе
+// с источниками, которые отдают float (флаги, старые колонки FLOAT)
+func FromFloat(f float64, mode Rounding) (Amount, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, ErrInvalidAmount
+	}
+	// через кратчайшую десятичную запись: 0.1+0.2 даёт 0.30000000000000004, а не 0.3 с хвостом двоичной ошибки
+	return ParseRound(strconv.FormatFloat(f, 'f', -1, 64), mode)
+}
+
+// Parse разбирает десятичную запись ("12", "-0.5", "100.25"). Больше двух знаков после запятой - ErrPrecision
+func Parse(s string) (Amount, error) {
+	return parse(s, nil)
+}
+
+// ParseRound разбирает десятичную запись, округляя лишние знаки по правилу mode
+func ParseRound(s string, mode Rounding) (Amount, error) {
+	return parse(s, &mode)
+}
+
+func parse(s string, mode *Rounding) (Amount, error) {
+	s = strings.TrimSpace(s)
+	neg := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg, s = true, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	// экспоненту пишут только float-форматтеры, а 1e400 не влезет в сумму
+	if mantissa, exp, ok := strings.Cut(strings.ToLower(s), "e"); ok {
+		shift, err := strconv.Atoi(exp)
+		if err != nil || shift > 20 || shift < -20 {
+			return 0, ErrInvalidAmount
+		}
+		s = shiftPoint(mantissa, shift)
+	}
+
+	intPart, frac, _ := strings.Cut(s, ".")
+	if intPart == "" && frac == "" || !digits(intPart) || !digits(frac) {
+		return 0, ErrInvalidAmount
+	}
+
+	rest := ""
+	if len(frac) > Scale {
+		frac, rest = frac[:Scale], frac[Scale:]
+	}
+	frac += strings.Repeat("0", Scale-len(frac))
+
+	intPart = strings.TrimLeft(intPart, "0")
+	if len(intPart) > 16 {
+		return 0, ErrOverflow
+	}
+	cents, err := strconv.ParseInt(intPart+frac, 10, 64)
+	if err != nil {
+		return 0, ErrOverflow
+	}
+
+	if strings.Trim(rest, "0") != "" {
+		if mode == nil {
+			return 0, ErrPrecision
+		}
+		cents = roundDigits(cents, rest, *mode)
+	}
+
+	if neg {
+		cents = -cents
+	}
+	return Amount(cents), nil
+}
+
+// roundDigits - округление целых сотых по отброшенным цифрам rest (по модулю)
+func roundDigits(cents int64, rest string, mode Rounding) int64 {
+	switch mode {
+	case Down:
+		return cents
+	case HalfUp:
+		if rest[0] >= '5' {
+			return cents + 1
+		}
+		return cents
+	default:
+		half := "5" + strings.Repeat("0", len(rest)-1)
+		switch {
+		case rest > half:
+			return cents + 1
+		case rest == half && cents%2 == 1:
+			return cents + 1
+		}
+		return cents
+	}
+}
+
+func shiftPoint(s string, shift int) string {
+	intPart, frac, _ := strings.Cut(s, ".")
+	all := intPart + frac
+	point := len(intPart) + shift
+	switch {
+	case point <= 0:
+		return "0." + strings.Repeat("0", -point) + all
+	case point >= len(all):
+		return all + strings.Repeat("0", point-len(all))
+	}
+	return all[:point] + "." + all[point:]
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Cents - сумма в сотых
+func (a Amount) Cents() int64 {
+	return int64(a)
+}
+
+// Float64 - приближённое значение, только для метрик и логов
+func (a Amount) Float64() float64 {
+	return float64(a) / centsPerUnit
+}
+
+func (a Amount) IsZero() bool {
+	return a == 0
+}
+
+func (a Amount) Abs() Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// Percent - percent процентов от суммы, округлённые до сотых по правилу mode.
+// Считается в целых числах без промежуточного округления
+func (a Amount) Percent(percent Amount, mode Rounding) Amount {
+	return a.MulDiv(int64(percent), 100*centsPerUnit, mode)
+}
+
+// MulDiv - a * num / den с одним округлением в конце
+func (a Amount) MulDiv(num, den int64, mode Rounding) Amount {
+	if den == 0 {
+		panic("money: division by zero")
+	}
+	n := new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(num))
+	d := big.NewInt(den)
+	if d.Sign() < 0 {
+		n.Neg(n)
+		d.Neg(d)
+	}
+
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	if r.Sign() != 0 && mode != Down {
+		// сравниваем удвоенный остаток с делителем
+		twice := new(big.Int).Abs(r)
+		twice.Lsh(twice, 1)
+		cmp := twice.Cmp(d)
+		if cmp > 0 || cmp == 0 && (mode == HalfUp || q.Bit(0) == 1) {
+			if n.Sign() < 0 {
+				q.Sub(q, big.NewInt(1))
+			} else {
+				q.Add(q, big.NewInt(1))
+			}
+		}
+	}
+	return Amount(q.Int64())
+}
+
+// String - запись с двумя знаками после запятой: "12.50", "-0.05"
+func (a Amount) String() string {
+	sign := ""
+	cents := int64(a)
+	if cents < 0 {
+		sign = "-"
+	}
+	u := uint64(cents)
+	if cents < 0 {
+		u = uint64(-cents)
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, u/centsPerUnit, u%centsPerUnit)
+}
+
+// MarshalJSON пишет число без лишних нулей: 500.5, 42, 0.05
+func (a Amount) MarshalJSON() ([]byte, error) {
+	s := a.String()
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimSuffix(s, ".")
+	return []byte(s), nil
+}
+
+// UnmarshalJSON принимает число или строку с числом. Больше двух знаков после запятой - ошибка,
+// чтобы списание 0.001 не превратилось молча в 0
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	v, err := unmarshalJSON(data, nil)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// UnmarshalJSONRound - как UnmarshalJSON, но лишние знаки округляются по правилу mode.
+// Для внешних данных (цены товаров, вознаграждения), где 47399.999 - не ошибка клиента. null и пустое значение - ноль
+func UnmarshalJSONRound(data []byte, mode Rounding) (Amount, error) {
+	// пустое значение - поле не пришло
+	if len(data) == 0 || string(data) == "null" {
+		return Zero, nil
+	}
+	return unmarshalJSON(data, &mode)
+}
+
+func unmarshalJSON(data []byte, mode *Rounding) (Amount, error) {
+	s := string(data)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	v, err := parse(s, mode)
+	if err != nil {
+		return 0, fmt.Errorf("money: %q: %w", s, err)
+	}
+	return v, nil
+}
+
+// Value - строка, чтобы NUMERIC получил точное значение
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// Scan читает NUMERIC (строкой), целые и FLOAT колонки. NULL - ноль
+func (a *Amount) Scan(src interface{}) error {
+	var (
+		v   Amount
+		err error
+	)
+	switch src := src.(type) {
+	case nil:
+	case []byte:
+		v, err = ParseRound(string(src), HalfEven)
+	case string:
+		v, err = ParseRound(src, HalfEven)
+	case int64:
+		v = FromUnits(src)
+	case float64:
+		v, err = FromFloat(src, HalfEven)
+	default:
+		err = fmt.Errorf("money: cannot scan %T", src)
+	}
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// Set - для flag.Var
+func (a *Amount) Set(s string) error {
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// partsPerCent - точность Accumulator: миллионные доли балла
+const partsPerCent = 10000
+
+// Accumulator копит точную сумму вместе с долями сотых от процентов
+// и округляет один раз, в Total. Нулевое значение готово к работе
+type Accumulator struct {
+	parts int64
+}
+
+func (s *Accumulator) Add(a Amount) {
+	s.parts += int64(a) * partsPerCent
+}
+
+// AddPercent добавляет percent процентов от base без округления
+func (s *Accumulator) AddPercent(base, percent Amount) {
+	// сотые * сотые процента = миллионные доли балла
+	s.parts += int64(base) * int64(percent)
+}
+
+// Total - сумма, округлённая до сотых по правилу mode
+func (s Accumulator) Total(mode Rounding) Amount {
+	return Amount(s.parts).MulDiv(1, partsPerCent, mode)
+}
@@ -0,0 +1,164 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Amount
+		err  error
+	}{
+		{"0", 0, nil},
+		{"12", 1200, nil},
+		{"12.5", 1250, nil},
+		{"-0.05", -5, nil},
+		{".5", 50, nil},
+		{"751.00", 75100, nil},
+		{"1e3", 100000, nil},
+		{"0.001", 0, ErrPrecision},
+		{"1.230", 123, nil},
+		{"", 0, ErrInvalidAmount},
+		{"1,5", 0, ErrInvalidAmount},
+		{"12345678901234567890", 0, ErrOverflow},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if tt.err != nil {
+			assert.ErrorIs(t, err, tt.err, tt.in)
+			continue
+		}
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+}
+
+func TestParseRound(t *testing.T) {
+	tests := []struct {
+		in       string
+		halfEven Amount
+		halfUp   Amount
+		down     Amount
+	}{
+		{"0.125", 12, 13, 12},
+		{"0.135", 14, 14, 13},
+		{"0.1251", 13, 13, 12},
+		{"-0.125", -12, -13, -12},
+		{"2.999", 300, 300, 299},
+	}
+	for _, tt := range tests {
+		for mode, want := range map[Rounding]Amount{HalfEven: tt.halfEven, HalfUp: tt.halfUp, Down: tt.down} {
+			got, err := ParseRound(tt.in, mode)
+			require.NoError(t, err)
+			assert.Equal(t, want, got, "%s mode %d", tt.in, mode)
+		}
+	}
+}
+
+func TestSumDoesNotDrift(t *testing.T) {
+	// 0.1 тысячу раз: во float64 получается 99.9999999999986
+	var total Amount
+	step, err := Parse("0.1")
+	require.NoError(t, err)
+	for i := 0; i < 1000; i++ {
+		total += step
+	}
+	assert.Equal(t, FromUnits(100), total)
+
+	f, err := FromFloat(0.1+0.2, HalfEven)
+	require.NoError(t, err)
+	assert.Equal(t, FromCents(30), f)
+}
+
+func TestPercent(t *testing.T) {
+	price := FromCents(47399_99)
+
+	assert.Equal(t, FromCents(3555_00), price.Percent(FromCents(7_50), HalfEven))
+	// 0.125 * 100% -> граница половины
+	assert.Equal(t, FromCents(12), FromCents(25).Percent(FromUnits(50), HalfEven))
+	assert.Equal(t, FromCents(13), FromCents(25).Percent(FromUnits(50), HalfUp))
+	assert.Equal(t, FromCents(-13), FromCents(-25).Percent(FromUnits(50), HalfUp))
+	assert.Equal(t, FromCents(2), FromCents(5).MulDiv(1, 2, HalfEven))
+	assert.Equal(t, FromCents(4), FromCents(7).MulDiv(1, 2, HalfEven))
+}
+
+func TestJSON(t *testing.T) {
+	var v struct {
+		Sum     Amount `json:"sum"`
+		Accrual Amount `json:"accrual,omitempty"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"sum": 751.5}`), &v))
+	assert.Equal(t, FromCents(75150), v.Sum)
+
+	out, err := json.Marshal(v)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"sum": 751.5}`, string(out))
+
+	require.NoError(t, json.Unmarshal([]byte(`{"sum": "42"}`), &v))
+	assert.Equal(t, FromUnits(42), v.Sum)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"sum": 0.001}`), &v))
+	assert.Error(t, json.Unmarshal([]byte(`{"sum": true}`), &v))
+}
+
+func TestUnmarshalJSONRound(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want Amount
+	}{
+		{`47399.999`, FromCents(4740000)},
+		{`"0.125"`, FromCents(12)},
+		{`0.135`, FromCents(14)},
+		{`12`, FromUnits(12)},
+		{`null`, Zero},
+	} {
+		got, err := UnmarshalJSONRound([]byte(tt.in), HalfEven)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+
+	_, err := UnmarshalJSONRound([]byte(`"abc"`), HalfEven)
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+}
+
+func TestSQL(t *testing.T) {
+	value, err := FromCents(-5).Value()
+	require.NoError(t, err)
+	assert.Equal(t, "-0.05", value)
+
+	var a Amount
+	for _, tt := range []struct {
+		src  interface{}
+		want Amount
+	}{
+		{[]byte("500.50"), FromCents(50050)},
+		{"0.00", 0},
+		{int64(3), FromUnits(3)},
+		{float64(0.3), FromCents(30)},
+	} {
+		require.NoError(t, a.Scan(tt.src))
+		assert.Equal(t, tt.want, a)
+	}
+
+	require.NoError(t, a.Scan(nil))
+	assert.Equal(t, Zero, a)
+	assert.Error(t, a.Scan(true))
+}
+
+func TestAccumulator(t *testing.T) {
+	var sum Accumulator
+	// по 16.5% от 1.00 трижды: 0.495 без промежуточных округлений
+	for i := 0; i < 3; i++ {
+		sum.AddPercent(FromUnits(1), FromCents(1650))
+	}
+	assert.Equal(t, FromCents(50), sum.Total(HalfEven))
+	assert.Equal(t, FromCents(49), sum.Total(Down))
+
+	sum.Add(FromCents(1))
+	assert.Equal(t, FromCents(51), sum.Total(HalfUp))
+}
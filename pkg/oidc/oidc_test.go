@@ -0,0 +1,125 @@
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"go-musthave-diploma-tpl/pkg/oidc/oidctest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const redirectURL = "http://localhost:8080/api/user/oidc/callback"
+
+func newProvider(t *testing.T, stub *oidctest.Server) *Provider {
+	t.Helper()
+
+	p, err := NewProvider(context.Background(), Config{
+		IssuerURL:    stub.URL,
+		ClientID:     stub.ClientID,
+		ClientSecret: stub.ClientSecret,
+		RedirectURL:  redirectURL,
+	}, stub.Client())
+	require.NoError(t, err)
+	return p
+}
+
+// authorize проходит страницу входа провайдера и возвращает код из редиректа
+func authorize(t *testing.T, authURL string) url.Values {
+	t.Helper()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return location.Query()
+}
+
+func TestProvider_CodeFlowWithPKCE(t *testing.T) {
+	stub := oidctest.NewServer("gophermart", "secret")
+	defer stub.Close()
+	p := newProvider(t, stub)
+
+	verifier, err := NewVerifier()
+	require.NoError(t, err)
+
+	callback := authorize(t, p.AuthCodeURL("state-1", "nonce-1", S256Challenge(verifier)))
+	assert.Equal(t, "state-1", callback.Get("state"))
+
+	// без верного code_verifier код не обменять
+	_, err = p.Exchange(context.Background(), callback.Get("code"), "wrong-verifier")
+	assert.Error(t, err)
+
+	callback = authorize(t, p.AuthCodeURL("state-2", "nonce-2", S256Challenge(verifier)))
+	rawIDToken, err := p.Exchange(context.Background(), callback.Get("code"), verifier)
+	require.NoError(t, err)
+
+	claims, err := p.Verify(context.Background(), rawIDToken, "nonce-2", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "subject-1", claims.Subject)
+	assert.Equal(t, "user@example.com", claims.Email)
+	assert.Equal(t, stub.URL, claims.Issuer)
+
+	_, err = p.Verify(context.Background(), rawIDToken, "other-nonce", time.Now())
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+}
+
+func TestProvider_VerifyRejects(t *testing.T) {
+	stub := oidctest.NewServer("gophermart", "")
+	defer stub.Close()
+	p := newProvider(t, stub)
+
+	now := time.Now()
+	valid := map[string]interface{}{
+		"iss": stub.URL, "sub": "subject-1", "aud": []string{"gophermart", "other"},
+		"exp": now.Add(time.Minute).Unix(), "nonce": "n",
+	}
+	with := func(key string, value interface{}) map[string]interface{} {
+		claims := make(map[string]interface{}, len(valid))
+		for k, v := range valid {
+			claims[k] = v
+		}
+		claims[key] = value
+		return claims
+	}
+
+	_, err := p.Verify(context.Background(), stub.SignIDToken(valid), "n", now)
+	assert.NoError(t, err)
+
+	_, err = p.Verify(context.Background(), stub.SignIDToken(with("aud", "other-client")), "n", now)
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+
+	_, err = p.Verify(context.Background(), stub.SignIDToken(with("iss", "https://evil.example")), "n", now)
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+
+	_, err = p.Verify(context.Background(), stub.SignIDToken(with("exp", now.Add(-time.Hour).Unix())), "n", now)
+	assert.ErrorIs(t, err, ErrIDTokenExpired)
+
+	// подпись другим ключом
+	other := oidctest.NewServer("gophermart", "")
+	defer other.Close()
+	_, err = p.Verify(context.Background(), other.SignIDToken(valid), "n", now)
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+}
+
+func TestNewProvider_IssuerMismatch(t *testing.T) {
+	stub := oidctest.NewServer("gophermart", "")
+	defer stub.Close()
+
+	_, err := NewProvider(context.Background(), Config{
+		IssuerURL:   stub.URL + "/other",
+		ClientID:    "gophermart",
+		RedirectURL: redirectURL,
+	}, stub.Client())
+	assert.Error(t, err)
+}
@@ -0,0 +1,93 @@
+package password
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DefaultCost - стоимость bcrypt по умолчанию
+const DefaultCost = bcrypt.DefaultCost
+
+// legacyHashLen - длина hex-строки старого несолёного SHA-256
+const legacyHashLen = sha256.Size * 2
+
+// Hasher - адаптивное хэширование паролей (bcrypt) с настраиваемой стоимостью
+type Hasher struct {
+	cost int
+	// dummy нужен, чтобы проверка несуществующего логина занимала столько же времени
+	dummy     []byte
+	dummyOnce sync.Once
+}
+
+func NewHasher(cost int) *Hasher {
+	if cost == 0 {
+		cost = DefaultCost
+	}
+	if cost < bcrypt.MinCost {
+		cost = bcrypt.MinCost
+	}
+	if cost > bcrypt.MaxCost {
+		cost = bcrypt.MaxCost
+	}
+
+	return &Hasher{cost: cost}
+}
+
+func (h *Hasher) Cost() int {
+	return h.cost
+}
+
+// Hash - солёный bcrypt-хэш пароля
+func (h *Hasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Verify сверяет пароль с сохранённым хэшем.
+// needsRehash = true, если хэш старого формата (SHA-256) или bcrypt с другой стоимостью
+func (h *Hasher) Verify(hash, password string) (ok bool, needsRehash bool) {
+	if IsLegacy(hash) {
+		expected := LegacyHash(password)
+		ok = subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(hash))) == 1
+		return ok, ok
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return false, false
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	return true, err != nil || cost != h.cost
+}
+
+// VerifyDummy тратит на проверку столько же времени, сколько Verify,
+// чтобы по времени ответа нельзя было понять, существует ли логин
+func (h *Hasher) VerifyDummy(password string) {
+	h.dummyOnce.Do(func() {
+		h.dummy, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), h.cost)
+	})
+	_ = bcrypt.CompareHashAndPassword(h.dummy, []byte(password))
+}
+
+// IsLegacy - хэш записан старой схемой (hex SHA-256 без соли)
+func IsLegacy(hash string) bool {
+	if len(hash) != legacyHashLen {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// LegacyHash - старая схема хэширования, нужна только для проверки не обновлённых записей
+func LegacyHash(password string) string {
+	hash := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(hash[:])
+}
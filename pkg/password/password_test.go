@@ -0,0 +1,110 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHasher_HashAndVerify(t *testing.T) {
+	h := NewHasher(bcrypt.MinCost)
+
+	hash, err := h.Hash("secret")
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(hash, "$2a$"))
+	assert.False(t, IsLegacy(hash))
+
+	// соль - два хэша одного пароля различаются
+	hash2, err := h.Hash("secret")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, hash2)
+
+	ok, needsRehash := h.Verify(hash, "secret")
+	assert.True(t, ok)
+	assert.False(t, needsRehash)
+
+	ok, _ = h.Verify(hash, "wrong")
+	assert.False(t, ok)
+}
+
+func TestHasher_VerifyLegacy(t *testing.T) {
+	h := NewHasher(bcrypt.MinCost)
+	legacy := LegacyHash("secret")
+
+	assert.True(t, IsLegacy(legacy))
+
+	ok, needsRehash := h.Verify(legacy, "secret")
+	assert.True(t, ok)
+	assert.True(t, needsRehash)
+
+	ok, needsRehash = h.Verify(legacy, "wrong")
+	assert.False(t, ok)
+	assert.False(t, needsRehash)
+}
+
+func TestHasher_VerifyCostChanged(t *testing.T) {
+	old := NewHasher(bcrypt.MinCost)
+	hash, err := old.Hash("secret")
+	require.NoError(t, err)
+
+	h := NewHasher(bcrypt.MinCost + 1)
+	ok, needsRehash := h.Verify(hash, "secret")
+	assert.True(t, ok)
+	assert.True(t, needsRehash)
+}
+
+func TestNewHasher_CostBounds(t *testing.T) {
+	assert.Equal(t, DefaultCost, NewHasher(0).Cost())
+	assert.Equal(t, bcrypt.MinCost, NewHasher(1).Cost())
+}
+
+func TestPolicy_Check(t *testing.T) {
+	p := NewPolicy(8, []string{"Password1", "qwertyuiop"})
+
+	rules := func(violations []Violation) []string {
+		var r []string
+		for _, v := range violations {
+			r = append(r, v.Rule)
+		}
+		return r
+	}
+
+	assert.Empty(t, p.Check("alice", "correct horse battery"))
+	assert.Equal(t, []string{RuleMinLength}, rules(p.Check("alice", "short")))
+	assert.Equal(t, []string{RuleDenyList}, rules(p.Check("alice", "PASSWORD1")))
+	assert.Equal(t, []string{RuleEqualsLogin}, rules(p.Check("LongLogin", "longlogin")))
+	assert.Equal(t, []string{RuleMinLength, RuleEqualsLogin}, rules(p.Check("bob", "bob")))
+	assert.Equal(t, []string{RuleRequired}, rules(p.Check("bob", "")))
+	// длина считается в символах, а не в байтах
+	assert.Equal(t, []string{RuleMinLength}, rules(p.Check("alice", "пароль")))
+	// а верхняя граница - в байтах, как у bcrypt
+	assert.Empty(t, p.Check("alice", strings.Repeat("a", MaxBytes)))
+	assert.Equal(t, []string{RuleMaxLength}, rules(p.Check("alice", strings.Repeat("я", MaxBytes/2+1))))
+}
+
+func TestDefaultPolicy(t *testing.T) {
+	p := DefaultPolicy()
+
+	// короткие пароли по умолчанию принимаются, как до появления политики
+	assert.Empty(t, p.Check("alice", "123"))
+	assert.Equal(t, RuleRequired, p.Check("alice", "")[0].Rule)
+	assert.Equal(t, RuleMaxLength, p.Check("alice", strings.Repeat("a", MaxBytes+1))[0].Rule)
+}
+
+func TestLoadDenyList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "common.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# top passwords\n123456\n\n  qwerty  \n"), 0o600))
+
+	list, err := LoadDenyList(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"123456", "qwerty"}, list)
+
+	_, err = LoadDenyList(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
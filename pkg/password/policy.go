@@ -0,0 +1,108 @@
+package password
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultMinLength - 0, минимальной длины нет, как до появления политики: иначе клиенты,
+// регистрировавшиеся с короткими паролями, начнут получать 400. Включается флагом -password-min-length
+const DefaultMinLength = 0
+
+// MaxBytes - bcrypt учитывает только первые 72 байта и отказывается хэшировать пароль длиннее
+const MaxBytes = 72
+
+// правила политики паролей
+const (
+	RuleMinLength   = "min_length"
+	RuleMaxLength   = "max_length"
+	RuleDenyList    = "common_password"
+	RuleEqualsLogin = "equals_login"
+	RuleRequired    = "required"
+)
+
+// Violation - нарушенное правило политики
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Policy - требования к новому паролю
+type Policy struct {
+	MinLength int
+	// распространённые пароли в нижнем регистре
+	denyList map[string]struct{}
+}
+
+func NewPolicy(minLength int, denyList []string) *Policy {
+	p := &Policy{MinLength: minLength, denyList: make(map[string]struct{}, len(denyList))}
+	for _, pw := range denyList {
+		p.denyList[strings.ToLower(pw)] = struct{}{}
+	}
+	return p
+}
+
+// DefaultPolicy - без минимальной длины и списка запрещённых паролей
+func DefaultPolicy() *Policy {
+	return NewPolicy(DefaultMinLength, nil)
+}
+
+// Check возвращает все нарушенные правила, пустой результат - пароль подходит
+func (p *Policy) Check(login, password string) []Violation {
+	if password == "" {
+		return []Violation{{Rule: RuleRequired, Message: "password is required"}}
+	}
+
+	var violations []Violation
+	if p.MinLength > 0 && utf8.RuneCountInString(password) < p.MinLength {
+		violations = append(violations, Violation{
+			Rule:    RuleMinLength,
+			Message: fmt.Sprintf("password must be at least %d characters long", p.MinLength),
+		})
+	}
+	if len(password) > MaxBytes {
+		violations = append(violations, Violation{
+			Rule:    RuleMaxLength,
+			Message: fmt.Sprintf("password must be at most %d bytes long", MaxBytes),
+		})
+	}
+	if _, ok := p.denyList[strings.ToLower(password)]; ok {
+		violations = append(violations, Violation{
+			Rule:    RuleDenyList,
+			Message: "password is too common",
+		})
+	}
+	if login != "" && strings.EqualFold(login, password) {
+		violations = append(violations, Violation{
+			Rule:    RuleEqualsLogin,
+			Message: "password must not be equal to login",
+		})
+	}
+	return violations
+}
+
+// LoadDenyList читает список запрещённых паролей: по одному на строку, # - комментарий
+func LoadDenyList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open password deny-list: %w", err)
+	}
+	defer f.Close()
+
+	var list []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list = append(list, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read password deny-list: %w", err)
+	}
+	return list, nil
+}
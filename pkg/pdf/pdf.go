@@ -0,0 +1,211 @@
+// Package pdf - минимальный генератор PDF: страницы A4 со строками текста стандартными шрифтами
+// Courier и Helvetica-Bold. Заполненная страница сразу уходит в writer, в памяти остаются
+// только смещения объектов, поэтому документ любой длины не собирается целиком
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// размеры страницы A4 и поля, в пунктах
+const (
+	PageWidth  = 595
+	PageHeight = 842
+	margin     = 50
+	fontSize   = 10
+	leading    = 14
+)
+
+// ErrClosed - запись в уже закрытый документ
+var ErrClosed = errors.New("pdf: document is closed")
+
+// объекты, номера которых известны заранее: страницы пишутся раньше дерева страниц
+const (
+	catalogID = 1
+	pagesID   = 2
+	regularID = 3
+	boldID    = 4
+	firstFree = 5
+)
+
+// Writer пишет документ построчно. Ошибка записи запоминается и возвращается из Close
+type Writer struct {
+	w       *bufio.Writer
+	written int64
+	offsets map[int]int64
+	nextID  int
+	pages   []int
+	page    bytes.Buffer
+	y       int
+	inPage  bool
+	closed  bool
+	err     error
+}
+
+func New(w io.Writer) *Writer {
+	p := &Writer{
+		w:       bufio.NewWriter(w),
+		offsets: make(map[int]int64),
+		nextID:  firstFree,
+	}
+	// второй строкой - байты старше 127, чтобы файл считался двоичным
+	p.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+	return p
+}
+
+// Line добавляет строку моноширинным шрифтом, колонки можно выравнивать пробелами
+func (p *Writer) Line(text string) {
+	p.text("F1", text)
+}
+
+// Bold добавляет строку заголовка
+func (p *Writer) Bold(text string) {
+	p.text("F2", text)
+}
+
+// Space - пустая строка
+func (p *Writer) Space() {
+	p.text("F1", "")
+}
+
+// NewPage начинает новую страницу, если на текущей уже что-то есть
+func (p *Writer) NewPage() {
+	if p.inPage && p.y < PageHeight-margin-fontSize {
+		p.flushPage()
+	}
+}
+
+func (p *Writer) text(font, text string) {
+	if p.closed || p.err != nil {
+		return
+	}
+	if !p.inPage {
+		p.inPage = true
+		p.y = PageHeight - margin - fontSize
+		p.page.Reset()
+	}
+	if text != "" {
+		fmt.Fprintf(&p.page, "BT /%s %d Tf %d %d Td (%s) Tj ET\n", font, fontSize, margin, p.y, escape(text))
+	}
+	p.y -= leading
+	if p.y < margin {
+		p.flushPage()
+	}
+}
+
+// flushPage пишет поток содержимого и объект страницы
+func (p *Writer) flushPage() {
+	contentID := p.alloc()
+	p.beginObject(contentID)
+	p.printf("<< /Length %d >>\nstream\n", p.page.Len())
+	p.write(p.page.Bytes())
+	p.printf("\nendstream\nendobj\n")
+
+	pageID := p.alloc()
+	p.beginObject(pageID)
+	p.printf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>\nendobj\n",
+		pagesID, PageWidth, PageHeight, regularID, boldID, contentID)
+	p.pages = append(p.pages, pageID)
+
+	p.inPage = false
+	p.page.Reset()
+}
+
+// Close дописывает шрифты, дерево страниц и таблицу ссылок. Writer под ним не закрывается
+func (p *Writer) Close() error {
+	if p.closed {
+		return ErrClosed
+	}
+	if p.inPage || len(p.pages) == 0 {
+		// пустой документ - одна пустая страница
+		p.inPage = true
+		p.flushPage()
+	}
+	p.closed = true
+
+	p.beginObject(regularID)
+	p.printf("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>\nendobj\n")
+	p.beginObject(boldID)
+	p.printf("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>\nendobj\n")
+
+	kids := make([]string, len(p.pages))
+	for i, id := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", id)
+	}
+	p.beginObject(pagesID)
+	p.printf("<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), len(p.pages))
+	p.beginObject(catalogID)
+	p.printf("<< /Type /Catalog /Pages %d 0 R >>\nendobj\n", pagesID)
+
+	xref := p.written
+	p.printf("xref\n0 %d\n0000000000 65535 f \n", p.nextID)
+	for id := 1; id < p.nextID; id++ {
+		p.printf("%010d 00000 n \n", p.offsets[id])
+	}
+	p.printf("trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", p.nextID, catalogID, xref)
+
+	if p.err != nil {
+		return p.err
+	}
+	return p.w.Flush()
+}
+
+// Err - первая ошибка записи, чтобы не готовить строки для writer, который уже не принимает данные
+func (p *Writer) Err() error {
+	return p.err
+}
+
+// Pages - сколько страниц уже записано
+func (p *Writer) Pages() int {
+	return len(p.pages)
+}
+
+func (p *Writer) alloc() int {
+	id := p.nextID
+	p.nextID++
+	return id
+}
+
+func (p *Writer) beginObject(id int) {
+	p.offsets[id] = p.written
+	p.printf("%d 0 obj\n", id)
+}
+
+func (p *Writer) printf(format string, args ...any) {
+	p.write([]byte(fmt.Sprintf(format, args...)))
+}
+
+func (p *Writer) write(b []byte) {
+	if p.err != nil {
+		return
+	}
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	p.err = err
+}
+
+// escape готовит строку для литерала PDF в кодировке WinAnsi: символы вне Latin-1 заменяются на '?'
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32:
+			b.WriteByte(' ')
+		case r < 127:
+			b.WriteRune(r)
+		case r >= 160 && r <= 255:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
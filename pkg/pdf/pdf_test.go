@@ -0,0 +1,63 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	doc := New(&buf)
+	doc.Bold("Statement")
+	for i := 0; i < 120; i++ {
+		doc.Line(fmt.Sprintf("line %d (total)", i))
+	}
+	require.NoError(t, doc.Close())
+
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "%PDF-1.4\n"))
+	assert.True(t, strings.HasSuffix(out, "%%EOF\n"))
+	// 53 строки на страницу
+	assert.Equal(t, 3, doc.Pages())
+	assert.Contains(t, out, "/Count 3")
+	assert.Contains(t, out, `(line 7 \(total\)) Tj`)
+
+	// каждая запись таблицы ссылок указывает на начало своего объекта
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(out)
+	require.Len(t, startxref, 2)
+	xref, err := strconv.Atoi(startxref[1])
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(out[xref:], "xref\n"))
+
+	lines := strings.Split(out[xref:], "\n")
+	var size int
+	_, err = fmt.Sscanf(lines[1], "0 %d", &size)
+	require.NoError(t, err)
+	for id := 1; id < size; id++ {
+		offset, err := strconv.Atoi(lines[2+id][:10])
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(out[offset:], fmt.Sprintf("%d 0 obj\n", id)), "object %d", id)
+	}
+}
+
+func TestWriterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	doc := New(&buf)
+	require.NoError(t, doc.Close())
+
+	assert.Equal(t, 1, doc.Pages())
+	assert.ErrorIs(t, doc.Close(), ErrClosed)
+}
+
+func TestEscape(t *testing.T) {
+	assert.Equal(t, `a\\b \(c\)`, escape(`a\b (c)`))
+	assert.Equal(t, `caf\351 ???`, escape("café баг"))
+	assert.Equal(t, "tab here", escape("tab\there"))
+}